@@ -18,7 +18,11 @@ func main() {
 	//mask := &mcworld.AllChunksMask{}
 	mask := &mcworld.RectangleChunkMask{-100, -100, 100, 100}
 
-	world := mcworld.OpenWorld(dir)
+	world, err := mcworld.OpenWorld(dir)
+	if err != nil {
+		fmt.Println("OpenWorld:", err)
+		return
+	}
 	chunks, box, err := ZigZagChunks(world, mask)
 	if err != nil {
 		fmt.Println("ZigZagChunks:", err)
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"github.com/quag/mcobj/resourcepack"
+)
+
+// The front of a banner's texture is 20 by 40 texels, a sixteenth of a
+// block each.
+const (
+	bannerWidth  = 20
+	bannerHeight = 40
+)
+
+// bannerPatterns are which texels of a banner's texture each pattern
+// covers, x from the left and y from the top. The game draws patterns from
+// textures; the shapes here follow them, with the pictures drawn small in
+// the middle of the banner.
+var bannerPatterns = map[string]func(x, y int) bool{
+	"base":                   func(x, y int) bool { return true },
+	"stripe_bottom":          func(x, y int) bool { return 3*y >= 2*bannerHeight },
+	"stripe_top":             func(x, y int) bool { return 3*y < bannerHeight },
+	"stripe_left":            func(x, y int) bool { return 3*x < bannerWidth },
+	"stripe_right":           func(x, y int) bool { return 3*(x+1) > 2*bannerWidth },
+	"stripe_center":          func(x, y int) bool { return 3*x >= bannerWidth && 3*(x+1) <= 2*bannerWidth },
+	"stripe_middle":          func(x, y int) bool { return 3*y >= bannerHeight && 3*y < 2*bannerHeight },
+	"stripe_downright":       func(x, y int) bool { return abs(2*x-y) <= 3 },
+	"stripe_downleft":        func(x, y int) bool { return abs(2*(bannerWidth-1-x)-y) <= 3 },
+	"small_stripes":          func(x, y int) bool { return x%4 == 1 || x%4 == 2 },
+	"cross":                  func(x, y int) bool { return abs(2*x-y) <= 3 || abs(2*(bannerWidth-1-x)-y) <= 3 },
+	"straight_cross":         func(x, y int) bool { return x >= 8 && x < 12 || y >= 18 && y < 22 },
+	"triangle_bottom":        func(x, y int) bool { return bannerHeight-1-y < bannerWidth/2-abs(2*x+1-bannerWidth)/2 },
+	"triangle_top":           func(x, y int) bool { return y < bannerWidth/2-abs(2*x+1-bannerWidth)/2 },
+	"triangles_bottom":       func(x, y int) bool { return bannerHeight-1-y < 3-abs(x%5-2) },
+	"triangles_top":          func(x, y int) bool { return y < 3-abs(x%5-2) },
+	"diagonal_left":          func(x, y int) bool { return 2*x+y < bannerHeight-1 },
+	"diagonal_right":         func(x, y int) bool { return 2*x > y },
+	"diagonal_up_left":       func(x, y int) bool { return 2*x < y },
+	"diagonal_up_right":      func(x, y int) bool { return 2*x+y > bannerHeight-1 },
+	"circle":                 func(x, y int) bool { return sq(2*x+1-bannerWidth)+sq(2*y+1-bannerHeight) <= 8*8 },
+	"rhombus":                func(x, y int) bool { return 2*abs(2*x+1-bannerWidth)+abs(2*y+1-bannerHeight) <= 20 },
+	"half_vertical":          func(x, y int) bool { return 2*x < bannerWidth },
+	"half_vertical_right":    func(x, y int) bool { return 2*x >= bannerWidth },
+	"half_horizontal":        func(x, y int) bool { return 2*y < bannerHeight },
+	"half_horizontal_bottom": func(x, y int) bool { return 2*y >= bannerHeight },
+	"square_top_left":        func(x, y int) bool { return 3*x < bannerWidth && 4*y < bannerHeight },
+	"square_top_right":       func(x, y int) bool { return 3*(x+1) > 2*bannerWidth && 4*y < bannerHeight },
+	"square_bottom_left":     func(x, y int) bool { return 3*x < bannerWidth && 4*(y+1) > 3*bannerHeight },
+	"square_bottom_right":    func(x, y int) bool { return 3*(x+1) > 2*bannerWidth && 4*(y+1) > 3*bannerHeight },
+	"border": func(x, y int) bool {
+		return x == 0 || y == 0 || x == bannerWidth-1 || y == bannerHeight-1
+	},
+	"curly_border": func(x, y int) bool {
+		var edge = x
+		for _, d := range [3]int{y, bannerWidth - 1 - x, bannerHeight - 1 - y} {
+			if d < edge {
+				edge = d
+			}
+		}
+		return edge == 0 || edge == 1 && (x+y)%2 == 0
+	},
+	"bricks": func(x, y int) bool {
+		return y%4 == 3 || (x+y/4%2*4)%8 == 7
+	},
+	// Fading from the pattern's color at the top to the base color at the
+	// bottom, dithered, as there are only the 16 colors to draw with
+	"gradient":    func(x, y int) bool { return bayer(x, y) < 16*(bannerHeight-y)/bannerHeight },
+	"gradient_up": func(x, y int) bool { return bayer(x, y) < 16*(y+1)/bannerHeight },
+	"globe": picture(
+		"....####....",
+		"..##.##.##..",
+		".#..#..#..#.",
+		".##########.",
+		"#..#....#..#",
+		"############",
+		"#..#....#..#",
+		".##########.",
+		".#..#..#..#.",
+		"..##.##.##..",
+		"....####....",
+	),
+	"creeper": picture(
+		"............",
+		".###....###.",
+		".###....###.",
+		".###....###.",
+		"....####....",
+		"...######...",
+		"...######...",
+		"...##..##...",
+		"...##..##...",
+	),
+	"skull": picture(
+		"...######...",
+		"..########..",
+		"..#..##..#..",
+		"..#..##..#..",
+		"..########..",
+		"...#.##.#...",
+		"#..######..#",
+		".##......##.",
+		"...##..##...",
+		"....####....",
+		"...##..##...",
+		".##......##.",
+		"#..........#",
+	),
+	"flower": picture(
+		"....####....",
+		"...#....#...",
+		".###.##.###.",
+		"#...#..#...#",
+		"#..#....#..#",
+		".##......##.",
+		".##......##.",
+		"#..#....#..#",
+		"#...#..#...#",
+		".###.##.###.",
+		"...#....#...",
+		"....####....",
+	),
+	"mojang": picture(
+		"#..........#",
+		"##........##",
+		"#.#......#.#",
+		"#..#....#..#",
+		"#...#..#...#",
+		"#....##....#",
+		"#..........#",
+		"#..........#",
+	),
+	"piglin": picture(
+		"..########..",
+		".##########.",
+		".##..##..##.",
+		".##..##..##.",
+		".##########.",
+		"..########..",
+		"...#....#...",
+	),
+	"flow": picture(
+		"############",
+		"#..........#",
+		"#.########.#",
+		"#.#......#.#",
+		"#.#.####.#.#",
+		"#.#.#..#.#.#",
+		"#.#.#.##.#.#",
+		"#.#.#....#.#",
+		"#.#.######.#",
+		"#.#........#",
+		"#.##########",
+	),
+	"guster": picture(
+		"...######...",
+		"..#......#..",
+		".#..####..#.",
+		".#.#....#.#.",
+		".#.#.##.#.#.",
+		".#.#..#.#.#.",
+		".#..##..#.#.",
+		"..#....#..#.",
+		"...####..#..",
+		"........#...",
+	),
+}
+
+// picture is a pattern drawn with a # for each texel it covers, in the
+// middle of the banner.
+func picture(rows ...string) func(x, y int) bool {
+	var left, top = (bannerWidth - len(rows[0])) / 2, (bannerHeight - len(rows)) / 2
+	return func(x, y int) bool {
+		x, y = x-left, y-top
+		return y >= 0 && y < len(rows) && x >= 0 && x < len(rows[y]) && rows[y][x] == '#'
+	}
+}
+
+// bayer is the threshold, from 0 to 15, a texel is dithered at.
+func bayer(x, y int) int {
+	var matrix = [4][4]int{{0, 8, 2, 10}, {12, 4, 14, 6}, {3, 11, 1, 9}, {15, 7, 13, 5}}
+	return matrix[y%4][x%4]
+}
+
+func sq(i int) int {
+	return i * i
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// bannerTexels are the colors of the texels of the front of a banner: its
+// base color, with each pattern layered over the last.
+func bannerTexels(banner *nbt.Banner) [bannerHeight][bannerWidth]int {
+	var texels [bannerHeight][bannerWidth]int
+	for y := range texels {
+		for x := range texels[y] {
+			texels[y][x] = banner.Base
+			for _, p := range banner.Patterns {
+				if covers := bannerPatterns[p.Pattern]; covers != nil && covers(x, y) {
+					texels[y][x] = p.Color
+				}
+			}
+		}
+	}
+	return texels
+}
+
+// banner is what's on the banner at a site, or a white banner if its
+// block entity wasn't saved.
+func (s blockSite) banner() *nbt.Banner {
+	if s.e.chunk != nil {
+		if e := s.e.chunk.BlockEntity(s.x, s.y+s.e.blocks.minY, s.z); e != nil && e.Banner != nil {
+			return e.Banner
+		}
+	}
+	return new(nbt.Banner)
+}
+
+var (
+	bannerFrontFaces = map[string]resourcepack.Face{"north": {}, "south": {}}
+	bannerEdgeFaces  = map[string]resourcepack.Face{"down": {}, "up": {}, "west": {}, "east": {}}
+)
+
+// addBanner adds a banner: its pole, or the bar it hangs from on a wall,
+// in oak, and its cloth in its base color, with its patterns layered over
+// the front and back a rectangle of texels of the same color at a time.
+// The cloth's material is that of a banner of each color.
+func (fs *Faces) addBanner(site blockSite, blockId nbt.Block, model []packModel) {
+	var wood, cloth = bannerElements, bannerCloth
+	if blockId&0xff == 177 {
+		wood, cloth = wallBannerElements, wallBannerCloth
+	}
+	fs.addPackModel(site, 5, turnLike(model, wood))
+
+	var banner = site.banner()
+	var edges = cloth
+	edges.Faces = bannerEdgeFaces
+	fs.addPackModel(site, bannerBlock(banner.Base), turnLike(model, []resourcepack.Element{edges}))
+
+	var texels = bannerTexels(banner)
+	var done [bannerHeight][bannerWidth]bool
+	var byColor [16][]resourcepack.Element
+	for y := 0; y < bannerHeight; y++ {
+		for x := 0; x < bannerWidth; x++ {
+			if done[y][x] {
+				continue
+			}
+			var color = texels[y][x]
+			var w, h = 1, 1
+			for x+w < bannerWidth && !done[y][x+w] && texels[y][x+w] == color {
+				w++
+			}
+		grow:
+			for y+h < bannerHeight {
+				for i := x; i < x+w; i++ {
+					if done[y+h][i] || texels[y+h][i] != color {
+						break grow
+					}
+				}
+				h++
+			}
+			for j := y; j < y+h; j++ {
+				for i := x; i < x+w; i++ {
+					done[j][i] = true
+				}
+			}
+
+			byColor[color] = append(byColor[color], resourcepack.Element{
+				From:  [3]float64{cloth.From[0] + float64(x), cloth.To[1] - float64(y+h), cloth.From[2]},
+				To:    [3]float64{cloth.From[0] + float64(x+w), cloth.To[1] - float64(y), cloth.To[2]},
+				Faces: bannerFrontFaces,
+			})
+		}
+	}
+	for color, elements := range byColor {
+		if len(elements) != 0 {
+			fs.addPackModel(site, bannerBlock(color), turnLike(model, elements))
+		}
+	}
+}
+
+// bannerBlock is the block whose material is the cloth of a banner of a
+// color.
+func bannerBlock(color int) nbt.Block {
+	return 176 + nbt.Block(color)<<8
+}
+
+// turnLike turns elements the way a block's model from turnedModels is
+// turned.
+func turnLike(model []packModel, elements []resourcepack.Element) []packModel {
+	var turned = make([]resourcepack.Element, len(elements))
+	for i, e := range elements {
+		e.Rotation = model[0].elements[0].Rotation
+		turned[i] = e
+	}
+	return []packModel{{turned, model[0].x, model[0].y}}
+}
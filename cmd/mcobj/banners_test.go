@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestBannerTexels(t *testing.T) {
+	// Red, with a blue stripe along the bottom and a black border over it
+	var texels = bannerTexels(&nbt.Banner{14, []nbt.BannerPattern{{"stripe_bottom", 11}, {"border", 15}, {"no_such_pattern", 4}}})
+	for _, c := range []struct{ x, y, color int }{
+		{10, 10, 14},
+		{10, 30, 11},
+		{0, 30, 15},
+		{10, 39, 15},
+		{10, 0, 15},
+	} {
+		if color := texels[c.y][c.x]; color != c.color {
+			t.Errorf("texel %d,%d is %d not %d", c.x, c.y, color, c.color)
+		}
+	}
+}
+
+func TestBannerFaces(t *testing.T) {
+	// A red banner facing south with a blue stripe along its bottom
+	var e, fs = testChunk(t, 4, emptySide, func(x, y, z int) nbt.Block {
+		if x == 8 && y == 0 && z == 8 {
+			return 176
+		}
+		return 0
+	})
+	e.chunk.BlockEntities = []nbt.BlockEntity{{Id: "minecraft:banner", X: 8, Y: 0, Z: 8,
+		Banner: &nbt.Banner{14, []nbt.BannerPattern{{"stripe_bottom", 11}}}}}
+	processTestChunk(fs, e)
+
+	// By material, the lowest and highest corner of the front faces
+	var lo, hi = make(map[nbt.Block]int), make(map[nbt.Block]int)
+	var wood = false
+	for i, face := range faceCorners(fs) {
+		var blockId = fs.faces[i].blockId
+		if blockId == 5 {
+			wood = true
+			continue
+		}
+		if blockId&0xff != 176 {
+			t.Errorf("face of %#x", blockId)
+			continue
+		}
+		if face[0][2] != face[2][2] || face[0][2] != 16*8+10 {
+			continue // Not on the front
+		}
+		for _, p := range face {
+			if y, ok := lo[blockId]; !ok || p[1] < y {
+				lo[blockId] = p[1]
+			}
+			if y, ok := hi[blockId]; !ok || p[1] > y {
+				hi[blockId] = p[1]
+			}
+		}
+	}
+
+	if !wood {
+		t.Error("no pole")
+	}
+	// 40 texels of cloth from 2 sixteenths up, the bottom 13 blue
+	var red, blue = bannerBlock(14), bannerBlock(11)
+	if lo[blue] != 2 || hi[blue] != 2+13 {
+		t.Errorf("blue from %d to %d", lo[blue], hi[blue])
+	}
+	if lo[red] != 2+13 || hi[red] != 2+40 {
+		t.Errorf("red from %d to %d", lo[red], hi[red])
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The colors of a biome that blocks are tinted by, as the game tints their
+// gray textures.
+const (
+	noTint = iota
+	grassTint
+	foliageTint
+	waterTint
+)
+
+// biomeTint is the grass, foliage and water colors of biomes, as 0xrrggbb,
+// and the name of the first biome with them, which the materials tinted by
+// them are named after.
+type biomeTint struct {
+	name   string
+	colors [4]uint32 // By the tints above
+}
+
+// biomeClimate is what the game works out the colors of a biome from: grass
+// and foliage from the colormaps, by temperature and downfall, unless the
+// biome has colors of its own, and water by the biome.
+type biomeClimate struct {
+	temperature, downfall float64
+	water                 uint32
+	grass, foliage        uint32 // 0 for the colormaps'
+}
+
+const defaultWater = 0x3f76e4
+
+// biomeClimates are the biomes of the overworld, by their names since 1.18
+// and those they had from 1.13, which -biomes tints blocks by. Biomes that
+// aren't here are tinted like plains.
+var biomeClimates = map[string]biomeClimate{
+	"plains":                   {0.8, 0.4, defaultWater, 0, 0},
+	"sunflower_plains":         {0.8, 0.4, defaultWater, 0, 0},
+	"snowy_plains":             {0, 0.5, defaultWater, 0, 0},
+	"snowy_tundra":             {0, 0.5, defaultWater, 0, 0},
+	"ice_spikes":               {0, 0.5, defaultWater, 0, 0},
+	"desert":                   {2, 0, defaultWater, 0, 0},
+	"swamp":                    {0.8, 0.9, 0x617b64, 0x6a7039, 0x6a7039},
+	"swamp_hills":              {0.8, 0.9, 0x617b64, 0x6a7039, 0x6a7039},
+	"mangrove_swamp":           {0.8, 0.9, 0x3a7a6a, 0x6a7039, 0x8db127},
+	"forest":                   {0.7, 0.8, defaultWater, 0, 0},
+	"flower_forest":            {0.7, 0.8, defaultWater, 0, 0},
+	"birch_forest":             {0.6, 0.6, defaultWater, 0, 0},
+	"old_growth_birch_forest":  {0.6, 0.6, defaultWater, 0, 0},
+	"tall_birch_forest":        {0.6, 0.6, defaultWater, 0, 0},
+	"dark_forest":              {0.7, 0.8, defaultWater, 0x487b30, 0},
+	"dark_forest_hills":        {0.7, 0.8, defaultWater, 0x487b30, 0},
+	"taiga":                    {0.25, 0.8, defaultWater, 0, 0},
+	"old_growth_pine_taiga":    {0.3, 0.8, defaultWater, 0, 0},
+	"giant_tree_taiga":         {0.3, 0.8, defaultWater, 0, 0},
+	"old_growth_spruce_taiga":  {0.25, 0.8, defaultWater, 0, 0},
+	"giant_spruce_taiga":       {0.25, 0.8, defaultWater, 0, 0},
+	"snowy_taiga":              {-0.5, 0.4, 0x3d57d6, 0, 0},
+	"savanna":                  {2, 0, defaultWater, 0, 0},
+	"savanna_plateau":          {2, 0, defaultWater, 0, 0},
+	"windswept_savanna":        {2, 0, defaultWater, 0, 0},
+	"shattered_savanna":        {2, 0, defaultWater, 0, 0},
+	"windswept_hills":          {0.2, 0.3, defaultWater, 0, 0},
+	"windswept_gravelly_hills": {0.2, 0.3, defaultWater, 0, 0},
+	"windswept_forest":         {0.2, 0.3, defaultWater, 0, 0},
+	"mountains":                {0.2, 0.3, defaultWater, 0, 0},
+	"gravelly_mountains":       {0.2, 0.3, defaultWater, 0, 0},
+	"wooded_mountains":         {0.2, 0.3, defaultWater, 0, 0},
+	"jungle":                   {0.95, 0.9, defaultWater, 0, 0},
+	"jungle_hills":             {0.95, 0.9, defaultWater, 0, 0},
+	"bamboo_jungle":            {0.95, 0.9, defaultWater, 0, 0},
+	"sparse_jungle":            {0.95, 0.8, defaultWater, 0, 0},
+	"jungle_edge":              {0.95, 0.8, defaultWater, 0, 0},
+	"badlands":                 {2, 0, defaultWater, 0x90814d, 0x9e814d},
+	"eroded_badlands":          {2, 0, defaultWater, 0x90814d, 0x9e814d},
+	"wooded_badlands":          {2, 0, defaultWater, 0x90814d, 0x9e814d},
+	"wooded_badlands_plateau":  {2, 0, defaultWater, 0x90814d, 0x9e814d},
+	"badlands_plateau":         {2, 0, defaultWater, 0x90814d, 0x9e814d},
+	"meadow":                   {0.5, 0.8, 0x0e4ecf, 0, 0},
+	"cherry_grove":             {0.5, 0.8, 0x5db7ef, 0xb6db61, 0xb6db61},
+	"grove":                    {-0.2, 0.8, defaultWater, 0, 0},
+	"snowy_slopes":             {-0.3, 0.9, defaultWater, 0, 0},
+	"frozen_peaks":             {-0.7, 0.9, defaultWater, 0, 0},
+	"jagged_peaks":             {-0.7, 0.9, defaultWater, 0, 0},
+	"stony_peaks":              {1, 0.3, defaultWater, 0, 0},
+	"river":                    {0.5, 0.5, defaultWater, 0, 0},
+	"frozen_river":             {0, 0.5, 0x3938c9, 0, 0},
+	"beach":                    {0.8, 0.4, defaultWater, 0, 0},
+	"snowy_beach":              {0.05, 0.3, 0x3d57d6, 0, 0},
+	"stony_shore":              {0.2, 0.3, defaultWater, 0, 0},
+	"ocean":                    {0.5, 0.5, defaultWater, 0, 0},
+	"deep_ocean":               {0.5, 0.5, defaultWater, 0, 0},
+	"warm_ocean":               {0.5, 0.5, 0x43d5ee, 0, 0},
+	"lukewarm_ocean":           {0.5, 0.5, 0x45adf2, 0, 0},
+	"deep_lukewarm_ocean":      {0.5, 0.5, 0x45adf2, 0, 0},
+	"cold_ocean":               {0.5, 0.5, 0x3d57d6, 0, 0},
+	"deep_cold_ocean":          {0.5, 0.5, 0x3d57d6, 0, 0},
+	"frozen_ocean":             {0, 0.5, 0x3938c9, 0, 0},
+	"deep_frozen_ocean":        {0.5, 0.5, 0x3938c9, 0, 0},
+	"mushroom_fields":          {0.9, 1, defaultWater, 0, 0},
+	"lush_caves":               {0.5, 0.5, defaultWater, 0, 0},
+}
+
+// The corners of the grass and foliage colormaps: hot and wet, hot and dry,
+// and cold. Colors between them are blended from them, which is near enough
+// to the colormaps' own.
+var (
+	grassColormap   = [3]uint32{0x47cd33, 0xbfb755, 0x80b497}
+	foliageColormap = [3]uint32{0x1abf00, 0xaea42a, 0x60a17b}
+)
+
+// colormap is the color of a colormap at a temperature and downfall.
+func colormap(corners [3]uint32, temperature, downfall float64) uint32 {
+	var t = clampFloat(temperature)
+	var d = clampFloat(downfall) * t
+	var weights = [3]float64{d, t - d, 1 - t}
+	var color uint32
+	for shift := uint(0); shift < 24; shift += 8 {
+		var c = 0.0
+		for i, corner := range corners {
+			c += weights[i] * float64(corner>>shift&0xff)
+		}
+		color |= uint32(c+0.5) << shift
+	}
+	return color
+}
+
+func clampFloat(f float64) float64 {
+	switch {
+	case f < 0:
+		return 0
+	case f > 1:
+		return 1
+	}
+	return f
+}
+
+func (c biomeClimate) colors() [4]uint32 {
+	var colors = [4]uint32{0, c.grass, c.foliage, c.water}
+	if colors[grassTint] == 0 {
+		colors[grassTint] = colormap(grassColormap, c.temperature, c.downfall)
+	}
+	if colors[foliageTint] == 0 {
+		colors[foliageTint] = colormap(foliageColormap, c.temperature, c.downfall)
+	}
+	return colors
+}
+
+// biomeTints are the different tints of the biomes, numbered from 1, as
+// the tint of plains, which blocks.json's colors are, needs no materials of
+// its own. biomeTintNumbers has the number of the tint of each biome,
+// or 0 for that of plains.
+var (
+	biomeTints       = []biomeTint{{"plains", biomeClimates["plains"].colors()}}
+	biomeTintNumbers = make(map[string]int)
+)
+
+func init() {
+	var names = make([]string, 0, len(biomeClimates))
+	for name := range biomeClimates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var numbers = map[[4]uint32]int{biomeTints[0].colors: 0}
+	for _, name := range names {
+		var tint = biomeClimates[name].colors()
+		var number, ok = numbers[tint]
+		if !ok {
+			number = len(biomeTints)
+			numbers[tint] = number
+			biomeTints = append(biomeTints, biomeTint{name, tint})
+		}
+		if number != 0 {
+			biomeTintNumbers["minecraft:"+name] = number
+		}
+	}
+}
+
+// tintOf is the biome color a block is tinted by. Spruce and birch leaves
+// have colors of their own, and dead shrubs aren't tinted.
+func tintOf(blockId nbt.Block) int {
+	switch blockId & 0xff {
+	case 2:
+		return grassTint
+	case 31:
+		if blockId>>8&0xf != 0 {
+			return grassTint
+		}
+	case 18:
+		if data := blockId >> 8 & 3; data != 1 && data != 2 {
+			return foliageTint
+		}
+	case 161, 106:
+		return foliageTint
+	case 8, 9:
+		return waterTint
+	}
+	return noTint
+}
+
+// biomeAt is the number of the tint of the biome at x,y,z, when -biomes is
+// on.
+func (fs *Faces) biomeAt(x, y, z int) int {
+	if !biomes {
+		return 0
+	}
+	return biomeTintNumbers[fs.enclosed.Biome(x, y, z)]
+}
+
+// tintedName is the name of the material of a block in a biome's tint.
+func tintedName(blockId nbt.Block, biome int) string {
+	var name = MaterialNamer.NameBlockId(blockId)
+	if biome != 0 {
+		name += "." + biomeTints[biome].name
+	}
+	return name
+}
+
+// printTintedMtls prints the materials of the blocks tinted by their biome,
+// in each tint but that of plains. Their colors are those of blocks.json
+// scaled by how much the tint differs from that of plains.
+func printTintedMtls(w io.Writer) {
+	for biome := 1; biome < len(biomeTints); biome++ {
+		for _, mtl := range colors {
+			var tint = tintOf(mtl.colorId())
+			if tint == noTint || strings.HasPrefix(mtl.name, "Unknown.") {
+				continue
+			}
+			var blockId = nbt.Block(mtl.blockId) + nbt.Block(mtl.metadata)*256
+			mtl.print(w, tintedName(blockId, biome), tinted(mtl.color, biomeTints[0].colors[tint], biomeTints[biome].colors[tint]))
+		}
+	}
+}
+
+// tinted scales a 0xrrggbbaa color by how much a 0xrrggbb tint differs from
+// the one the color is in.
+func tinted(color, from, to uint32) uint32 {
+	var result = color & 0xff
+	for shift := uint(8); shift < 32; shift += 8 {
+		var c, f, t = color >> shift & 0xff, from >> (shift - 8) & 0xff, to >> (shift - 8) & 0xff
+		var scaled = c * t / f
+		if scaled > 0xff {
+			scaled = 0xff
+		}
+		result |= scaled << shift
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestColormap(t *testing.T) {
+	var corners = [3]uint32{0x102030, 0x405060, 0x708090}
+	for _, c := range []struct {
+		temperature, downfall float64
+		color                 uint32
+	}{
+		{1, 1, 0x102030},
+		{2, 0, 0x405060},
+		{-1, 1, 0x708090},
+		{0.5, 0, 0x586878},
+	} {
+		if color := colormap(corners, c.temperature, c.downfall); color != c.color {
+			t.Errorf("%v, %v: %#06x not %#06x", c.temperature, c.downfall, color, c.color)
+		}
+	}
+
+	if color := tinted(0x808080ff, 0x808080, 0x408080); color != 0x408080ff {
+		t.Errorf("tinted %#08x", color)
+	}
+	if color := tinted(0x808080ff, 0x408080, 0x808080); color != 0xff8080ff {
+		t.Errorf("tinted past white %#08x", color)
+	}
+}
+
+func TestBiomeTints(t *testing.T) {
+	if biomeTintNumbers["minecraft:beach"] != 0 {
+		t.Error("beach not tinted like plains")
+	}
+	var swamp = biomeTintNumbers["minecraft:swamp"]
+	if swamp == 0 || biomeTints[swamp].name != "swamp" || biomeTintNumbers["minecraft:swamp_hills"] != swamp {
+		t.Errorf("swamp tint %d", swamp)
+	}
+	if biomeTintNumbers["minecraft:snowy_taiga"] == biomeTintNumbers["minecraft:taiga"] {
+		t.Error("snowy taiga's water tinted like taiga's")
+	}
+
+	for _, c := range []struct {
+		blockId nbt.Block
+		tint    int
+	}{
+		{2, grassTint},
+		{31, noTint},
+		{31 + 1<<8, grassTint},
+		{18, foliageTint},
+		{18 + 1<<8, noTint},
+		{18 + 2<<8, noTint},
+		{18 + 3<<8, foliageTint},
+		{9, waterTint},
+		{1, noTint},
+	} {
+		if tint := tintOf(c.blockId); tint != c.tint {
+			t.Errorf("%#x tinted %d not %d", c.blockId, tint, c.tint)
+		}
+	}
+}
+
+func TestBiomeFaces(t *testing.T) {
+	defer func(b bool) { biomes = b }(biomes)
+
+	// A row of grass on stone, in a swamp to the west and plains to the east
+	var e, fs = testChunk(t, 4, emptySide, func(x, y, z int) nbt.Block {
+		switch {
+		case z != 8 || y > 1:
+			return 0
+		case y == 1:
+			return 2
+		}
+		return 1
+	})
+	var names = make([]string, 16*16)
+	for i := range names {
+		names[i] = "minecraft:plains"
+		if i&15 < 8 {
+			names[i] = "minecraft:swamp"
+		}
+	}
+	e.chunk.Biomes = nbt.BiomeMap{Names: names}
+
+	var swamp = biomeTintNumbers["minecraft:swamp"]
+	for _, on := range []bool{false, true} {
+		biomes = on
+		processTestChunk(fs, e)
+
+		var tops = make(map[int][2]int)
+		for i, face := range faceCorners(fs) {
+			var f = fs.faces[i]
+			if f.blockId != 2 {
+				if f.biome != 0 {
+					t.Errorf("%#x tinted %d", f.blockId, f.biome)
+				}
+				continue
+			}
+			if face[0][1] != 32 || face[1][1] != 32 || face[2][1] != 32 {
+				continue
+			}
+			var lo, hi = face[0][0], face[0][0]
+			for _, p := range face {
+				if p[0] < lo {
+					lo = p[0]
+				}
+				if p[0] > hi {
+					hi = p[0]
+				}
+			}
+			tops[f.biome] = [2]int{lo, hi}
+		}
+
+		var want = map[int][2]int{0: {0, 16 * 16}}
+		if on {
+			want = map[int][2]int{swamp: {0, 8 * 16}, 0: {8 * 16, 16 * 16}}
+		}
+		if len(tops) != len(want) || tops[0] != want[0] || tops[swamp] != want[swamp] {
+			t.Errorf("biomes %v: tops %v, want %v", on, tops, want)
+		}
+	}
+}
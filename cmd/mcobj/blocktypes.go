@@ -1,9 +1,35 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"github.com/quag/mcobj/nbt"
 )
 
+// TransparentFaces is which faces are kept against transparent blocks such
+// as glass, leaves and water, trading seeing the terrain through them for
+// fewer faces.
+type TransparentFaces int
+
+const (
+	TransparentShow TransparentFaces = iota // Against other kinds of transparent block
+	TransparentAll                          // Even between blocks of the same kind, such as inside leaves
+	TransparentHide                         // Never, as though they were solid
+)
+
+// ParseTransparentFaces reads show, all or hide.
+func ParseTransparentFaces(s string) (TransparentFaces, error) {
+	switch s {
+	case "show":
+		return TransparentShow, nil
+	case "all":
+		return TransparentAll, nil
+	case "hide":
+		return TransparentHide, nil
+	}
+	return TransparentShow, errors.New(fmt.Sprintf("%q isn't show, all or hide", s))
+}
+
 type BoundaryLocator struct {
 	describer BlockDescriber
 }
@@ -25,17 +51,41 @@ func (b *BoundaryLocator) IsBoundary(blockId, otherBlockId nbt.Block) bool {
 			return true
 		}
 
-		if block.IsItem() {
+		// Block models such as panes don't fill their block. Fluids
+		// nearly do, and are left to the transparent faces setting.
+		if block.IsItem() || hasModel(otherBlockId) && fluid(otherBlockId) == 0 {
 			return true
 		}
 
-		if other.IsTransparent() && (other.IsItem() || blockId&0xff != otherBlockId&0xff) {
-			return true
+		if other.IsTransparent() {
+			// Faces behind items such as torches and flowers are
+			// always kept, as the items don't cover them
+			switch transparentFaces {
+			case TransparentAll:
+				return true
+			case TransparentHide:
+				return other.IsItem()
+			default:
+				return other.IsItem() || blockId&0xff != otherBlockId&0xff
+			}
 		}
 	}
 	return false
 }
 
+// IsSolid reports whether a block is a whole cube that hides the faces of
+// block models against it.
+func (b *BoundaryLocator) IsSolid(blockId nbt.Block) bool {
+	var info = b.describer.BlockInfo(byte(blockId & 0xff))
+	return !info.IsEmpty() && !info.IsTransparent() && !hasModel(blockId)
+}
+
+// IsTerrain reports whether a block is part of the smooth surface -smooth
+// draws: a solid block that isn't an item.
+func (b *BoundaryLocator) IsTerrain(blockId nbt.Block) bool {
+	return b.IsSolid(blockId) && b.describer.BlockInfo(byte(blockId&0xff)).IsMass()
+}
+
 type Describer struct {
 	unknown BlockInfo
 	cache   map[byte]BlockInfoByte
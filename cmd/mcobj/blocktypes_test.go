@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestTransparentFaces(t *testing.T) {
+	var _, fs = testChunk(t, 1, emptySide, func(x, y, z int) nbt.Block { return 0 })
+	defer func(faces TransparentFaces) { transparentFaces = faces }(transparentFaces)
+
+	const (
+		air    = 0
+		stone  = 1
+		leaves = 18
+		glass  = 20
+	)
+	// Whether there's a face of the first block against the second, kept
+	// with show, all and hide
+	var tests = []struct {
+		block, other    nbt.Block
+		show, all, hide bool
+	}{
+		{stone, air, true, true, true},
+		{stone, stone, false, false, false},
+		{stone, glass, true, true, false},
+		{stone, leaves, true, true, false},
+		{glass, leaves, true, true, false},
+		{leaves, leaves, false, true, false},
+		{glass, glass, false, true, false},
+		{glass, stone, false, false, false},
+	}
+	for _, test := range tests {
+		for _, mode := range []string{"show", "all", "hide"} {
+			var faces, err = ParseTransparentFaces(mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transparentFaces = faces
+			var want = map[string]bool{"show": test.show, "all": test.all, "hide": test.hide}[mode]
+			if boundary := fs.boundary.IsBoundary(test.block, test.other); boundary != want {
+				t.Errorf("-transparent %s: %d against %d is %v, want %v", mode, test.block, test.other, boundary, want)
+			}
+		}
+	}
+
+	if _, err := ParseTransparentFaces("some"); err == nil {
+		t.Error("no error for some")
+	}
+}
+
+func TestStainedGlassId(t *testing.T) {
+	var _, fs = testChunk(t, 1, emptySide, func(x, y, z int) nbt.Block { return 0 })
+
+	// 95 is stained glass, as it has been since 1.7, not the locked chest
+	// it was before
+	var red nbt.Block = 95 | 14<<8
+	if name := MaterialNamer.NameBlockId(red); name != "StainedGlass.Red" {
+		t.Errorf("95:14 named %q", name)
+	}
+	if fs.boundary.IsSolid(red) {
+		t.Error("stained glass hides what's behind it")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"container/list"
+
+	"github.com/quag/mcobj/mcworld"
+	"github.com/quag/mcobj/nbt"
+)
+
+// ChunkCache keeps the chunks most recently loaded for their sides, so a
+// chunk read to enclose its neighbours isn't decompressed and parsed again
+// when its own turn comes. The oldest chunks are dropped past size.
+type ChunkCache struct {
+	opener mcworld.ChunkOpener
+	errors *ChunkErrors
+	size   int
+	order  *list.List // Most recently used at the front
+	chunks map[uint64]*list.Element
+}
+
+type cachedChunk struct {
+	key    uint64
+	chunk  *nbt.Chunk
+	damage error // What was wrong with it, if it was salvaged
+}
+
+func NewChunkCache(opener mcworld.ChunkOpener, errors *ChunkErrors, size int) *ChunkCache {
+	return &ChunkCache{opener, errors, size, list.New(), make(map[uint64]*list.Element)}
+}
+
+// Load reads a chunk, from the cache when it's there, and keeps it for
+// later.
+func (c *ChunkCache) Load(x, z int) (*nbt.Chunk, error) {
+	var key = c.key(x, z)
+	if e, ok := c.chunks[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*cachedChunk).chunk, nil
+	}
+
+	var chunk, damage, err = loadChunk2(c.opener, x, z)
+	if err != nil || c.size <= 0 {
+		return chunk, err
+	}
+	c.chunks[key] = c.order.PushFront(&cachedChunk{key, chunk, damage})
+	for c.order.Len() > c.size {
+		var oldest = c.order.Remove(c.order.Back()).(*cachedChunk)
+		delete(c.chunks, oldest.key)
+	}
+	return chunk, nil
+}
+
+// Take reads a chunk like Load but leaves it out of the cache, as the
+// chunk is about to be enclosed and handed to the generator. Only chunks
+// taken go through the error policy, as neighbours read for their sides
+// often don't exist.
+func (c *ChunkCache) Take(x, z int) (*nbt.Chunk, error) {
+	var key = c.key(x, z)
+	if e, ok := c.chunks[key]; ok {
+		delete(c.chunks, key)
+		var cached = c.order.Remove(e).(*cachedChunk)
+		if cached.damage != nil {
+			c.errors.Salvaged(x, z, cached.damage)
+		}
+		return cached.chunk, nil
+	}
+	return c.errors.Load(c.opener, x, z)
+}
+
+func (c *ChunkCache) key(x, z int) uint64 {
+	return (uint64(x) << 32) + uint64(uint32(z))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// countingOpener opens every chunk as an empty one, counting how many times
+// each is opened.
+type countingOpener struct {
+	opens map[[2]int]int
+}
+
+func (o *countingOpener) OpenChunk(x, z int) (io.ReadCloser, error) {
+	o.opens[[2]int{x, z}]++
+	var data = []byte{10, 0, 0, 10, 0, 5, 'L', 'e', 'v', 'e', 'l', 0, 0}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestChunkCache(t *testing.T) {
+	type load struct {
+		take bool
+		x, z int
+	}
+	var tests = []struct {
+		name  string
+		size  int
+		loads []load
+		opens map[[2]int]int
+	}{
+		{"loaded then taken", 4, []load{{false, 0, 0}, {false, 0, 0}, {true, 0, 0}}, map[[2]int]int{{0, 0}: 1}},
+		{"taken twice", 4, []load{{true, 0, 0}, {true, 0, 0}}, map[[2]int]int{{0, 0}: 2}},
+		{"taken then loaded", 4, []load{{true, 0, 0}, {false, 0, 0}}, map[[2]int]int{{0, 0}: 2}},
+		{"no cache", 0, []load{{false, 0, 0}, {true, 0, 0}}, map[[2]int]int{{0, 0}: 2}},
+		// The oldest is dropped, unless it's used again first
+		{"oldest dropped", 2, []load{{false, 0, 0}, {false, 1, 0}, {false, 2, 0}, {true, 0, 0}, {true, 2, 0}},
+			map[[2]int]int{{0, 0}: 2, {1, 0}: 1, {2, 0}: 1}},
+		{"used again", 2, []load{{false, 0, 0}, {false, 1, 0}, {false, 0, 0}, {false, 2, 0}, {true, 0, 0}, {true, 1, 0}},
+			map[[2]int]int{{0, 0}: 1, {1, 0}: 2, {2, 0}: 1}},
+		// Keyed apart at negative z
+		{"negative z", 4, []load{{false, 1, -1}, {false, 0, -1}, {true, 1, -1}, {true, 0, -1}},
+			map[[2]int]int{{1, -1}: 1, {0, -1}: 1}},
+	}
+	for _, test := range tests {
+		var opener = &countingOpener{make(map[[2]int]int)}
+		var cache = NewChunkCache(opener, NewChunkErrors(ChunkErrorPolicy{}), test.size)
+		for _, l := range test.loads {
+			var err error
+			if l.take {
+				_, err = cache.Take(l.x, l.z)
+			} else {
+				_, err = cache.Load(l.x, l.z)
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		for p, opens := range test.opens {
+			if opener.opens[p] != opens {
+				t.Errorf("%s: %v opened %d times, want %d", test.name, p, opener.opens[p], opens)
+			}
+		}
+	}
+}
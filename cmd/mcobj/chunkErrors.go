@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/quag/mcobj/mcworld"
+	"github.com/quag/mcobj/nbt"
+)
+
+// ChunkErrorPolicy is what to do when a chunk can't be read or parsed:
+// stop the export, or try again Retries times and then skip the chunk.
+type ChunkErrorPolicy struct {
+	Abort   bool
+	Retries int
+}
+
+// ParseChunkErrorPolicy reads skip, abort or retry-N.
+func ParseChunkErrorPolicy(s string) (ChunkErrorPolicy, error) {
+	switch {
+	case s == "skip":
+		return ChunkErrorPolicy{}, nil
+	case s == "abort":
+		return ChunkErrorPolicy{Abort: true}, nil
+	case strings.HasPrefix(s, "retry-"):
+		var retries, err = strconv.Atoi(s[len("retry-"):])
+		if err == nil && retries >= 0 {
+			return ChunkErrorPolicy{Retries: retries}, nil
+		}
+	}
+	return ChunkErrorPolicy{}, errors.New(fmt.Sprintf("%q isn't skip, abort or retry-N", s))
+}
+
+type SkippedChunk struct {
+	X, Z int
+	Err  error
+}
+
+// ChunkErrors loads chunks following a policy, keeping the chunks that
+// failed, and those -salvage read what it could of, for a summary at the
+// end rather than among the progress output.
+type ChunkErrors struct {
+	policy   ChunkErrorPolicy
+	skipped  []SkippedChunk
+	salvaged []SkippedChunk
+	aborted  bool
+}
+
+func NewChunkErrors(policy ChunkErrorPolicy) *ChunkErrors {
+	return &ChunkErrors{policy: policy}
+}
+
+func (e *ChunkErrors) Load(opener mcworld.ChunkOpener, x, z int) (*nbt.Chunk, error) {
+	var chunk, damage, err = loadChunk2(opener, x, z)
+	for i := 0; err != nil && i < e.policy.Retries; i++ {
+		chunk, damage, err = loadChunk2(opener, x, z)
+	}
+	if err != nil {
+		e.skipped = append(e.skipped, SkippedChunk{x, z, err})
+		e.aborted = e.aborted || e.policy.Abort
+	}
+	if damage != nil {
+		e.Salvaged(x, z, damage)
+	}
+	return chunk, err
+}
+
+// Salvaged notes a chunk that was damaged, of which -salvage kept what it
+// could read.
+func (e *ChunkErrors) Salvaged(x, z int, damage error) {
+	e.salvaged = append(e.salvaged, SkippedChunk{x, z, damage})
+}
+
+// Aborted is whether a chunk failed and the policy is to stop.
+func (e *ChunkErrors) Aborted() bool {
+	return e.aborted
+}
+
+func (e *ChunkErrors) PrintSummary(w io.Writer) {
+	if len(e.salvaged) != 0 {
+		fmt.Fprintf(w, "Salvaged %d damaged chunks:\n", len(e.salvaged))
+		for _, salvaged := range e.salvaged {
+			fmt.Fprintf(w, "  %d,%d: %v\n", salvaged.X, salvaged.Z, salvaged.Err)
+		}
+	}
+	if len(e.skipped) == 0 {
+		return
+	}
+	if e.aborted {
+		var last = e.skipped[len(e.skipped)-1]
+		fmt.Fprintf(w, "Aborted at chunk %d,%d: %v\n", last.X, last.Z, last.Err)
+		return
+	}
+	fmt.Fprintf(w, "Skipped %d chunks:\n", len(e.skipped))
+	for _, skipped := range e.skipped {
+		fmt.Fprintf(w, "  %d,%d: %v\n", skipped.X, skipped.Z, skipped.Err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// truncatedOpener opens every chunk as one cut off partway through its
+// Level, before its position.
+type truncatedOpener struct{}
+
+func (truncatedOpener) OpenChunk(x, z int) (io.ReadCloser, error) {
+	var data = []byte{10, 0, 0, 10, 0, 5, 'L', 'e', 'v', 'e', 'l', 3, 0, 4, 'x', 'P', 'o'}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// flakyOpener fails to open a chunk the first failures times, and then
+// opens it as an empty one.
+type flakyOpener struct {
+	failures int
+	opens    int
+}
+
+func (o *flakyOpener) OpenChunk(x, z int) (io.ReadCloser, error) {
+	o.opens++
+	if o.opens <= o.failures {
+		return nil, errors.New("flaky")
+	}
+	return (&countingOpener{make(map[[2]int]int)}).OpenChunk(x, z)
+}
+
+func TestParseChunkErrorPolicy(t *testing.T) {
+	var tests = []struct {
+		s      string
+		policy ChunkErrorPolicy
+		ok     bool
+	}{
+		{"skip", ChunkErrorPolicy{}, true},
+		{"abort", ChunkErrorPolicy{Abort: true}, true},
+		{"retry-0", ChunkErrorPolicy{}, true},
+		{"retry-3", ChunkErrorPolicy{Retries: 3}, true},
+		{"retry--1", ChunkErrorPolicy{}, false},
+		{"retry-", ChunkErrorPolicy{}, false},
+		{"retry", ChunkErrorPolicy{}, false},
+		{"", ChunkErrorPolicy{}, false},
+	}
+	for _, test := range tests {
+		var policy, err = ParseChunkErrorPolicy(test.s)
+		if (err == nil) != test.ok || err == nil && policy != test.policy {
+			t.Errorf("%q: %+v, %v", test.s, policy, err)
+		}
+	}
+}
+
+func TestChunkErrorPolicy(t *testing.T) {
+	var tests = []struct {
+		policy   string
+		failures int
+		opens    int
+		ok       bool
+		summary  string
+	}{
+		{"skip", 0, 1, true, ""},
+		{"skip", 1, 1, false, "Skipped 1 chunks:\n  2,-3: flaky\n"},
+		{"abort", 1, 1, false, "Aborted at chunk 2,-3: flaky\n"},
+		{"retry-2", 2, 3, true, ""},
+		{"retry-2", 3, 3, false, "Skipped 1 chunks:\n  2,-3: flaky\n"},
+	}
+	for _, test := range tests {
+		var policy, err = ParseChunkErrorPolicy(test.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var opener = &flakyOpener{failures: test.failures}
+		var errors = NewChunkErrors(policy)
+		var _, loadErr = errors.Load(opener, 2, -3)
+
+		var summary bytes.Buffer
+		errors.PrintSummary(&summary)
+		if opener.opens != test.opens || (loadErr == nil) != test.ok || summary.String() != test.summary {
+			t.Errorf("%s failing %d times: opened %d times, %v, summary %q", test.policy, test.failures, opener.opens, loadErr, summary.String())
+		}
+		if errors.Aborted() != (policy.Abort && !test.ok) {
+			t.Errorf("%s failing %d times: aborted is %v", test.policy, test.failures, errors.Aborted())
+		}
+	}
+}
+
+func TestSalvagedChunks(t *testing.T) {
+	defer func(s bool) { salvage = s }(salvage)
+	salvage = true
+
+	var errors = NewChunkErrors(ChunkErrorPolicy{})
+	var cache = NewChunkCache(truncatedOpener{}, errors, 4)
+
+	// Taken straight away, and loaded for a neighbour's side and then taken
+	var taken, err = cache.Take(3, -2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Load(-7, 5); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := cache.Take(-7, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if taken.XPos != 3 || taken.ZPos != -2 {
+		t.Errorf("chunk 3,-2 is at %d,%d", taken.XPos, taken.ZPos)
+	}
+	if cached.XPos != -7 || cached.ZPos != 5 {
+		t.Errorf("chunk -7,5 is at %d,%d", cached.XPos, cached.ZPos)
+	}
+
+	var summary bytes.Buffer
+	errors.PrintSummary(&summary)
+	for _, want := range []string{"Salvaged 2 damaged chunks:", "  3,-2: ", "  -7,5: "} {
+		if !strings.Contains(summary.String(), want) {
+			t.Errorf("summary %q is missing %q", summary.String(), want)
+		}
+	}
+}
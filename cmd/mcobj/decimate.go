@@ -0,0 +1,253 @@
+package main
+
+import (
+	"container/heap"
+	"github.com/quag/mcobj/nbt"
+)
+
+// quadric is the sum of the squared distances to a set of planes, as the
+// upper triangle of a symmetric 4x4 matrix.
+type quadric [10]float64
+
+func planeQuadric(p [3]float64, n [3]float64) quadric {
+	var d = -(n[0]*p[0] + n[1]*p[1] + n[2]*p[2])
+	var a, b, c = n[0], n[1], n[2]
+	return quadric{a * a, a * b, a * c, a * d, b * b, b * c, b * d, c * c, c * d, d * d}
+}
+
+func (q *quadric) add(o quadric) {
+	for i := range q {
+		q[i] += o[i]
+	}
+}
+
+// error is the sum of the squared distances of p to the planes.
+func (q *quadric) error(p [3]float64) float64 {
+	var x, y, z = p[0], p[1], p[2]
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z + q[9]
+}
+
+// collapse is moving vertex from onto vertex to, joining them.
+type collapse struct {
+	from, to int
+	cost     float64
+	version  int // Of from and to, when the cost was worked out
+}
+
+type collapses []collapse
+
+func (c collapses) Len() int            { return len(c) }
+func (c collapses) Less(i, j int) bool  { return c[i].cost < c[j].cost }
+func (c collapses) Swap(i, j int)       { c[i], c[j] = c[j], c[i] }
+func (c *collapses) Push(x interface{}) { *c = append(*c, x.(collapse)) }
+func (c *collapses) Pop() interface{} {
+	var last = (*c)[len(*c)-1]
+	*c = (*c)[:len(*c)-1]
+	return last
+}
+
+type decimateTriangle struct {
+	blockId nbt.Block
+	biome   int
+	v       [3]int
+	removed bool
+}
+
+// decimate simplifies the faces of a chunk for -triangles and -tolerance,
+// splitting them into triangles and collapsing their edges, one end onto
+// the other, cheapest first by the squared distances of the moved end from
+// the planes of the faces it was on. It stops when the chunk is down to
+// its share of -triangles, or the next collapse would move the surface
+// further than -tolerance. Vertexes on the sides of the chunk, on the edge
+// of a hole, or between faces of different materials aren't moved, so
+// chunks still meet and materials keep their outlines.
+func (fs *Faces) decimate() {
+	var tris = make([]decimateTriangle, 0, 2*len(fs.faces))
+	for _, face := range fs.faces {
+		var i = face.indexes
+		tris = append(tris, decimateTriangle{face.blockId, face.biome, [3]int{i[0], i[1], i[2]}, false})
+		if !face.isTriangle() {
+			tris = append(tris, decimateTriangle{face.blockId, face.biome, [3]int{i[0], i[2], i[3]}, false})
+		}
+	}
+
+	var (
+		positions = make(map[int][3]float64)
+		around    = make(map[int][]int) // The triangles around each vertex
+		quadrics  = make(map[int]*quadric)
+		locked    = make(map[int]bool)
+		versions  = make(map[int]int)
+		edges     = make(map[[2]int]int) // How many triangles share each edge
+	)
+	var position = func(i int) [3]float64 {
+		var p, ok = positions[i]
+		if !ok {
+			var v = fs.vertexes.Position(i)
+			p = [3]float64{float64(v.x), float64(v.y), float64(v.z)}
+			positions[i] = p
+			locked[i] = v.x == 0 || v.x == 16*16 || v.z == 0 || v.z == 16*16
+			quadrics[i] = &quadric{}
+		}
+		return p
+	}
+	var normal = func(v [3]int) [3]float64 {
+		var a, b, c = position(v[0]), position(v[1]), position(v[2])
+		var u = [3]float64{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+		var w = [3]float64{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+		return [3]float64{u[1]*w[2] - u[2]*w[1], u[2]*w[0] - u[0]*w[2], u[0]*w[1] - u[1]*w[0]}
+	}
+	var edge = func(a, b int) [2]int {
+		if a > b {
+			a, b = b, a
+		}
+		return [2]int{a, b}
+	}
+
+	for t, tri := range tris {
+		var q = planeQuadric(position(tri.v[0]), unit(normal(tri.v)))
+		for j, i := range tri.v {
+			quadrics[i].add(q)
+			around[i] = append(around[i], t)
+			edges[edge(i, tri.v[(j+1)%3])]++
+			var first = tris[around[i][0]]
+			if first.blockId != tri.blockId || first.biome != tri.biome {
+				locked[i] = true
+			}
+		}
+	}
+	for e, count := range edges {
+		if count != 2 {
+			locked[e[0]], locked[e[1]] = true, true
+		}
+	}
+
+	var queue collapses
+	var push = func(from, to int) {
+		if locked[from] {
+			return
+		}
+		var q = *quadrics[from]
+		q.add(*quadrics[to])
+		heap.Push(&queue, collapse{from, to, q.error(positions[to]), versions[from] + versions[to]})
+	}
+	for e := range edges {
+		push(e[0], e[1])
+		push(e[1], e[0])
+	}
+
+	var count = len(tris)
+	var maxCost = 16 * tolerance * 16 * tolerance
+	for queue.Len() != 0 && (fs.triangleTarget == 0 || count > fs.triangleTarget) {
+		var c = heap.Pop(&queue).(collapse)
+		if c.version != versions[c.from]+versions[c.to] || locked[c.from] {
+			continue
+		}
+		if tolerance > 0 && c.cost > maxCost {
+			break
+		}
+		if !canCollapse(tris, around, c.from, c.to, normal) {
+			continue
+		}
+
+		for _, t := range around[c.from] {
+			var tri = &tris[t]
+			if tri.removed {
+				continue
+			}
+			if tri.v[0] == c.to || tri.v[1] == c.to || tri.v[2] == c.to {
+				tri.removed = true
+				count--
+				continue
+			}
+			for j := range tri.v {
+				if tri.v[j] == c.from {
+					tri.v[j] = c.to
+				}
+			}
+			around[c.to] = append(around[c.to], t)
+		}
+		around[c.from] = nil
+		quadrics[c.to].add(*quadrics[c.from])
+		versions[c.from]++
+		versions[c.to]++
+
+		var pushed = make(map[int]bool)
+		for _, t := range around[c.to] {
+			if tris[t].removed {
+				continue
+			}
+			for _, i := range tris[t].v {
+				if i != c.to && !pushed[i] {
+					pushed[i] = true
+					push(i, c.to)
+					push(c.to, i)
+				}
+			}
+		}
+	}
+
+	fs.faces = fs.faces[:0]
+	fs.vertexes.Unreference()
+	for _, tri := range tris {
+		if tri.removed {
+			continue
+		}
+		for _, i := range tri.v {
+			fs.vertexes.Reference(i)
+		}
+		fs.faces = append(fs.faces, IndexFace{tri.blockId, [4]int{tri.v[0], tri.v[1], tri.v[2], tri.v[2]}, tri.biome})
+	}
+}
+
+// canCollapse reports whether moving vertex from onto to keeps the surface
+// as it was around them: no triangle around from turns over, and the only
+// vertexes next to both are those of the triangles on the edge between
+// them, so no two triangles end up on top of each other, and to is left
+// with at least three triangles, so closed shapes don't fold up to nothing.
+func canCollapse(tris []decimateTriangle, around map[int][]int, from, to int, normal func(v [3]int) [3]float64) bool {
+	var nextToFrom = make(map[int]bool)
+	var onEdge, left = 0, 0
+	for _, t := range around[from] {
+		var tri = tris[t]
+		if tri.removed {
+			continue
+		}
+		left++
+		var hasTo = false
+		for _, i := range tri.v {
+			nextToFrom[i] = true
+			hasTo = hasTo || i == to
+		}
+		if hasTo {
+			onEdge++
+			continue
+		}
+
+		var before = normal(tri.v)
+		for j := range tri.v {
+			if tri.v[j] == from {
+				tri.v[j] = to
+			}
+		}
+		var after = normal(tri.v)
+		if before[0]*after[0]+before[1]*after[1]+before[2]*after[2] <= 0 {
+			return false
+		}
+	}
+
+	var shared = make(map[int]bool)
+	for _, t := range around[to] {
+		if tris[t].removed {
+			continue
+		}
+		left++
+		for _, i := range tris[t].v {
+			if i != from && i != to && nextToFrom[i] {
+				shared[i] = true
+			}
+		}
+	}
+	return len(shared) <= onEdge && left-2*onEdge >= 3
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"math/rand"
+	"testing"
+)
+
+// bumpyChunk is a chunk of bumpy ground, stone for x < 8 and dirt beyond,
+// with a hole down through it.
+func bumpyChunk(t *testing.T) (*EnclosedChunk, *Faces) {
+	var r = rand.New(rand.NewSource(1))
+	var heights [16][16]int
+	for x := range heights {
+		for z := range heights[x] {
+			heights[x][z] = 4 + r.Intn(3)
+		}
+	}
+	return testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+		switch {
+		case x >= 4 && x < 6 && z >= 4 && z < 6:
+			return 0
+		case y >= heights[x][z]:
+			return 0
+		case x < 8:
+			return 1
+		}
+		return 3
+	})
+}
+
+// decimated is the faces of a bumpy chunk, simplified to target triangles
+// and by a tolerance, or not at all when both are 0.
+func decimated(t *testing.T, target int, within float64) *Faces {
+	var e, fs = bumpyChunk(t)
+	triangles, tolerance = target, within
+	defer func() {
+		triangles, tolerance = 0, 0
+	}()
+	fs.triangleTarget = target
+	processTestChunk(fs, e)
+	return fs
+}
+
+func triangleCount(fs *Faces) int {
+	var count = 0
+	for _, face := range fs.faces {
+		if face.isTriangle() {
+			count++
+		} else {
+			count += 2
+		}
+	}
+	return count
+}
+
+func usedPositions(fs *Faces) map[[3]int]bool {
+	var used = make(map[[3]int]bool)
+	for _, face := range faceCorners(fs) {
+		for _, p := range face {
+			used[p] = true
+		}
+	}
+	return used
+}
+
+func TestDecimateTarget(t *testing.T) {
+	var all = triangleCount(decimated(t, 0, 0))
+	var least = triangleCount(decimated(t, 1, 0))
+	if least >= all {
+		t.Fatalf("only simplified from %d to %d triangles", all, least)
+	}
+
+	var target = (all + least) / 2
+	var got = triangleCount(decimated(t, target, 0))
+	if got > target || got < target-2 {
+		t.Errorf("simplified to %d triangles, want %d", got, target)
+	}
+}
+
+func TestDecimateLocked(t *testing.T) {
+	var original = decimated(t, 0, 0)
+
+	// The vertexes on the sides of the chunk, on the edges of holes, and
+	// between stone and dirt
+	var locked = make(map[[3]int]bool)
+	var materials = make(map[[3]int]nbt.Block)
+	var edges = make(map[[2][3]int]int)
+	for i, face := range faceCorners(original) {
+		for j, p := range face {
+			if p[0] == 0 || p[0] == 16*16 || p[2] == 0 || p[2] == 16*16 {
+				locked[p] = true
+			}
+			if blockId, ok := materials[p]; ok && blockId != original.faces[i].blockId {
+				locked[p] = true
+			}
+			materials[p] = original.faces[i].blockId
+			var q = face[(j+1)%len(face)]
+			if q[0] < p[0] || q[0] == p[0] && (q[1] < p[1] || q[1] == p[1] && q[2] < p[2]) {
+				p, q = q, p
+			}
+			edges[[2][3]int{p, q}]++
+		}
+	}
+	for edge, count := range edges {
+		if count != 2 {
+			locked[edge[0]], locked[edge[1]] = true, true
+		}
+	}
+
+	var used = usedPositions(decimated(t, 1, 0))
+	if len(used) >= len(usedPositions(original)) {
+		t.Fatal("nothing was simplified")
+	}
+	for p := range locked {
+		if !used[p] {
+			t.Errorf("%v was moved", p)
+		}
+	}
+}
+
+func TestDecimateTolerance(t *testing.T) {
+	// Faces of each block, many of which can be joined without moving
+	// the surface
+	blockFaces = true
+	defer func() {
+		blockFaces = false
+	}()
+	var original = decimated(t, 0, 0)
+
+	// The corners of blocks, where faces pointing three ways meet, which
+	// can't be moved without moving the surface
+	var normals = make(map[[3]int]map[[3]int]bool)
+	for _, face := range faceCorners(original) {
+		var a, b, c = face[0], face[1], face[2]
+		var u = [3]int{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+		var w = [3]int{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+		var n = [3]int{sign(u[1]*w[2] - u[2]*w[1]), sign(u[2]*w[0] - u[0]*w[2]), sign(u[0]*w[1] - u[1]*w[0])}
+		for _, p := range face {
+			if normals[p] == nil {
+				normals[p] = make(map[[3]int]bool)
+			}
+			normals[p][n] = true
+		}
+	}
+
+	var tight = decimated(t, 0, 0.01)
+	var loose = decimated(t, 0, 2)
+	if triangleCount(loose) >= triangleCount(tight) || triangleCount(tight) >= triangleCount(original) {
+		t.Fatalf("%d triangles, %d within 0.01 and %d within 2", triangleCount(original), triangleCount(tight), triangleCount(loose))
+	}
+
+	var used = usedPositions(tight)
+	var corners, moved = 0, 0
+	for p, ns := range normals {
+		if len(ns) >= 3 {
+			corners++
+			if !used[p] {
+				moved++
+			}
+		}
+	}
+	if corners == 0 || moved != 0 {
+		t.Errorf("%d of %d corners moved within 0.01", moved, corners)
+	}
+	if used = usedPositions(loose); len(used) >= len(usedPositions(tight)) {
+		t.Errorf("%d vertexes within 2, but %d within 0.01", len(used), len(usedPositions(tight)))
+	}
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/quag/mcobj/mcworld"
+	"github.com/quag/mcobj/nbt"
+)
+
+// dumpFile prints a loose NBT file, such as a level.dat, playerdata file
+// or chunk from before region files, as JSON, or compares it with another.
+func dumpFile(filename string, settings *ProcessingSettings) {
+	var r, err = openNbtFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Dump error:", err)
+		return
+	}
+	defer r.Close()
+
+	if settings.Diff != "" {
+		var other, otherErr = openNbtFile(settings.Diff)
+		if otherErr != nil {
+			fmt.Fprintln(os.Stderr, "Diff error:", otherErr)
+			return
+		}
+		defer other.Close()
+		diffNbt(r, other)
+		return
+	}
+
+	dumpNbt(r, settings.Query)
+}
+
+func openNbtFile(filename string) (io.ReadCloser, error) {
+	var file, err = os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	var r, decompressErr = nbt.NewDecompressor(file)
+	if decompressErr != nil {
+		file.Close()
+		return nil, decompressErr
+	}
+	return &nbtFile{r, file}, nil
+}
+
+// nbtFile closes the file under its decompressor too.
+type nbtFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (f *nbtFile) Close() error {
+	f.ReadCloser.Close()
+	return f.file.Close()
+}
+
+// dumpChunk prints the NBT of a world's chunk as JSON, or compares it with
+// the same chunk of another world.
+func dumpChunk(world mcworld.World, x, z int, dimension string, settings *ProcessingSettings) {
+	var r, err = world.OpenChunk(x, z)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Dump error:", err)
+		return
+	}
+	defer r.Close()
+
+	if settings.Diff != "" {
+		var otherWorld, worldErr = mcworld.OpenDimension(settings.Diff, dimension)
+		if worldErr != nil {
+			fmt.Fprintln(os.Stderr, "Diff error:", worldErr)
+			return
+		}
+		if closer, ok := otherWorld.(io.Closer); ok {
+			defer closer.Close()
+		}
+		var other, otherErr = otherWorld.OpenChunk(x, z)
+		if otherErr != nil {
+			fmt.Fprintln(os.Stderr, "Diff error:", otherErr)
+			return
+		}
+		defer other.Close()
+		diffNbt(r, other)
+		return
+	}
+
+	dumpNbt(r, settings.Query)
+}
+
+// dumpNbt prints the whole of the NBT as JSON, or with a query, just the
+// values the query's path selects as SNBT, one to a line.
+func dumpNbt(r io.Reader, query string) {
+	if query == "" {
+		if err := nbt.DumpJSON(r, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Dump error:", err)
+		}
+		return
+	}
+
+	var path, pathErr = nbt.ParsePath(query)
+	if pathErr != nil {
+		fmt.Fprintln(os.Stderr, "Query error:", pathErr)
+		return
+	}
+	var root, err = nbt.Parse(r)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Dump error:", err)
+		return
+	}
+	for _, value := range path.Select(root) {
+		fmt.Println(nbt.FormatSNBT(value))
+	}
+}
+
+// diffNbt prints the tags that differ between two NBT files, a line each.
+func diffNbt(old, new io.Reader) {
+	var oldRoot, oldErr = nbt.Parse(old)
+	if oldErr != nil {
+		fmt.Fprintln(os.Stderr, "Diff error:", oldErr)
+		return
+	}
+	var newRoot, newErr = nbt.Parse(new)
+	if newErr != nil {
+		fmt.Fprintln(os.Stderr, "Diff error:", newErr)
+		return
+	}
+
+	var differences = nbt.Diff(oldRoot, newRoot)
+	for _, difference := range differences {
+		fmt.Println(difference)
+	}
+	fmt.Printf("%d differences\n", len(differences))
+}
@@ -5,10 +5,17 @@ import (
 )
 
 type EnclosingSides [4]ChunkSide
+
+// EnclosingCorners are the columns of the chunks diagonally across the
+// corners at x,z -1,-1, 16,-1, -1,16 and 16,16.
+type EnclosingCorners [4]ChunkSide
+
 type EnclosedChunk struct {
 	xPos, zPos int
 	blocks     Blocks
 	enclosing  EnclosingSides
+	corners    EnclosingCorners
+	chunk      *nbt.Chunk // For its light, or nil
 }
 
 func (s *EnclosingSides) side(i int) ChunkSide {
@@ -22,14 +29,25 @@ func (e *EnclosedChunk) Get(x, y, z int) (blockId nbt.Block) {
 	case y < 0 && !hideBottom:
 	case y >= e.blocks.height:
 		blockId = 0
+	case x < -1, x > 16, z < -1, z > 16:
+		blockId = 0 // Past the sides, as block models can look
+	case (x == -1 || x == 16) && (z == -1 || z == 16):
+		var i = 0
+		if x == 16 {
+			i |= 1
+		}
+		if z == 16 {
+			i |= 2
+		}
+		blockId = e.corners[i].BlockId(0, y+e.blocks.minY)
 	case x == -1:
-		blockId = e.enclosing.side(0).BlockId(z, y)
+		blockId = e.enclosing.side(0).BlockId(z, y+e.blocks.minY)
 	case x == 16:
-		blockId = e.enclosing.side(1).BlockId(z, y)
+		blockId = e.enclosing.side(1).BlockId(z, y+e.blocks.minY)
 	case z == -1:
-		blockId = e.enclosing.side(2).BlockId(x, y)
+		blockId = e.enclosing.side(2).BlockId(x, y+e.blocks.minY)
 	case z == 16:
-		blockId = e.enclosing.side(3).BlockId(x, y)
+		blockId = e.enclosing.side(3).BlockId(x, y+e.blocks.minY)
 	default:
 		blockId = e.blocks.Get(x, y, z)
 	}
@@ -40,3 +58,32 @@ func (e *EnclosedChunk) Get(x, y, z int) (blockId nbt.Block) {
 func (e *EnclosedChunk) height() int {
 	return e.blocks.height
 }
+
+// Light is the block light and sky light of the block at x,y,z. The light
+// of the chunks around isn't kept, so past the sides it's the light at the
+// edge of the chunk. A chunk with no light saved is in full sky light.
+func (e *EnclosedChunk) Light(x, y, z int) (block, sky int) {
+	if e.chunk == nil || len(e.chunk.BlockLight) == 0 && len(e.chunk.SkyLight) == 0 {
+		return 0, 15
+	}
+	return e.chunk.Light(clamp(x, 0, 15), y+e.blocks.minY, clamp(z, 0, 15))
+}
+
+// Biome is the name of the biome at x,y,z, past the sides that at the edge
+// of the chunk, or "" where it isn't known.
+func (e *EnclosedChunk) Biome(x, y, z int) string {
+	if e.chunk == nil {
+		return ""
+	}
+	return e.chunk.Biomes.At(clamp(x, 0, 15), y+e.blocks.minY, clamp(z, 0, 15))
+}
+
+func clamp(i, lo, hi int) int {
+	switch {
+	case i < lo:
+		return lo
+	case i > hi:
+		return hi
+	}
+	return i
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+)
+
+// hollowed is a chunk with the blocks taken out that are further than
+// -hollow blocks from anything that isn't solid, counting across edges and
+// corners, leaving walls of that thickness around the hollows. Only the
+// chunk's own blocks are known well enough, so blocks are never taken out
+// within -hollow of its sides, and the chunks next door see the blocks
+// along its sides as they are.
+//
+// Solid is as -watertight draws it with -watertight, and otherwise whole
+// cubes that hide what's behind them.
+func (fs *Faces) hollowed(e *EnclosedChunk) *EnclosedChunk {
+	var height = e.blocks.height
+	var index = func(x, y, z int) int {
+		return y + height*(z+16*x)
+	}
+
+	var solid = make([]bool, len(e.blocks.data))
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y, blockId := range e.blocks.Column(x, z) {
+				if y+e.blocks.minY < yMin {
+					continue
+				}
+				if watertight {
+					solid[index(x, y, z)] = fs.isPrintable(blockId)
+				} else {
+					solid[index(x, y, z)] = fs.boundary.IsSolid(blockId)
+				}
+			}
+		}
+	}
+
+	// Whether all the blocks within -hollow along each axis in turn are
+	// solid, which leaves whether all those in the cube around are
+	var size = [3]int{16, height, 16}
+	var next = make([]bool, len(solid))
+	for axis := 0; axis < 3; axis++ {
+		for x := 0; x < 16; x++ {
+			for z := 0; z < 16; z++ {
+				for y := 0; y < height; y++ {
+					var p = [3]int{x, y, z}
+					var all = true
+					for d := -hollow; d <= hollow && all; d++ {
+						var q = p
+						q[axis] += d
+						all = q[axis] >= 0 && q[axis] < size[axis] && solid[index(q[0], q[1], q[2])]
+					}
+					next[index(x, y, z)] = all
+				}
+			}
+		}
+		solid, next = next, solid
+	}
+
+	var blocks = Blocks{make([]nbt.Block, len(e.blocks.data)), height, e.blocks.minY}
+	for i, blockId := range e.blocks.data {
+		if !solid[i] {
+			blocks.data[i] = blockId
+		}
+	}
+	return &EnclosedChunk{e.xPos, e.zPos, blocks, e.enclosing, e.corners, e.chunk}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestHollowed(t *testing.T) {
+	defer func(h int) { hollow = h }(hollow)
+	hollow = 2
+
+	// A cube of stone filling the chunk, with a pocket of air in the middle
+	var e, fs = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+		if x == 8 && y == 8 && z == 8 {
+			return 0
+		}
+		return 1
+	})
+	var hollowed = fs.hollowed(e)
+
+	// Taken out at least 2 from the sides and the pocket
+	var taken = 0
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y, blockId := range hollowed.blocks.Column(x, z) {
+				var inside = x >= 2 && x < 14 && y >= 2 && y < 14 && z >= 2 && z < 14
+				var nearPocket = abs(x-8) <= 2 && abs(y-8) <= 2 && abs(z-8) <= 2
+				var want nbt.Block = 1
+				if inside && !nearPocket || x == 8 && y == 8 && z == 8 {
+					want = 0
+				}
+				if blockId != want {
+					t.Errorf("%d,%d,%d is %d not %d", x, y, z, blockId, want)
+				}
+				if blockId == 0 {
+					taken++
+				}
+			}
+		}
+	}
+	if taken != 12*12*12-5*5*5+1 {
+		t.Errorf("%d blocks taken out", taken)
+	}
+	if e.blocks.Get(8, 2, 8) != 1 {
+		t.Error("the chunk's own blocks changed")
+	}
+
+	// The walls are drawn inside, facing into the hollow, as a closed
+	// surface, and around the pocket's wall
+	processTestChunk(fs, e)
+	checkManifold(t, "hollowed", faceCorners(fs))
+	if facesIn(fs, 0, 2*16) == 0 || facesIn(fs, 0, 14*16) == 0 {
+		t.Error("no faces inside the walls")
+	}
+	if facesIn(fs, 0, 6*16) == 0 || facesIn(fs, 0, 11*16) == 0 {
+		t.Error("no faces around the pocket")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"path/filepath"
+)
+
+// lodScales are the levels of detail -lods writes, by how many blocks
+// across each of their blocks is.
+var lodScales = []int{1, 2, 4, 8}
+
+// LodGenerator writes the chunks to a .obj file for each of lodScales, all
+// from the one read of the world.
+type LodGenerator struct {
+	levels        []*ObjGenerator
+	enclosedsChan chan *EnclosedChunkJob
+	completeChan  chan bool
+}
+
+// lodFilename is the name of the file of a level of detail: the name given
+// for the full detail, and that with _lod and the scale before the
+// extension for the others, such as a_lod4.obj.
+func lodFilename(outFilename string, scale int) string {
+	if scale == 1 {
+		return outFilename
+	}
+	var ext = filepath.Ext(outFilename)
+	return fmt.Sprintf("%s_lod%d%s", outFilename[:len(outFilename)-len(ext)], scale, ext)
+}
+
+func (g *LodGenerator) Start(outFilename string, total int, maxProcs int, boundary *BoundaryLocator) error {
+	g.enclosedsChan = make(chan *EnclosedChunkJob, maxProcs*2)
+	g.completeChan = make(chan bool)
+
+	for _, scale := range lodScales {
+		var level = new(ObjGenerator)
+		if err := level.Start(lodFilename(outFilename, scale), total, maxProcs, boundary); err != nil {
+			for _, started := range g.levels {
+				started.Close()
+			}
+			return err
+		}
+		g.levels = append(g.levels, level)
+	}
+
+	go func() {
+		for {
+			var job = <-g.enclosedsChan
+			for i, level := range g.levels {
+				var enclosed = job.enclosed
+				if lodScales[i] != 1 {
+					enclosed = coarsen(enclosed, lodScales[i])
+				}
+				level.GetEnclosedJobsChan() <- &EnclosedChunkJob{job.last, enclosed}
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			for _, level := range g.levels {
+				<-level.GetCompleteChan()
+			}
+			g.completeChan <- true
+		}
+	}()
+
+	return nil
+}
+
+func (g *LodGenerator) GetEnclosedJobsChan() chan *EnclosedChunkJob {
+	return g.enclosedsChan
+}
+
+func (g *LodGenerator) GetCompleteChan() chan bool {
+	return g.completeChan
+}
+
+func (g *LodGenerator) Close() error {
+	var firstErr error
+	for _, level := range g.levels {
+		if err := level.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// coarsen makes a chunk at a scale'th of the detail, with each cube of
+// scale blocks on a side filled with the commonest block in it, ties going
+// to blocks over air. The sides and corners of the chunks around are
+// coarsened from the blocks along them, which are all that's kept of those
+// chunks, and
+// count as air unless they're all blocks, so that no faces are left out
+// against them.
+func coarsen(e *EnclosedChunk, scale int) *EnclosedChunk {
+	var height = e.blocks.height
+	var blocks = Blocks{make([]nbt.Block, len(e.blocks.data)), height, e.blocks.minY}
+	var counts = make(map[nbt.Block]int)
+	for x := 0; x < 16; x += scale {
+		for z := 0; z < 16; z += scale {
+			for y := 0; y < height; y += scale {
+				for blockId := range counts {
+					delete(counts, blockId)
+				}
+				for i := x; i < x+scale; i++ {
+					for k := z; k < z+scale; k++ {
+						for j := y; j < y+scale && j < height; j++ {
+							counts[lodBlock(e.blocks.Get(i, j, k))]++
+						}
+					}
+				}
+				var blockId = commonest(counts)
+				for i := x; i < x+scale; i++ {
+					for k := z; k < z+scale; k++ {
+						for j := y; j < y+scale && j < height; j++ {
+							blocks.data[j+height*(k+16*i)] = blockId
+						}
+					}
+				}
+			}
+		}
+	}
+
+	var sides EnclosingSides
+	for i, side := range e.enclosing {
+		sides[i] = coarsenSide(side, scale, blocks.minY, height)
+	}
+	var corners EnclosingCorners
+	for i, corner := range e.corners {
+		corners[i] = coarsenSide(corner, scale, blocks.minY, height)
+	}
+	return &EnclosedChunk{e.xPos, e.zPos, blocks, sides, corners, e.chunk}
+}
+
+func coarsenSide(side ChunkSide, scale int, minY, height int) ChunkSide {
+	if _, fixed := side.(*FixedChunkSide); fixed {
+		return side
+	}
+	var coarse = NewChunkSide(height, minY)
+	var counts = make(map[nbt.Block]int)
+	for x := 0; x < 16; x += scale {
+		for y := 0; y < height; y += scale {
+			for blockId := range counts {
+				delete(counts, blockId)
+			}
+			for i := x; i < x+scale; i++ {
+				for j := y; j < y+scale && j < height; j++ {
+					counts[lodBlock(side.BlockId(i, minY+j))]++
+				}
+			}
+			var blockId nbt.Block
+			if counts[0] == 0 {
+				blockId = commonest(counts)
+			}
+			for i := x; i < x+scale; i++ {
+				for j := y; j < y+scale && j < height; j++ {
+					coarse.data[coarse.index(i, j)] = blockId
+				}
+			}
+		}
+	}
+	return coarse
+}
+
+// lodBlock is the block a block counts as when coarsening: air for items
+// and block models, which are too small to keep, but still fluids for
+// fluids.
+func lodBlock(blockId nbt.Block) nbt.Block {
+	blockId &^= nbt.Waterlogged
+	switch blockId & 0xff {
+	case 8, 9:
+		return 9
+	case 10, 11:
+		return 11
+	}
+	var info = blockTypeMap[byte(blockId&0xff)]
+	if info != nil && (info.empty || info.mass == Item) || hasModel(blockId) {
+		return 0
+	}
+	return blockId
+}
+
+// commonest is the block counted the most times, going to blocks over air
+// and then the lowest block when they tie.
+func commonest(counts map[nbt.Block]int) nbt.Block {
+	var best nbt.Block
+	var bestCount = -1
+	for blockId, count := range counts {
+		switch {
+		case count > bestCount,
+			count == bestCount && best == 0,
+			count == bestCount && blockId != 0 && blockId < best:
+			best, bestCount = blockId, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestLodFilename(t *testing.T) {
+	var tests = []struct {
+		name  string
+		scale int
+		want  string
+	}{
+		{"a.obj", 1, "a.obj"},
+		{"a.obj", 4, "a_lod4.obj"},
+		{"out/world.v2.obj", 2, "out/world.v2_lod2.obj"},
+		{"world", 8, "world_lod8"},
+	}
+	for _, test := range tests {
+		if got := lodFilename(test.name, test.scale); got != test.want {
+			t.Errorf("lodFilename(%q, %d) = %q, want %q", test.name, test.scale, got, test.want)
+		}
+	}
+}
+
+func TestCommonest(t *testing.T) {
+	var tests = []struct {
+		counts map[nbt.Block]int
+		want   nbt.Block
+	}{
+		{map[nbt.Block]int{0: 5, 1: 3}, 0},
+		{map[nbt.Block]int{0: 4, 1: 4}, 1},
+		{map[nbt.Block]int{0: 2, 3: 3, 1: 3}, 1},
+		{map[nbt.Block]int{12: 1}, 12},
+	}
+	for _, test := range tests {
+		if got := commonest(test.counts); got != test.want {
+			t.Errorf("commonest(%v) = %d, want %d", test.counts, got, test.want)
+		}
+	}
+}
+
+func TestCoarsen(t *testing.T) {
+	// Stone below y=4, with dirt in the bottom of the corner 2x2 column,
+	// a torch on top, and water in one cube
+	var e, _ = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+		switch {
+		case x < 2 && z < 2 && y < 3:
+			return 3
+		case y < 4:
+			return 1
+		case x == 0 && z == 0 && y == 4:
+			return 50
+		case x >= 8 && x < 10 && z >= 8 && z < 10 && y >= 8 && y < 10:
+			return 8
+		}
+		return 0
+	})
+
+	var coarse = coarsen(e, 2)
+	var tests = []struct {
+		x, y, z int
+		want    nbt.Block
+	}{
+		{0, 0, 0, 3},   // All dirt
+		{1, 2, 1, 1},   // Dirt and stone, tied
+		{0, 4, 0, 0},   // Torches count as air
+		{9, 9, 9, 9},   // Flowing water is still
+		{15, 3, 15, 1}, // All stone
+		{15, 6, 15, 0},
+	}
+	for _, test := range tests {
+		if got := coarse.blocks.Get(test.x, test.y, test.z); got != test.want {
+			t.Errorf("%d,%d,%d: got %d, want %d", test.x, test.y, test.z, got, test.want)
+		}
+	}
+
+	// Each 2x2x2 cube is the one block
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y := 0; y < 16; y++ {
+				if coarse.blocks.Get(x, y, z) != coarse.blocks.Get(x&^1, y&^1, z&^1) {
+					t.Fatalf("%d,%d,%d differs from its cube", x, y, z)
+				}
+			}
+		}
+	}
+}
+
+func TestCoarsenSide(t *testing.T) {
+	var side = NewChunkSide(16, 0)
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 4; y++ {
+			side.data[side.index(x, y)] = 1
+		}
+	}
+	side.data[side.index(4, 3)] = 0
+
+	var coarse = coarsenSide(side, 4, 0, 16)
+	var tests = []struct {
+		x, y int
+		want nbt.Block
+	}{
+		{0, 0, 1},
+		{4, 0, 0}, // Any air makes it air
+		{15, 3, 1},
+		{0, 4, 0},
+	}
+	for _, test := range tests {
+		if got := coarse.BlockId(test.x, test.y); got != test.want {
+			t.Errorf("%d,%d: got %d, want %d", test.x, test.y, got, test.want)
+		}
+	}
+	if coarsenSide(emptySide, 4, 0, 16) != ChunkSide(emptySide) {
+		t.Error("fixed sides should be kept")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -16,6 +17,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -24,12 +27,28 @@ var (
 	blockFaces bool
 	hideBottom bool
 	noColor    bool
+	beams      bool
+	smooth     bool
+	biomes     bool
+	watertight bool
+
+	transparentFaces TransparentFaces
+	vertexNormals    VertexNormals
+	normalAngle      float64
+	ambientOcclusion bool
+	lighting         bool
+	triangles        int
+	tolerance        float64
+	hollow           int
+	stitch           bool
 
 	faceCount int
 	faceLimit int
 
 	chunkCount int
 
+	salvage bool
+
 	obj3dsmax bool
 )
 
@@ -40,8 +59,37 @@ func main() {
 	var rectx, rectz int
 	var maxProcs = runtime.GOMAXPROCS(0)
 	var prt bool
+	var lods bool
 	var solidSides bool
 	var mtlNumber bool
+	var dimension string
+	var worldPath string
+	var fullOnly bool
+	var minInhabited time.Duration
+	var indexFilename string
+	var since string
+	var stream bool
+	var spiral bool
+	var player string
+	var radius int
+	var overlays worldList
+	var live bool
+	var mmap bool
+	var validate bool
+	var dump bool
+	var query string
+	var diff string
+	var trimDir string
+	var maskImage string
+	var maskCx, maskCz, maskScale int
+	var polygonFile string
+	var exclude string
+	var selection string
+	var chunkCache int
+	var onError string
+	var transparent string
+	var normals string
+	var resources string
 
 	var defaultObjOutFilename = "a.obj"
 	var defaultPrtOutFilename = "a.prt"
@@ -51,10 +99,24 @@ func main() {
 	var outFilename string
 	commandLine.IntVar(&maxProcs, "cpu", maxProcs, "Number of cores to use")
 	commandLine.StringVar(&outFilename, "o", defaultObjOutFilename, "Name for output file")
-	commandLine.IntVar(&yMin, "y", 0, "Omit all blocks below this height. 63 is sea level")
+	commandLine.IntVar(&yMin, "y", math.MinInt32, "Omit all blocks below this height. 63 is sea level")
 	commandLine.BoolVar(&solidSides, "sides", false, "Solid sides, rather than showing underground")
-	commandLine.BoolVar(&blockFaces, "bf", false, "Don't combine adjacent faces of the same block within a column")
+	commandLine.BoolVar(&blockFaces, "bf", false, "Don't combine adjacent faces of the same block into larger rectangles")
 	commandLine.BoolVar(&hideBottom, "hb", false, "Hide bottom of world")
+	commandLine.StringVar(&transparent, "transparent", "show", "Faces behind glass, leaves and water: show, all (even inside leaves) or hide")
+	commandLine.StringVar(&resources, "resources", "", "Resource pack folder or zip, or client jar, whose block models draw the blocks mcobj has no model of its own for")
+	commandLine.StringVar(&normals, "normals", "none", "Vertex normals to write: none, smooth (averaged over the faces around each vertex) or material (only faces of the same material)")
+	commandLine.Float64Var(&normalAngle, "normalangle", 180, "Leave faces turned more than this many degrees from a face out of the normals of its corners")
+	commandLine.BoolVar(&ambientOcclusion, "ao", false, "Shade the corners of blocks by the blocks around them, as vertex colors")
+	commandLine.BoolVar(&lighting, "light", false, "Shade the corners of blocks by the block light and sky light around them, as vertex colors")
+	commandLine.BoolVar(&biomes, "biomes", false, "Tint grass, leaves and water by the colors of their biomes")
+	commandLine.IntVar(&triangles, "triangles", 0, "Simplify the mesh to about this many triangles in all")
+	commandLine.Float64Var(&tolerance, "tolerance", 0, "Simplify the mesh as far as it can without moving the surface more than this many blocks")
+	commandLine.BoolVar(&watertight, "watertight", false, "Draw a closed, manifold solid of the blocks for 3D printing, leaving out items, fluids and the insides of block models")
+	commandLine.IntVar(&hollow, "hollow", 0, "Hollow out solid blocks, leaving walls this many blocks thick")
+	commandLine.BoolVar(&stitch, "stitch", false, "Split merged faces where the corners of other faces meet their edges, so that no corner is partway along an edge")
+	commandLine.BoolVar(&smooth, "smooth", false, "Draw solid blocks as a smooth surface rather than cubes")
+	commandLine.BoolVar(&beams, "beams", false, "Draw the beams of beacons with a pyramid under them and the sky above them")
 	commandLine.BoolVar(&noColor, "g", false, "Omit materials")
 	commandLine.Float64Var(&bx, "x", 0, "Center x coordinate in blocks")
 	commandLine.Float64Var(&bz, "z", 0, "Center z coordinate in blocks")
@@ -65,8 +127,37 @@ func main() {
 	commandLine.IntVar(&rectz, "rz", math.MaxInt32, "Height(z) of rectangle size")
 	commandLine.IntVar(&faceLimit, "fk", math.MaxInt32, "Face limit (thousands of faces)")
 	commandLine.BoolVar(&prt, "prt", false, "Write out PRT file instead of Obj file")
+	commandLine.BoolVar(&lods, "lods", false, "Also write the world at a half, a quarter and an eighth of the detail, to files named with _lod2, _lod4 and _lod8")
 	commandLine.BoolVar(&obj3dsmax, "3dsmax", false, "Create .obj file compatible with 3dsMax")
 	commandLine.BoolVar(&mtlNumber, "mtlnum", false, "Number materials instead of using names")
+	commandLine.StringVar(&worldPath, "world", "", "World to export, instead of the last argument. Can be an http(s)://, sftp://user@host/path or s3://bucket/prefix URL")
+	commandLine.BoolVar(&fullOnly, "full", false, "Skip proto-chunks the world generator hasn't finished")
+	commandLine.StringVar(&indexFilename, "index", "", "File to keep which chunks each region file holds in, to skip rescanning unchanged regions next time")
+	commandLine.DurationVar(&minInhabited, "inhabited", 0, "Skip chunks players have spent less than this long near, such as 10m")
+	commandLine.StringVar(&since, "since", "", "Only export chunks saved since a time (2006-01-02T15:04:05Z) or for a duration (36h)")
+	commandLine.BoolVar(&stream, "stream", false, "Read the world a region at a time rather than from the center out, for worlds too big to list up front")
+	commandLine.BoolVar(&spiral, "spiral", false, "Output chunks in a spiral out from the center, so a cut short export is still centered")
+	commandLine.StringVar(&player, "player", "", "Center on a player, by the UUID of their playerdata file, or 'level' for the single player")
+	commandLine.IntVar(&radius, "r", math.MaxInt32, "Radius of a circle of chunks around the center")
+	commandLine.Var(&overlays, "overlay", "Another world whose chunks replace the world's. Can be given more than once, with later worlds on top")
+	commandLine.BoolVar(&live, "live", false, "Read each region file whole, and again if it changes while being read, for worlds a server is running")
+	commandLine.BoolVar(&mmap, "mmap", false, "Memory map the region files of a world on the local disk")
+	commandLine.BoolVar(&validate, "validate", false, "Check every region file and chunk for damage instead of exporting")
+	commandLine.BoolVar(&dump, "dump", false, "Print the chunk at the center, or a .dat file such as level.dat, as JSON instead of exporting")
+	commandLine.StringVar(&query, "query", "", "Print the values a path such as Level.Sections[*].Y selects from the chunk or file -dump would print")
+	commandLine.StringVar(&diff, "diff", "", "Print the tags that differ between the chunk or file -dump would print and the same in this other world or file")
+	commandLine.StringVar(&trimDir, "trim", "", "Write the selected chunks to new region files in this folder instead of exporting")
+	commandLine.StringVar(&maskImage, "mask", "", "PNG with a pixel per chunk, of which only the white chunks are exported")
+	commandLine.IntVar(&maskCx, "maskcx", 0, "Chunk x coordinate of the top left pixel of -mask")
+	commandLine.IntVar(&maskCz, "maskcz", 0, "Chunk z coordinate of the top left pixel of -mask")
+	commandLine.IntVar(&maskScale, "maskscale", 1, "Chunks across each pixel of -mask")
+	commandLine.StringVar(&polygonFile, "polygon", "", "GeoJSON file of polygons in block x,z coordinates, of which only the chunks they overlap are exported")
+	commandLine.StringVar(&selection, "sel", "", "WorldEdit selection, as CUI messages or a session file, of which only the chunks it covers are exported")
+	commandLine.StringVar(&exclude, "exclude", "", "PNG mask or GeoJSON polygons of chunks to leave out, placed like -mask")
+	commandLine.StringVar(&onError, "onerror", "skip", "What to do with a chunk that can't be read: skip, abort, or retry-N to try N more times then skip")
+	commandLine.BoolVar(&salvage, "salvage", false, "Export what can be read of chunks whose NBT is truncated or damaged, rather than skipping them")
+	commandLine.IntVar(&chunkCache, "chunkcache", 256, "Number of chunks read for their neighbours' sides to keep parsed until they're exported themselves")
+	commandLine.StringVar(&dimension, "dim", "overworld", "Dimension: overworld, nether, end, a number or namespace:name")
 	var showHelp = commandLine.Bool("h", false, "Show Help")
 	commandLine.Parse(os.Args[1:])
 
@@ -75,7 +166,7 @@ func main() {
 
 	exeDir, _ := filepath.Split(strings.Replace(os.Args[0], "\\", "/", -1))
 
-	if *showHelp || commandLine.NArg() == 0 {
+	if *showHelp || commandLine.NArg() == 0 && worldPath == "" {
 		settingsPath := filepath.Join(exeDir, "settings.txt")
 		fi, err := os.Stat(settingsPath)
 		if err == nil && (!fi.IsDir() || fi.Mode()&os.ModeSymlink != 0) {
@@ -87,7 +178,7 @@ func main() {
 			}
 		}
 
-		if commandLine.NArg() == 0 {
+		if commandLine.NArg() == 0 && worldPath == "" {
 			fmt.Fprintln(os.Stderr)
 			fmt.Fprintln(os.Stderr, "Usage: mcobj -cpu 4 -s 20 -o world1.obj", ExampleWorldPath)
 			fmt.Fprintln(os.Stderr)
@@ -96,7 +187,7 @@ func main() {
 			fmt.Println()
 			stdin := bufio.NewReader(os.Stdin)
 
-			for commandLine.NArg() == 0 {
+			for commandLine.NArg() == 0 && worldPath == "" {
 				fmt.Printf("command line: ")
 				line, _, err := stdin.ReadLine()
 				if err == io.EOF {
@@ -114,8 +205,11 @@ func main() {
 	}
 
 	manualCenter := false
+	manualDimension := false
 	commandLine.Visit(func(f *flag.Flag) {
 		switch f.Name {
+		case "dim":
+			manualDimension = true
 		case "x":
 			fallthrough
 		case "z":
@@ -155,10 +249,29 @@ func main() {
 		outFilename = defaultPrtOutFilename
 	}
 
-	if solidSides {
+	if prt && lods {
+		fmt.Fprintln(os.Stderr, "-lods error: levels of detail are only written as obj files, not with -prt")
+		return
+	}
+
+	if solidSides || watertight {
 		defaultSide = emptySide
 	}
 
+	if faces, err := ParseTransparentFaces(transparent); err == nil {
+		transparentFaces = faces
+	} else {
+		fmt.Fprintln(os.Stderr, "-transparent error:", err)
+		return
+	}
+
+	if n, err := ParseVertexNormals(normals); err == nil {
+		vertexNormals = n
+	} else {
+		fmt.Fprintln(os.Stderr, "-normals error:", err)
+		return
+	}
+
 	{
 		var jsonError = loadBlockTypesJson(filepath.Join(exeDir, "blocks.json"))
 		if jsonError != nil {
@@ -167,8 +280,16 @@ func main() {
 		}
 	}
 
+	if resources != "" {
+		if err := loadResourcePack(resources); err != nil {
+			fmt.Fprintln(os.Stderr, "-resources error:", err)
+			return
+		}
+	}
+
 	settings := &ProcessingSettings{
 		Prt:          prt,
+		Lods:         lods,
 		OutFilename:  outFilename,
 		MaxProcs:     maxProcs,
 		ManualCenter: manualCenter,
@@ -177,6 +298,55 @@ func main() {
 		Square:       square,
 		Rectx:        rectx,
 		Rectz:        rectz,
+		Dimension:    dimension,
+		FullOnly:     fullOnly,
+		Index:        indexFilename,
+		MinInhabited: int(minInhabited / (time.Second / 20)),
+		Stream:       stream,
+		Spiral:       spiral,
+		Player:       player,
+		Radius:       radius,
+		Overlays:     overlays,
+		Live:         live,
+		Mmap:         mmap,
+		Validate:     validate,
+		Dump:         dump || query != "" || diff != "",
+		Query:        query,
+		Diff:         diff,
+		TrimDir:      trimDir,
+		MaskImage:    maskImage,
+		MaskCx:       maskCx,
+		MaskCz:       maskCz,
+		MaskScale:    maskScale,
+		PolygonFile:  polygonFile,
+		Selection:    selection,
+		Exclude:      exclude,
+		ChunkCache:   chunkCache,
+	}
+	if policy, err := ParseChunkErrorPolicy(onError); err == nil {
+		settings.OnError = policy
+	} else {
+		fmt.Fprintln(os.Stderr, "-onerror error:", err)
+		return
+	}
+	if player != "" && !manualDimension {
+		settings.Dimension = ""
+	}
+
+	if since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			settings.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			settings.Since = t
+		} else {
+			fmt.Fprintln(os.Stderr, "-since error:", err)
+			return
+		}
+	}
+
+	if worldPath != "" {
+		processWorldDir(worldPath, settings)
+		return
 	}
 
 	validPath := false
@@ -211,41 +381,167 @@ func parseFakeCommandLine(commandLine *flag.FlagSet, line []byte) {
 
 type ProcessingSettings struct {
 	Prt          bool
+	Lods         bool // Write levels of detail too
 	OutFilename  string
 	MaxProcs     int
 	ManualCenter bool
 	Cx, Cz       int
 	Square       int
 	Rectx, Rectz int
+	Dimension    string
+	FullOnly     bool
+	MinInhabited int // Ticks
+	Index        string
+	Since        time.Time
+	Stream       bool
+	Spiral       bool
+	Player       string // Center on a player, and in their dimension if Dimension is ""
+	Radius       int
+	Overlays     []string // Worlds merged on top, with the last on top
+	Live         bool
+	Mmap         bool
+	Validate     bool
+	Dump         bool // Print the center chunk as JSON
+	Query        string
+	Diff         string // World or file to compare the dump with
+	TrimDir      string
+	MaskImage    string
+	MaskCx       int
+	MaskCz       int
+	MaskScale    int
+	PolygonFile  string
+	Selection    string
+	Exclude      string // PNG or GeoJSON file of chunks to leave out
+	ChunkCache   int    // Chunks kept parsed after loading them for their sides
+	OnError      ChunkErrorPolicy
+}
+
+// loadExcludeMask reads -exclude as a PNG or as GeoJSON polygons, going by
+// its extension.
+func loadExcludeMask(settings *ProcessingSettings) (mcworld.ChunkMask, error) {
+	if strings.EqualFold(filepath.Ext(settings.Exclude), ".png") {
+		return mcworld.LoadImageChunkMask(settings.Exclude, &mcworld.AllChunksMask{}, settings.MaskCx, settings.MaskCz, settings.MaskScale)
+	}
+	return mcworld.LoadPolygonChunkMask(settings.Exclude, &mcworld.AllChunksMask{})
+}
+
+// worldList is a flag that can be given more than once.
+type worldList []string
+
+func (l *worldList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *worldList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
 func processWorldDir(dirpath string, settings *ProcessingSettings) {
-	var fi, err = os.Stat(dirpath)
-	if err != nil {
+	if fi, err := os.Stat(dirpath); settings.Dump && err == nil && !fi.IsDir() && strings.EqualFold(filepath.Ext(dirpath), ".dat") {
+		dumpFile(dirpath, settings)
+		return
+	}
+
+	if _, err := os.Stat(dirpath); err != nil && !strings.Contains(dirpath, "://") {
 		fmt.Fprintln(os.Stderr, "World error:", err)
 		return
-	} else if !fi.IsDir() {
-		fmt.Fprintln(os.Stderr, dirpath, "is not a directory")
+	}
+
+	var (
+		dimension = settings.Dimension
+		player    *nbt.Player
+	)
+	if settings.Player != "" {
+		var name = settings.Player
+		if name == "level" {
+			name = ""
+		}
+		var playerErr error
+		player, playerErr = mcworld.ReadPlayer(dirpath, name)
+		if playerErr != nil {
+			fmt.Fprintln(os.Stderr, "Player error:", playerErr)
+			return
+		}
+		if dimension == "" {
+			dimension = player.Dimension
+		}
+	}
+
+	var openDimension = mcworld.OpenDimension
+	if settings.Mmap {
+		openDimension = mcworld.OpenMappedDimension
+	}
+	if settings.Live {
+		openDimension = mcworld.OpenLiveDimension
+	}
+
+	var world, worldErr = openDimension(dirpath, dimension)
+	if worldErr != nil {
+		fmt.Fprintln(os.Stderr, "World error:", worldErr)
+		return
+	}
+	if len(settings.Overlays) != 0 {
+		var worlds = []mcworld.World{world}
+		for _, overlayPath := range settings.Overlays {
+			var overlay, overlayErr = openDimension(overlayPath, dimension)
+			if overlayErr != nil {
+				fmt.Fprintln(os.Stderr, "Overlay world error:", overlayErr)
+				return
+			}
+			worlds = append(worlds, overlay)
+		}
+		world = mcworld.NewOverlayWorld(worlds...)
+	}
+	if closer, ok := world.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if indexer, ok := world.(mcworld.RegionIndexer); ok && settings.Index != "" {
+		var index, indexErr = mcworld.OpenRegionIndex(settings.Index)
+		if indexErr != nil {
+			fmt.Fprintln(os.Stderr, "Index error:", indexErr)
+			return
+		}
+		indexer.SetRegionIndex(index)
+		defer func() {
+			if err := index.Save(); err != nil {
+				fmt.Fprintln(os.Stderr, "Index error:", err)
+			}
+		}()
+	}
+
+	var level, levelErr = world.Level()
+	if levelErr == nil && level.Name != "" {
+		fmt.Printf("World: %s (seed %d)\n", level.Name, level.Seed)
+	}
+	fmt.Println("Format:", mcworld.Describe(world))
+
+	if settings.Validate {
+		validateWorld(world)
+		return
 	}
 
 	// Pick cx, cz
 	var cx, cz int
 	if settings.ManualCenter {
 		cx, cz = settings.Cx, settings.Cz
+	} else if player != nil {
+		cx, cz = int(math.Floor(player.X/16)), int(math.Floor(player.Z/16))
 	} else {
-		var file, fileErr = os.Open(filepath.Join(dirpath, "level.dat"))
-		defer file.Close()
-		if fileErr != nil {
-			fmt.Fprintln(os.Stderr, "os.Open", fileErr)
-			return
-		}
-		level, err := nbt.ReadLevelDat(file)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "nbt.ReadLevelDat", err)
+		if os.IsNotExist(levelErr) {
+			// Such as a folder of loose region files
+			fmt.Fprintln(os.Stderr, "Level warning: no level.dat, so centering on chunk 0,0")
+		} else if levelErr != nil {
+			fmt.Fprintln(os.Stderr, "Level error:", levelErr)
 			return
+		} else {
+			cx, cz = level.SpawnX/16, level.SpawnZ/16
 		}
-		file.Close()
-		cx, cz = level.SpawnX/16, level.SpawnZ/16
+	}
+
+	if settings.Dump {
+		dumpChunk(world, cx, cz, dimension, settings)
+		return
 	}
 
 	// Create ChunkMask
@@ -275,35 +571,130 @@ func processWorldDir(dirpath string, settings *ProcessingSettings) {
 			var hz = settings.Rectz / 2
 			chunkMask = &mcworld.RectangleChunkMask{math.MinInt32, cz - hz, math.MaxInt32, cz - hz + settings.Rectz}
 		}
+	} else if settings.Radius != math.MaxInt32 {
+		chunkLimit = math.MaxInt32
+		chunkMask = &mcworld.CircleChunkMask{X: cx, Z: cz, Radius: settings.Radius}
 	} else {
 		chunkLimit = math.MaxInt32
 		chunkMask = &mcworld.AllChunksMask{}
 	}
+	if settings.MaskImage != "" {
+		var imageMask, imageErr = mcworld.LoadImageChunkMask(settings.MaskImage, chunkMask, settings.MaskCx, settings.MaskCz, settings.MaskScale)
+		if imageErr != nil {
+			fmt.Fprintln(os.Stderr, "Mask error:", imageErr)
+			return
+		}
+		chunkMask = imageMask
+	}
+	if settings.PolygonFile != "" {
+		var polygonMask, polygonErr = mcworld.LoadPolygonChunkMask(settings.PolygonFile, chunkMask)
+		if polygonErr != nil {
+			fmt.Fprintln(os.Stderr, "Mask error:", polygonErr)
+			return
+		}
+		chunkMask = polygonMask
+	}
+	if settings.Selection != "" {
+		var selectionMask, selectionErr = mcworld.LoadSelectionChunkMask(settings.Selection, chunkMask)
+		if selectionErr != nil {
+			fmt.Fprintln(os.Stderr, "Mask error:", selectionErr)
+			return
+		}
+		chunkMask = selectionMask
+	}
+	if settings.Exclude != "" {
+		var excludeMask, excludeErr = loadExcludeMask(settings)
+		if excludeErr != nil {
+			fmt.Fprintln(os.Stderr, "Mask error:", excludeErr)
+			return
+		}
+		chunkMask = &mcworld.DifferenceChunkMask{Mask: chunkMask, Minus: excludeMask}
+	}
+	if settings.MinInhabited > 0 {
+		chunkMask = &mcworld.InhabitedChunkMask{Mask: chunkMask, Opener: world, MinTicks: settings.MinInhabited}
+	}
+	if settings.FullOnly {
+		chunkMask = &mcworld.GeneratedChunkMask{Mask: chunkMask, Opener: world}
+	}
+	if !settings.Since.IsZero() {
+		chunkMask = &mcworld.ModifiedSinceMask{Mask: chunkMask, Since: settings.Since}
+	}
 
-	var world = mcworld.OpenWorld(dirpath)
-	var pool, poolErr = world.ChunkPool(chunkMask)
-	if poolErr != nil {
-		fmt.Fprintln(os.Stderr, "Chunk pool error:", poolErr)
+	if settings.TrimDir != "" {
+		var skipped int
+		var written, trimErr = mcworld.TrimWorld(world, chunkMask, settings.TrimDir, func(x, z int, err error) {
+			fmt.Fprintf(os.Stderr, "Trim warning: skipped chunk %d,%d: %v\n", x, z, err)
+			skipped++
+		})
+		if trimErr != nil {
+			fmt.Fprintln(os.Stderr, "Trim error:", trimErr)
+			return
+		}
+		fmt.Printf("Wrote %d chunks to %s\n", written, settings.TrimDir)
+		if skipped != 0 {
+			fmt.Printf("Skipped %d chunks that couldn't be read\n", skipped)
+		}
 		return
 	}
 
+	var (
+		pool   mcworld.ChunkPool
+		chunks mcworld.ChunkIterator
+		total  int
+	)
+	if settings.Stream {
+		var iterErr error
+		chunks, iterErr = mcworld.IterateChunks(world, chunkMask)
+		if iterErr != nil {
+			fmt.Fprintln(os.Stderr, "Chunk iterator error:", iterErr)
+			return
+		}
+		total = chunks.Remaining()
+	} else {
+		var poolErr error
+		pool, poolErr = world.ChunkPool(chunkMask)
+		if poolErr != nil {
+			fmt.Fprintln(os.Stderr, "Chunk pool error:", poolErr)
+			return
+		}
+		printRegionReports(pool)
+		total = pool.Remaining()
+		if settings.Spiral {
+			chunks = mcworld.SpiralChunks(pool, cx, cz)
+		}
+	}
+
 	var generator OutputGenerator
 	if settings.Prt {
 		generator = new(PrtGenerator)
+	} else if settings.Lods {
+		generator = new(LodGenerator)
 	} else {
 		generator = new(ObjGenerator)
 	}
 	var boundary = new(BoundaryLocator)
 	boundary.Init()
-	var startErr = generator.Start(settings.OutFilename, pool.Remaining(), settings.MaxProcs, boundary)
+	var startErr = generator.Start(settings.OutFilename, total, settings.MaxProcs, boundary)
 	if startErr != nil {
 		fmt.Fprintln(os.Stderr, "Generator start error:", startErr)
 		return
 	}
 
-	if walkEnclosedChunks(pool, world, chunkMask, chunkLimit, cx, cz, generator.GetEnclosedJobsChan()) {
+	var (
+		started     bool
+		chunkErrors = NewChunkErrors(settings.OnError)
+		chunkCache  = NewChunkCache(world, chunkErrors, settings.ChunkCache)
+	)
+	if chunks != nil {
+		started = walkStreamedChunks(chunks, chunkCache, chunkMask, chunkLimit, generator.GetEnclosedJobsChan())
+		printRegionReports(chunks)
+	} else {
+		started = walkEnclosedChunks(pool, chunkCache, chunkMask, chunkLimit, cx, cz, generator.GetEnclosedJobsChan())
+	}
+	if started {
 		<-generator.GetCompleteChan()
 	}
+	chunkErrors.PrintSummary(os.Stderr)
 
 	var closeErr = generator.Close()
 	if closeErr != nil {
@@ -324,47 +715,110 @@ type EnclosedChunkJob struct {
 	enclosed *EnclosedChunk
 }
 
-func walkEnclosedChunks(pool mcworld.ChunkPool, opener mcworld.ChunkOpener, chunkMask mcworld.ChunkMask, chunkLimit int, cx, cz int, enclosedsChan chan *EnclosedChunkJob) bool {
+// walkEnclosedChunks encloses chunks from the center out. Each chunk is
+// held back until the next is read, so the last one sent is marked last
+// even when the chunks after it fail or the export is aborted.
+func walkEnclosedChunks(pool mcworld.ChunkPool, chunkCache *ChunkCache, chunkMask mcworld.ChunkMask, chunkLimit int, cx, cz int, enclosedsChan chan *EnclosedChunkJob) bool {
 	var (
 		sideCache = new(SideCache)
-		started   = false
+		pending   *EnclosedChunk
 	)
 
-	for i := 0; moreChunks(pool.Remaining(), chunkLimit); i++ {
-		for x := 0; x < i && moreChunks(pool.Remaining(), chunkLimit); x++ {
-			for z := 0; z < i && moreChunks(pool.Remaining(), chunkLimit); z++ {
+	var more = func() bool {
+		return moreChunks(pool.Remaining(), chunkLimit) && !chunkCache.errors.Aborted()
+	}
+	for i := 0; more(); i++ {
+		for x := 0; x < i && more(); x++ {
+			for z := 0; z < i && more(); z++ {
 				var (
 					ax = cx + unzigzag(x)
 					az = cz + unzigzag(z)
 				)
 
 				if pool.Pop(ax, az) {
-					loadSide(sideCache, opener, chunkMask, ax-1, az)
-					loadSide(sideCache, opener, chunkMask, ax+1, az)
-					loadSide(sideCache, opener, chunkMask, ax, az-1)
-					loadSide(sideCache, opener, chunkMask, ax, az+1)
-
-					var chunk, loadErr = loadChunk2(opener, ax, az)
-					if loadErr != nil {
-						fmt.Println(loadErr)
-					} else {
+					loadSides(sideCache, chunkCache, chunkMask, ax, az)
+
+					var chunk, loadErr = chunkCache.Take(ax, az)
+					if loadErr == nil {
 						var enclosed = sideCache.EncloseChunk(chunk)
 						sideCache.AddChunk(chunk)
 						chunkCount++
-						enclosedsChan <- &EnclosedChunkJob{!moreChunks(pool.Remaining(), chunkLimit), enclosed}
-						started = true
+						if pending != nil {
+							enclosedsChan <- &EnclosedChunkJob{false, pending}
+						}
+						pending = enclosed
 					}
 				}
 			}
 		}
 	}
 
-	return started
+	if pending != nil {
+		enclosedsChan <- &EnclosedChunkJob{true, pending}
+	}
+	return pending != nil
+}
+
+// walkStreamedChunks encloses chunks in the order the iterator reads them,
+// holding each back until the next is read like walkEnclosedChunks.
+func walkStreamedChunks(chunks mcworld.ChunkIterator, chunkCache *ChunkCache, chunkMask mcworld.ChunkMask, chunkLimit int, enclosedsChan chan *EnclosedChunkJob) bool {
+	var (
+		sideCache = new(SideCache)
+		pending   *EnclosedChunk
+	)
+
+	var x, z, more = chunks.Next()
+	for ; more && moreChunks(1, chunkLimit) && !chunkCache.errors.Aborted(); x, z, more = chunks.Next() {
+		loadSides(sideCache, chunkCache, chunkMask, x, z)
+
+		var chunk, loadErr = chunkCache.Take(x, z)
+		if loadErr == nil {
+			var enclosed = sideCache.EncloseChunk(chunk)
+			sideCache.AddChunk(chunk)
+			chunkCount++
+			if pending != nil {
+				enclosedsChan <- &EnclosedChunkJob{false, pending}
+			}
+			pending = enclosed
+		}
+	}
+
+	if pending != nil {
+		enclosedsChan <- &EnclosedChunkJob{true, pending}
+	}
+	return pending != nil
+}
+
+func validateWorld(world mcworld.World) {
+	var validator, ok = world.(mcworld.Validator)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Validate error: this world format can't be validated")
+		return
+	}
+
+	var validation, err = validator.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Validate error:", err)
+		return
+	}
+	for _, report := range validation.Reports {
+		fmt.Println("Region problem:", report)
+	}
+	fmt.Printf("Validated %d chunks in %d region files: %d with problems\n", validation.Chunks, validation.Regions, len(validation.Reports))
+}
+
+func printRegionReports(pool interface{}) {
+	if reporter, ok := pool.(mcworld.RegionReporter); ok {
+		for _, report := range reporter.Reports() {
+			fmt.Fprintln(os.Stderr, "Region warning:", report)
+		}
+	}
 }
 
 type Blocks struct {
 	data   []nbt.Block
 	height int
+	minY   int
 }
 
 type BlockColumn []nbt.Block
@@ -403,26 +857,61 @@ func loadChunk(filename string) (*nbt.Chunk, error) {
 	return chunk, err
 }
 
-func loadChunk2(opener mcworld.ChunkOpener, x, z int) (*nbt.Chunk, error) {
+var chunkBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// loadChunk2 reads the chunk at x,z. With -salvage, a chunk that's
+// damaged is read as far as it can be, and what was wrong with it is
+// returned as damage.
+func loadChunk2(opener mcworld.ChunkOpener, x, z int) (chunk *nbt.Chunk, damage error, err error) {
 	var r, openErr = opener.OpenChunk(x, z)
 	if openErr != nil {
-		return nil, openErr
+		return nil, nil, openErr
 	}
 	defer r.Close()
 
-	var chunk, nbtErr = nbt.ReadChunkNbt(r)
-	if nbtErr != nil {
-		return nil, nbtErr
+	// The chunk is read in place from the buffer, which it doesn't keep
+	var buf = chunkBuffers.Get().(*bytes.Buffer)
+	defer chunkBuffers.Put(buf)
+	buf.Reset()
+	var _, readErr = buf.ReadFrom(r)
+
+	if salvage {
+		chunk, damage = nbt.ReadChunkBytesLenient(buf.Bytes())
+		if damage == nil {
+			damage = readErr
+		}
+		if damage != nil {
+			// What's left may not have got as far as the chunk's position
+			chunk.XPos, chunk.ZPos = x, z
+		}
+		return chunk, damage, nil
+	}
+	if readErr != nil {
+		return nil, nil, readErr
 	}
-	return chunk, nil
+
+	chunk, err = nbt.ReadChunkBytes(buf.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunk, nil, nil
 }
 
-func loadSide(sideCache *SideCache, opener mcworld.ChunkOpener, chunkMask mcworld.ChunkMask, x, z int) {
+// loadSides loads the sides of the chunks around x,z, and the corners of
+// those diagonally across, that aren't already cached.
+func loadSides(sideCache *SideCache, chunkCache *ChunkCache, chunkMask mcworld.ChunkMask, x, z int) {
+	for dx := -1; dx <= 1; dx++ {
+		for dz := -1; dz <= 1; dz++ {
+			if dx != 0 || dz != 0 {
+				loadSide(sideCache, chunkCache, chunkMask, x+dx, z+dz)
+			}
+		}
+	}
+}
+
+func loadSide(sideCache *SideCache, chunkCache *ChunkCache, chunkMask mcworld.ChunkMask, x, z int) {
 	if !sideCache.HasSide(x, z) && !chunkMask.IsMasked(x, z) {
-		var chunk, loadErr = loadChunk2(opener, x, z)
-		if loadErr != nil {
-			fmt.Println(loadErr)
-		} else {
+		if chunk, loadErr := chunkCache.Load(x, z); loadErr == nil {
 			sideCache.AddChunk(chunk)
 		}
 	}
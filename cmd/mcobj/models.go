@@ -0,0 +1,1182 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"github.com/quag/mcobj/resourcepack"
+	"math"
+)
+
+// modelBox is a box of a block model, from x0,y0,z0 to x1,y1,z1 in
+// sixteenths of a block.
+type modelBox [6]int
+
+// blockModel gives the boxes of a block that isn't a whole cube, looking at
+// its neighbours for the shapes that depend on them.
+type blockModel func(site blockSite, blockId nbt.Block) []modelBox
+
+// blockSite is where a block is in an enclosed chunk.
+type blockSite struct {
+	e        *EnclosedChunk
+	boundary *BoundaryLocator
+	x, y, z  int
+}
+
+func (s blockSite) next(dx, dy, dz int) blockSite {
+	return blockSite{s.e, s.boundary, s.x + dx, s.y + dy, s.z + dz}
+}
+
+func (s blockSite) neighbour(dx, dy, dz int) nbt.Block {
+	return s.e.Get(s.x+dx, s.y+dy, s.z+dz)
+}
+
+// blockModels are the models of blocks by id. Blocks without one are cubes.
+var blockModels [256]blockModel
+
+var (
+	stairsBlocks    [256]bool
+	woodFenceBlocks [256]bool
+	fenceGateBlocks [256]bool
+	wallBlocks      [256]bool
+	paneBlocks      [256]bool
+	plantBlocks     [256]bool
+	railBlocks      [256]bool
+	attachedBlocks  [256]bool // Drawn flat against the blocks they hang on
+	redstoneBlocks  [256]bool // That wire runs to from any side
+)
+
+func init() {
+	for _, id := range []byte{53, 67, 108, 109, 114, 128, 134, 135, 136, 156, 163, 164, 180, 203} {
+		blockModels[id] = stairsModel
+		stairsBlocks[id] = true
+	}
+	for _, id := range []byte{44, 126, 182, 205} {
+		blockModels[id] = slabModel
+	}
+	for _, id := range []byte{85, 188, 189, 190, 191, 192} {
+		blockModels[id] = fenceModel
+		woodFenceBlocks[id] = true
+	}
+	blockModels[113] = fenceModel // Nether brick
+	blockModels[139] = wallModel
+	wallBlocks[139] = true
+	for _, id := range []byte{107, 183, 184, 185, 186, 187} {
+		fenceGateBlocks[id] = true
+	}
+	for _, id := range []byte{101, 102, 160} {
+		blockModels[id] = paneModel
+		paneBlocks[id] = true
+	}
+	for _, id := range []byte{50, 75, 76} {
+		blockModels[id] = torchModel
+	}
+	for _, id := range []byte{64, 71, 193, 194, 195, 196, 197} {
+		blockModels[id] = doorModel
+	}
+	blockModels[96] = trapdoorModel
+	blockModels[167] = trapdoorModel
+	blockModels[78] = snowModel
+	blockModels[26] = bedModel
+	blockModels[68] = wallSignModel
+	for _, id := range []byte{54, 130, 146} {
+		blockModels[id] = chestModel
+	}
+	blockModels[60] = loweredModel  // Farmland
+	blockModels[208] = loweredModel // Dirt path
+	blockModels[171] = flatModel    // Carpet
+	blockModels[111] = flatModel    // Lily pad
+	for _, id := range []byte{70, 72, 147, 148} {
+		blockModels[id] = plateModel
+	}
+	for _, id := range []byte{29, 33, 34} {
+		blockModels[id] = pistonModel
+	}
+	blockModels[198] = endRodModel
+	blockModels[116] = enchantingTableModel
+	blockModels[118] = cauldronModel
+	blockModels[145] = anvilModel
+	blockModels[154] = hopperModel
+	blockModels[199] = chorusPlantModel
+	for _, id := range []byte{28, 55, 69, 70, 72, 75, 76, 77, 131, 143, 146, 147, 148, 149, 150, 151, 152, 178} {
+		redstoneBlocks[id] = true
+	}
+	for _, id := range []byte{6, 31, 32, 37, 38, 39, 40, 59, 83, 115, 141, 142, 175, 207} {
+		plantBlocks[id] = true
+	}
+	for _, id := range []byte{27, 28, 66, 157} {
+		railBlocks[id] = true
+	}
+	turnedModels[63] = standingModels(signElements)
+	turnedModels[176] = standingModels([]resourcepack.Element{bannerElements[0], bannerElements[1], bannerCloth})
+	turnedModels[177] = wallModels([]resourcepack.Element{wallBannerElements[0], wallBannerCloth})
+	turnedModels[144] = skullModels()
+	attachedBlocks[65] = true  // Ladder
+	attachedBlocks[106] = true // Vines and glow lichen
+}
+
+func hasModel(blockId nbt.Block) bool {
+	var id = blockId & 0xff
+	return blockModels[id] != nil || plantBlocks[id] || railBlocks[id] || attachedBlocks[id] || turnedModels[id] != nil || id == 55 || fluid(id) != 0 || packBlocks[id] && packModels[blockId&^nbt.Waterlogged] != nil
+}
+
+// addModel adds the faces of a model's boxes, leaving out those against
+// another of its boxes, a solid neighbour or a box of a neighbour's model.
+func (fs *Faces) addModel(site blockSite, blockId nbt.Block, boxes []modelBox) {
+	for i, box := range boxes {
+	sides:
+		for side, faceSide := range faceSides {
+			for j, other := range boxes {
+				if j != i && box.faceCoveredBy(other, faceSide) {
+					continue sides
+				}
+			}
+
+			var lo, hi = box.corners()
+			var plane = lo[faceSide.normal]
+			if faceSide.positive {
+				plane = hi[faceSide.normal]
+			}
+			if plane == 0 || plane == 16 {
+				var d [3]int
+				if plane == 0 {
+					d[faceSide.normal] = -1
+				} else {
+					d[faceSide.normal] = 1
+				}
+				var neighbour = site.neighbour(d[0], d[1], d[2])
+				if fs.boundary.IsSolid(neighbour) {
+					continue
+				}
+				if model := blockModels[neighbour&0xff]; model != nil {
+					// Moved next to this block, over the face
+					var offset = 16
+					if plane == 0 {
+						offset = -16
+					}
+					for _, other := range model(site.next(d[0], d[1], d[2]), neighbour) {
+						other[faceSide.normal] += offset
+						other[faceSide.normal+3] += offset
+						if box.faceCoveredBy(other, faceSide) {
+							continue sides
+						}
+					}
+				}
+			}
+
+			for k, base := range [3]int{16 * site.x, 16 * site.y, 16 * site.z} {
+				lo[k] += base
+				hi[k] += base
+			}
+			var v = boxFace(side, lo, hi)
+			fs.AddModelFace(blockId, v[0], v[1], v[2], v[3])
+		}
+	}
+}
+
+func (box modelBox) corners() (lo, hi [3]int) {
+	return [3]int{box[0], box[1], box[2]}, [3]int{box[3], box[4], box[5]}
+}
+
+// faceCoveredBy reports whether other is against the whole of the face of
+// box on the given side.
+func (box modelBox) faceCoveredBy(other modelBox, side faceSide) bool {
+	var lo, hi = box.corners()
+	var otherLo, otherHi = other.corners()
+	if side.positive {
+		if otherLo[side.normal] != hi[side.normal] {
+			return false
+		}
+	} else if otherHi[side.normal] != lo[side.normal] {
+		return false
+	}
+	return otherLo[side.u] <= lo[side.u] && otherHi[side.u] >= hi[side.u] &&
+		otherLo[side.v] <= lo[side.v] && otherHi[side.v] >= hi[side.v]
+}
+
+// A direction across the ground, as steps in x and z.
+type direction struct{ x, z int }
+
+var (
+	east  = direction{1, 0}
+	west  = direction{-1, 0}
+	south = direction{0, 1}
+	north = direction{0, -1}
+)
+
+func (d direction) ccw() direction      { return direction{d.z, -d.x} }
+func (d direction) cw() direction       { return direction{-d.z, d.x} }
+func (d direction) opposite() direction { return direction{-d.x, -d.z} }
+
+// Stair shapes, as nbt.BlockState saves them in the data above the facing
+// and half, less one.
+const (
+	stairsStraight = iota
+	stairsInnerLeft
+	stairsInnerRight
+	stairsOuterLeft
+	stairsOuterRight
+)
+
+var stairsFacings = [4]direction{east, west, south, north}
+
+// stairsModel is a half slab with a step on it, or under it for upside down
+// stairs, that turns inner and outer corners with the stairs beside it.
+func stairsModel(site blockSite, blockId nbt.Block) []modelBox {
+	var data = int(blockId >> 8)
+	var facing = stairsFacings[data&3]
+	var shape = data>>3 - 1
+	if shape < 0 {
+		shape = stairsShape(site, blockId)
+	}
+
+	var slabY, stepY = 0, 8
+	if data&4 != 0 {
+		slabY, stepY = 8, 0
+	}
+	var boxes = []modelBox{{0, slabY, 0, 16, slabY + 8, 16}}
+
+	var side = func(qx, qz int, d direction) bool {
+		return d.x == 2*qx-1 || d.z == 2*qz-1
+	}
+	for qx := 0; qx < 2; qx++ {
+		for qz := 0; qz < 2; qz++ {
+			var step bool
+			switch shape {
+			case stairsStraight:
+				step = side(qx, qz, facing)
+			case stairsInnerLeft:
+				step = side(qx, qz, facing) || side(qx, qz, facing.ccw())
+			case stairsInnerRight:
+				step = side(qx, qz, facing) || side(qx, qz, facing.cw())
+			case stairsOuterLeft:
+				step = side(qx, qz, facing) && side(qx, qz, facing.ccw())
+			case stairsOuterRight:
+				step = side(qx, qz, facing) && side(qx, qz, facing.cw())
+			}
+			if step {
+				boxes = append(boxes, modelBox{8 * qx, stepY, 8 * qz, 8*qx + 8, stepY + 8, 8*qz + 8})
+			}
+		}
+	}
+	return append(boxes[:1], mergeBoxes(boxes[1:])...)
+}
+
+// stairsShape works out the shape of stairs from those beside them, as the
+// game does, for worlds from before the shape was saved.
+func stairsShape(site blockSite, blockId nbt.Block) int {
+	var data = int(blockId >> 8)
+	var facing = stairsFacings[data&3]
+	var neighbour = func(d direction) (bool, direction) {
+		var other = site.neighbour(d.x, 0, d.z)
+		var otherData = int(other >> 8)
+		return stairsBlocks[other&0xff] && otherData&4 == data&4, stairsFacings[otherData&3]
+	}
+	var canTakeShape = func(d direction) bool {
+		var stairs, otherFacing = neighbour(d)
+		return !stairs || otherFacing != facing
+	}
+
+	if stairs, behind := neighbour(facing); stairs && behind.x != facing.x && behind.z != facing.z && canTakeShape(behind.opposite()) {
+		if behind == facing.ccw() {
+			return stairsOuterLeft
+		}
+		return stairsOuterRight
+	}
+	if stairs, front := neighbour(facing.opposite()); stairs && front.x != facing.x && front.z != facing.z && canTakeShape(front) {
+		if front == facing.ccw() {
+			return stairsInnerLeft
+		}
+		return stairsInnerRight
+	}
+	return stairsStraight
+}
+
+// slabModel is the bottom half of a block, or the top half when the data
+// has 8 set.
+func slabModel(site blockSite, blockId nbt.Block) []modelBox {
+	if blockId>>8&8 != 0 {
+		return []modelBox{{0, 8, 0, 16, 16, 16}}
+	}
+	return []modelBox{{0, 0, 0, 16, 8, 16}}
+}
+
+var sideDirections = [4]direction{north, east, south, west}
+
+// armBox is a box from the edge of a post halfWidth across, out in d to
+// the side of the block.
+func armBox(d direction, halfWidth, postHalfWidth, y0, y1 int) modelBox {
+	var box = modelBox{8 - halfWidth, y0, 8 - halfWidth, 8 + halfWidth, y1, 8 + halfWidth}
+	switch d {
+	case east:
+		box[0], box[3] = 8+postHalfWidth, 16
+	case west:
+		box[0], box[3] = 0, 8-postHalfWidth
+	case south:
+		box[2], box[5] = 8+postHalfWidth, 16
+	case north:
+		box[2], box[5] = 0, 8-postHalfWidth
+	}
+	return box
+}
+
+// fenceModel is a post with two rails out to each neighbour it connects
+// to: fences of the same sort, gates and solid blocks.
+func fenceModel(site blockSite, blockId nbt.Block) []modelBox {
+	var boxes = []modelBox{{6, 0, 6, 10, 16, 10}}
+	for _, d := range sideDirections {
+		var other = site.neighbour(d.x, 0, d.z)
+		var sameSort = other&0xff == blockId&0xff || woodFenceBlocks[other&0xff] && woodFenceBlocks[blockId&0xff]
+		if sameSort || fenceGateBlocks[other&0xff] || site.boundary.IsSolid(other) {
+			boxes = append(boxes, armBox(d, 1, 2, 6, 9), armBox(d, 1, 2, 12, 15))
+		}
+	}
+	return boxes
+}
+
+// wallModel is a post with a lower wall out to each neighbour it connects
+// to: walls, gates, panes and bars, and solid blocks. A straight run of
+// wall with nothing on top has no post.
+func wallModel(site blockSite, blockId nbt.Block) []modelBox {
+	var connected [4]bool
+	for i, d := range sideDirections {
+		var other = site.neighbour(d.x, 0, d.z) & 0xff
+		connected[i] = wallBlocks[other] || fenceGateBlocks[other] || paneBlocks[other] || site.boundary.IsSolid(other)
+	}
+
+	var straight = connected[0] == connected[2] && connected[1] == connected[3] && connected[0] != connected[1]
+	if straight && site.neighbour(0, 1, 0) == 0 {
+		if connected[0] {
+			return []modelBox{{5, 0, 0, 11, 14, 16}}
+		}
+		return []modelBox{{0, 0, 5, 16, 14, 11}}
+	}
+
+	var boxes = []modelBox{{4, 0, 4, 12, 16, 12}}
+	for i, d := range sideDirections {
+		if connected[i] {
+			boxes = append(boxes, armBox(d, 3, 4, 0, 14))
+		}
+	}
+	return boxes
+}
+
+// paneModel is a thin post with slats out to each neighbour it connects
+// to: other panes and bars, walls and solid blocks.
+func paneModel(site blockSite, blockId nbt.Block) []modelBox {
+	var boxes = []modelBox{{7, 0, 7, 9, 16, 9}}
+	for _, d := range sideDirections {
+		var other = site.neighbour(d.x, 0, d.z) & 0xff
+		if paneBlocks[other] || wallBlocks[other] || site.boundary.IsSolid(other) {
+			boxes = append(boxes, armBox(d, 1, 1, 0, 16))
+		}
+	}
+	return mergeBoxes(boxes)
+}
+
+// torchFacings are the directions wall torches point, away from their wall,
+// by their data less one.
+var torchFacings = [4]direction{east, west, south, north}
+
+// torchModel is a stick standing on the floor, or leaning out from a wall
+// in the direction it faces. Torches also hold lanterns, standing and
+// hanging, and from one to four candles.
+func torchModel(site blockSite, blockId nbt.Block) []modelBox {
+	var data = int(blockId >> 8)
+	switch {
+	case data >= 1 && data <= 4:
+		// Against the wall behind it, a little above the floor
+		var facing = torchFacings[data-1]
+		var box = modelBox{7, 3, 7, 9, 13, 9}
+		box[0] -= 5 * facing.x
+		box[3] -= 5 * facing.x
+		box[2] -= 5 * facing.z
+		box[5] -= 5 * facing.z
+		return []modelBox{box}
+	case data == nbt.TorchLantern:
+		return []modelBox{{5, 0, 5, 11, 7, 11}, {6, 7, 6, 10, 9, 10}}
+	case data == nbt.TorchHangingLantern:
+		return []modelBox{{5, 1, 5, 11, 8, 11}, {6, 8, 6, 10, 10, 10}, {7, 10, 7, 9, 16, 9}}
+	case data >= nbt.TorchCandles && data < nbt.TorchCandles+4:
+		var candles = []modelBox{{7, 0, 7, 9, 6, 9}, {9, 0, 6, 11, 5, 8}, {5, 0, 8, 7, 4, 10}, {8, 0, 9, 10, 3, 11}}
+		var boxes = candles[:data-nbt.TorchCandles+1]
+		if len(boxes) > 1 {
+			// Moved apart from the one in the middle
+			boxes[0] = modelBox{6, 0, 6, 8, 6, 8}
+		}
+		return boxes
+	}
+	return []modelBox{{7, 0, 7, 9, 10, 9}}
+}
+
+// snowModel is from one to eight layers of snow, each two sixteenths
+// thick, by the data plus one.
+func snowModel(site blockSite, blockId nbt.Block) []modelBox {
+	var layers = int(blockId>>8&7) + 1
+	return []modelBox{{0, 0, 0, 16, 2 * layers, 16}}
+}
+
+// loweredModel is a block a sixteenth short of the top, as farmland and
+// dirt paths are.
+func loweredModel(site blockSite, blockId nbt.Block) []modelBox {
+	return []modelBox{{0, 0, 0, 16, 15, 16}}
+}
+
+var bedFacings = [4]direction{south, west, north, east}
+
+// bedModel is a mattress on legs at the corners of the end of the bed each
+// half is at: the head's in the way the bed faces, and the foot's behind.
+func bedModel(site blockSite, blockId nbt.Block) []modelBox {
+	var data = int(blockId >> 8)
+	var end = bedFacings[data&3]
+	if data&8 == 0 {
+		end = end.opposite()
+	}
+	var boxes = []modelBox{{0, 3, 0, 16, 9, 16}}
+	for _, side := range [2]direction{end.cw(), end.ccw()} {
+		var corner = direction{end.x + side.x, end.z + side.z}
+		var box = modelBox{0, 0, 0, 3, 3, 3}
+		if corner.x > 0 {
+			box[0], box[3] = 13, 16
+		}
+		if corner.z > 0 {
+			box[2], box[5] = 13, 16
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes
+}
+
+var chestFacings = [4]direction{north, south, west, east}
+
+// chestModel is a chest, in a sixteenth from the sides and two from the
+// top, reaching over to the other half of a double chest. Chests from
+// before 1.13 join the first chest of the same kind beside them.
+func chestModel(site blockSite, blockId nbt.Block) []modelBox {
+	var box = modelBox{1, 0, 1, 15, 14, 15}
+	var data = int(blockId >> 8)
+	if blockId&0xff == 130 || data&7 < 2 || data&7 > 5 {
+		return []modelBox{box}
+	}
+
+	var facing = chestFacings[data&7-2]
+	var other direction
+	switch data >> 4 & 3 {
+	case 0:
+		for _, d := range [2]direction{facing.cw(), facing.ccw()} {
+			if site.neighbour(d.x, 0, d.z)&0xff == blockId&0xff {
+				other = d
+				break
+			}
+		}
+	case 2: // Left
+		other = facing.cw()
+	case 3: // Right
+		other = facing.ccw()
+	}
+	switch other {
+	case east:
+		box[3] = 16
+	case west:
+		box[0] = 0
+	case south:
+		box[5] = 16
+	case north:
+		box[2] = 0
+	}
+	return []modelBox{box}
+}
+
+var signFacings = [4]direction{north, south, west, east}
+
+// wallSignModel is a board against the side behind the way a wall sign
+// faces. The text on signs isn't drawn.
+func wallSignModel(site blockSite, blockId nbt.Block) []modelBox {
+	var data = int(blockId>>8) & 7
+	if data < 2 || data > 5 {
+		data = 2
+	}
+	var box = sideBox(signFacings[data-2].opposite(), 2)
+	box[1], box[4] = 4, 12
+	return []modelBox{box}
+}
+
+// signElements are a standing sign facing south, a board on a post, as
+// the game draws it, but without its text.
+var signElements = []resourcepack.Element{
+	{From: [3]float64{7.25, 0, 7.25}, To: [3]float64{8.75, 9.33, 8.75}, Faces: allFaces},
+	{From: [3]float64{0, 9.33, 7.25}, To: [3]float64{16, 17.33, 8.75}, Faces: allFaces},
+}
+
+// bannerElements are the wood of a standing banner facing south: a pole
+// and a bar across the top of it, that bannerCloth hangs from, over two
+// and a half blocks high. wallBannerElements are the bar of a banner on a
+// wall facing south, that wallBannerCloth hangs from down into the block
+// below.
+var (
+	bannerElements = []resourcepack.Element{
+		{From: [3]float64{7, 0, 7}, To: [3]float64{9, 42, 9}, Faces: allFaces},
+		{From: [3]float64{-2, 42, 7}, To: [3]float64{18, 44, 9}, Faces: allFaces},
+	}
+	bannerCloth        = resourcepack.Element{From: [3]float64{-2, 2, 9}, To: [3]float64{18, 42, 10}, Faces: allFaces}
+	wallBannerElements = []resourcepack.Element{
+		{From: [3]float64{-2, 14, 0}, To: [3]float64{18, 16, 2}, Faces: allFaces},
+	}
+	wallBannerCloth = resourcepack.Element{From: [3]float64{-2, -26, 2}, To: [3]float64{18, 14, 3}, Faces: allFaces}
+)
+
+var allFaces = map[string]resourcepack.Face{"down": {}, "up": {}, "north": {}, "south": {}, "west": {}, "east": {}}
+
+// turnedModels are the models of blocks turned by their data, by their id
+// and data: standing signs and banners, each a sixteenth of a turn
+// clockwise from facing south, wall banners by the way they face, and
+// skulls either way.
+var turnedModels [256][][]packModel
+
+// standingModels are the models of a standing block facing south turned
+// for each of its 16 rotations.
+func standingModels(elements []resourcepack.Element) [][]packModel {
+	var models = make([][]packModel, 16)
+	for rotation := range models {
+		var turned = make([]resourcepack.Element, len(elements))
+		for i, e := range elements {
+			e.Rotation = &resourcepack.ElementRotation{Origin: [3]float64{8, 8, 8}, Axis: "y", Angle: -22.5 * float64(rotation)}
+			turned[i] = e
+		}
+		models[rotation] = []packModel{{elements: turned}}
+	}
+	return models
+}
+
+// wallModels are the models of a block on a wall facing south turned for
+// each way it can face, by its data from 2 to 5: north, south, west and
+// east.
+func wallModels(elements []resourcepack.Element) [][]packModel {
+	var models = make([][]packModel, 6)
+	for i, y := range [4]int{180, 0, 90, 270} {
+		models[2+i] = []packModel{{elements, 0, y}}
+	}
+	return models
+}
+
+// skullModels are skulls on walls, and on the floor, data 1, turned by the
+// rotation in the bits above. Wall skulls from 1.12 can have 8 set, for
+// those that drop nothing when broken. Player heads are skulls without
+// their skins.
+func skullModels() [][]packModel {
+	var models = make([][]packModel, 128)
+	var wall = wallModels([]resourcepack.Element{
+		{From: [3]float64{4, 4, 0}, To: [3]float64{12, 12, 8}, Faces: allFaces},
+	})
+	copy(models, wall)
+	copy(models[8:], wall)
+	for rotation, floor := range standingModels([]resourcepack.Element{
+		{From: [3]float64{4, 0, 4}, To: [3]float64{12, 8, 12}, Faces: allFaces},
+	}) {
+		models[1|rotation<<3] = floor
+	}
+	return models
+}
+
+// flatModel is a block a sixteenth high across the floor, as carpets and
+// lily pads are.
+func flatModel(site blockSite, blockId nbt.Block) []modelBox {
+	return []modelBox{{0, 0, 0, 16, 1, 16}}
+}
+
+// plateModel is a pressure plate, a sixteenth high and in a sixteenth from
+// each side.
+func plateModel(site blockSite, blockId nbt.Block) []modelBox {
+	return []modelBox{{1, 0, 1, 15, 1, 15}}
+}
+
+// turnUp turns a box made for a block facing up to face another way, by
+// the index 1.12 gave the direction: down, up, north, south, west then
+// east.
+func turnUp(box modelBox, facing int) modelBox {
+	var turn = func(x, y, z int) (int, int, int) {
+		switch facing {
+		case 0:
+			return x, 16 - y, z
+		case 2:
+			return x, z, 16 - y
+		case 3:
+			return x, z, y
+		case 4:
+			return 16 - y, x, z
+		case 5:
+			return y, x, z
+		}
+		return x, y, z
+	}
+	var x0, y0, z0 = turn(box[0], box[1], box[2])
+	var x1, y1, z1 = turn(box[3], box[4], box[5])
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	if z0 > z1 {
+		z0, z1 = z1, z0
+	}
+	return modelBox{x0, y0, z0, x1, y1, z1}
+}
+
+// pistonModel is a whole block for a piston that's in, the base without
+// its head for one that's out, and a head of a board on an arm that reaches
+// back to the base.
+func pistonModel(site blockSite, blockId nbt.Block) []modelBox {
+	var data = int(blockId >> 8)
+	var facing = data & 7
+	if blockId&0xff == 34 {
+		return []modelBox{turnUp(modelBox{0, 12, 0, 16, 16, 16}, facing), turnUp(modelBox{6, 0, 6, 10, 12, 10}, facing)}
+	}
+	if data&8 != 0 {
+		return []modelBox{turnUp(modelBox{0, 0, 0, 16, 12, 16}, facing)}
+	}
+	return []modelBox{{0, 0, 0, 16, 16, 16}}
+}
+
+// endRodModel is a rod on a base, the base against the block it's on.
+func endRodModel(site blockSite, blockId nbt.Block) []modelBox {
+	var facing = int(blockId>>8) & 7
+	return []modelBox{turnUp(modelBox{6, 0, 6, 10, 1, 10}, facing), turnUp(modelBox{7, 1, 7, 9, 16, 9}, facing)}
+}
+
+// chorusPlantModel is a stem in the middle of the block with arms out to
+// the chorus plants and flowers beside it, and down to the end stone it
+// grows from.
+func chorusPlantModel(site blockSite, blockId nbt.Block) []modelBox {
+	var boxes = []modelBox{{4, 4, 4, 12, 12, 12}}
+	for _, d := range [6][3]int{{0, -1, 0}, {0, 1, 0}, {0, 0, -1}, {0, 0, 1}, {-1, 0, 0}, {1, 0, 0}} {
+		var other = site.neighbour(d[0], d[1], d[2]) & 0xff
+		if other != 199 && other != 200 && !(other == 121 && d[1] == -1) {
+			continue
+		}
+		var arm = modelBox{4, 4, 4, 12, 12, 12}
+		for k := range d {
+			if d[k] < 0 {
+				arm[k], arm[k+3] = 0, 4
+			} else if d[k] > 0 {
+				arm[k], arm[k+3] = 12, 16
+			}
+		}
+		boxes = append(boxes, arm)
+	}
+	return boxes
+}
+
+// enchantingTableModel is a table three quarters of a block high.
+func enchantingTableModel(site blockSite, blockId nbt.Block) []modelBox {
+	return []modelBox{{0, 0, 0, 16, 12, 16}}
+}
+
+// cauldronModel is a pot with walls two sixteenths thick on legs at the
+// corners.
+func cauldronModel(site blockSite, blockId nbt.Block) []modelBox {
+	return []modelBox{
+		{0, 3, 0, 2, 16, 16}, {14, 3, 0, 16, 16, 16}, {2, 3, 0, 14, 16, 2}, {2, 3, 14, 14, 16, 16},
+		{2, 3, 2, 14, 4, 14},
+		{0, 0, 0, 4, 3, 2}, {0, 0, 2, 2, 3, 4}, {12, 0, 0, 16, 3, 2}, {14, 0, 2, 16, 3, 4},
+		{0, 0, 14, 4, 3, 16}, {0, 0, 12, 2, 3, 14}, {12, 0, 14, 16, 3, 16}, {14, 0, 12, 16, 3, 14},
+	}
+}
+
+// cauldronContents are the blocks whose material the top of what's in a
+// cauldron has: water, lava or powder snow, by the bits of the cauldron's
+// data above its level.
+var cauldronContents = [4]nbt.Block{9, 11, 80, 9}
+
+// addCauldronContents adds the top of what's in a cauldron, as high as its
+// level, from 0 for empty to 3 for full.
+func (fs *Faces) addCauldronContents(site blockSite, blockId nbt.Block) {
+	var data = int(blockId >> 8)
+	if data&3 == 0 {
+		return
+	}
+	var lo = [3]int{16*site.x + 2, 16*site.y + 6 + 3*(data&3), 16*site.z + 2}
+	var hi = [3]int{lo[0] + 12, lo[1], lo[2] + 12}
+	var v = boxFace(1, lo, hi)
+	fs.AddModelFace(cauldronContents[data>>4&3], v[0], v[1], v[2], v[3])
+}
+
+// anvilModel is an anvil facing south or north, with its top running north
+// to south, or turned to run east to west for east and west.
+func anvilModel(site blockSite, blockId nbt.Block) []modelBox {
+	var boxes = []modelBox{{2, 0, 2, 14, 4, 14}, {4, 4, 3, 12, 5, 13}, {6, 5, 4, 10, 10, 12}, {3, 10, 0, 13, 16, 16}}
+	if blockId>>8&1 != 0 { // West or east
+		for i, box := range boxes {
+			boxes[i] = modelBox{box[2], box[1], box[0], box[5], box[4], box[3]}
+		}
+	}
+	return boxes
+}
+
+// hopperModel is a bowl on top, open at the top, over a narrower middle
+// and a spout down or out to the side it faces.
+func hopperModel(site blockSite, blockId nbt.Block) []modelBox {
+	var boxes = []modelBox{
+		{0, 10, 0, 2, 16, 16}, {14, 10, 0, 16, 16, 16}, {2, 10, 0, 14, 16, 2}, {2, 10, 14, 14, 16, 16},
+		{2, 10, 2, 14, 11, 14},
+		{4, 4, 4, 12, 10, 12},
+	}
+	switch int(blockId>>8) & 7 {
+	case 2:
+		boxes = append(boxes, modelBox{6, 4, 0, 10, 8, 4})
+	case 3:
+		boxes = append(boxes, modelBox{6, 4, 12, 10, 8, 16})
+	case 4:
+		boxes = append(boxes, modelBox{0, 4, 6, 4, 8, 10})
+	case 5:
+		boxes = append(boxes, modelBox{12, 4, 6, 16, 8, 10})
+	default:
+		boxes = append(boxes, modelBox{6, 0, 6, 10, 4, 10})
+	}
+	return boxes
+}
+
+// sideBox is a board thick sixteenths thick against the side of the block
+// in direction d.
+func sideBox(d direction, thick int) modelBox {
+	var box = modelBox{0, 0, 0, 16, 16, 16}
+	switch d {
+	case east:
+		box[0] = 16 - thick
+	case west:
+		box[3] = thick
+	case south:
+		box[2] = 16 - thick
+	case north:
+		box[5] = thick
+	}
+	return box
+}
+
+var doorFacings = [4]direction{east, south, west, north}
+
+// doorModel is a board against the side behind the way the door faces, or
+// swung round on its hinge when open. The bottom half has the facing and
+// whether it's open, and the top half which side the hinge is on, so each
+// half looks at the other.
+func doorModel(site blockSite, blockId nbt.Block) []modelBox {
+	var lower, upper = blockId, site.neighbour(0, 1, 0)
+	if blockId>>8&8 != 0 {
+		lower, upper = site.neighbour(0, -1, 0), blockId
+	}
+	if lower&0xff != blockId&0xff || lower>>8&8 != 0 {
+		lower = blockId &^ (8 << 8)
+	}
+
+	var d = doorFacings[lower>>8&3]
+	if lower>>8&4 != 0 {
+		if upper&0xff == blockId&0xff && upper>>8&9 == 9 { // Hinge on the right
+			d = d.ccw()
+		} else {
+			d = d.cw()
+		}
+	}
+	return []modelBox{sideBox(d.opposite(), 3)}
+}
+
+var trapdoorFacings = [4]direction{north, south, west, east}
+
+// trapdoorModel is a board at the bottom or top of the block, or against
+// the side behind the way it faces when open.
+func trapdoorModel(site blockSite, blockId nbt.Block) []modelBox {
+	var data = int(blockId >> 8)
+	if data&4 != 0 {
+		return []modelBox{sideBox(trapdoorFacings[data&3].opposite(), 3)}
+	}
+	if data&8 != 0 {
+		return []modelBox{{0, 13, 0, 16, 16, 16}}
+	}
+	return []modelBox{{0, 0, 0, 16, 3, 16}}
+}
+
+// addPlant adds a plant as two quads crossed corner to corner, each facing
+// both ways. Crops are as tall as they have grown.
+func (fs *Faces) addPlant(site blockSite, blockId nbt.Block) {
+	var height = 16
+	switch blockId & 0xff {
+	case 59, 141, 142: // Wheat, carrots and potatoes, from age 0 to 7
+		height = 2 * (int(blockId>>8&7) + 1)
+	case 115, 207: // Nether wart and beetroots, from age 0 to 3
+		height = 4 * (int(blockId>>8&3) + 1)
+	}
+
+	var x, y, z = 16 * site.x, 16 * site.y, 16 * site.z
+	for _, x0 := range [2]int{x, x + 16} {
+		var x1 = 2*x + 16 - x0
+		var v = [4]Vertex{{x0, y, z}, {x1, y, z + 16}, {x1, y + height, z + 16}, {x0, y + height, z}}
+		fs.AddModelFace(blockId, v[0], v[1], v[2], v[3])
+		fs.AddModelFace(blockId, v[3], v[2], v[1], v[0])
+	}
+}
+
+// railCurves are the corners curved rails turn about, for shapes 6 to 9:
+// south east, south west, north west and north east.
+var railCurves = [4][2]int{{16, 16}, {0, 16}, {0, 0}, {16, 0}}
+
+// addRail adds the two rails of a track as strips a sixteenth over the
+// ground: straight across the block, sloping up a block to the side it
+// ascends to, or curving round a corner. Only plain rails curve; the
+// others keep whether they're powered in the top bit of their data.
+func (fs *Faces) addRail(site blockSite, blockId nbt.Block) {
+	var shape = int(blockId>>8) & 0xf
+	if blockId&0xff != 66 {
+		shape &= 7
+	}
+	var base = Vertex{16 * site.x, 16 * site.y, 16 * site.z}
+	var add = func(p [4][3]float64) {
+		var v [4]Vertex
+		for i := range p {
+			v[i] = Vertex{
+				base.x + int(math.Floor(p[i][0]+0.5)),
+				base.y + int(math.Floor(p[i][1]+0.5)),
+				base.z + int(math.Floor(p[i][2]+0.5)),
+			}
+		}
+		// Wound to face up
+		var a, b = [2]int{v[1].x - v[0].x, v[1].z - v[0].z}, [2]int{v[2].x - v[0].x, v[2].z - v[0].z}
+		if a[1]*b[0]-a[0]*b[1] < 0 {
+			v[1], v[3] = v[3], v[1]
+		}
+		fs.AddModelFace(blockId, v[0], v[1], v[2], v[3])
+	}
+	var rails = [2][2]float64{{3, 5}, {11, 13}}
+
+	if shape >= 6 && shape <= 9 {
+		const segments = 4
+		var corner = railCurves[shape-6]
+		var sx, sz = 1.0, 1.0
+		if corner[0] == 16 {
+			sx = -1
+		}
+		if corner[1] == 16 {
+			sz = -1
+		}
+		var at = func(r float64, i int) [3]float64 {
+			var angle = float64(i) * math.Pi / 2 / segments
+			return [3]float64{float64(corner[0]) + sx*r*math.Cos(angle), 1, float64(corner[1]) + sz*r*math.Sin(angle)}
+		}
+		for _, rail := range rails {
+			for i := 0; i < segments; i++ {
+				add([4][3]float64{at(rail[0], i), at(rail[1], i), at(rail[1], i+1), at(rail[0], i+1)})
+			}
+		}
+		return
+	}
+
+	for _, rail := range rails {
+		var p [4][3]float64
+		for i, ut := range [4][2]float64{{rail[0], 0}, {rail[1], 0}, {rail[1], 16}, {rail[0], 16}} {
+			var u, t = ut[0], ut[1]
+			switch shape {
+			case 1: // East west
+				p[i] = [3]float64{t, 1, u}
+			case 2: // Ascending east
+				p[i] = [3]float64{t, 1 + t, u}
+			case 3: // Ascending west
+				p[i] = [3]float64{t, 17 - t, u}
+			case 4: // Ascending north
+				p[i] = [3]float64{u, 17 - t, t}
+			case 5: // Ascending south
+				p[i] = [3]float64{u, 1 + t, t}
+			default: // North south
+				p[i] = [3]float64{u, 1, t}
+			}
+		}
+		add(p)
+	}
+}
+
+// ladderSides are the faceSides ladders are against, by their data from 2
+// to 5: facing north, south, west and east.
+var ladderSides = [4]int{5, 4, 3, 2}
+
+// vineSides are the faceSides of the bits of a vine's data: south, west,
+// north, east, up and down.
+var vineSides = [6]int{5, 2, 4, 3, 1, 0}
+
+// addAttached adds ladders and vines as quads a sixteenth off the sides of
+// the block they're on, facing both ways.
+func (fs *Faces) addAttached(site blockSite, blockId nbt.Block) {
+	var data = int(blockId>>8) & 0x3f
+	var sides []int
+	if blockId&0xff == 65 {
+		if data >= 2 && data <= 5 {
+			sides = append(sides, ladderSides[data-2])
+		}
+	} else {
+		if data == 0 {
+			data = 16 // Hanging from the block above, from before 1.13
+		}
+		for i, side := range vineSides {
+			if data&(1<<uint(i)) != 0 {
+				sides = append(sides, side)
+			}
+		}
+	}
+
+	for _, side := range sides {
+		var lo = [3]int{16 * site.x, 16 * site.y, 16 * site.z}
+		var hi = [3]int{lo[0] + 16, lo[1] + 16, lo[2] + 16}
+		var faceSide = faceSides[side]
+		if faceSide.positive {
+			hi[faceSide.normal]--
+		} else {
+			lo[faceSide.normal]++
+		}
+		var v = boxFace(side, lo, hi)
+		fs.AddModelFace(blockId, v[0], v[1], v[2], v[3])
+		fs.AddModelFace(blockId, v[3], v[2], v[1], v[0])
+	}
+}
+
+// Where redstone wire runs to each side: not at all, along the ground, or
+// up the side of the solid block there.
+const (
+	wireNone = iota
+	wireSide
+	wireUp
+)
+
+var repeaterFacings = [4]direction{south, west, north, east}
+
+// redstoneConnections works out where redstone wire runs, by sideDirections,
+// as the game does: to other wire beside it, or a block lower or higher,
+// and to what gives it power. Repeaters only take it at their ends, and
+// observers at their backs.
+func redstoneConnections(site blockSite) [4]int {
+	var solidAbove = site.boundary.IsSolid(site.neighbour(0, 1, 0))
+	var connections [4]int
+	for i, d := range sideDirections {
+		var other = site.neighbour(d.x, 0, d.z)
+		var id, data = other & 0xff, int(other >> 8)
+		var solid = site.boundary.IsSolid(other)
+		switch {
+		case redstoneBlocks[id]:
+			connections[i] = wireSide
+		case id == 93 || id == 94:
+			if facing := repeaterFacings[data&3]; facing.x*d.x+facing.z*d.z != 0 {
+				connections[i] = wireSide
+			}
+		case id == 218:
+			if data&7 >= 2 && data&7 <= 5 && [4]direction{north, south, west, east}[data&7-2] == d {
+				connections[i] = wireSide
+			}
+		case !solid && site.neighbour(d.x, -1, d.z)&0xff == 55:
+			connections[i] = wireSide
+		case solid && !solidAbove && site.neighbour(d.x, 1, d.z)&0xff == 55:
+			connections[i] = wireUp
+		}
+	}
+
+	// Wire that runs one way, or nowhere, runs right across the block
+	var northSouth = connections[0] != wireNone || connections[2] != wireNone
+	var eastWest = connections[1] != wireNone || connections[3] != wireNone
+	for i := range connections {
+		var northOrSouth = i%2 == 0
+		if connections[i] == wireNone && (northOrSouth && !eastWest || !northOrSouth && !northSouth) {
+			connections[i] = wireSide
+		}
+	}
+	return connections
+}
+
+// addRedstone adds redstone wire as flat strips a sixteenth over the
+// ground, out from the middle to where it runs, and up the sides of the
+// blocks it climbs.
+func (fs *Faces) addRedstone(site blockSite, blockId nbt.Block) {
+	var connections = redstoneConnections(site)
+	var base = [3]int{16 * site.x, 16 * site.y, 16 * site.z}
+	var add = func(side int, box modelBox) {
+		var lo, hi = box.corners()
+		for k := range base {
+			lo[k] += base[k]
+			hi[k] += base[k]
+		}
+		var v = boxFace(side, lo, hi)
+		fs.AddModelFace(blockId, v[0], v[1], v[2], v[3])
+	}
+
+	var boxes = []modelBox{{5, 0, 5, 11, 1, 11}}
+	for i, d := range sideDirections {
+		if connections[i] != wireNone {
+			boxes = append(boxes, armBox(d, 3, 3, 0, 1))
+		}
+	}
+	for _, box := range mergeBoxes(boxes) {
+		add(1, box)
+	}
+
+	for i, d := range sideDirections {
+		if connections[i] != wireUp {
+			continue
+		}
+		// Facing back into the block, off the side of the one beside
+		var box = sideBox(d, 1)
+		if d.x != 0 {
+			box[2], box[5] = 5, 11
+		} else {
+			box[0], box[3] = 5, 11
+		}
+		add(map[direction]int{east: 2, west: 3, south: 4, north: 5}[d], box)
+	}
+}
+
+// beaconBeam is the material of beacon beams.
+const beaconBeam = 138 | 1<<8
+
+// addBeam adds the beam of a beacon, from its top up to the top of the
+// world, when it has a layer of a pyramid of iron, gold, diamond or
+// emerald blocks under it, as the game works out from its block entity,
+// and there's nothing solid above it.
+func (fs *Faces) addBeam(site blockSite) {
+	for dx := -1; dx <= 1; dx++ {
+		for dz := -1; dz <= 1; dz++ {
+			switch site.neighbour(dx, -1, dz) & 0xff {
+			case 41, 42, 57, 133:
+			default:
+				return
+			}
+		}
+	}
+	var height = site.e.height()
+	for y := site.y + 1; y < height; y++ {
+		if site.boundary.IsSolid(site.neighbour(0, y-site.y, 0)) {
+			return
+		}
+	}
+
+	var lo = [3]int{16*site.x + 6, 16 * (site.y + 1), 16*site.z + 6}
+	var hi = [3]int{lo[0] + 4, 16 * height, lo[2] + 4}
+	for side := 2; side < 6; side++ {
+		var v = boxFace(side, lo, hi)
+		fs.AddModelFace(beaconBeam, v[0], v[1], v[2], v[3])
+	}
+}
+
+// fluid is water (8) or lava (10), whether still or flowing, or 0 for any
+// other block. Waterlogged blocks are water.
+func fluid(blockId nbt.Block) nbt.Block {
+	if blockId&nbt.Waterlogged != 0 {
+		return 8
+	}
+	switch blockId & 0xff {
+	case 8, 9:
+		return 8
+	case 10, 11:
+		return 10
+	}
+	return 0
+}
+
+// fluidHeight is how full a block of fluid is from its level: 0 for a
+// source, 1 to 7 as it flows away, and 8 and up falling.
+func fluidHeight(level int) float64 {
+	if level >= 8 {
+		level = 0
+	}
+	return float64(8-level) / 9
+}
+
+// fluidCornerHeight is the height in sixteenths of the surface of a fluid
+// at the corner of a block, averaged from the four blocks around it as the
+// game does. Sources and falling fluid count ten times as much as flowing,
+// and the corner is at the top of the block if any of them has the same
+// fluid over it.
+func fluidCornerHeight(site blockSite, kind nbt.Block, cx, cz int) int {
+	var total, count float64
+	for dx := cx - 1; dx <= cx; dx++ {
+		for dz := cz - 1; dz <= cz; dz++ {
+			if fluid(site.neighbour(dx, 1, dz)) == kind {
+				return 16
+			}
+			var other = site.neighbour(dx, 0, dz)
+			if fluid(other) != kind {
+				if !site.boundary.IsSolid(other) {
+					count++
+				}
+				continue
+			}
+			var level = int(other >> 8)
+			if other&nbt.Waterlogged != 0 {
+				level = 0
+			}
+			var height = fluidHeight(level)
+			if level == 0 || level >= 8 {
+				total += 10 * height
+				count += 10
+			}
+			total += height
+			count++
+		}
+	}
+	if count == 0 {
+		return 16
+	}
+	return int(16*total/count + 0.5)
+}
+
+// addFluid adds the faces of a block of fluid that aren't against the same
+// fluid or a solid block, with its top sloping down to where it flows.
+func (fs *Faces) addFluid(site blockSite, blockId nbt.Block) {
+	var kind = fluid(blockId)
+	var heights [2][2]int
+	for cx := 0; cx < 2; cx++ {
+		for cz := 0; cz < 2; cz++ {
+			heights[cx][cz] = fluidCornerHeight(site, kind, cx, cz)
+		}
+	}
+
+	var base = [3]int{16 * site.x, 16 * site.y, 16 * site.z}
+	var lo, hi = base, [3]int{base[0] + 16, base[1] + 16, base[2] + 16}
+	for side, faceSide := range faceSides {
+		var d [3]int
+		if faceSide.positive {
+			d[faceSide.normal] = 1
+		} else {
+			d[faceSide.normal] = -1
+		}
+		var neighbour = site.neighbour(d[0], d[1], d[2])
+		if fluid(neighbour) == kind || side != 1 && fs.boundary.IsSolid(neighbour) {
+			continue
+		}
+
+		var v = boxFace(side, lo, hi)
+		for i := range v {
+			if v[i].y == hi[1] {
+				v[i].y = base[1] + heights[(v[i].x-base[0])/16][(v[i].z-base[2])/16]
+			}
+		}
+		fs.AddModelFace(blockId, v[0], v[1], v[2], v[3])
+	}
+}
+
+// mergeBoxes joins boxes that together make a bigger box.
+func mergeBoxes(boxes []modelBox) []modelBox {
+	for i := 0; i < len(boxes); i++ {
+		for j := i + 1; j < len(boxes); j++ {
+			var a, b = boxes[i], boxes[j]
+			var same, joined = 0, -1
+			for axis := 0; axis < 3; axis++ {
+				if a[axis] == b[axis] && a[axis+3] == b[axis+3] {
+					same++
+				} else if a[axis+3] == b[axis] || b[axis+3] == a[axis] {
+					joined = axis
+				}
+			}
+			if same == 2 && joined != -1 {
+				if b[joined] < a[joined] {
+					a[joined] = b[joined]
+				} else {
+					a[joined+3] = b[joined+3]
+				}
+				boxes[i] = a
+				boxes = append(boxes[:j], boxes[j+1:]...)
+				j = i
+			}
+		}
+	}
+	return boxes
+}
@@ -0,0 +1,719 @@
+package main
+
+import (
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"reflect"
+	"testing"
+)
+
+func TestRedstoneConnectionsObserver(t *testing.T) {
+	// Wire at 8,1,8, on stone, running north, with an observer to the east
+	// of it
+	for data := 0; data < 16; data++ {
+		var observer = nbt.Block(218) | nbt.Block(data)<<8
+		var e, fs = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+			switch {
+			case y == 0:
+				return 1
+			case y == 1 && x == 8 && (z == 7 || z == 8):
+				return 55
+			case y == 1 && x == 9 && z == 8:
+				return observer
+			}
+			return 0
+		})
+
+		// The observer's back faces the wire when it faces east
+		var connections = redstoneConnections(blockSite{e, fs.boundary, 8, 1, 8})
+		var want = wireNone
+		if data&7 == 5 {
+			want = wireSide
+		}
+		if connections[1] != want {
+			t.Errorf("data %d: east is %d, want %d", data, connections[1], want)
+		}
+
+		processTestChunk(fs, e)
+	}
+}
+
+// placedSite is the site of the block at 8,1,8 of a chunk of air 4 blocks
+// high, with blocks placed in it by where they are from there.
+func placedSite(t *testing.T, blocks map[[3]int]nbt.Block) (blockSite, *Faces) {
+	var e, fs = testChunk(t, 4, emptySide, func(x, y, z int) nbt.Block {
+		return blocks[[3]int{x - 8, y - 1, z - 8}]
+	})
+	return blockSite{e, fs.boundary, 8, 1, 8}, fs
+}
+
+// filled is the sixteenths of a block that boxes fill.
+func filled(boxes []modelBox) map[[3]int]bool {
+	var cells = make(map[[3]int]bool)
+	for _, box := range boxes {
+		for x := box[0]; x < box[3]; x++ {
+			for y := box[1]; y < box[4]; y++ {
+				for z := box[2]; z < box[5]; z++ {
+					cells[[3]int{x, y, z}] = true
+				}
+			}
+		}
+	}
+	return cells
+}
+
+// modelTest is a block at 0,0,0 among blocks, and the boxes its model
+// should fill.
+type modelTest struct {
+	name   string
+	blocks map[[3]int]nbt.Block
+	boxes  []modelBox
+}
+
+// checkModels checks that the model of each test's block fills its boxes,
+// however they're split up.
+func checkModels(t *testing.T, tests []modelTest) {
+	for _, test := range tests {
+		var site, _ = placedSite(t, test.blocks)
+		var blockId = test.blocks[[3]int{}]
+		var boxes = blockModels[blockId&0xff](site, blockId)
+		if !reflect.DeepEqual(filled(boxes), filled(test.boxes)) {
+			t.Errorf("%s: %v, want %v", test.name, boxes, test.boxes)
+		}
+	}
+}
+
+func TestStairsModel(t *testing.T) {
+	const stairs = 53
+	var data = func(facing, shape int) nbt.Block {
+		return stairs | nbt.Block(facing|shape<<3)<<8
+	}
+	checkModels(t, []modelTest{
+		{"east", map[[3]int]nbt.Block{{}: data(0, 0)},
+			[]modelBox{{0, 0, 0, 16, 8, 16}, {8, 8, 0, 16, 16, 16}}},
+		{"north upside down", map[[3]int]nbt.Block{{}: data(3|4, 0)},
+			[]modelBox{{0, 8, 0, 16, 16, 16}, {0, 0, 0, 16, 8, 8}}},
+		{"saved outer left", map[[3]int]nbt.Block{{}: data(0, 1+stairsOuterLeft)},
+			[]modelBox{{0, 0, 0, 16, 8, 16}, {8, 8, 0, 16, 16, 8}}},
+		{"saved inner right", map[[3]int]nbt.Block{{}: data(0, 1+stairsInnerRight)},
+			[]modelBox{{0, 0, 0, 16, 8, 16}, {8, 8, 0, 16, 16, 16}, {0, 8, 8, 8, 16, 16}}},
+		{"saved straight", map[[3]int]nbt.Block{{}: data(0, 1+stairsStraight), {1, 0, 0}: data(3, 0)},
+			[]modelBox{{0, 0, 0, 16, 8, 16}, {8, 8, 0, 16, 16, 16}}},
+		// Worked out from stairs in front facing north, and behind facing
+		// south
+		{"outer left", map[[3]int]nbt.Block{{}: data(0, 0), {1, 0, 0}: data(3, 0)},
+			[]modelBox{{0, 0, 0, 16, 8, 16}, {8, 8, 0, 16, 16, 8}}},
+		{"inner right", map[[3]int]nbt.Block{{}: data(0, 0), {-1, 0, 0}: data(2, 0)},
+			[]modelBox{{0, 0, 0, 16, 8, 16}, {8, 8, 0, 16, 16, 16}, {0, 8, 8, 8, 16, 16}}},
+		// Not with stairs upside down
+		{"not outer", map[[3]int]nbt.Block{{}: data(0, 0), {1, 0, 0}: data(3|4, 0)},
+			[]modelBox{{0, 0, 0, 16, 8, 16}, {8, 8, 0, 16, 16, 16}}},
+	})
+}
+
+// facesIn is how many faces lie in the plane at sixteenths along axis.
+func facesIn(fs *Faces, axis, plane int) int {
+	var count = 0
+faces:
+	for _, face := range faceCorners(fs) {
+		for _, p := range face {
+			if p[axis] != plane {
+				continue faces
+			}
+		}
+		count++
+	}
+	return count
+}
+
+func TestSlabModel(t *testing.T) {
+	const slab, top = 44, 44 | 8<<8
+	checkModels(t, []modelTest{
+		{"bottom", map[[3]int]nbt.Block{{}: slab}, []modelBox{{0, 0, 0, 16, 8, 16}}},
+		{"top", map[[3]int]nbt.Block{{}: top}, []modelBox{{0, 8, 0, 16, 16, 16}}},
+	})
+
+	// The faces between a slab and the block east of it. Stone keeps its
+	// face, as the slab doesn't cover it.
+	var tests = []struct {
+		name  string
+		next  nbt.Block
+		faces int
+	}{
+		{"bottom", slab, 0},
+		{"top", top, 2},
+		{"stone", 1, 1},
+		{"air", 0, 1},
+	}
+	for _, test := range tests {
+		var site, fs = placedSite(t, map[[3]int]nbt.Block{{}: slab, {1, 0, 0}: test.next})
+		processTestChunk(fs, site.e)
+		if faces := facesIn(fs, 0, 16*9); faces != test.faces {
+			t.Errorf("%s: %d faces between, want %d", test.name, faces, test.faces)
+		}
+	}
+}
+
+func TestFenceAndWallModels(t *testing.T) {
+	const (
+		oak    = 85
+		spruce = 188
+		nether = 113
+		gate   = 107
+		wall   = 139
+		pane   = 102
+		stone  = 1
+		glass  = 20
+	)
+	var post = modelBox{6, 0, 6, 10, 16, 10}
+	var wallPost = modelBox{4, 0, 4, 12, 16, 12}
+	checkModels(t, []modelTest{
+		{"fence", map[[3]int]nbt.Block{{}: oak}, []modelBox{post}},
+		{"fence to stone", map[[3]int]nbt.Block{{}: oak, {1, 0, 0}: stone},
+			[]modelBox{post, {10, 6, 7, 16, 9, 9}, {10, 12, 7, 16, 15, 9}}},
+		{"fence to another wood", map[[3]int]nbt.Block{{}: oak, {0, 0, -1}: spruce},
+			[]modelBox{post, {7, 6, 0, 9, 9, 6}, {7, 12, 0, 9, 15, 6}}},
+		{"fence to a gate", map[[3]int]nbt.Block{{}: oak, {0, 0, 1}: gate},
+			[]modelBox{post, {7, 6, 10, 9, 9, 16}, {7, 12, 10, 9, 15, 16}}},
+		{"nether brick to wood", map[[3]int]nbt.Block{{}: nether, {-1, 0, 0}: oak}, []modelBox{post}},
+		{"fence to glass", map[[3]int]nbt.Block{{}: oak, {-1, 0, 0}: glass}, []modelBox{post}},
+
+		{"wall", map[[3]int]nbt.Block{{}: wall}, []modelBox{wallPost}},
+		{"wall running north", map[[3]int]nbt.Block{{}: wall, {0, 0, -1}: wall, {0, 0, 1}: wall},
+			[]modelBox{{5, 0, 0, 11, 14, 16}}},
+		{"wall running east to panes", map[[3]int]nbt.Block{{}: wall, {-1, 0, 0}: pane, {1, 0, 0}: pane},
+			[]modelBox{{0, 0, 5, 16, 14, 11}}},
+		// A post where there's something on top, or at a corner
+		{"wall under stone", map[[3]int]nbt.Block{{}: wall, {0, 0, -1}: wall, {0, 0, 1}: wall, {0, 1, 0}: stone},
+			[]modelBox{wallPost, {5, 0, 0, 11, 14, 4}, {5, 0, 12, 11, 14, 16}}},
+		{"wall corner", map[[3]int]nbt.Block{{}: wall, {0, 0, -1}: wall, {1, 0, 0}: gate},
+			[]modelBox{wallPost, {5, 0, 0, 11, 14, 4}, {12, 0, 5, 16, 14, 11}}},
+	})
+}
+
+func TestPaneModel(t *testing.T) {
+	const (
+		bars   = 101
+		pane   = 102
+		tinted = 160
+		wall   = 139
+		stone  = 1
+	)
+	var post = modelBox{7, 0, 7, 9, 16, 9}
+	checkModels(t, []modelTest{
+		{"pane", map[[3]int]nbt.Block{{}: pane}, []modelBox{post}},
+		{"between stone", map[[3]int]nbt.Block{{}: pane, {-1, 0, 0}: stone, {1, 0, 0}: stone},
+			[]modelBox{{0, 0, 7, 16, 16, 9}}},
+		{"bars to panes", map[[3]int]nbt.Block{{}: bars, {0, 0, -1}: pane, {0, 0, 1}: tinted},
+			[]modelBox{{7, 0, 0, 9, 16, 16}}},
+		{"corner to a wall", map[[3]int]nbt.Block{{}: pane, {0, 0, -1}: wall, {1, 0, 0}: bars},
+			[]modelBox{post, {7, 0, 0, 9, 16, 7}, {9, 0, 7, 16, 16, 9}}},
+	})
+}
+
+func TestTorchModel(t *testing.T) {
+	var torch = func(data int) map[[3]int]nbt.Block {
+		return map[[3]int]nbt.Block{{}: 50 | nbt.Block(data)<<8}
+	}
+	checkModels(t, []modelTest{
+		{"standing", torch(5), []modelBox{{7, 0, 7, 9, 10, 9}}},
+		{"standing from 1.13", torch(0), []modelBox{{7, 0, 7, 9, 10, 9}}},
+		{"on a wall facing east", torch(1), []modelBox{{2, 3, 7, 4, 13, 9}}},
+		{"on a wall facing north", torch(4), []modelBox{{7, 3, 12, 9, 13, 14}}},
+		{"redstone on a wall facing south", map[[3]int]nbt.Block{{}: 76 | 3<<8}, []modelBox{{7, 3, 2, 9, 13, 4}}},
+		{"lantern", torch(nbt.TorchLantern), []modelBox{{5, 0, 5, 11, 7, 11}, {6, 7, 6, 10, 9, 10}}},
+		{"hanging lantern", torch(nbt.TorchHangingLantern),
+			[]modelBox{{5, 1, 5, 11, 8, 11}, {6, 8, 6, 10, 10, 10}, {7, 10, 7, 9, 16, 9}}},
+		{"candle", torch(nbt.TorchCandles), []modelBox{{7, 0, 7, 9, 6, 9}}},
+		{"three candles", torch(nbt.TorchCandles + 2),
+			[]modelBox{{6, 0, 6, 8, 6, 8}, {9, 0, 6, 11, 5, 8}, {5, 0, 8, 7, 4, 10}}},
+	})
+}
+
+// placedFaces is the faces of the chunk placedSite makes, and the box
+// around the faces of each material, from the corner of the block at
+// 8,1,8.
+func placedFaces(t *testing.T, blocks map[[3]int]nbt.Block) (*Faces, map[nbt.Block]modelBox) {
+	var site, fs = placedSite(t, blocks)
+	processTestChunk(fs, site.e)
+	var bounds = make(map[nbt.Block]modelBox)
+	for i, face := range faceCorners(fs) {
+		var blockId = fs.faces[i].blockId
+		var box, seen = bounds[blockId]
+		for _, p := range face {
+			p = [3]int{p[0] - 16*8, p[1] - 16, p[2] - 16*8}
+			for k := range p {
+				if !seen || p[k] < box[k] {
+					box[k] = p[k]
+				}
+				if !seen || p[k] > box[k+3] {
+					box[k+3] = p[k]
+				}
+			}
+			seen = true
+		}
+		bounds[blockId] = box
+	}
+	return fs, bounds
+}
+
+func TestPlants(t *testing.T) {
+	var tests = []struct {
+		name    string
+		blockId nbt.Block
+		height  int
+	}{
+		{"flower", 38, 16},
+		{"tall grass", 31 | 1<<8, 16},
+		{"sapling", 6, 16},
+		{"wheat just planted", 59, 2},
+		{"wheat half grown", 59 | 3<<8, 8},
+		{"carrots grown", 141 | 7<<8, 16},
+		{"beetroots", 207 | 1<<8, 8},
+		{"nether wart grown", 115 | 3<<8, 16},
+	}
+	for _, test := range tests {
+		var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: test.blockId})
+		// Two quads crossed corner to corner, each facing both ways
+		if len(fs.faces) != 4 {
+			t.Errorf("%s: %d faces", test.name, len(fs.faces))
+		}
+		if want := (modelBox{0, 0, 0, 16, test.height, 16}); bounds[test.blockId] != want {
+			t.Errorf("%s: faces over %v, want %v", test.name, bounds[test.blockId], want)
+		}
+	}
+}
+
+func TestDoorModels(t *testing.T) {
+	const door, trapdoor = 64, 96
+	var block = func(id, data int) nbt.Block {
+		return nbt.Block(id | data<<8)
+	}
+	checkModels(t, []modelTest{
+		{"door facing east", map[[3]int]nbt.Block{{}: block(door, 0), {0, 1, 0}: block(door, 8)},
+			[]modelBox{{0, 0, 0, 3, 16, 16}}},
+		{"top of a door facing east", map[[3]int]nbt.Block{{}: block(door, 8), {0, -1, 0}: block(door, 0)},
+			[]modelBox{{0, 0, 0, 3, 16, 16}}},
+		{"door facing north", map[[3]int]nbt.Block{{}: block(door, 3), {0, 1, 0}: block(door, 8)},
+			[]modelBox{{0, 0, 13, 16, 16, 16}}},
+		{"open with the hinge on the left", map[[3]int]nbt.Block{{}: block(door, 4), {0, 1, 0}: block(door, 8)},
+			[]modelBox{{0, 0, 0, 16, 16, 3}}},
+		{"open with the hinge on the right", map[[3]int]nbt.Block{{}: block(door, 4), {0, 1, 0}: block(door, 9)},
+			[]modelBox{{0, 0, 13, 16, 16, 16}}},
+		{"top, open with the hinge on the right", map[[3]int]nbt.Block{{}: block(door, 9), {0, -1, 0}: block(door, 4)},
+			[]modelBox{{0, 0, 13, 16, 16, 16}}},
+
+		{"trapdoor", map[[3]int]nbt.Block{{}: block(trapdoor, 0)}, []modelBox{{0, 0, 0, 16, 3, 16}}},
+		{"trapdoor at the top", map[[3]int]nbt.Block{{}: block(trapdoor, 8)}, []modelBox{{0, 13, 0, 16, 16, 16}}},
+		{"open trapdoor facing north", map[[3]int]nbt.Block{{}: block(trapdoor, 4)}, []modelBox{{0, 0, 13, 16, 16, 16}}},
+		{"open trapdoor facing east", map[[3]int]nbt.Block{{}: block(trapdoor, 4|3)}, []modelBox{{0, 0, 0, 3, 16, 16}}},
+	})
+}
+
+func TestSnowModel(t *testing.T) {
+	var tests []modelTest
+	for data := 0; data < 8; data++ {
+		tests = append(tests, modelTest{fmt.Sprintf("%d layers", data+1), map[[3]int]nbt.Block{{}: 78 | nbt.Block(data)<<8},
+			[]modelBox{{0, 0, 0, 16, 2 * (data + 1), 16}}})
+	}
+	checkModels(t, tests)
+}
+
+func TestFluidCornerHeight(t *testing.T) {
+	const (
+		water = 8
+		still = 9
+		lava  = 10
+		stone = 1
+	)
+	var tests = []struct {
+		name   string
+		blocks map[[3]int]nbt.Block
+		height int
+	}{
+		// Of the corner to the south east, between the water and the three
+		// blocks there
+		{"source", map[[3]int]nbt.Block{{}: still}, 11},
+		{"falling", map[[3]int]nbt.Block{{}: water | 8<<8}, 11},
+		{"flowing", map[[3]int]nbt.Block{{}: water | 4<<8}, 2},
+		{"sources", map[[3]int]nbt.Block{{}: still, {1, 0, 0}: still, {0, 0, 1}: still, {1, 0, 1}: still}, 14},
+		{"by stone", map[[3]int]nbt.Block{{}: still, {1, 0, 0}: stone, {0, 0, 1}: stone, {1, 0, 1}: stone}, 14},
+		{"by lava", map[[3]int]nbt.Block{{}: still, {1, 0, 0}: lava, {0, 0, 1}: lava, {1, 0, 1}: lava}, 11},
+		{"waterlogged beside", map[[3]int]nbt.Block{{}: water | 4<<8, {1, 0, 0}: 44 | nbt.Waterlogged}, 12},
+		{"under water", map[[3]int]nbt.Block{{}: water | 4<<8, {1, 1, 1}: still}, 16},
+	}
+	for _, test := range tests {
+		var site, _ = placedSite(t, test.blocks)
+		if height := fluidCornerHeight(site, fluid(test.blocks[[3]int{}]), 1, 1); height != test.height {
+			t.Errorf("%s: %d high, want %d", test.name, height, test.height)
+		}
+	}
+
+	// A source on stone has a top and four sides, as high as its corners,
+	// and none against water beside it
+	var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: still, {0, -1, 0}: stone})
+	var faces = 0
+	for _, face := range fs.faces {
+		if face.blockId == still {
+			faces++
+		}
+	}
+	if faces != 5 || bounds[still] != (modelBox{0, 0, 0, 16, 11, 16}) {
+		t.Errorf("source: %d faces over %v", faces, bounds[still])
+	}
+	fs, _ = placedFaces(t, map[[3]int]nbt.Block{{}: still, {1, 0, 0}: still})
+	if faces = facesIn(fs, 0, 16*9); faces != 0 {
+		t.Errorf("%d faces between sources", faces)
+	}
+}
+
+func TestWaterlogged(t *testing.T) {
+	const still = 9
+	var tests = []struct {
+		name    string
+		blockId nbt.Block
+		water   bool
+	}{
+		{"slab", 44 | nbt.Waterlogged, true},
+		{"stairs", 53 | 3<<8 | nbt.Waterlogged, true},
+		{"fence", 85 | nbt.Waterlogged, true},
+		{"ladder", 65 | 2<<8 | nbt.Waterlogged, true},
+		{"dry slab", 44, false},
+	}
+	for _, test := range tests {
+		var _, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: test.blockId})
+		var _, block = bounds[test.blockId&^nbt.Waterlogged]
+		var _, water = bounds[still]
+		if !block || water != test.water {
+			t.Errorf("%s: faces of the block %v, of water %v", test.name, block, water)
+		}
+	}
+}
+
+func TestTurnUp(t *testing.T) {
+	// The base of an end rod, against the block it's on
+	var base = modelBox{6, 0, 6, 10, 1, 10}
+	var want = [6]modelBox{
+		{6, 15, 6, 10, 16, 10}, // Down
+		{6, 0, 6, 10, 1, 10},   // Up
+		{6, 6, 15, 10, 10, 16}, // North
+		{6, 6, 0, 10, 10, 1},   // South
+		{15, 6, 6, 16, 10, 10}, // West
+		{0, 6, 6, 1, 10, 10},   // East
+	}
+	for facing := range want {
+		if box := turnUp(base, facing); box != want[facing] {
+			t.Errorf("facing %d: %v, want %v", facing, box, want[facing])
+		}
+	}
+}
+
+func TestPistonModel(t *testing.T) {
+	const piston, sticky, head = 33, 29, 34
+	var block = func(id, data int) map[[3]int]nbt.Block {
+		return map[[3]int]nbt.Block{{}: nbt.Block(id | data<<8)}
+	}
+	checkModels(t, []modelTest{
+		{"in", block(piston, 1), []modelBox{{0, 0, 0, 16, 16, 16}}},
+		{"out up", block(piston, 8|1), []modelBox{{0, 0, 0, 16, 12, 16}}},
+		{"sticky out north", block(sticky, 8|2), []modelBox{{0, 0, 4, 16, 16, 16}}},
+		{"head up", block(head, 1), []modelBox{{0, 12, 0, 16, 16, 16}, {6, 0, 6, 10, 12, 10}}},
+		{"head east", block(head, 5), []modelBox{{12, 0, 0, 16, 16, 16}, {0, 6, 6, 12, 10, 10}}},
+	})
+}
+
+func TestRails(t *testing.T) {
+	const rail, powered = 66, 27
+	var tests = []struct {
+		name    string
+		blockId nbt.Block
+		faces   int
+		bounds  modelBox
+	}{
+		{"north south", rail, 2, modelBox{3, 1, 0, 13, 1, 16}},
+		{"east west", rail | 1<<8, 2, modelBox{0, 1, 3, 16, 1, 13}},
+		{"ascending east", rail | 2<<8, 2, modelBox{0, 1, 3, 16, 17, 13}},
+		{"ascending west", rail | 3<<8, 2, modelBox{0, 1, 3, 16, 17, 13}},
+		{"ascending north", rail | 4<<8, 2, modelBox{3, 1, 0, 13, 17, 16}},
+		{"curving south east", rail | 6<<8, 8, modelBox{3, 1, 3, 16, 1, 16}},
+		{"curving north west", rail | 8<<8, 8, modelBox{0, 1, 0, 13, 1, 13}},
+		{"powered east west", powered | (8|1)<<8, 2, modelBox{0, 1, 3, 16, 1, 13}},
+	}
+	for _, test := range tests {
+		var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: test.blockId})
+		if len(fs.faces) != test.faces || bounds[test.blockId] != test.bounds {
+			t.Errorf("%s: %d faces over %v, want %d over %v", test.name, len(fs.faces), bounds[test.blockId], test.faces, test.bounds)
+		}
+		for _, face := range faceCorners(fs) {
+			var a, b = face[1], face[2]
+			for k := range a {
+				a[k] -= face[0][k]
+				b[k] -= face[0][k]
+			}
+			if a[2]*b[0]-a[0]*b[2] <= 0 {
+				t.Errorf("%s: %v doesn't face up", test.name, face)
+			}
+		}
+	}
+}
+
+func TestLoweredModel(t *testing.T) {
+	checkModels(t, []modelTest{
+		{"farmland", map[[3]int]nbt.Block{{}: 60 | 7<<8}, []modelBox{{0, 0, 0, 16, 15, 16}}},
+		{"dirt path", map[[3]int]nbt.Block{{}: 208}, []modelBox{{0, 0, 0, 16, 15, 16}}},
+	})
+
+	// The sides between farmland and the blocks beside it. Dirt keeps its
+	// side, which shows above the farmland.
+	var tests = []struct {
+		name  string
+		next  nbt.Block
+		faces int
+	}{
+		{"farmland", 60, 0},
+		{"dirt", 3, 1},
+		{"air", 0, 1},
+	}
+	for _, test := range tests {
+		var fs, _ = placedFaces(t, map[[3]int]nbt.Block{{}: 60, {1, 0, 0}: test.next})
+		if faces := facesIn(fs, 0, 16*9); faces != test.faces {
+			t.Errorf("%s: %d faces between, want %d", test.name, faces, test.faces)
+		}
+	}
+}
+
+func TestFlatModels(t *testing.T) {
+	var flat = []modelBox{{0, 0, 0, 16, 1, 16}}
+	var plate = []modelBox{{1, 0, 1, 15, 1, 15}}
+	checkModels(t, []modelTest{
+		{"carpet", map[[3]int]nbt.Block{{}: 171 | 14<<8}, flat},
+		{"lily pad", map[[3]int]nbt.Block{{}: 111}, flat},
+		{"stone pressure plate", map[[3]int]nbt.Block{{}: 70}, plate},
+		{"wooden pressure plate", map[[3]int]nbt.Block{{}: 72}, plate},
+		{"gold pressure plate", map[[3]int]nbt.Block{{}: 147}, plate},
+		{"iron pressure plate", map[[3]int]nbt.Block{{}: 148}, plate},
+	})
+
+	// A carpet on stone has no bottom, leaving only the top of the stone
+	// there, and is drawn in its color
+	var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: 171 | 14<<8, {0, -1, 0}: 1})
+	if _, ok := bounds[171|14<<8]; !ok || facesIn(fs, 1, 16) != 1 {
+		t.Errorf("carpet: %d faces at the bottom, faces by material %v", facesIn(fs, 1, 16), bounds)
+	}
+}
+
+func TestAttached(t *testing.T) {
+	const ladder, vine = 65, 106
+	var tests = []struct {
+		name    string
+		blockId nbt.Block
+		faces   int
+		bounds  modelBox
+	}{
+		{"ladder facing north", ladder | 2<<8, 2, modelBox{0, 0, 15, 16, 16, 15}},
+		{"ladder facing east", ladder | 5<<8, 2, modelBox{1, 0, 0, 1, 16, 16}},
+		{"vine on the south", vine | 1<<8, 2, modelBox{0, 0, 15, 16, 16, 15}},
+		{"vine on the south and east", vine | (1|8)<<8, 4, modelBox{0, 0, 0, 16, 16, 16}},
+		{"vine from before 1.13", vine, 2, modelBox{0, 15, 0, 16, 15, 16}},
+		{"glow lichen on the floor", vine | 32<<8, 2, modelBox{0, 1, 0, 16, 1, 16}},
+	}
+	for _, test := range tests {
+		var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: test.blockId})
+		if len(fs.faces) != test.faces || bounds[test.blockId] != test.bounds {
+			t.Errorf("%s: %d faces over %v, want %d over %v", test.name, len(fs.faces), bounds[test.blockId], test.faces, test.bounds)
+		}
+	}
+}
+
+func TestBedModel(t *testing.T) {
+	var bed = func(data int) map[[3]int]nbt.Block {
+		return map[[3]int]nbt.Block{{}: 26 | nbt.Block(data)<<8}
+	}
+	var mattress = modelBox{0, 3, 0, 16, 9, 16}
+	checkModels(t, []modelTest{
+		// The foot has its legs behind, and the head in the way it faces
+		{"red foot facing south", bed(0), []modelBox{mattress, {13, 0, 0, 16, 3, 3}, {0, 0, 0, 3, 3, 3}}},
+		{"red head facing south", bed(8), []modelBox{mattress, {0, 0, 13, 3, 3, 16}, {13, 0, 13, 16, 3, 16}}},
+		{"white head facing east", bed(100 | 8 | 3), []modelBox{mattress, {13, 0, 13, 16, 3, 16}, {13, 0, 0, 16, 3, 3}}},
+		{"black foot facing west", bed(16 | 1), []modelBox{mattress, {13, 0, 0, 16, 3, 3}, {13, 0, 13, 16, 3, 16}}},
+	})
+}
+
+func TestChestModel(t *testing.T) {
+	const chest, ender, trapped = 54, 130, 146
+	var block = func(id, data int) nbt.Block {
+		return nbt.Block(id | data<<8)
+	}
+	var single = []modelBox{{1, 0, 1, 15, 14, 15}}
+	checkModels(t, []modelTest{
+		{"chest", map[[3]int]nbt.Block{{}: block(chest, 2)}, single},
+		{"ender chest", map[[3]int]nbt.Block{{}: block(ender, 2), {1, 0, 0}: block(ender, 2)}, single},
+		{"left half", map[[3]int]nbt.Block{{}: block(chest, 2|2<<4)}, []modelBox{{1, 0, 1, 16, 14, 15}}},
+		{"right half", map[[3]int]nbt.Block{{}: block(chest, 2|3<<4)}, []modelBox{{0, 0, 1, 15, 14, 15}}},
+		{"left half facing east", map[[3]int]nbt.Block{{}: block(chest, 5|2<<4)}, []modelBox{{1, 0, 1, 15, 14, 16}}},
+		// From before 1.13, joined to the chest beside it
+		{"beside a chest", map[[3]int]nbt.Block{{}: block(chest, 2), {1, 0, 0}: block(chest, 2)}, []modelBox{{1, 0, 1, 16, 14, 15}}},
+		{"beside a trapped chest", map[[3]int]nbt.Block{{}: block(chest, 2), {-1, 0, 0}: block(trapped, 2)}, single},
+		{"in front of a chest", map[[3]int]nbt.Block{{}: block(chest, 2), {0, 0, -1}: block(chest, 2)}, single},
+	})
+}
+
+func TestSignModels(t *testing.T) {
+	const standing, wall = 63, 68
+	checkModels(t, []modelTest{
+		{"on a wall facing north", map[[3]int]nbt.Block{{}: wall | 2<<8}, []modelBox{{0, 4, 14, 16, 12, 16}}},
+		{"on a wall facing east", map[[3]int]nbt.Block{{}: wall | 5<<8}, []modelBox{{0, 4, 0, 2, 12, 16}}},
+		{"on a wall facing nowhere", map[[3]int]nbt.Block{{}: wall}, []modelBox{{0, 4, 14, 16, 12, 16}}},
+	})
+
+	// A board on a post, turned a sixteenth of a turn at a time from
+	// facing south
+	var tests = []struct {
+		name     string
+		rotation int
+		bounds   modelBox
+	}{
+		{"facing south", 0, modelBox{0, 0, 7, 16, 17, 9}},
+		{"facing west", 4, modelBox{7, 0, 0, 9, 17, 16}},
+		{"facing north", 8, modelBox{0, 0, 7, 16, 17, 9}},
+	}
+	for _, test := range tests {
+		var blockId = standing | nbt.Block(test.rotation)<<8
+		var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: blockId})
+		if len(fs.faces) != 12 || bounds[blockId] != test.bounds {
+			t.Errorf("%s: %d faces over %v, want 12 over %v", test.name, len(fs.faces), bounds[blockId], test.bounds)
+		}
+	}
+}
+
+func TestSkullModels(t *testing.T) {
+	var tests = []struct {
+		name   string
+		data   int
+		bounds modelBox
+	}{
+		{"on a wall facing south", 3, modelBox{4, 4, 0, 12, 12, 8}},
+		{"on a wall facing north", 2, modelBox{4, 4, 8, 12, 12, 16}},
+		{"on a wall facing east, from 1.12", 8 | 5, modelBox{0, 4, 4, 8, 12, 12}},
+		{"on the floor", 1, modelBox{4, 0, 4, 12, 8, 12}},
+		{"on the floor turned an eighth", 1 | 2<<3, modelBox{2, 0, 2, 14, 8, 14}},
+	}
+	for _, test := range tests {
+		var blockId = 144 | nbt.Block(test.data)<<8
+		var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: blockId})
+		if len(fs.faces) != 6 || bounds[blockId] != test.bounds {
+			t.Errorf("%s: %d faces over %v, want 6 over %v", test.name, len(fs.faces), bounds[blockId], test.bounds)
+		}
+	}
+}
+
+func TestEndRodAndChorusModels(t *testing.T) {
+	const rod, plant, flower, endStone = 198, 199, 200, 121
+	var stem = modelBox{4, 4, 4, 12, 12, 12}
+	checkModels(t, []modelTest{
+		{"rod up", map[[3]int]nbt.Block{{}: rod | 1<<8}, []modelBox{{6, 0, 6, 10, 1, 10}, {7, 1, 7, 9, 16, 9}}},
+		{"rod east", map[[3]int]nbt.Block{{}: rod | 5<<8}, []modelBox{{0, 6, 6, 1, 10, 10}, {1, 7, 7, 16, 9, 9}}},
+
+		{"chorus plant", map[[3]int]nbt.Block{{}: plant}, []modelBox{stem}},
+		{"on end stone under a flower", map[[3]int]nbt.Block{{}: plant, {0, -1, 0}: endStone, {0, 1, 0}: flower},
+			[]modelBox{stem, {4, 0, 4, 12, 4, 12}, {4, 12, 4, 12, 16, 12}}},
+		{"to plants north and east", map[[3]int]nbt.Block{{}: plant, {0, 0, -1}: plant, {1, 0, 0}: plant},
+			[]modelBox{stem, {4, 4, 0, 12, 12, 4}, {12, 4, 4, 16, 12, 12}}},
+		// Only end stone below
+		{"beside end stone", map[[3]int]nbt.Block{{}: plant, {-1, 0, 0}: endStone, {0, 1, 0}: endStone}, []modelBox{stem}},
+	})
+}
+
+func TestBeaconBeams(t *testing.T) {
+	defer func(b bool) { beams = b }(beams)
+	const beacon, iron, gold, stone, glass = 138, 42, 41, 1, 20
+	var pyramid = func(above nbt.Block, missing bool) map[[3]int]nbt.Block {
+		var blocks = map[[3]int]nbt.Block{{}: beacon, {0, 2, 0}: above}
+		for dx := -1; dx <= 1; dx++ {
+			for dz := -1; dz <= 1; dz++ {
+				blocks[[3]int{dx, -1, dz}] = iron
+			}
+		}
+		blocks[[3]int{1, -1, 1}] = gold
+		if missing {
+			delete(blocks, [3]int{-1, -1, 0})
+		}
+		return blocks
+	}
+	var tests = []struct {
+		name   string
+		on     bool
+		blocks map[[3]int]nbt.Block
+		beam   bool
+	}{
+		{"beacon", true, pyramid(0, false), true},
+		{"under glass", true, pyramid(glass, false), true},
+		{"under stone", true, pyramid(stone, false), false},
+		{"without a whole layer", true, pyramid(0, true), false},
+		{"without -beams", false, pyramid(0, false), false},
+	}
+	for _, test := range tests {
+		beams = test.on
+		var fs, bounds = placedFaces(t, test.blocks)
+		var faces = 0
+		for _, face := range fs.faces {
+			if face.blockId == beaconBeam {
+				faces++
+			}
+		}
+		// Up from the top of the beacon to the top of the world, 4 high
+		var want = 0
+		if test.beam {
+			want = 4
+		}
+		if faces != want || test.beam && bounds[beaconBeam] != (modelBox{6, 16, 6, 10, 48, 10}) {
+			t.Errorf("%s: %d faces of beam over %v, want %d", test.name, faces, bounds[beaconBeam], want)
+		}
+	}
+}
+
+func TestUtilityModels(t *testing.T) {
+	const cauldron, hopper, anvil, table = 118, 154, 145, 116
+	var block = func(id, data int) map[[3]int]nbt.Block {
+		return map[[3]int]nbt.Block{{}: nbt.Block(id | data<<8)}
+	}
+	var bowl = []modelBox{
+		{0, 10, 0, 2, 16, 16}, {14, 10, 0, 16, 16, 16}, {2, 10, 0, 14, 16, 2}, {2, 10, 14, 14, 16, 16},
+		{2, 10, 2, 14, 11, 14},
+		{4, 4, 4, 12, 10, 12},
+	}
+	checkModels(t, []modelTest{
+		{"enchanting table", block(table, 0), []modelBox{{0, 0, 0, 16, 12, 16}}},
+		{"anvil facing south", block(anvil, 0),
+			[]modelBox{{2, 0, 2, 14, 4, 14}, {4, 4, 3, 12, 5, 13}, {6, 5, 4, 10, 10, 12}, {3, 10, 0, 13, 16, 16}}},
+		{"damaged anvil facing east", block(anvil, 4|1),
+			[]modelBox{{2, 0, 2, 14, 4, 14}, {3, 4, 4, 13, 5, 12}, {4, 5, 6, 12, 10, 10}, {0, 10, 3, 16, 16, 13}}},
+		{"hopper down", block(hopper, 0), append(bowl, modelBox{6, 0, 6, 10, 4, 10})},
+		{"hopper north", block(hopper, 2), append(bowl, modelBox{6, 4, 0, 10, 8, 4})},
+		{"hopper east, disabled", block(hopper, 8|5), append(bowl, modelBox{12, 4, 6, 16, 8, 10})},
+	})
+
+	// The top of what's in a cauldron, by level and contents
+	var tests = []struct {
+		name     string
+		data     int
+		contents nbt.Block
+		y        int
+	}{
+		{"empty", 0, 0, 0},
+		{"water", 2, 9, 12},
+		{"full of lava", 3 | 1<<4, 11, 15},
+		{"powder snow", 1 | 2<<4, 80, 9},
+	}
+	for _, test := range tests {
+		var _, bounds = placedFaces(t, block(cauldron, test.data))
+		var top, ok = bounds[test.contents]
+		if test.contents == 0 {
+			if len(bounds) != 1 {
+				t.Errorf("%s: faces of %v", test.name, bounds)
+			}
+		} else if !ok || top != (modelBox{2, test.y, 2, 14, test.y, 14}) {
+			t.Errorf("%s: top over %v, want at %d", test.name, top, test.y)
+		}
+	}
+}
@@ -7,9 +7,9 @@ import (
 	"os"
 )
 
-func printMtl(w io.Writer, blockId nbt.Block) {
+func printMtl(w io.Writer, blockId nbt.Block, biome int) {
 	if !noColor {
-		fmt.Fprintln(w, "usemtl", MaterialNamer.NameBlockId(blockId))
+		fmt.Fprintln(w, "usemtl", tintedName(blockId, biome))
 	}
 }
 
@@ -27,6 +27,9 @@ func writeMtlFile(filename string) error {
 	for _, color := range colors {
 		color.Print(outFile)
 	}
+	if biomes {
+		printTintedMtls(outFile)
+	}
 
 	return nil
 }
@@ -39,14 +42,19 @@ type MTL struct {
 }
 
 func (mtl *MTL) Print(w io.Writer) {
+	mtl.print(w, MaterialNamer.NameBlockId(nbt.Block(mtl.blockId)+nbt.Block(mtl.metadata)*256), mtl.color)
+}
+
+// print prints the material by another name and color.
+func (mtl *MTL) print(w io.Writer, name string, color uint32) {
 	var (
-		r = mtl.color >> 24
-		g = mtl.color >> 16 & 0xff
-		b = mtl.color >> 8 & 0xff
-		a = mtl.color & 0xff
+		r = color >> 24
+		g = color >> 16 & 0xff
+		b = color >> 8 & 0xff
+		a = color & 0xff
 	)
 
-	fmt.Fprintf(w, "# %s\nnewmtl %s\nKd %.4f %.4f %.4f\nd %.4f\nillum 1\n\n", mtl.name, MaterialNamer.NameBlockId(nbt.Block(mtl.blockId)+nbt.Block(mtl.metadata)*256), float64(r)/255, float64(g)/255, float64(b)/255, float64(a)/255)
+	fmt.Fprintf(w, "# %s\nnewmtl %s\nKd %.4f %.4f %.4f\nd %.4f\nillum 1\n\n", mtl.name, name, float64(r)/255, float64(g)/255, float64(b)/255, float64(a)/255)
 }
 
 func (mtl *MTL) colorId() nbt.Block {
@@ -84,7 +92,7 @@ func (n *NumberBlockIdNamer) NameBlockId(blockId nbt.Block) (name string) {
 	var idByte = byte(blockId & 0xff)
 	var extraValue, extraPresent = extraData[idByte]
 	if extraValue && extraPresent {
-		name = fmt.Sprintf("%d_%d", idByte, blockId>>8)
+		name = fmt.Sprintf("%d_%d", idByte, mtlMetadata(idByte, byte(blockId>>8)))
 	} else {
 		name = fmt.Sprintf("%d", idByte)
 	}
@@ -97,8 +105,9 @@ func (n *NameBlockIdNamer) NameBlockId(blockId nbt.Block) (name string) {
 	var idByte = byte(blockId & 0xff)
 	var extraValue, extraPresent = extraData[idByte]
 	if extraValue && extraPresent {
+		var metadata = mtlMetadata(idByte, byte(blockId>>8))
 		for _, color := range colors {
-			if color.blockId == idByte && color.metadata == uint8(blockId>>8) {
+			if color.blockId == idByte && color.metadata == metadata {
 				return color.name
 			}
 		}
@@ -111,3 +120,22 @@ func (n *NameBlockIdNamer) NameBlockId(blockId nbt.Block) (name string) {
 	}
 	return
 }
+
+// mtlMetadata returns metadata if blocks.json lists a color for it, otherwise
+// the first listed metadata for the block. Block states from newer worlds can
+// carry data values (orientation, growth stage and so on) that never needed
+// their own color.
+func mtlMetadata(idByte byte, metadata byte) byte {
+	var first, found = metadata, false
+	for _, color := range colors {
+		if color.blockId == idByte {
+			if color.metadata == metadata {
+				return metadata
+			}
+			if !found && color.metadata != 255 {
+				first, found = color.metadata, true
+			}
+		}
+	}
+	return first
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// VertexNormals is which normals the corners of faces are given, for
+// renderers to shade them smoothly by.
+type VertexNormals int
+
+const (
+	NormalsNone     VertexNormals = iota // None, for each face to be shaded flat
+	NormalsSmooth                        // The average of the faces around the vertex
+	NormalsMaterial                      // The average of the faces of the same material around the vertex
+)
+
+// ParseVertexNormals reads none, smooth or material.
+func ParseVertexNormals(s string) (VertexNormals, error) {
+	switch s {
+	case "none":
+		return NormalsNone, nil
+	case "smooth":
+		return NormalsSmooth, nil
+	case "material":
+		return NormalsMaterial, nil
+	}
+	return NormalsNone, errors.New(fmt.Sprintf("%q isn't none, smooth or material", s))
+}
+
+// vertexNormals works out the normal of each corner of each face, as the
+// average of the faces around its vertex that are within normalAngle
+// degrees of the face, numbered from 1 in the normals it gives. Normals are
+// rounded, so that those that are the same are only written once.
+func (fs *Faces) vertexNormals() (corners []VertexNumFace, normals [][3]float64) {
+	var faceNormals = make([][3]float64, len(fs.faces))
+	var around = make(map[int][]int) // The faces around each vertex, by its index
+	for i, face := range fs.faces {
+		var p [4]Vertex
+		for j, index := range face.indexes {
+			p[j] = fs.vertexes.Position(index)
+			if j != 3 || !face.isTriangle() {
+				around[index] = append(around[index], i)
+			}
+		}
+		// Newell's method, for quads that aren't quite flat
+		var n [3]float64
+		for j := range p {
+			var a, b = p[j], p[(j+1)%4]
+			n[0] += float64((a.y - b.y) * (a.z + b.z))
+			n[1] += float64((a.z - b.z) * (a.x + b.x))
+			n[2] += float64((a.x - b.x) * (a.y + b.y))
+		}
+		faceNormals[i] = unit(n)
+	}
+
+	var minCos = math.Cos(normalAngle*math.Pi/180) - 1e-9
+	var numbers = make(map[[3]float64]int)
+	corners = make([]VertexNumFace, len(fs.faces))
+	for i, face := range fs.faces {
+		var normal = faceNormals[i]
+		for j, index := range face.indexes {
+			var sum [3]float64
+			for _, k := range around[index] {
+				var other = faceNormals[k]
+				if vertexNormals == NormalsMaterial && fs.faces[k].blockId != face.blockId || normal[0]*other[0]+normal[1]*other[1]+normal[2]*other[2] < minCos {
+					continue
+				}
+				for a := range sum {
+					sum[a] += other[a]
+				}
+			}
+			var n = unit(sum)
+			if n == [3]float64{} {
+				n = normal // Faces back to back, such as plants'
+			}
+			for a := range n {
+				n[a] = math.Floor(n[a]*10000+0.5) / 10000
+			}
+
+			var number, ok = numbers[n]
+			if !ok {
+				normals = append(normals, n)
+				number = len(normals)
+				numbers[n] = number
+			}
+			corners[i][j] = number
+		}
+	}
+	return corners, normals
+}
+
+func unit(v [3]float64) [3]float64 {
+	var length = math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	if length < 1e-9 {
+		return [3]float64{}
+	}
+	return [3]float64{v[0] / length, v[1] / length, v[2] / length}
+}
+
+// occlusionShades are the shades of vertexes by how many of the corner's
+// neighbours are in the way of the light, as the game shades the corners of
+// faces.
+var occlusionShades = [4]float64{1, 0.8, 0.6, 0.4}
+
+// shade is how bright a block corner is drawn, by its ambient occlusion and
+// light, whichever of -ao and -light are on.
+func (fs *Faces) shade(x, y, z int) float64 {
+	var shade = 1.0
+	if ambientOcclusion {
+		shade *= occlusionShades[fs.occlusion(x, y, z)]
+	}
+	if lighting {
+		shade *= fs.light(x, y, z)
+	}
+	return shade
+}
+
+// faceShade is the shade of the corners of the face of the block at p on
+// the given side of faceSides, or -1 if they aren't all shaded the same.
+func (fs *Faces) faceShade(side int, p [3]int) float64 {
+	var corners = boxFace(side, p, [3]int{p[0] + 1, p[1] + 1, p[2] + 1})
+	var shade = fs.shade(corners[0].x, corners[0].y, corners[0].z)
+	for _, v := range corners[1:] {
+		if fs.shade(v.x, v.y, v.z) != shade {
+			return -1
+		}
+	}
+	return shade
+}
+
+// occlusion is how much the solid blocks around a block corner shade it,
+// from 0 to 3. A corner on flat ground has four solid blocks around it and
+// isn't shaded, and each more than that shades it more.
+func (fs *Faces) occlusion(x, y, z int) int {
+	var solid = 0
+	for i := 0; i < 8; i++ {
+		if fs.boundary.IsSolid(fs.enclosed.Get(x-1+i&1, y-1+i>>1&1, z-1+i>>2)) {
+			solid++
+		}
+	}
+	switch {
+	case solid <= 4:
+		return 0
+	case solid >= 7:
+		return 3
+	}
+	return solid - 4
+}
+
+// light is the brightness of a block corner, from the average of the
+// brighter of the block light and sky light of the blocks around it that
+// aren't solid, as the game's smooth lighting has it. Even the darkest
+// corners are a little lit, for caves not to be black.
+func (fs *Faces) light(x, y, z int) float64 {
+	var sum, n = 0, 0
+	for i := 0; i < 8; i++ {
+		var bx, by, bz = x - 1 + i&1, y - 1 + i>>1&1, z - 1 + i>>2
+		if fs.boundary.IsSolid(fs.enclosed.Get(bx, by, bz)) {
+			continue
+		}
+		var block, sky = fs.enclosed.Light(bx, by, bz)
+		if block > sky {
+			sky = block
+		}
+		sum += sky
+		n++
+	}
+	if n == 0 {
+		return 0.05
+	}
+	var f = float64(sum) / float64(n) / 15
+	return 0.05 + 0.95*f/(4-3*f)
+}
+
+// appendShade appends a shade as the color of a vertex.
+func appendShade(buf []byte, shade float64) []byte {
+	shade = math.Floor(shade*1000+0.5) / 1000
+	for i := 0; i < 3; i++ {
+		buf = append(buf, ' ')
+		buf = strconv.AppendFloat(buf, shade, 'f', -1, 64)
+	}
+	return buf
+}
+
+func printNormals(w io.Writer, normals [][3]float64) {
+	var buf = make([]byte, 0, 64)
+	for _, n := range normals {
+		buf = append(buf[:0], "vn"...)
+		for _, a := range n {
+			buf = append(buf, ' ')
+			buf = strconv.AppendFloat(buf, a, 'f', -1, 64)
+		}
+		buf = append(buf, '\n')
+		w.Write(buf)
+	}
+}
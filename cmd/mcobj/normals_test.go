@@ -0,0 +1,196 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"math"
+	"testing"
+)
+
+// checkFaceShades checks that the shade of each block corner on each face
+// is what drawing the face would blend it to from the face's corners, for
+// the shading of merged faces not to lose what's in their middle.
+func checkFaceShades(t *testing.T, fs *Faces) {
+	for _, face := range faceCorners(fs) {
+		var lo, hi = face[0], face[0]
+		for _, p := range face[1:] {
+			for k := range p {
+				if p[k] < lo[k] {
+					lo[k] = p[k]
+				}
+				if p[k] > hi[k] {
+					hi[k] = p[k]
+				}
+			}
+		}
+		for k := range lo {
+			lo[k] /= 16
+			hi[k] /= 16
+		}
+
+		// Blended between the shades at the corners, along the two axes
+		// the face is across
+		var corner = func(p [3]int) float64 { return fs.shade(p[0], p[1], p[2]) }
+		var blend = func(p [3]int) float64 {
+			var shade = 0.0
+			for i := 0; i < 8; i++ {
+				var c, weight = p, 1.0
+				for k := range p {
+					if lo[k] == hi[k] {
+						if i>>k&1 != 0 {
+							weight = 0
+						}
+						continue
+					}
+					var f = float64(p[k]-lo[k]) / float64(hi[k]-lo[k])
+					if i>>k&1 == 0 {
+						c[k], weight = lo[k], weight*(1-f)
+					} else {
+						c[k], weight = hi[k], weight*f
+					}
+				}
+				if weight != 0 {
+					shade += weight * corner(c)
+				}
+			}
+			return shade
+		}
+
+		for x := lo[0]; x <= hi[0]; x++ {
+			for y := lo[1]; y <= hi[1]; y++ {
+				for z := lo[2]; z <= hi[2]; z++ {
+					var p = [3]int{x, y, z}
+					if got, want := blend(p), corner(p); math.Abs(got-want) > 1e-9 {
+						t.Errorf("face %v-%v is shaded %.3f at %v, not %.3f", lo, hi, got, p, want)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAmbientOcclusionOfMergedFaces(t *testing.T) {
+	defer func(ao bool) { ambientOcclusion = ao }(ambientOcclusion)
+	ambientOcclusion = true
+
+	// A stone floor with a block on it in the middle
+	var e, fs = testChunk(t, 4, emptySide, func(x, y, z int) nbt.Block {
+		if y == 0 || x == 8 && y == 1 && z == 8 {
+			return 1
+		}
+		return 0
+	})
+	processTestChunk(fs, e)
+	checkFaceShades(t, fs)
+
+	if s := fs.shade(8, 1, 8); s != occlusionShades[1] {
+		t.Errorf("corner next to the block is shaded %v not %v", s, occlusionShades[1])
+	}
+	if len(fs.faces) > 40 {
+		t.Errorf("%d faces, the floor away from the block isn't merged", len(fs.faces))
+	}
+}
+
+func TestLightOfMergedFaces(t *testing.T) {
+	defer func(l bool) { lighting = l }(lighting)
+	lighting = true
+
+	// A stone floor in the dark, with a torch on it in the middle
+	const height = 4
+	var e, fs = testChunk(t, height, emptySide, func(x, y, z int) nbt.Block {
+		switch {
+		case y == 0:
+			return 1
+		case x == 8 && y == 1 && z == 8:
+			return 50 + 5<<8
+		}
+		return 0
+	})
+	e.chunk.BlockLight = make([]byte, len(e.chunk.Blocks))
+	e.chunk.SkyLight = make([]byte, len(e.chunk.Blocks))
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y := 1; y < height; y++ {
+				var d = abs(x-8) + abs(y-1) + abs(z-8)
+				if d < 14 {
+					e.chunk.BlockLight[y+height*(z+16*x)] = byte(14 - d)
+				}
+			}
+		}
+	}
+	processTestChunk(fs, e)
+	checkFaceShades(t, fs)
+}
+
+func TestParseVertexNormals(t *testing.T) {
+	var tests = []struct {
+		s       string
+		normals VertexNormals
+		ok      bool
+	}{
+		{"none", NormalsNone, true},
+		{"smooth", NormalsSmooth, true},
+		{"material", NormalsMaterial, true},
+		{"flat", NormalsNone, false},
+	}
+	for _, test := range tests {
+		var normals, err = ParseVertexNormals(test.s)
+		if normals != test.normals || (err == nil) != test.ok {
+			t.Errorf("%q: %v, %v", test.s, normals, err)
+		}
+	}
+}
+
+func TestVertexNormals(t *testing.T) {
+	defer func(n VertexNormals, angle float64) { vertexNormals, normalAngle = n, angle }(vertexNormals, normalAngle)
+
+	// The normal at the north east corner of the top of stone, with dirt on
+	// top of the stone to the east, which the stone's east face is under
+	var third, fifth = math.Sqrt(1.0 / 3), math.Sqrt(1.0 / 5)
+	var tests = []struct {
+		normals VertexNormals
+		angle   float64
+		want    [3]float64
+	}{
+		// Up, east, north, and the dirt's west, bottom and north
+		{NormalsSmooth, 180, [3]float64{0, 0, -1}},
+		{NormalsMaterial, 180, [3]float64{third, third, -third}},
+		// Up and both norths, as the rest are at right angles to up or further
+		{NormalsSmooth, 90, [3]float64{0, fifth, -2 * fifth}},
+		{NormalsSmooth, 45, [3]float64{0, 1, 0}},
+	}
+	for _, test := range tests {
+		vertexNormals, normalAngle = test.normals, test.angle
+		var e, fs = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+			switch {
+			case x == 8 && y == 8 && z == 8:
+				return 1
+			case x == 9 && y == 9 && z == 8:
+				return 3
+			}
+			return 0
+		})
+		processTestChunk(fs, e)
+		fs.vertexes.Number()
+		var corners, normals = fs.vertexNormals()
+
+		var got [3]float64
+		var found = false
+		for i, face := range faceCorners(fs) {
+			if fs.faces[i].blockId != 1 || face[0][1] != 16*9 || face[1][1] != 16*9 || face[2][1] != 16*9 {
+				continue
+			}
+			for j, p := range face {
+				if p == [3]int{16 * 9, 16 * 9, 16 * 8} {
+					got, found = normals[corners[i][j]-1], true
+				}
+			}
+		}
+		for k := range got {
+			if !found || math.Abs(got[k]-test.want[k]) > 1e-3 {
+				t.Errorf("%v within %v degrees: %v, want %v", test.normals, test.angle, got, test.want)
+				break
+			}
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 type ObjGenerator struct {
@@ -37,15 +38,18 @@ func (o *ObjGenerator) Start(outFilename string, total int, maxProcs int, bounda
 		go func() {
 			var faces Faces
 			faces.boundary = boundary
+			if triangles > 0 && total > 0 {
+				faces.triangleTarget = (triangles + total - 1) / total
+			}
 			for {
 				var job = <-o.enclosedsChan
 
 				var b = o.memoryWriterPool.GetWriter()
 				var vb = o.memoryWriterPool.GetWriter()
 
-				var faceCount, vertexCount, mtls = faces.ProcessChunk(job.enclosed, b, vb)
+				var faceCount, vertexCount, normalCount, mtls = faces.ProcessChunk(job.enclosed, b, vb)
 
-				o.writeFacesChan <- &WriteFacesJob{job.enclosed.xPos, job.enclosed.zPos, faceCount, vertexCount, mtls, b, vb, job.last}
+				o.writeFacesChan <- &WriteFacesJob{job.enclosed.xPos, job.enclosed.zPos, faceCount, vertexCount, normalCount, mtls, b, vb, job.last}
 			}
 		}()
 	}
@@ -54,6 +58,7 @@ func (o *ObjGenerator) Start(outFilename string, total int, maxProcs int, bounda
 		var chunkCount = 0
 		var size = 0
 		var vertexBase = 0
+		var normalBase = 0
 		for {
 			var job = <-o.writeFacesChan
 			chunkCount++
@@ -65,13 +70,18 @@ func (o *ObjGenerator) Start(outFilename string, total int, maxProcs int, bounda
 				o.vout.Flush()
 
 				for _, mtl := range job.mtls {
-					printMtl(o.fout, mtl.blockId)
-					for _, face := range mtl.faces {
-						printFaceLine(o.fout, face, vertexBase)
+					printMtl(o.fout, mtl.blockId, mtl.biome)
+					for i, face := range mtl.faces {
+						var normals *VertexNumFace
+						if mtl.normals != nil {
+							normals = mtl.normals[i]
+						}
+						printFaceLine(o.fout, face, normals, vertexBase, normalBase)
 					}
 				}
 				o.fout.Flush()
 				vertexBase += job.vertexCount
+				normalBase += job.normalCount
 			}
 
 			size += len(job.b.buf)
@@ -217,34 +227,67 @@ func copyFile(w io.Writer, filename string) error {
 }
 
 type WriteFacesJob struct {
-	xPos, zPos, faceCount, vertexCount int
-	mtls                               []*MtlFaces
-	b, vb                              *MemoryWriter
-	last                               bool
+	xPos, zPos, faceCount, vertexCount, normalCount int
+	mtls                                            []*MtlFaces
+	b, vb                                           *MemoryWriter
+	last                                            bool
 }
 
 type Faces struct {
 	xPos, zPos int
+	minY       int
 	count      int
 
 	vertexes Vertexes
 	faces    []IndexFace
 	boundary *BoundaryLocator
+	mask     []nbt.Block // A layer of faces, while merging them
+	terrain  []bool      // Which blocks are smooth terrain, for -smooth
+	enclosed *EnclosedChunk
+
+	// The biome tint of the block whose faces are being added, and of each
+	// face of a layer while merging them, for -biomes
+	biome     int
+	biomeMask []int
+
+	// The shade of each face of a layer while merging them, for -ao and
+	// -light, or -1 where its corners are shaded differently
+	shadeMask []float64
+
+	triangleTarget int // This chunk's share of -triangles, or 0
 }
 
-func (fs *Faces) ProcessChunk(enclosed *EnclosedChunk, w io.Writer, vw io.Writer) (faceCount, vertexCount int, mtls []*MtlFaces) {
-	fs.clean(enclosed.xPos, enclosed.zPos, enclosed.height())
-	fs.processBlocks(enclosed)
-	vertexCount, mtls = fs.Write(w, vw)
-	return len(fs.faces), vertexCount, mtls
+func (fs *Faces) ProcessChunk(enclosed *EnclosedChunk, w io.Writer, vw io.Writer) (faceCount, vertexCount, normalCount int, mtls []*MtlFaces) {
+	fs.clean(enclosed.xPos, enclosed.zPos, enclosed.blocks.minY, enclosed.height())
+	if hollow > 0 {
+		enclosed = fs.hollowed(enclosed)
+	}
+	fs.enclosed = enclosed
+	if watertight {
+		fs.processWatertight(enclosed)
+	} else {
+		fs.processBlocks(enclosed)
+		if smooth {
+			fs.processSmooth(enclosed)
+		}
+		if stitch {
+			fs.stitch()
+		}
+	}
+	if triangles > 0 || tolerance > 0 {
+		fs.decimate()
+	}
+	vertexCount, normalCount, mtls = fs.Write(w, vw)
+	return len(fs.faces), vertexCount, normalCount, mtls
 }
 
-func (fs *Faces) clean(xPos, zPos int, height int) {
+func (fs *Faces) clean(xPos, zPos int, minY int, height int) {
 	fs.xPos = xPos
 	fs.zPos = zPos
+	fs.minY = minY
 
-	if fs.vertexes.data == nil {
-		fs.vertexes.data = make([]int16, (height+1)*(16+1)*(16+1))
+	if fs.vertexes.data == nil || fs.vertexes.height != height {
+		fs.vertexes.data = make([]int32, (height+1)*(16+1)*(16+1))
 		fs.vertexes.height = height
 	} else {
 		fs.vertexes.Clear()
@@ -260,60 +303,120 @@ func (fs *Faces) clean(xPos, zPos int, height int) {
 type IndexFace struct {
 	blockId nbt.Block
 	indexes [4]int
+	biome   int // The biome tint of a block tinted by its biome, or 0
+}
+
+// isTriangle reports whether the face is a triangle, which has the same
+// last two corners.
+func (face *IndexFace) isTriangle() bool {
+	return face.indexes[3] == face.indexes[2]
 }
 
 type VertexNumFace [4]int
 
 type MtlFaces struct {
 	blockId nbt.Block
+	biome   int
 	faces   []*VertexNumFace
+	normals []*VertexNumFace // The vertex normals of each face, or nil for none
 }
 
 func (fs *Faces) AddFace(blockId nbt.Block, v1, v2, v3, v4 Vertex) {
-	var face = IndexFace{blockId, [4]int{fs.vertexes.Use(v1), fs.vertexes.Use(v2), fs.vertexes.Use(v3), fs.vertexes.Use(v4)}}
+	var face = IndexFace{blockId, [4]int{fs.vertexes.Use(v1), fs.vertexes.Use(v2), fs.vertexes.Use(v3), fs.vertexes.Use(v4)}, fs.tint(blockId)}
 	fs.faces = append(fs.faces, face)
 }
 
-func (fs *Faces) Write(w io.Writer, vw io.Writer) (vertexCount int, mtls []*MtlFaces) {
+// AddModelFace adds a face of a block model, whose vertexes are in
+// sixteenths of a block.
+func (fs *Faces) AddModelFace(blockId nbt.Block, v1, v2, v3, v4 Vertex) {
+	var face = IndexFace{blockId, [4]int{fs.vertexes.UseModel(v1), fs.vertexes.UseModel(v2), fs.vertexes.UseModel(v3), fs.vertexes.UseModel(v4)}, fs.tint(blockId)}
+	fs.faces = append(fs.faces, face)
+}
+
+// tint is the biome tint of a face of a block, that of the block being
+// added if it's tinted by its biome.
+func (fs *Faces) tint(blockId nbt.Block) int {
+	if tintOf(blockId) == noTint {
+		return 0
+	}
+	return fs.biome
+}
+
+func (fs *Faces) Write(w io.Writer, vw io.Writer) (vertexCount, normalCount int, mtls []*MtlFaces) {
 	fs.vertexes.Number()
-	var vc = int16(fs.vertexes.Print(io.MultiWriter(w, vw), fs.xPos, fs.zPos))
+	var shade func(x, y, z int) float64
+	if ambientOcclusion || lighting {
+		shade = fs.shade
+	}
+	var vc = int32(fs.vertexes.Print(io.MultiWriter(w, vw), fs.xPos, fs.zPos, fs.minY, shade))
+
+	var corners []VertexNumFace
+	if vertexNormals != NormalsNone {
+		var normals [][3]float64
+		corners, normals = fs.vertexNormals()
+		printNormals(io.MultiWriter(w, vw), normals)
+		normalCount = len(normals)
+	}
 
-	var blockIds = make([]nbt.Block, 0, 16)
+	var materials = make([]IndexFace, 0, 16) // Only their blockId and biome
 	for _, face := range fs.faces {
 		var found = false
-		for _, id := range blockIds {
-			if id == face.blockId {
+		for _, m := range materials {
+			if m.blockId == face.blockId && m.biome == face.biome {
 				found = true
 				break
 			}
 		}
 
 		if !found {
-			blockIds = append(blockIds, face.blockId)
+			materials = append(materials, IndexFace{blockId: face.blockId, biome: face.biome})
 		}
 	}
 
-	var mfs = make([]*MtlFaces, 0, len(blockIds))
+	var mfs = make([]*MtlFaces, 0, len(materials))
 
-	for _, blockId := range blockIds {
-		printMtl(w, blockId)
-		var mf = &MtlFaces{blockId, make([]*VertexNumFace, 0, len(fs.faces))}
+	for _, m := range materials {
+		printMtl(w, m.blockId, m.biome)
+		var mf = &MtlFaces{m.blockId, m.biome, make([]*VertexNumFace, 0, len(fs.faces)), nil}
 		mfs = append(mfs, mf)
-		for _, face := range fs.faces {
-			if face.blockId == blockId {
+		for i, face := range fs.faces {
+			if face.blockId == m.blockId && face.biome == m.biome {
 				var vf = face.VertexNumFace(fs.vertexes)
-				printFaceLine(w, vf, -int(vc+1))
+				var nf *VertexNumFace
+				if corners != nil {
+					nf = &corners[i]
+					mf.normals = append(mf.normals, nf)
+				}
+				printFaceLine(w, vf, nf, -int(vc+1), -(normalCount + 1))
 				mf.faces = append(mf.faces, vf)
 				faceCount++
 			}
 		}
 	}
 
-	return int(vc), mfs
+	return int(vc), normalCount, mfs
 }
 
-func printFaceLine(w io.Writer, f *VertexNumFace, offset int) {
-	fmt.Fprintln(w, "f", f[0]+offset, f[1]+offset, f[2]+offset, f[3]+offset)
+// printFaceLine prints a face, with the vertex normals of its corners
+// unless normals is nil.
+func printFaceLine(w io.Writer, f, normals *VertexNumFace, offset, normalOffset int) {
+	if f[3] == f[2] {
+		if normals == nil {
+			fmt.Fprintln(w, "f", f[0]+offset, f[1]+offset, f[2]+offset)
+		} else {
+			fmt.Fprintf(w, "f %d//%d %d//%d %d//%d\n",
+				f[0]+offset, normals[0]+normalOffset, f[1]+offset, normals[1]+normalOffset,
+				f[2]+offset, normals[2]+normalOffset)
+		}
+		return
+	}
+	if normals == nil {
+		fmt.Fprintln(w, "f", f[0]+offset, f[1]+offset, f[2]+offset, f[3]+offset)
+		return
+	}
+	fmt.Fprintf(w, "f %d//%d %d//%d %d//%d %d//%d\n",
+		f[0]+offset, normals[0]+normalOffset, f[1]+offset, normals[1]+normalOffset,
+		f[2]+offset, normals[2]+normalOffset, f[3]+offset, normals[3]+normalOffset)
 }
 
 type Vertex struct {
@@ -321,8 +424,14 @@ type Vertex struct {
 }
 
 type Vertexes struct {
-	data   []int16
+	data   []int32
 	height int
+
+	// Vertexes of block models that aren't on the corners of blocks, in
+	// sixteenths of a block, numbered after the block corners in data
+	models     map[Vertex]int
+	modelOrder []Vertex
+	modelData  []int32
 }
 
 func (vs *Vertexes) Index(x, y, z int) int {
@@ -335,13 +444,65 @@ func (vs *Vertexes) Use(v Vertex) int {
 	return i
 }
 
+// UseModel is Use for a vertex in sixteenths of a block. Its index is past
+// those of the block corners unless it's on one.
+func (vs *Vertexes) UseModel(v Vertex) int {
+	if v.x%16 == 0 && v.y%16 == 0 && v.z%16 == 0 {
+		return vs.Use(Vertex{v.x / 16, v.y / 16, v.z / 16})
+	}
+	if vs.models == nil {
+		vs.models = make(map[Vertex]int)
+	}
+	var i, ok = vs.models[v]
+	if !ok {
+		i = len(vs.modelOrder)
+		vs.models[v] = i
+		vs.modelOrder = append(vs.modelOrder, v)
+		vs.modelData = append(vs.modelData, 0)
+	}
+	vs.modelData[i]++
+	return len(vs.data) + i
+}
+
+// Unreference forgets every use of the vertexes, for them to be used again
+// by Reference.
+func (vs *Vertexes) Unreference() {
+	for i := range vs.data {
+		vs.data[i] = 0
+	}
+	for i := range vs.modelData {
+		vs.modelData[i] = 0
+	}
+}
+
+// Reference uses the vertex of an index again.
+func (vs *Vertexes) Reference(i int) {
+	if i >= len(vs.data) {
+		vs.modelData[i-len(vs.data)]++
+	} else {
+		vs.data[i]++
+	}
+}
+
 func (vs *Vertexes) Release(v Vertex) int {
 	var i = vs.Index(v.x, v.y, v.z)
 	vs.data[i]--
 	return i
 }
 
-func (vs *Vertexes) Get(i int) int16 {
+// Position is where the vertex of an index is, in sixteenths of a block.
+func (vs *Vertexes) Position(i int) Vertex {
+	if i >= len(vs.data) {
+		return vs.modelOrder[i-len(vs.data)]
+	}
+	var column = i / (vs.height + 1)
+	return Vertex{16 * (column / 17), 16 * (i % (vs.height + 1)), 16 * (column % 17)}
+}
+
+func (vs *Vertexes) Get(i int) int32 {
+	if i >= len(vs.data) {
+		return vs.modelData[i-len(vs.data)]
+	}
 	return vs.data[i]
 }
 
@@ -357,10 +518,15 @@ func (vs *Vertexes) Clear() {
 	for i, _ := range vs.data {
 		vs.data[i] = 0
 	}
+	for v := range vs.models {
+		delete(vs.models, v)
+	}
+	vs.modelOrder = vs.modelOrder[:0]
+	vs.modelData = vs.modelData[:0]
 }
 
 func (vs *Vertexes) Number() {
-	var count int16 = 0
+	var count int32 = 0
 	for i, references := range vs.data {
 		if references != 0 {
 			count++
@@ -369,9 +535,19 @@ func (vs *Vertexes) Number() {
 			vs.data[i] = -1
 		}
 	}
+	for i, references := range vs.modelData {
+		if references != 0 {
+			count++
+			vs.modelData[i] = count
+		} else {
+			vs.modelData[i] = -1
+		}
+	}
 }
 
-func (vs *Vertexes) Print(w io.Writer, xPos, zPos int) (count int) {
+// Print prints the vertexes, with their shade as their color if shade isn't
+// nil.
+func (vs *Vertexes) Print(w io.Writer, xPos, zPos, minY int, shade func(x, y, z int) float64) (count int) {
 	var buf = make([]byte, 64)
 	copy(buf[0:2], "v ")
 
@@ -387,7 +563,7 @@ func (vs *Vertexes) Print(w io.Writer, xPos, zPos int) (count int) {
 
 				var (
 					xa = x + xPos*16
-					ya = y - 64
+					ya = y + minY - 64
 					za = z + zPos*16
 				)
 
@@ -397,15 +573,43 @@ func (vs *Vertexes) Print(w io.Writer, xPos, zPos int) (count int) {
 				buf = appendCoord(buf, ya)
 				buf = append(buf, ' ')
 				buf = appendCoord(buf, za)
+				if shade != nil {
+					buf = appendShade(buf, shade(x, y, z))
+				}
 				buf = append(buf, '\n')
 
 				w.Write(buf)
 			}
 		}
 	}
+
+	for i, v := range vs.modelOrder {
+		if vs.modelData[i] == -1 {
+			continue
+		}
+		count++
+
+		buf = buf[:2]
+		buf = appendModelCoord(buf, v.x+16*xPos*16)
+		buf = append(buf, ' ')
+		buf = appendModelCoord(buf, v.y+16*(minY-64))
+		buf = append(buf, ' ')
+		buf = appendModelCoord(buf, v.z+16*zPos*16)
+		if shade != nil {
+			buf = appendShade(buf, shade((v.x+8)>>4, (v.y+8)>>4, (v.z+8)>>4))
+		}
+		buf = append(buf, '\n')
+
+		w.Write(buf)
+	}
 	return
 }
 
+// appendModelCoord is appendCoord for sixteenths of a block.
+func appendModelCoord(buf []byte, x int) []byte {
+	return strconv.AppendFloat(buf, float64(x)/(16*20), 'f', -1, 64)
+}
+
 func appendCoord(buf []byte, x int) []byte {
 	var b [64]byte
 	var j = len(b)
@@ -452,101 +656,189 @@ func appendCoord(buf []byte, x int) []byte {
 	return buf[:end]
 }
 
+// faceSide is one of the six directions a face can point, as the axis it
+// points along (0 for x, 1 for y and 2 for z) and which way.
+type faceSide struct {
+	normal, u, v int
+	positive     bool
+}
+
+var faceSides = [6]faceSide{
+	{1, 0, 2, false}, // Bottom
+	{1, 0, 2, true},  // Top
+	{0, 2, 1, false},
+	{0, 2, 1, true},
+	{2, 0, 1, false},
+	{2, 0, 1, true},
+}
+
+// processBlocks adds the faces between blocks and what they're next to,
+// merging them greedily: each layer of faces pointing the same way is
+// covered with rectangles of the same block, each grown as wide and then as
+// tall as it can go. Blocks with models add the faces of their boxes
+// instead, plants their crossed quads, fluids their sloping surfaces,
+// redstone wire its strips and blocks drawn from a resource pack the
+// elements of their models.
 func (fs *Faces) processBlocks(enclosedChunk *EnclosedChunk) {
-	type blockRun struct {
-		blockId        nbt.Block
-		v1, v2, v3, v4 Vertex
-		dirty          bool
+	var height = enclosedChunk.blocks.height
+	var size = [3]int{16, height, 16}
+	var layer = 16 * height // The most faces in a layer, of one side or the top
+	if layer < 16*16 {
+		layer = 16 * 16
 	}
-
-	var finishRun = func(r *blockRun) {
-		if r.dirty {
-			fs.AddFace(r.blockId, r.v1, r.v2, r.v3, r.v4)
-			r.dirty = false
-		}
+	if len(fs.mask) < layer {
+		fs.mask = make([]nbt.Block, layer)
+		fs.biomeMask = make([]int, layer)
+		fs.shadeMask = make([]float64, layer)
 	}
 
-	var updateBlockRun func(rp **blockRun, nr *blockRun, flag bool)
-	if !blockFaces {
-		updateBlockRun = func(rp **blockRun, nr *blockRun, flag bool) {
-			var r = *rp
-			if r.dirty {
-				if nr.blockId == r.blockId {
-					if flag {
-						r.v3 = nr.v3
-						r.v4 = nr.v4
-					} else {
-						r.v2 = nr.v2
-						r.v3 = nr.v3
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y, blockId := range enclosedChunk.blocks.Column(x, z) {
+				if beams && blockId == 138 && y+enclosedChunk.blocks.minY >= yMin {
+					fs.addBeam(blockSite{enclosedChunk, fs.boundary, x, y, z})
+				}
+				if blockId&nbt.Waterlogged == 0 && !hasModel(blockId) || y+enclosedChunk.blocks.minY < yMin {
+					continue
+				}
+				var site = blockSite{enclosedChunk, fs.boundary, x, y, z}
+				fs.biome = fs.biomeAt(x, y, z)
+				if blockId&nbt.Waterlogged != 0 {
+					fs.addFluid(site, 9) // Still water around the block
+					blockId &^= nbt.Waterlogged
+				}
+				if model := blockModels[blockId&0xff]; model != nil {
+					fs.addModel(site, blockId, model(site, blockId))
+					if blockId&0xff == 118 {
+						fs.addCauldronContents(site, blockId)
+					}
+				} else if fluid(blockId) != 0 {
+					fs.addFluid(site, blockId)
+				} else if models := packModels[blockId]; models != nil {
+					fs.addPackModel(site, blockId, models)
+				} else if blockId&0xff == 55 {
+					fs.addRedstone(site, blockId)
+				} else if plantBlocks[blockId&0xff] {
+					fs.addPlant(site, blockId)
+				} else if railBlocks[blockId&0xff] {
+					fs.addRail(site, blockId)
+				} else if attachedBlocks[blockId&0xff] {
+					fs.addAttached(site, blockId)
+				} else if models := turnedModels[blockId&0xff]; models != nil {
+					if data := int(blockId >> 8); data < len(models) && len(models[data]) != 0 {
+						if blockId&0xff == 176 || blockId&0xff == 177 {
+							fs.addBanner(site, blockId, models[data])
+						} else {
+							fs.addPackModel(site, blockId, models[data])
+						}
 					}
-				} else {
-					finishRun(r)
-					*rp = nr
 				}
-			} else {
-				*rp = nr
 			}
 		}
-	} else {
-		updateBlockRun = func(rp **blockRun, nr *blockRun, flag bool) {
-			finishRun(nr)
-		}
 	}
 
-	height := enclosedChunk.blocks.height
-
-	for i := 0; i < len(enclosedChunk.blocks.data); i += height {
-		var x, z = (i / height) / 16, (i / height) % 16
-
-		var (
-			r1 = new(blockRun)
-			r2 = new(blockRun)
-			r3 = new(blockRun)
-			r4 = new(blockRun)
-		)
-
-		var column = BlockColumn(enclosedChunk.blocks.data[i : i+height])
-		for y, blockId := range column {
-			if y < yMin {
-				continue
-			}
-
-			if fs.boundary.IsBoundary(blockId, enclosedChunk.Get(x, y-1, z)) {
-				fs.AddFace(blockId, Vertex{x, y, z}, Vertex{x + 1, y, z}, Vertex{x + 1, y, z + 1}, Vertex{x, y, z + 1})
-			}
-
-			if fs.boundary.IsBoundary(blockId, enclosedChunk.Get(x, y+1, z)) {
-				fs.AddFace(blockId, Vertex{x, y + 1, z}, Vertex{x, y + 1, z + 1}, Vertex{x + 1, y + 1, z + 1}, Vertex{x + 1, y + 1, z})
-			}
-
-			if fs.boundary.IsBoundary(blockId, enclosedChunk.Get(x-1, y, z)) {
-				updateBlockRun(&r1, &blockRun{blockId, Vertex{x, y, z}, Vertex{x, y, z + 1}, Vertex{x, y + 1, z + 1}, Vertex{x, y + 1, z}, true}, true)
-			} else {
-				finishRun(r1)
+	for sideIndex, side := range faceSides {
+		var uSize, vSize = size[side.u], size[side.v]
+		var mask = fs.mask[:uSize*vSize]
+		var biomeMask = fs.biomeMask[:uSize*vSize]
+		var shadeMask = fs.shadeMask[:uSize*vSize]
+
+		for layer := 0; layer < size[side.normal]; layer++ {
+			// The block of each face in the layer, or air where there's none
+			var p, n [3]int
+			p[side.normal] = layer
+			n[side.normal] = layer - 1
+			if side.positive {
+				n[side.normal] = layer + 1
 			}
-
-			if fs.boundary.IsBoundary(blockId, enclosedChunk.Get(x+1, y, z)) {
-				updateBlockRun(&r2, &blockRun{blockId, Vertex{x + 1, y, z}, Vertex{x + 1, y + 1, z}, Vertex{x + 1, y + 1, z + 1}, Vertex{x + 1, y, z + 1}, true}, false)
-			} else {
-				finishRun(r2)
+			for v := 0; v < vSize; v++ {
+				p[side.v], n[side.v] = v, v
+				for u := 0; u < uSize; u++ {
+					p[side.u], n[side.u] = u, u
+					var blockId = enclosedChunk.blocks.Get(p[0], p[1], p[2]) &^ nbt.Waterlogged
+					if p[1]+enclosedChunk.blocks.minY < yMin || hasModel(blockId) || smooth && fs.boundary.IsTerrain(blockId) || !fs.boundary.IsBoundary(blockId, enclosedChunk.Get(n[0], n[1], n[2])) {
+						blockId = 0
+					}
+					mask[u+v*uSize] = blockId
+					biomeMask[u+v*uSize] = 0
+					if blockId != 0 && tintOf(blockId) != noTint {
+						biomeMask[u+v*uSize] = fs.biomeAt(p[0], p[1], p[2])
+					}
+					shadeMask[u+v*uSize] = 0
+					if blockId != 0 && (ambientOcclusion || lighting) {
+						shadeMask[u+v*uSize] = fs.faceShade(sideIndex, p)
+					}
+				}
 			}
 
-			if fs.boundary.IsBoundary(blockId, enclosedChunk.Get(x, y, z-1)) {
-				updateBlockRun(&r3, &blockRun{blockId, Vertex{x, y, z}, Vertex{x, y + 1, z}, Vertex{x + 1, y + 1, z}, Vertex{x + 1, y, z}, true}, false)
-			} else {
-				finishRun(r3)
-			}
+			for v := 0; v < vSize; v++ {
+				for u := 0; u < uSize; {
+					var blockId = mask[u+v*uSize]
+					if blockId == 0 {
+						u++
+						continue
+					}
+					fs.biome = biomeMask[u+v*uSize]
+					var shade = shadeMask[u+v*uSize]
+
+					// Merged faces are only shaded at their corners, so
+					// only faces shaded the same all over are merged
+					var w, h = 1, 1
+					if !blockFaces && shade >= 0 {
+						for u+w < uSize && mask[u+w+v*uSize] == blockId && biomeMask[u+w+v*uSize] == fs.biome && shadeMask[u+w+v*uSize] == shade {
+							w++
+						}
+					grow:
+						for v+h < vSize {
+							for i := u; i < u+w; i++ {
+								if mask[i+(v+h)*uSize] != blockId || biomeMask[i+(v+h)*uSize] != fs.biome || shadeMask[i+(v+h)*uSize] != shade {
+									break grow
+								}
+							}
+							h++
+						}
+					}
+					for j := v; j < v+h; j++ {
+						for i := u; i < u+w; i++ {
+							mask[i+j*uSize] = 0
+						}
+					}
 
-			if fs.boundary.IsBoundary(blockId, enclosedChunk.Get(x, y, z+1)) {
-				updateBlockRun(&r4, &blockRun{blockId, Vertex{x, y, z + 1}, Vertex{x + 1, y, z + 1}, Vertex{x + 1, y + 1, z + 1}, Vertex{x, y + 1, z + 1}, true}, true)
-			} else {
-				finishRun(r4)
+					var lo, hi [3]int
+					lo[side.normal], hi[side.normal] = layer, layer+1
+					lo[side.u], hi[side.u] = u, u+w
+					lo[side.v], hi[side.v] = v, v+h
+					fs.addBoxFace(sideIndex, blockId, lo, hi)
+					u += w
+				}
 			}
 		}
+	}
+}
+
+// addBoxFace adds the face of the box from lo to hi on the given side of
+// faceSides.
+func (fs *Faces) addBoxFace(side int, blockId nbt.Block, lo, hi [3]int) {
+	var v = boxFace(side, lo, hi)
+	fs.AddFace(blockId, v[0], v[1], v[2], v[3])
+}
 
-		finishRun(r1)
-		finishRun(r2)
-		finishRun(r3)
-		finishRun(r4)
+// boxFace is the corners of a side of a box, wound the same way round as
+// every other face on that side.
+func boxFace(side int, lo, hi [3]int) [4]Vertex {
+	var x0, y0, z0 = lo[0], lo[1], lo[2]
+	var x1, y1, z1 = hi[0], hi[1], hi[2]
+	switch side {
+	case 0:
+		return [4]Vertex{{x0, y0, z0}, {x1, y0, z0}, {x1, y0, z1}, {x0, y0, z1}}
+	case 1:
+		return [4]Vertex{{x0, y1, z0}, {x0, y1, z1}, {x1, y1, z1}, {x1, y1, z0}}
+	case 2:
+		return [4]Vertex{{x0, y0, z0}, {x0, y0, z1}, {x0, y1, z1}, {x0, y1, z0}}
+	case 3:
+		return [4]Vertex{{x1, y0, z0}, {x1, y1, z0}, {x1, y1, z1}, {x1, y0, z1}}
+	case 4:
+		return [4]Vertex{{x0, y0, z0}, {x0, y1, z0}, {x1, y1, z0}, {x1, y0, z0}}
 	}
+	return [4]Vertex{{x0, y0, z1}, {x1, y0, z1}, {x1, y1, z1}, {x0, y1, z1}}
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"github.com/quag/mcobj/nbt"
+	"math"
+	"sync"
+	"testing"
+)
+
+var loadBlockTypes sync.Once
+
+// testChunk is a chunk height blocks high of the blocks fill gives, with
+// sides of side, and the Faces to process it with.
+func testChunk(t *testing.T, height int, side ChunkSide, fill func(x, y, z int) nbt.Block) (*EnclosedChunk, *Faces) {
+	loadBlockTypes.Do(func() {
+		if err := loadBlockTypesJson("../../blocks.json"); err != nil {
+			t.Fatal(err)
+		}
+		MaterialNamer = new(NameBlockIdNamer)
+	})
+	yMin = math.MinInt32
+
+	var chunk = &nbt.Chunk{Blocks: make([]nbt.Block, 16*16*height)}
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y := 0; y < height; y++ {
+				chunk.Blocks[y+height*(z+16*x)] = fill(x, y, z)
+			}
+		}
+	}
+	var e = &EnclosedChunk{0, 0, wrapBlockData(chunk), EnclosingSides{side, side, side, side}, EnclosingCorners{side, side, side, side}, chunk}
+	var fs = &Faces{boundary: new(BoundaryLocator)}
+	fs.boundary.Init()
+	return e, fs
+}
+
+// processTestChunk processes a chunk, leaving its faces in fs.faces.
+func processTestChunk(fs *Faces, e *EnclosedChunk) {
+	var w, vw bytes.Buffer
+	fs.ProcessChunk(e, &w, &vw)
+}
+
+// faceCorners is the corners of each face, in sixteenths of a block.
+func faceCorners(fs *Faces) [][][3]int {
+	var faces = make([][][3]int, 0, len(fs.faces))
+	for _, face := range fs.faces {
+		var corners = 4
+		if face.isTriangle() {
+			corners = 3
+		}
+		var points = make([][3]int, corners)
+		for i := range points {
+			var v = fs.vertexes.Position(face.indexes[i])
+			points[i] = [3]int{v.x, v.y, v.z}
+		}
+		faces = append(faces, points)
+	}
+	return faces
+}
+
+// edgeCounts is how many times each edge, from one corner to the next, is
+// gone along by the faces.
+func edgeCounts(faces [][][3]int) map[[2][3]int]int {
+	var edges = make(map[[2][3]int]int)
+	for _, face := range faces {
+		for i, a := range face {
+			edges[[2][3]int{a, face[(i+1)%len(face)]}]++
+		}
+	}
+	return edges
+}
+
+func TestProcessBlocksMerges(t *testing.T) {
+	var tests = []struct {
+		name   string
+		height int
+		fill   func(x, y, z int) nbt.Block
+		faces  int
+	}{
+		// A box's six sides, each one face
+		{"short level", 4, func(x, y, z int) nbt.Block {
+			if y == 0 {
+				return 1
+			}
+			return 0
+		}, 6},
+		{"one block high", 1, func(x, y, z int) nbt.Block { return 1 }, 6},
+		// The top and the sides split where stone meets dirt
+		{"two halves", 2, func(x, y, z int) nbt.Block {
+			if x < 8 {
+				return 1
+			}
+			return 3
+		}, 10},
+		// The top around the step, and the sides under it, are each two
+		// rectangles
+		{"step", 16, func(x, y, z int) nbt.Block {
+			if y == 0 || y == 1 && x < 8 && z < 8 {
+				return 1
+			}
+			return 0
+		}, 12},
+	}
+	for _, test := range tests {
+		var e, fs = testChunk(t, test.height, emptySide, test.fill)
+		processTestChunk(fs, e)
+		if len(fs.faces) != test.faces {
+			t.Errorf("%s: %d faces, want %d", test.name, len(fs.faces), test.faces)
+		}
+	}
+
+	blockFaces = true
+	defer func() {
+		blockFaces = false
+	}()
+	var e, fs = testChunk(t, 4, emptySide, tests[0].fill)
+	processTestChunk(fs, e)
+	if len(fs.faces) != 2*16*16+4*16 {
+		t.Errorf("-bf: %d faces, want %d", len(fs.faces), 2*16*16+4*16)
+	}
+}
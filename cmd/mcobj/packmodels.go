@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"github.com/quag/mcobj/resourcepack"
+	"math"
+	"sort"
+)
+
+// packModel is a model from a resource pack, turned by its variant.
+type packModel struct {
+	elements []resourcepack.Element
+	x, y     int
+}
+
+// packModels are the models of blocks drawn from a resource pack, by their
+// id and data. packBlocks has the ids of them, to look them up quickly.
+var (
+	packBlocks [256]bool
+	packModels = make(map[nbt.Block][]packModel)
+)
+
+// loadResourcePack takes the models of the blocks in a pack that have no
+// model of their own. Each variant of a block state is the block
+// nbt.BlockState gives for its properties, and the first to give a block
+// draws it. Of the parts of multipart blocks, only those that always apply
+// are drawn, as the other properties they depend on aren't kept. Blocks
+// whose models fill the block are left to be cubes.
+func loadResourcePack(path string) error {
+	var pack, err = resourcepack.Open(path)
+	if err != nil {
+		return err
+	}
+
+	var done = make(map[nbt.Block]bool)
+	var add = func(name string, properties map[string]string, variants resourcepack.Variants) error {
+		var stateProperties = make(map[string]interface{})
+		for key, value := range properties {
+			stateProperties[key] = value
+		}
+		var blockId = nbt.BlockState(name, stateProperties) &^ nbt.Waterlogged
+		var id = blockId & 0xff
+		if done[blockId] || blockModels[id] != nil || plantBlocks[id] || railBlocks[id] || attachedBlocks[id] || turnedModels[id] != nil || fluid(id) != 0 {
+			return nil
+		}
+		done[blockId] = true
+
+		var models []packModel
+		var cube = true
+		for _, variant := range variants {
+			var model, err = pack.Model(variant.Model)
+			if err != nil {
+				return err
+			}
+			cube = cube && model.IsCube()
+			models = append(models, packModel{model.Elements, variant.X, variant.Y})
+		}
+		if !cube && len(models) != 0 {
+			packModels[blockId] = models
+			packBlocks[id] = true
+		}
+		return nil
+	}
+
+	for _, name := range pack.BlockStateNames() {
+		if !nbt.IsBlockStateName(name) {
+			continue
+		}
+		var state, err = pack.BlockState(name)
+		if err != nil {
+			return err
+		}
+
+		var keys = make([]string, 0, len(state.Variants))
+		for key := range state.Variants {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			// The first of those to choose between at random
+			if variants := state.Variants[key]; len(variants) != 0 {
+				if err := add(name, resourcepack.ParseProperties(key), variants[:1]); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(state.Multipart) != 0 {
+			var variants resourcepack.Variants
+			for _, part := range state.Multipart {
+				if len(part.When) == 0 && len(part.Apply) != 0 {
+					variants = append(variants, part.Apply[0])
+				}
+			}
+			if err := add(name, nil, variants); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// packFaceSides are the directions of element faces, by the faceSides they
+// are.
+var packFaceSides = [6]string{"down", "up", "west", "east", "north", "south"}
+
+var packDirections = map[string][3]float64{
+	"down": {0, -1, 0}, "up": {0, 1, 0},
+	"west": {-1, 0, 0}, "east": {1, 0, 0},
+	"north": {0, 0, -1}, "south": {0, 0, 1},
+}
+
+// addPackModel adds the faces of the elements of a block's models, leaving
+// out those whose cullface is against a solid block. Elements turned by
+// other than a right angle have their corners rounded to the nearest
+// sixteenth.
+func (fs *Faces) addPackModel(site blockSite, blockId nbt.Block, models []packModel) {
+	for _, m := range models {
+		for _, e := range m.elements {
+			for side, name := range packFaceSides {
+				var face, ok = e.Faces[name]
+				if !ok {
+					continue
+				}
+				if d, ok := packDirections[face.CullFace]; ok {
+					d = m.turn([3]float64{8 + d[0], 8 + d[1], 8 + d[2]})
+					var neighbour = site.neighbour(int(d[0]-8), int(d[1]-8), int(d[2]-8))
+					if fs.boundary.IsSolid(neighbour) {
+						continue
+					}
+				}
+
+				var v [4]Vertex
+				for i, unit := range boxFace(side, [3]int{0, 0, 0}, [3]int{1, 1, 1}) {
+					var p = [3]float64{
+						e.From[0] + float64(unit.x)*(e.To[0]-e.From[0]),
+						e.From[1] + float64(unit.y)*(e.To[1]-e.From[1]),
+						e.From[2] + float64(unit.z)*(e.To[2]-e.From[2]),
+					}
+					p = m.turn(rotateElement(p, e.Rotation))
+					v[i] = Vertex{
+						16*site.x + int(math.Floor(p[0]+0.5)),
+						16*site.y + int(math.Floor(p[1]+0.5)),
+						16*site.z + int(math.Floor(p[2]+0.5)),
+					}
+				}
+				if v[0] == v[2] || v[1] == v[3] {
+					continue // Rounded away to nothing
+				}
+				fs.AddModelFace(blockId, v[0], v[1], v[2], v[3])
+			}
+		}
+	}
+}
+
+// rotateElement turns a corner of an element by the element's rotation.
+func rotateElement(p [3]float64, r *resourcepack.ElementRotation) [3]float64 {
+	if r == nil || r.Angle == 0 {
+		return p
+	}
+	var axis = map[string]int{"x": 0, "y": 1, "z": 2}[r.Axis]
+	var b, c = (axis + 1) % 3, (axis + 2) % 3
+	var angle = r.Angle * math.Pi / 180
+	var sin, cos = math.Sin(angle), math.Cos(angle)
+	var scale = 1.0
+	if r.Rescale {
+		scale = 1 / cos
+	}
+
+	var pb, pc = p[b] - r.Origin[b], p[c] - r.Origin[c]
+	p[b] = r.Origin[b] + scale*(pb*cos-pc*sin)
+	p[c] = r.Origin[c] + scale*(pb*sin+pc*cos)
+	return p
+}
+
+// turn turns a point about the center of the block by the model's variant,
+// first about the x axis and then about the y axis.
+func (m packModel) turn(p [3]float64) [3]float64 {
+	for i := 0; i < (m.x/90)&3; i++ {
+		p[1], p[2] = p[2], 16-p[1]
+	}
+	for i := 0; i < (m.y/90)&3; i++ {
+		p[0], p[2] = 16-p[2], p[0]
+	}
+	return p
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"github.com/quag/mcobj/resourcepack"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackModelTurn(t *testing.T) {
+	var tests = []struct {
+		x, y int
+		p    [3]float64
+		want [3]float64
+	}{
+		{0, 0, [3]float64{2, 3, 4}, [3]float64{2, 3, 4}},
+		{0, 90, [3]float64{2, 3, 4}, [3]float64{12, 3, 2}},
+		{0, 180, [3]float64{2, 3, 4}, [3]float64{14, 3, 12}},
+		{0, 270, [3]float64{2, 3, 4}, [3]float64{4, 3, 14}},
+		{90, 0, [3]float64{2, 3, 4}, [3]float64{2, 4, 13}},
+		{180, 0, [3]float64{2, 3, 4}, [3]float64{2, 13, 12}},
+		// About x and then y
+		{90, 90, [3]float64{2, 3, 4}, [3]float64{3, 4, 2}},
+	}
+	for _, test := range tests {
+		if p := (packModel{nil, test.x, test.y}).turn(test.p); p != test.want {
+			t.Errorf("x %d y %d: %v turns to %v, want %v", test.x, test.y, test.p, p, test.want)
+		}
+	}
+}
+
+func TestRotateElement(t *testing.T) {
+	var tests = []struct {
+		rotation *resourcepack.ElementRotation
+		p        [3]float64
+		want     [3]float64
+	}{
+		{nil, [3]float64{1, 2, 3}, [3]float64{1, 2, 3}},
+		{&resourcepack.ElementRotation{Origin: [3]float64{8, 8, 8}, Axis: "y", Angle: 90}, [3]float64{16, 0, 8}, [3]float64{8, 0, 0}},
+		{&resourcepack.ElementRotation{Origin: [3]float64{8, 8, 8}, Axis: "x", Angle: 45}, [3]float64{0, 8, 0}, [3]float64{0, 8 + 8*math.Sqrt2/2, 8 - 8*math.Sqrt2/2}},
+		// Rescaled to stretch across the block again
+		{&resourcepack.ElementRotation{Origin: [3]float64{8, 8, 8}, Axis: "z", Angle: 45, Rescale: true}, [3]float64{16, 8, 0}, [3]float64{16, 16, 0}},
+	}
+	for _, test := range tests {
+		var p = rotateElement(test.p, test.rotation)
+		for k := range p {
+			if math.Abs(p[k]-test.want[k]) > 1e-9 {
+				t.Errorf("%+v: %v turns to %v, want %v", test.rotation, test.p, p, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLoadResourcePack(t *testing.T) {
+	const brewingStand, beacon = 117, 138
+	var dir, err = ioutil.TempDir("", "resourcepack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, data := range map[string]string{
+		"assets/minecraft/blockstates/brewing_stand.json": `{"variants": {"": {"model": "block/brewing_stand", "y": 90}}}`,
+		"assets/minecraft/blockstates/beacon.json":        `{"variants": {"": {"model": "block/beacon"}}}`,
+		"assets/minecraft/models/block/brewing_stand.json": `{"elements": [{"from": [2, 0, 4], "to": [6, 2, 12],
+			"faces": {"down": {"cullface": "down"}, "up": {}, "north": {}, "south": {}, "west": {}, "east": {}}}]}`,
+		"assets/minecraft/models/block/beacon.json": `{"elements": [{"from": [0, 0, 0], "to": [16, 16, 16],
+			"faces": {"down": {}, "up": {}, "north": {}, "south": {}, "west": {}, "east": {}}}]}`,
+		"pack.mcmeta": "{}",
+	} {
+		var path = filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Loaded after the block types, which the models are checked against
+	placedSite(t, nil)
+	defer func() {
+		for _, id := range []nbt.Block{brewingStand, beacon} {
+			delete(packModels, id)
+			packBlocks[id] = false
+		}
+	}()
+	if err := loadResourcePack(dir); err != nil {
+		t.Fatal(err)
+	}
+	if packModels[beacon] != nil {
+		t.Error("the beacon, a cube, has a model")
+	}
+
+	// Turned a quarter, on stone, which hides its bottom
+	var fs, bounds = placedFaces(t, map[[3]int]nbt.Block{{}: brewingStand, {0, -1, 0}: 1})
+	var faces = 0
+	for _, face := range fs.faces {
+		if face.blockId == brewingStand {
+			faces++
+		}
+	}
+	if want := (modelBox{4, 0, 2, 12, 2, 6}); faces != 5 || bounds[brewingStand] != want {
+		t.Errorf("%d faces over %v, want 5 over %v", faces, bounds[brewingStand], want)
+	}
+}
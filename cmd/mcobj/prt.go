@@ -5,6 +5,7 @@ import (
 	"compress/zlib"
 	"encoding/binary"
 	"fmt"
+	"github.com/quag/mcobj/nbt"
 	"io"
 	"os"
 )
@@ -65,7 +66,7 @@ func (o *PrtGenerator) chunkProcessor() {
 
 			var column = BlockColumn(e.blocks.data[i : i+height])
 			for y, blockId := range column {
-				if y < yMin {
+				if y+e.blocks.minY < yMin {
 					continue
 				}
 
@@ -84,13 +85,13 @@ func (o *PrtGenerator) chunkProcessor() {
 					o.particleCount++
 					var (
 						xa = x + e.xPos*16
-						ya = y - 64
+						ya = y + e.blocks.minY - 64
 						za = -(z + e.zPos*16)
 					)
 					binary.Write(o.zw, binary.LittleEndian, float32(xa))
 					binary.Write(o.zw, binary.LittleEndian, float32(za))
 					binary.Write(o.zw, binary.LittleEndian, float32(ya))
-					binary.Write(o.zw, binary.LittleEndian, int32(blockId))
+					binary.Write(o.zw, binary.LittleEndian, int32(blockId&^nbt.Waterlogged))
 				}
 			}
 		}
@@ -4,8 +4,19 @@ import (
 	"github.com/quag/mcobj/nbt"
 )
 
+// SideCache keeps the edge blocks of chunks next to those still to be
+// exported, so that the faces along a chunk's border are culled against
+// what's really in the chunk next door rather than defaultSide. It keeps
+// their corner columns too, for the chunks diagonally across.
 type SideCache struct {
-	chunks map[uint64]*ChunkSidesData
+	chunks map[uint64]*cachedSides
+}
+
+// cachedSides are what's left of a chunk's sides and corners, each set to
+// nil once taken.
+type cachedSides struct {
+	sides   ChunkSidesData
+	corners ChunkCornersData
 }
 
 func (s *SideCache) Clear() {
@@ -18,14 +29,15 @@ func (s *SideCache) AddChunk(chunk *nbt.Chunk) {
 	}
 
 	if s.chunks == nil {
-		s.chunks = make(map[uint64]*ChunkSidesData)
+		s.chunks = make(map[uint64]*cachedSides)
 	}
 
-	s.chunks[s.key(chunk.XPos, chunk.ZPos)] = calculateSides(wrapBlockData(chunk.Blocks))
+	var blocks = wrapBlockData(chunk)
+	s.chunks[s.key(chunk.XPos, chunk.ZPos)] = &cachedSides{*calculateSides(blocks), calculateCorners(blocks)}
 }
 
-func wrapBlockData(data []nbt.Block) Blocks {
-	return Blocks{data, len(data) / (16 * 16)}
+func wrapBlockData(chunk *nbt.Chunk) Blocks {
+	return Blocks{chunk.Blocks, len(chunk.Blocks) / (16 * 16), chunk.MinY}
 }
 
 func (s *SideCache) HasSide(x, z int) bool {
@@ -40,18 +52,25 @@ func (s *SideCache) EncloseChunk(chunk *nbt.Chunk) *EnclosedChunk {
 	return &EnclosedChunk{
 		chunk.XPos,
 		chunk.ZPos,
-		wrapBlockData(chunk.Blocks),
+		wrapBlockData(chunk),
 		EnclosingSides{
 			s.getSide(chunk.XPos-1, chunk.ZPos, 1),
 			s.getSide(chunk.XPos+1, chunk.ZPos, 0),
 			s.getSide(chunk.XPos, chunk.ZPos-1, 3),
 			s.getSide(chunk.XPos, chunk.ZPos+1, 2),
 		},
+		EnclosingCorners{
+			s.getCorner(chunk.XPos-1, chunk.ZPos-1, 3),
+			s.getCorner(chunk.XPos+1, chunk.ZPos-1, 2),
+			s.getCorner(chunk.XPos-1, chunk.ZPos+1, 1),
+			s.getCorner(chunk.XPos+1, chunk.ZPos+1, 0),
+		},
+		chunk,
 	}
 }
 
 func calculateSides(blocks Blocks) *ChunkSidesData {
-	var sides = &ChunkSidesData{NewChunkSide(blocks.height), NewChunkSide(blocks.height), NewChunkSide(blocks.height), NewChunkSide(blocks.height)}
+	var sides = &ChunkSidesData{NewChunkSide(blocks.height, blocks.minY), NewChunkSide(blocks.height, blocks.minY), NewChunkSide(blocks.height, blocks.minY), NewChunkSide(blocks.height, blocks.minY)}
 	for i := 0; i < 16; i++ {
 		copy(sides[0].Column(i), blocks.Column(0, i))
 		copy(sides[1].Column(i), blocks.Column(15, i))
@@ -62,6 +81,15 @@ func calculateSides(blocks Blocks) *ChunkSidesData {
 	return sides
 }
 
+func calculateCorners(blocks Blocks) ChunkCornersData {
+	var corners ChunkCornersData
+	for i := range corners {
+		var x, z = 15 * (i & 1), 15 * (i >> 1)
+		corners[i] = &ChunkCorner{append(BlockColumn(nil), blocks.Column(x, z)...), blocks.minY}
+	}
+	return corners
+}
+
 func (s *SideCache) getSide(x, z int, side int) ChunkSide {
 	if s.chunks == nil {
 		return defaultSide
@@ -71,17 +99,41 @@ func (s *SideCache) getSide(x, z int, side int) ChunkSide {
 		return defaultSide
 	}
 
-	var chunkSide = chunk[side]
+	var chunkSide = chunk.sides[side]
 
-	chunk[side] = nil
+	chunk.sides[side] = nil
+	s.drop(x, z, chunk)
 
-	if chunk[0] == nil && chunk[1] == nil && chunk[2] == nil && chunk[3] == nil {
-		delete(s.chunks, s.key(x, z))
+	return chunkSide
+}
+
+func (s *SideCache) getCorner(x, z int, corner int) ChunkSide {
+	if s.chunks == nil {
+		return defaultSide
+	}
+	var chunk, present = s.chunks[s.key(x, z)]
+	if !present {
+		return defaultSide
 	}
 
-	return chunkSide
+	var chunkCorner = chunk.corners[corner]
+
+	chunk.corners[corner] = nil
+	s.drop(x, z, chunk)
+
+	return chunkCorner
+}
+
+// drop forgets a chunk once all its sides and corners have been taken.
+func (s *SideCache) drop(x, z int, chunk *cachedSides) {
+	for i := 0; i < 4; i++ {
+		if chunk.sides[i] != nil || chunk.corners[i] != nil {
+			return
+		}
+	}
+	delete(s.chunks, s.key(x, z))
 }
 
 func (s *SideCache) key(x, z int) uint64 {
-	return (uint64(x) << 32) + uint64(z)
+	return (uint64(x) << 32) + uint64(uint32(z))
 }
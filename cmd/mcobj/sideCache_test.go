@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestSideCacheCullsBorderFaces(t *testing.T) {
+	// A chunk of stone next to one of air and one of stone, enclosed through
+	// the side cache, at a negative z as the cache's key has to handle
+	for _, next := range []nbt.Block{0, 1} {
+		var sideCache = new(SideCache)
+		var chunks [2]*nbt.Chunk
+		var faces [2]*Faces
+		for i := range chunks {
+			var blockId = nbt.Block(1)
+			if i == 1 {
+				blockId = next
+			}
+			var e *EnclosedChunk
+			e, faces[i] = testChunk(t, 2, emptySide, func(x, y, z int) nbt.Block {
+				return blockId
+			})
+			chunks[i] = e.chunk
+			chunks[i].XPos, chunks[i].ZPos = i, -1
+			sideCache.AddChunk(chunks[i])
+		}
+		processTestChunk(faces[0], sideCache.EncloseChunk(chunks[0]))
+
+		var border = 0
+		for _, face := range faceCorners(faces[0]) {
+			if face[0][0] == 16*16 && face[1][0] == 16*16 && face[2][0] == 16*16 {
+				border++
+			}
+		}
+		if next == 0 && border == 0 {
+			t.Error("no faces against the chunk of air")
+		}
+		if next != 0 && border != 0 {
+			t.Errorf("%d faces against the chunk of stone", border)
+		}
+	}
+}
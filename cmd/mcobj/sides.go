@@ -10,6 +10,8 @@ var (
 	defaultSide = solidSide
 )
 
+// ChunkSide gives the blocks along one edge of a neighbouring chunk. y is
+// the world height as neighbours don't necessarily share the same range.
 type ChunkSide interface {
 	BlockId(x, y int) nbt.Block
 }
@@ -22,21 +24,44 @@ func (s *FixedChunkSide) BlockId(x, y int) nbt.Block {
 	return s.blockId
 }
 
-func NewChunkSide(height int) *ChunkSideData {
-	return &ChunkSideData{make([]nbt.Block, height*16)}
+func NewChunkSide(height int, minY int) *ChunkSideData {
+	return &ChunkSideData{make([]nbt.Block, height*16), minY}
 }
 
 type ChunkSideData struct {
 	data []nbt.Block
+	minY int
 }
 
 type ChunkSidesData [4]*ChunkSideData
 
+// ChunkCorner is the column of blocks at a corner of a chunk, for the chunk
+// diagonally across from it. As a ChunkSide x is ignored.
+type ChunkCorner struct {
+	column BlockColumn
+	minY   int
+}
+
+// ChunkCornersData are a chunk's corners at x,z 0,0, 15,0, 0,15 and 15,15.
+type ChunkCornersData [4]*ChunkCorner
+
+func (c *ChunkCorner) BlockId(x, y int) nbt.Block {
+	y -= c.minY
+	if y < 0 || y >= len(c.column) {
+		return 0
+	}
+	return c.column[y]
+}
+
 func (s *ChunkSideData) index(x, y int) int {
 	return y + (x * s.height())
 }
 
 func (s *ChunkSideData) BlockId(x, y int) nbt.Block {
+	y -= s.minY
+	if y < 0 || y >= s.height() {
+		return 0
+	}
 	return s.data[s.index(x, y)]
 }
 
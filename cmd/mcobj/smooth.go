@@ -0,0 +1,119 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+)
+
+// processSmooth adds the surface -smooth draws instead of the faces of
+// solid blocks, by surface nets: the middle of each block is a sample of
+// whether there's terrain there, and each cube of eight samples the surface
+// passes through has a vertex at the average of the points halfway along
+// its edges between terrain and not. Each edge between terrain and not has
+// a quad across it joining the vertexes of the four cubes around it, the
+// material of the block on the terrain side.
+//
+// Each chunk draws the edges starting in its own blocks. The cubes along
+// its sides and corners have samples in the chunks next to it and
+// diagonal to it, which the chunks around see the same way, so they agree.
+func (fs *Faces) processSmooth(e *EnclosedChunk) {
+	var height = e.height()
+	var samples = 18 * 18 * (height + 2)
+	if len(fs.terrain) < samples {
+		fs.terrain = make([]bool, samples)
+	}
+	var at = func(x, y, z int) int {
+		return (x + 1) + 18*((z+1)+18*(y+1))
+	}
+	for y := -1; y <= height; y++ {
+		for z := -1; z <= 16; z++ {
+			for x := -1; x <= 16; x++ {
+				fs.terrain[at(x, y, z)] = fs.boundary.IsTerrain(e.Get(x, y, z))
+			}
+		}
+	}
+
+	var vertex = func(c [3]int) Vertex {
+		var sum [3]int
+		var n = 0
+		for d := 0; d < 3; d++ {
+			var u, v = (d + 1) % 3, (d + 2) % 3
+			for i := 0; i < 4; i++ {
+				var a = c
+				a[u] += i & 1
+				a[v] += i >> 1
+				var b = a
+				b[d]++
+				if fs.terrain[at(a[0], a[1], a[2])] == fs.terrain[at(b[0], b[1], b[2])] {
+					continue
+				}
+				for k := range sum {
+					sum[k] += 16*a[k] + 8
+				}
+				sum[d] += 8
+				n++
+			}
+		}
+		var rounded = func(s int) int {
+			if s < 0 {
+				return -((-s + n/2) / n)
+			}
+			return (s + n/2) / n
+		}
+		return Vertex{rounded(sum[0]), rounded(sum[1]), rounded(sum[2])}
+	}
+
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y := -1; y < height; y++ {
+				if y+e.blocks.minY < yMin {
+					continue
+				}
+				var p = [3]int{x, y, z}
+				for d := 0; d < 3; d++ {
+					if y == -1 && d != 1 {
+						continue
+					}
+					var q = p
+					q[d]++
+					var inside = fs.terrain[at(p[0], p[1], p[2])]
+					if inside == fs.terrain[at(q[0], q[1], q[2])] {
+						continue
+					}
+
+					var blockId nbt.Block
+					var normal [3]int
+					if inside {
+						blockId = e.Get(p[0], p[1], p[2])
+						normal[d] = 1
+					} else {
+						blockId = e.Get(q[0], q[1], q[2])
+						normal[d] = -1
+					}
+
+					var u, v = (d + 1) % 3, (d + 2) % 3
+					var quad [4]Vertex
+					for i, offset := range [4][2]int{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+						var c = p
+						c[u] -= offset[0]
+						c[v] -= offset[1]
+						quad[i] = vertex(c)
+					}
+					if quad[0] == quad[2] || quad[1] == quad[3] {
+						continue
+					}
+					if facing(quad, normal) < 0 {
+						quad[1], quad[3] = quad[3], quad[1]
+					}
+					fs.AddModelFace(blockId&^nbt.Waterlogged, quad[0], quad[1], quad[2], quad[3])
+				}
+			}
+		}
+	}
+}
+
+// facing is how much a quad wound counterclockwise faces the way of normal.
+func facing(quad [4]Vertex, normal [3]int) int {
+	var a = [3]int{quad[1].x - quad[0].x, quad[1].y - quad[0].y, quad[1].z - quad[0].z}
+	var b = [3]int{quad[2].x - quad[0].x, quad[2].y - quad[0].y, quad[2].z - quad[0].z}
+	return (a[1]*b[2]-a[2]*b[1])*normal[0] + (a[2]*b[0]-a[0]*b[2])*normal[1] + (a[0]*b[1]-a[1]*b[0])*normal[2]
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+// volume is six times the volume the faces enclose, in cubic sixteenths,
+// positive when they face out.
+func volume(faces [][][3]int) int {
+	var total = 0
+	for _, face := range faces {
+		for i := 1; i+1 < len(face); i++ {
+			var a, b, c = face[0], face[i], face[i+1]
+			total += a[0]*(b[1]*c[2]-b[2]*c[1]) - a[1]*(b[0]*c[2]-b[2]*c[0]) + a[2]*(b[0]*c[1]-b[1]*c[0])
+		}
+	}
+	return total
+}
+
+func TestSmooth(t *testing.T) {
+	var tests = []struct {
+		name  string
+		solid func(x, y, z int) bool
+		faces int
+	}{
+		// A block is the octahedron through the middles of its sides
+		{"block", func(x, y, z int) bool { return x == 8 && y == 8 && z == 8 }, 6},
+		{"cube", func(x, y, z int) bool { return x >= 6 && x < 10 && y >= 6 && y < 10 && z >= 6 && z < 10 }, 6 * 4 * 4},
+		{"steps", func(x, y, z int) bool { return x >= 4 && x < 12 && z >= 4 && z < 12 && y >= 4 && y < 4+(x-4)/2 }, -1},
+		{"ball", func(x, y, z int) bool { return sq(x-8)+sq(y-8)+sq(z-8) <= 16 }, -1},
+	}
+	defer func(s bool) { smooth = s }(smooth)
+	for _, test := range tests {
+		smooth = true
+		var e, fs = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+			if test.solid(x, y, z) {
+				return 1
+			}
+			return 0
+		})
+		processTestChunk(fs, e)
+		var faces = faceCorners(fs)
+		if test.faces != -1 && len(faces) != test.faces {
+			t.Errorf("%s: %d faces, want %d", test.name, len(faces), test.faces)
+		}
+		checkManifold(t, test.name, faces)
+		if volume(faces) <= 0 {
+			t.Errorf("%s: the faces face in", test.name)
+		}
+	}
+
+	// Glass isn't terrain, and keeps its faces
+	smooth = true
+	var e, fs = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+		if x == 8 && y == 8 && z == 8 {
+			return 20
+		}
+		return 0
+	})
+	processTestChunk(fs, e)
+	if faces := faceCorners(fs); len(faces) != 6 || volume(faces) != 6*16*16*16 {
+		t.Errorf("glass: %d faces enclosing %d", len(faces), volume(faces))
+	}
+}
+
+func TestSmoothChunkCorner(t *testing.T) {
+	defer func(s bool, side *FixedChunkSide) { smooth, defaultSide = s, side }(smooth, defaultSide)
+	smooth, defaultSide = true, emptySide
+
+	// A lopsided lump in the middle of a chunk, and over the corner where
+	// four chunks meet, draws the same surface in both
+	var lump = func(dx, dz int) func(x, y, z int) bool {
+		return func(x, y, z int) bool {
+			x, z = x-dx, z-dz
+			return x >= 0 && x < 2 && z >= 0 && z < 2 && y >= 6 && (y < 9 && x+z < 2 || y < 7)
+		}
+	}
+	var inside, across = cornerFaces(t, lump(7, 7)), cornerFaces(t, lump(15, 15))
+	checkManifold(t, "inside a chunk", inside)
+	checkManifold(t, "across chunks", across)
+	if len(across) != len(inside) || volume(across) != volume(inside) {
+		t.Errorf("%d faces enclosing %d across chunks, %d enclosing %d inside one", len(across), volume(across), len(inside), volume(inside))
+	}
+}
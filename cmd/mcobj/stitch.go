@@ -0,0 +1,200 @@
+package main
+
+import (
+	"sort"
+)
+
+// stitch splits the faces of a chunk wherever a corner of another face is
+// partway along one of their edges, as where the rectangles processBlocks
+// merges meet smaller ones, so that faces next to each other share the
+// corners along the edges between them. Edges on the sides of the chunk are
+// also split at each block along them, as those of the chunks next door
+// are, so the chunks meet the same way. Faces that are split are drawn as
+// triangles.
+//
+// Only edges along the x, y or z axis are split, which are all of those of
+// blocks, but not all of those of block models and fluids.
+func (fs *Faces) stitch() {
+	var vs = &fs.vertexes
+	var indexes = make(map[[3]int]int)
+	var lines = make(map[[3]int][]int) // Where the vertexes are along each line along an axis
+	for _, face := range fs.faces {
+		for _, i := range face.indexes {
+			var v = vs.Position(i)
+			var p = [3]int{v.x, v.y, v.z}
+			if _, ok := indexes[p]; ok {
+				continue
+			}
+			indexes[p] = i
+			for axis := 0; axis < 3; axis++ {
+				var key = lineKey(p, axis)
+				lines[key] = append(lines[key], p[axis])
+			}
+		}
+	}
+	for _, line := range lines {
+		sort.Ints(line)
+	}
+
+	// The points partway along the edge from a to b, in order from a
+	var between = func(a, b [3]int) [][3]int {
+		var axis = -1
+		for k := range a {
+			if a[k] != b[k] {
+				if axis != -1 {
+					return nil
+				}
+				axis = k
+			}
+		}
+		if axis == -1 {
+			return nil
+		}
+		var lo, hi = a[axis], b[axis]
+		if hi < lo {
+			lo, hi = hi, lo
+		}
+
+		var along []int
+		var line = lines[lineKey(a, axis)]
+		for j := sort.SearchInts(line, lo+1); j < len(line) && line[j] < hi; j++ {
+			along = append(along, line[j])
+		}
+		if onChunkSide(a, b) {
+			for c := (lo/16 + 1) * 16; c < hi; c += 16 {
+				along = append(along, c)
+			}
+			sort.Ints(along)
+		}
+
+		var points = make([][3]int, 0, len(along))
+		for j := range along {
+			var c = along[j]
+			if a[axis] > b[axis] {
+				c = along[len(along)-1-j]
+			}
+			if len(points) != 0 && points[len(points)-1][axis] == c {
+				continue
+			}
+			var p = a
+			p[axis] = c
+			points = append(points, p)
+		}
+		return points
+	}
+
+	var faces = make([]IndexFace, 0, len(fs.faces))
+	for _, face := range fs.faces {
+		var corners = 4
+		if face.isTriangle() {
+			corners = 3
+		}
+		var polygon = make([][3]int, 0, 8)
+		var split = false
+		for k := 0; k < corners; k++ {
+			var a, b = vs.Position(face.indexes[k]), vs.Position(face.indexes[(k+1)%corners])
+			var points = between([3]int{a.x, a.y, a.z}, [3]int{b.x, b.y, b.z})
+			polygon = append(polygon, [3]int{a.x, a.y, a.z})
+			polygon = append(polygon, points...)
+			split = split || len(points) != 0
+		}
+		if !split {
+			faces = append(faces, face)
+			continue
+		}
+
+		var index = func(p [3]int) int {
+			var i, ok = indexes[p]
+			if !ok {
+				i = vs.UseModel(Vertex{p[0], p[1], p[2]})
+				indexes[p] = i
+			}
+			return i
+		}
+		for _, t := range triangulate(polygon) {
+			var a, b, c = index(polygon[t[0]]), index(polygon[t[1]]), index(polygon[t[2]])
+			faces = append(faces, IndexFace{face.blockId, [4]int{a, b, c, c}, face.biome})
+		}
+	}
+
+	fs.faces = faces
+	vs.Unreference()
+	for _, face := range fs.faces {
+		for _, i := range face.indexes {
+			vs.Reference(i)
+		}
+	}
+}
+
+// lineKey is the line along an axis through p, as the axis and where the
+// line crosses the other two.
+func lineKey(p [3]int, axis int) [3]int {
+	return [3]int{axis, p[(axis+1)%3], p[(axis+2)%3]}
+}
+
+// onChunkSide reports whether the edge from a to b, in sixteenths of a
+// block, is on a side of the chunk.
+func onChunkSide(a, b [3]int) bool {
+	for _, k := range [2]int{0, 2} {
+		if a[k] == b[k] && (a[k] == 0 || a[k] == 16*16) {
+			return true
+		}
+	}
+	return false
+}
+
+// triangulate splits a convex polygon, which may have corners partway along
+// its sides, into triangles without any of its corners partway along their
+// edges, as the indexes of their corners in the polygon. Corners are cut off
+// one at a time, until all the corners left but one are in a line, which
+// that one is joined to in a fan.
+func triangulate(polygon [][3]int) [][3]int {
+	var order = make([]int, len(polygon))
+	for i := range order {
+		order[i] = i
+	}
+	var triangles [][3]int
+	for len(order) > 3 {
+		var n = len(order)
+		var clip, fan = -1, -1
+		for k := 0; k < n && fan == -1; k++ {
+			var prev, next = polygon[order[(k+n-1)%n]], polygon[order[(k+1)%n]]
+			if collinear(prev, polygon[order[k]], next) {
+				continue
+			}
+			if clip == -1 {
+				clip = k
+			}
+			var rest = true
+			for j := 2; j < n-1 && rest; j++ {
+				rest = collinear(prev, next, polygon[order[(k+j)%n]])
+			}
+			if rest {
+				fan = k
+			}
+		}
+
+		switch {
+		case fan != -1:
+			for j := 1; j < n-1; j++ {
+				triangles = append(triangles, [3]int{order[fan], order[(fan+j)%n], order[(fan+j+1)%n]})
+			}
+			return triangles
+		case clip == -1: // All in a line, with nothing to draw
+			return triangles
+		}
+		triangles = append(triangles, [3]int{order[(clip+n-1)%n], order[clip], order[(clip+1)%n]})
+		order = append(order[:clip], order[clip+1:]...)
+	}
+	if !collinear(polygon[order[0]], polygon[order[1]], polygon[order[2]]) {
+		triangles = append(triangles, [3]int{order[0], order[1], order[2]})
+	}
+	return triangles
+}
+
+// collinear reports whether c is on the line through a and b.
+func collinear(a, b, c [3]int) bool {
+	var u = [3]int{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+	var w = [3]int{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+	return u[1]*w[2] == u[2]*w[1] && u[2]*w[0] == u[0]*w[2] && u[0]*w[1] == u[1]*w[0]
+}
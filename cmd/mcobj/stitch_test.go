@@ -0,0 +1,184 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"math/rand"
+	"testing"
+)
+
+// strictlyBetween reports whether p is on the edge from a to b, but not at
+// either end.
+func strictlyBetween(a, b, p [3]int) bool {
+	if p == a || p == b || !collinear(a, b, p) {
+		return false
+	}
+	for k := range p {
+		if a[k] < b[k] && (p[k] < a[k] || p[k] > b[k]) || a[k] > b[k] && (p[k] > a[k] || p[k] < b[k]) || a[k] == b[k] && p[k] != a[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// stitchedFaces is the stitched faces of a chunk of bumpy ground of stone
+// and dirt, and of the chunk to the east of it, moved there.
+func stitchedFaces(t *testing.T) [][][3]int {
+	var r = rand.New(rand.NewSource(1))
+	var chunks [2]*EnclosedChunk
+	var faces [2]*Faces
+	for i := range chunks {
+		var heights [16][16]int
+		var blocks [16][16]nbt.Block
+		for x := range heights {
+			for z := range heights[x] {
+				heights[x][z] = 2 + r.Intn(4)
+				blocks[x][z] = nbt.Block(1 + 2*r.Intn(2))
+			}
+		}
+		chunks[i], faces[i] = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+			if y < heights[x][z] {
+				return blocks[x][z]
+			}
+			return 0
+		})
+	}
+	chunks[0].enclosing[1] = calculateSides(chunks[1].blocks)[0]
+	chunks[1].enclosing[0] = calculateSides(chunks[0].blocks)[1]
+
+	stitch = true
+	defer func() {
+		stitch = false
+	}()
+	var all [][][3]int
+	for i := range chunks {
+		processTestChunk(faces[i], chunks[i])
+		for _, face := range faceCorners(faces[i]) {
+			for j := range face {
+				face[j][0] += 16 * 16 * i
+			}
+			all = append(all, face)
+		}
+	}
+	return all
+}
+
+func TestStitch(t *testing.T) {
+	// No corner is partway along an edge, in either chunk or across the
+	// side between them
+	var faces = stitchedFaces(t)
+	var used = make(map[[3]int]bool)
+	for _, face := range faces {
+		for _, p := range face {
+			used[p] = true
+		}
+	}
+	for _, face := range faces {
+		for i, a := range face {
+			var b = face[(i+1)%len(face)]
+			for p := range used {
+				if strictlyBetween(a, b, p) {
+					t.Fatalf("%v is partway along %v to %v", p, a, b)
+				}
+			}
+		}
+	}
+
+	// Each edge meets another running the other way
+	var edges = edgeCounts(faces)
+	for edge, count := range edges {
+		if edges[[2][3]int{edge[1], edge[0]}] != count {
+			t.Errorf("edge %v is gone along %d times, but back %d", edge, count, edges[[2][3]int{edge[1], edge[0]}])
+		}
+	}
+}
+
+func TestStitchChunkSides(t *testing.T) {
+	// Along the sides of a chunk, the edges of faces that aren't on the
+	// side are split at every block
+	var e, fs = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+		if y < 2 || y < 4 && x < 8 {
+			return 1
+		}
+		return 0
+	})
+	stitch = true
+	defer func() {
+		stitch = false
+	}()
+	processTestChunk(fs, e)
+
+	var split = 0
+	for _, face := range faceCorners(fs) {
+		if onChunkSide(face[0], face[1]) && onChunkSide(face[1], face[2]) && onChunkSide(face[0], face[2]) {
+			continue // Across the side itself
+		}
+		for i, a := range face {
+			var b = face[(i+1)%len(face)]
+			if !onChunkSide(a, b) {
+				continue
+			}
+			split++
+			var length = 0
+			for k := range a {
+				length += (a[k] - b[k]) * (a[k] - b[k])
+			}
+			if length > 16*16 {
+				t.Errorf("%v to %v along the side of the chunk isn't split", a, b)
+			}
+		}
+	}
+	if split == 0 {
+		t.Error("no edges along the sides")
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	var polygons = map[string][][3]int{
+		"square": {{0, 0, 0}, {16, 0, 0}, {16, 0, 16}, {0, 0, 16}},
+		"all sides": {
+			{0, 0, 0}, {8, 0, 0}, {16, 0, 0}, {16, 0, 8}, {16, 0, 16},
+			{8, 0, 16}, {0, 0, 16}, {0, 0, 8}},
+		"opposite sides": {
+			{0, 0, 0}, {16, 0, 0}, {32, 0, 0}, {48, 0, 0}, {48, 16, 0},
+			{32, 16, 0}, {16, 16, 0}, {0, 16, 0}},
+		"triangle on a long base": {
+			{0, 0, 0}, {16, 0, 0}, {32, 0, 0}, {48, 0, 0}, {64, 0, 0}, {0, 16, 0}},
+		"one long side": {
+			{0, 0, 0}, {0, 16, 0}, {0, 32, 0}, {0, 48, 0}, {0, 64, 0},
+			{16, 64, 0}, {16, 0, 0}},
+	}
+	for name, polygon := range polygons {
+		var triangles = triangulate(polygon)
+		if len(triangles) != len(polygon)-2 {
+			t.Errorf("%s: %d triangles, want %d", name, len(triangles), len(polygon)-2)
+		}
+
+		var sides = make(map[[2]int]int)
+		for _, tri := range triangles {
+			var a, b, c = polygon[tri[0]], polygon[tri[1]], polygon[tri[2]]
+			if collinear(a, b, c) {
+				t.Errorf("%s: %v has no area", name, tri)
+			}
+			for j, i := range tri {
+				var next = tri[(j+1)%3]
+				sides[[2]int{i, next}]++
+				for _, p := range polygon {
+					if strictlyBetween(polygon[i], polygon[next], p) {
+						t.Errorf("%s: %v is partway along an edge of %v", name, p, tri)
+					}
+				}
+			}
+		}
+
+		// Each side of the polygon is one of a triangle, wound the same way
+		for i := range polygon {
+			if sides[[2]int{i, (i + 1) % len(polygon)}] != 1 {
+				t.Errorf("%s: side %d isn't a triangle's", name, i)
+			}
+		}
+	}
+
+	if triangles := triangulate([][3]int{{0, 0, 0}, {16, 0, 0}, {32, 0, 0}, {48, 0, 0}}); len(triangles) != 0 {
+		t.Errorf("points in a line: got %v", triangles)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+)
+
+// isPrintable reports whether a block is part of the solid -watertight
+// draws: anything but air, items and fluids, with block models filling
+// their blocks.
+func (fs *Faces) isPrintable(blockId nbt.Block) bool {
+	var info = fs.boundary.describer.BlockInfo(byte(blockId & 0xff))
+	return !info.IsEmpty() && !info.IsItem() && fluid(blockId&^nbt.Waterlogged) == 0
+}
+
+// processWatertight adds the faces -watertight draws instead of those of
+// processBlocks: a face of a block for each side it has against a block
+// that isn't printable, or against where the export stops, so that the
+// faces close up around the blocks. Faces aren't merged, so each corner is
+// shared by the faces around it.
+//
+// Where blocks only meet along an edge or at a corner, the surface would
+// touch itself there, which slicers can't make sense of. The corners and
+// the middles of the edges there are pinched apart by a sixteenth of a
+// block, each into the blocks of its own faces, and the faces with pinched
+// edges are split into triangles to take the middles.
+func (fs *Faces) processWatertight(e *EnclosedChunk) {
+	var printable = func(x, y, z int) bool {
+		return y >= 0 && y+e.blocks.minY >= yMin && fs.isPrintable(e.Get(x, y, z))
+	}
+
+	for x := 0; x < 16; x++ {
+		for z := 0; z < 16; z++ {
+			for y := 0; y < e.blocks.height; y++ {
+				if !printable(x, y, z) {
+					continue
+				}
+				var blockId = e.Get(x, y, z) &^ nbt.Waterlogged
+				var p = [3]int{x, y, z}
+				for side, faceSide := range faceSides {
+					var n = p
+					if faceSide.positive {
+						n[faceSide.normal]++
+					} else {
+						n[faceSide.normal]--
+					}
+					if printable(n[0], n[1], n[2]) {
+						continue
+					}
+
+					var face = boxFace(side, p, [3]int{x + 1, y + 1, z + 1})
+					var corners = make([]Vertex, 0, 8)
+					var split = false
+					for i, v := range face {
+						var pinch = pinchCorner(printable, v, p, n)
+						corners = append(corners, Vertex{16*v.x + pinch[0], 16*v.y + pinch[1], 16*v.z + pinch[2]})
+						if middle, ok := pinchEdge(printable, v, face[(i+1)%4], p); ok {
+							corners = append(corners, middle)
+							split = true
+						}
+					}
+					if !split {
+						fs.AddModelFace(blockId, corners[0], corners[1], corners[2], corners[3])
+						continue
+					}
+					// A fan of triangles from the middle of the face
+					var middle = Vertex{8 * (face[0].x + face[2].x), 8 * (face[0].y + face[2].y), 8 * (face[0].z + face[2].z)}
+					for i, v := range corners {
+						var next = corners[(i+1)%len(corners)]
+						fs.AddModelFace(blockId, middle, v, next, next)
+					}
+				}
+			}
+		}
+	}
+}
+
+// pinchEdge is the middle of the edge from a to b of a face of the
+// printable block p, moved a sixteenth of a block towards p, when only two
+// of the four blocks around the edge are printable and they're across the
+// edge from each other, so that the faces of each meet along an edge of
+// their own.
+func pinchEdge(printable func(x, y, z int) bool, a, b Vertex, p [3]int) (Vertex, bool) {
+	var lo, hi = [3]int{a.x, a.y, a.z}, [3]int{b.x, b.y, b.z}
+	var axis = 0
+	for lo[axis] == hi[axis] {
+		axis++
+	}
+	if hi[axis] < lo[axis] {
+		lo = hi
+	}
+	var u, v = (axis + 1) % 3, (axis + 2) % 3
+
+	var around [4]bool
+	for i := range around {
+		var c = lo
+		c[u] += i&1 - 1
+		c[v] += i>>1 - 1
+		around[i] = printable(c[0], c[1], c[2])
+	}
+	if around[0] != around[3] || around[1] != around[2] || around[0] == around[1] {
+		return Vertex{}, false
+	}
+
+	var middle = [3]int{16 * lo[0], 16 * lo[1], 16 * lo[2]}
+	middle[axis] += 8
+	for _, k := range [2]int{u, v} {
+		if p[k] < lo[k] {
+			middle[k]--
+		} else {
+			middle[k]++
+		}
+	}
+	return Vertex{middle[0], middle[1], middle[2]}, true
+}
+
+// pinchCorner is how far, in sixteenths, to move the corner v of a face
+// between the printable block p and block n. The faces around a corner
+// are between the eight blocks around it, and those along each edge from
+// the corner are joined in pairs, each pair the two sides of a run of
+// printable blocks around the edge, so that blocks that only meet along
+// the edge are apart. If that leaves the faces around the corner in more
+// than one ring, each ring's corner moves towards the printable blocks of
+// its faces.
+func pinchCorner(printable func(x, y, z int) bool, v Vertex, p, n [3]int) [3]int {
+	var cells [8]bool
+	var pi, ni = -1, -1
+	for i := range cells {
+		var c = [3]int{v.x - 1 + i&1, v.y - 1 + i>>1&1, v.z - 1 + i>>2}
+		cells[i] = printable(c[0], c[1], c[2])
+		if c == p {
+			pi = i
+		} else if c == n {
+			ni = i
+		}
+	}
+
+	// The faces, by the blocks they're between, joined by union-find
+	var wall = func(i, j int) int {
+		if i > j {
+			i, j = j, i
+		}
+		return i*8 + j
+	}
+	var parents = make(map[int]int)
+	var find func(w int) int
+	find = func(w int) int {
+		if parent, ok := parents[w]; ok && parent != w {
+			parents[w] = find(parent)
+			return parents[w]
+		}
+		return w
+	}
+
+	for axis := uint(0); axis < 3; axis++ {
+		var b, c = uint(1) << ((axis + 1) % 3), uint(1) << ((axis + 2) % 3)
+		for side := 0; side < 2; side++ {
+			var base = side << axis
+			var ring = [4]int{base, base | int(b), base | int(b|c), base | int(c)}
+			var runStart = -1 // Going round twice, to join runs across the start
+			for k := 0; k < 8; k++ {
+				var i, j = ring[k%4], ring[(k+1)%4]
+				if cells[i] == cells[j] {
+					continue
+				}
+				var w = wall(i, j)
+				if cells[j] {
+					runStart = w
+				} else if runStart != -1 {
+					parents[find(runStart)] = find(w)
+					runStart = -1
+				}
+			}
+		}
+	}
+
+	var face = find(wall(pi, ni))
+	var rings = make(map[int]bool)
+	var sum [3]int
+	for i := range cells {
+		for _, bit := range [3]int{1, 2, 4} {
+			var j = i ^ bit
+			if i > j || cells[i] == cells[j] {
+				continue
+			}
+			var root = find(wall(i, j))
+			rings[root] = true
+			if root != face {
+				continue
+			}
+			var solid = i
+			if cells[j] {
+				solid = j
+			}
+			sum[0] += 2*(solid&1) - 1
+			sum[1] += 2*(solid>>1&1) - 1
+			sum[2] += 2*(solid>>2) - 1
+		}
+	}
+
+	var pinch [3]int
+	if len(rings) < 2 {
+		return pinch
+	}
+	for a, s := range sum {
+		switch {
+		case s > 0:
+			pinch[a] = 1
+		case s < 0:
+			pinch[a] = -1
+		}
+	}
+	return pinch
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+// checkManifold checks that the faces are closed, with each edge gone along
+// once each way, and that the faces around each corner are joined into one
+// fan by the edges from it.
+func checkManifold(t *testing.T, name string, faces [][][3]int) {
+	var edges = edgeCounts(faces)
+	for edge, count := range edges {
+		if count != 1 || edges[[2][3]int{edge[1], edge[0]}] != 1 {
+			t.Errorf("%s: edge %v is gone along %d times, and back %d", name, edge, count, edges[[2][3]int{edge[1], edge[0]}])
+			return
+		}
+	}
+
+	// The faces around each corner, joined by union-find over the edges
+	var around = make(map[[3]int][]int)
+	for i, face := range faces {
+		for _, p := range face {
+			around[p] = append(around[p], i)
+		}
+	}
+	for p, fans := range around {
+		var parents = make(map[int]int)
+		var find func(i int) int
+		find = func(i int) int {
+			if parent, ok := parents[i]; ok && parent != i {
+				return find(parent)
+			}
+			return i
+		}
+		var from = make(map[[3]int]int) // The face with each edge out of p
+		for _, i := range fans {
+			var face = faces[i]
+			for j, q := range face {
+				if q != p {
+					continue
+				}
+				from[face[(j+1)%len(face)]] = i
+			}
+		}
+		for _, i := range fans {
+			var face = faces[i]
+			for j, q := range face {
+				if q != p {
+					continue
+				}
+				// The edge into p is the way back along another's edge out
+				var prev = face[(j+len(face)-1)%len(face)]
+				if other, ok := from[prev]; ok {
+					parents[find(i)] = find(other)
+				}
+			}
+		}
+		var roots = make(map[int]bool)
+		for _, i := range fans {
+			roots[find(i)] = true
+		}
+		if len(roots) != 1 {
+			t.Errorf("%s: the surface touches itself at %v", name, p)
+			return
+		}
+	}
+}
+
+func watertightFaces(t *testing.T, solid func(x, y, z int) bool) [][][3]int {
+	var e, fs = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+		if solid(x, y, z) {
+			return 1
+		}
+		return 0
+	})
+	watertight = true
+	defer func() {
+		watertight = false
+	}()
+	processTestChunk(fs, e)
+	return faceCorners(fs)
+}
+
+func TestWatertightEdge(t *testing.T) {
+	// Two blocks only meeting along an edge, each way
+	var pairs = [][2][3]int{
+		{{7, 7, 7}, {8, 8, 7}},
+		{{7, 7, 7}, {8, 7, 8}},
+		{{7, 7, 7}, {7, 8, 8}},
+		{{8, 7, 7}, {7, 8, 7}},
+	}
+	for _, pair := range pairs {
+		var faces = watertightFaces(t, func(x, y, z int) bool {
+			var p = [3]int{x, y, z}
+			return p == pair[0] || p == pair[1]
+		})
+		if len(faces) < 12 {
+			t.Errorf("%v: only %d faces", pair, len(faces))
+		}
+		checkManifold(t, "edge", faces)
+	}
+}
+
+func TestWatertightCorner(t *testing.T) {
+	// Two blocks only meeting at a corner
+	var faces = watertightFaces(t, func(x, y, z int) bool {
+		return x == 7 && y == 7 && z == 7 || x == 8 && y == 8 && z == 8
+	})
+	checkManifold(t, "corner", faces)
+
+	// Four blocks around a corner, each only meeting the others at it
+	faces = watertightFaces(t, func(x, y, z int) bool {
+		if x < 7 || x > 8 || y < 7 || y > 8 || z < 7 || z > 8 {
+			return false
+		}
+		return (x+y+z)%2 == 0
+	})
+	checkManifold(t, "corners", faces)
+}
+
+func TestWatertightCube(t *testing.T) {
+	// Each of the ways of filling a 2x2x2 cube
+	for fill := 0; fill < 256; fill++ {
+		var faces = watertightFaces(t, func(x, y, z int) bool {
+			if x < 7 || x > 8 || y < 7 || y > 8 || z < 7 || z > 8 {
+				return false
+			}
+			return fill>>uint(x-7+2*(y-7)+4*(z-7))&1 != 0
+		})
+		checkManifold(t, "cube", faces)
+	}
+}
+
+// watertightCornerFaces is the watertight faces of the four chunks meeting
+// at x,z 16,16.
+func watertightCornerFaces(t *testing.T, solid func(x, y, z int) bool) [][][3]int {
+	watertight = true
+	defer func() {
+		watertight = false
+	}()
+	return cornerFaces(t, solid)
+}
+
+// cornerFaces is the faces of the four chunks meeting at x,z 16,16,
+// enclosed by a SideCache as an export would, each moved to where it is.
+func cornerFaces(t *testing.T, solid func(x, y, z int) bool) [][][3]int {
+	var sideCache = new(SideCache)
+	var chunks [4]*nbt.Chunk
+	var faces [4]*Faces
+	for i := range chunks {
+		var cx, cz = i & 1, i >> 1
+		var e *EnclosedChunk
+		e, faces[i] = testChunk(t, 16, emptySide, func(x, y, z int) nbt.Block {
+			if solid(16*cx+x, y, 16*cz+z) {
+				return 1
+			}
+			return 0
+		})
+		chunks[i] = e.chunk
+		chunks[i].XPos, chunks[i].ZPos = cx, cz
+		sideCache.AddChunk(chunks[i])
+	}
+
+	var all [][][3]int
+	for i, chunk := range chunks {
+		processTestChunk(faces[i], sideCache.EncloseChunk(chunk))
+		for _, face := range faceCorners(faces[i]) {
+			for j := range face {
+				face[j][0] += 16 * 16 * chunk.XPos
+				face[j][2] += 16 * 16 * chunk.ZPos
+			}
+			all = append(all, face)
+		}
+	}
+	return all
+}
+
+func TestWatertightChunkCorner(t *testing.T) {
+	// Blocks in the chunks diagonally across from each other, meeting along
+	// the edge where the four chunks meet, and at a corner on it
+	var pairs = [][2][3]int{
+		{{15, 7, 15}, {16, 7, 16}},
+		{{16, 7, 15}, {15, 7, 16}},
+		{{15, 7, 15}, {16, 8, 16}},
+	}
+	for _, pair := range pairs {
+		var faces = watertightCornerFaces(t, func(x, y, z int) bool {
+			var p = [3]int{x, y, z}
+			return p == pair[0] || p == pair[1]
+		})
+		if len(faces) < 12 {
+			t.Errorf("%v: only %d faces", pair, len(faces))
+		}
+		checkManifold(t, "chunk corner", faces)
+	}
+
+	// Four blocks around a corner, one in each chunk
+	var faces = watertightCornerFaces(t, func(x, y, z int) bool {
+		if x < 15 || x > 16 || y < 7 || y > 8 || z < 15 || z > 16 {
+			return false
+		}
+		return (x+y+z)%2 == 0
+	})
+	checkManifold(t, "chunk corners", faces)
+}
@@ -1,7 +1,7 @@
 package mcworld
 
 import (
-	"compress/gzip"
+	"github.com/quag/mcobj/nbt"
 	"io"
 	"os"
 	"path/filepath"
@@ -10,18 +10,20 @@ import (
 )
 
 type AlphaWorld struct {
-	worldDir string
+	fs           FileSystem
+	worldDir     string
+	dimensionDir string
 }
 
 func (w *AlphaWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
-	var file, fileErr = os.Open(chunkPath(w.worldDir, x, z))
+	var file, fileErr = w.fs.Open(chunkPath(w.dimensionDir, x, z))
 	if fileErr != nil {
 		return nil, fileErr
 	}
-	var decompressor, gzipErr = gzip.NewReader(file)
-	if gzipErr != nil {
+	var decompressor, decompressErr = nbt.NewDecompressor(file)
+	if decompressErr != nil {
 		file.Close()
-		return nil, gzipErr
+		return nil, decompressErr
 	}
 	return &ReadCloserPair{decompressor, file}, nil
 }
@@ -47,13 +49,19 @@ func (p *AlphaChunkPool) Remaining() int {
 	return len(p.chunkMap)
 }
 
+func (w *AlphaWorld) Level() (*nbt.Level, error) {
+	return readJavaLevel(w.fs, w.worldDir)
+}
+
 func (w *AlphaWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
 	chunks := make(map[string]bool)
 	box := EmptyBoundingBox()
+	modifiedMask, hasModified := mask.(ModifiedChunkMask)
 
-	err := filepath.Walk(w.worldDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	err := walkFiles(w.fs, w.dimensionDir, func(path string, info os.FileInfo) error {
+		// Alpha 1.2 keeps the Nether's chunks in DIM-1, in the same layout
+		if info.IsDir() && path != w.dimensionDir && strings.HasPrefix(info.Name(), "DIM") {
+			return filepath.SkipDir
 		}
 
 		if !info.IsDir() {
@@ -64,7 +72,17 @@ func (w *AlphaWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
 					x, xErr = strconv.ParseInt(s[1], 36, 64)
 					z, zErr = strconv.ParseInt(s[2], 36, 64)
 				)
-				if xErr == nil && zErr == nil && !mask.IsMasked(int(x), int(z)) {
+				if xErr != nil || zErr != nil {
+					return nil
+				}
+
+				var masked bool
+				if hasModified {
+					masked = modifiedMask.IsMaskedModified(int(x), int(z), info.ModTime())
+				} else {
+					masked = mask.IsMasked(int(x), int(z))
+				}
+				if !masked {
 					chunks[path] = true
 					box.Union(int(x), int(z))
 				}
@@ -73,7 +91,7 @@ func (w *AlphaWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
 
 		return nil
 	})
-	return &AlphaChunkPool{chunks, box, w.worldDir}, err
+	return &AlphaChunkPool{chunks, box, w.dimensionDir}, err
 }
 
 func chunkPath(world string, x, z int) string {
@@ -0,0 +1,52 @@
+package mcworld
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAlphaChunkPath(t *testing.T) {
+	var checkPath = func(x, z int, expected string) {
+		if path := chunkPath("world", x, z); path != filepath.FromSlash(expected) {
+			t.Errorf("chunk %d,%d at %s not %s", x, z, path, expected)
+		}
+	}
+	checkPath(0, 0, "world/0/0/c.0.0.dat")
+	checkPath(-13, 44, "world/1f/18/c.-d.18.dat")
+	checkPath(100, -1, "world/10/1r/c.2s.-1.dat")
+}
+
+func TestAlphaChunkPool(t *testing.T) {
+	var worldDir, err = ioutil.TempDir("", "alphaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(worldDir)
+
+	var create = func(path string) {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	create(chunkPath(worldDir, -13, 44))
+	create(chunkPath(worldDir, 2, 3))
+	create(chunkPath(filepath.Join(worldDir, "DIM-1"), 7, 7))
+	create(filepath.Join(worldDir, "level.dat"))
+
+	pool, err := (&AlphaWorld{osFileSystem{}, worldDir, worldDir}).ChunkPool(&AllChunksMask{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Remaining() != 2 {
+		t.Errorf("%d chunks not 2", pool.Remaining())
+	}
+	if !pool.Pop(-13, 44) || !pool.Pop(2, 3) {
+		t.Error("chunks -13,44 and 2,3 not pooled")
+	}
+	if box := pool.BoundingBox(); box.X0 != -13 || box.Z1 != 44 {
+		t.Errorf("bounding box %v", *box)
+	}
+}
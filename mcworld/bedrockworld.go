@@ -0,0 +1,535 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BedrockWorld reads Bedrock (and Pocket) Edition worlds, which keep their
+// chunks in a LevelDB database in the world's db directory.
+//
+// Each 16x16x16 sub-chunk is stored under its own key. OpenChunk gathers the
+// sub-chunks of a column and re-encodes them as a Java Edition style chunk
+// (palette sections, like a 1.18 region chunk) so that they can be read by
+// nbt.ReadChunkNbt like any other chunk.
+type BedrockWorld struct {
+	fs        FileSystem
+	worldDir  string
+	dimension int // 0 for the Overworld, 1 the Nether and 2 the End
+
+	db    *levelDB
+	dbErr error
+}
+
+const (
+	bedrockTagData3D           = 0x2b
+	bedrockTagVersion          = 0x2c
+	bedrockTagSubChunkPrefix   = 0x2f
+	bedrockTagLegacyTerrain    = 0x30
+	bedrockTagLegacyVersion    = 0x76
+	bedrockMinSubChunk         = -4
+	bedrockMaxSubChunk         = 19
+	bedrockSubChunkBlockCount  = 16 * 16 * 16
+	bedrockPersistentPaletteId = 0
+)
+
+func (w *BedrockWorld) open() (*levelDB, error) {
+	if w.db == nil && w.dbErr == nil {
+		w.db, w.dbErr = openLevelDB(w.fs, filepath.Join(w.worldDir, "db"))
+	}
+	return w.db, w.dbErr
+}
+
+// chunkKey is the key of a chunk's tag: the chunk's x and z, the
+// dimension unless it is the Overworld, then the tag.
+func (w *BedrockWorld) chunkKey(x, z int, tag byte) []byte {
+	var key = make([]byte, 8, 14)
+	binary.LittleEndian.PutUint32(key[0:], uint32(int32(x)))
+	binary.LittleEndian.PutUint32(key[4:], uint32(int32(z)))
+	if w.dimension != 0 {
+		var dimension [4]byte
+		binary.LittleEndian.PutUint32(dimension[:], uint32(int32(w.dimension)))
+		key = append(key, dimension[:]...)
+	}
+	return append(key, tag)
+}
+
+func (w *BedrockWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
+	var db, dbErr = w.open()
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	var sections [][]byte
+	for y := bedrockMinSubChunk; y <= bedrockMaxSubChunk; y++ {
+		var value, getErr = db.Get(append(w.chunkKey(x, z, bedrockTagSubChunkPrefix), byte(int8(y))))
+		if getErr != nil {
+			return nil, getErr
+		}
+		if value == nil {
+			continue
+		}
+
+		var section, sectionErr = bedrockSection(value, y)
+		if sectionErr != nil {
+			return nil, errors.New(fmt.Sprintf("Chunk %v,%v sub-chunk %v: %v", x, z, y, sectionErr))
+		}
+		sections = append(sections, section)
+	}
+
+	if sections == nil {
+		var legacy, legacyErr = db.Get(w.chunkKey(x, z, bedrockTagLegacyTerrain))
+		if legacyErr != nil {
+			return nil, legacyErr
+		}
+		if legacy != nil {
+			return bedrockLegacyTerrain(x, z, legacy)
+		}
+
+		var version, versionErr = w.chunkVersion(db, x, z)
+		if versionErr != nil {
+			return nil, versionErr
+		}
+		if version == nil {
+			return nil, errors.New(fmt.Sprintf("Chunk missing: %v,%v", x, z))
+		}
+	}
+
+	var chunk = new(chunkNbtWriter)
+	chunk.beginStruct("")
+	chunk.int32("xPos", x)
+	chunk.int32("zPos", z)
+	chunk.structList("sections", sections)
+	chunk.endStruct()
+
+	return ioutil.NopCloser(bytes.NewReader(chunk.Bytes())), nil
+}
+
+// bedrockLegacyTerrain reads the chunks of worlds from before 1.0, which
+// keep all of a chunk's blocks under one key: 128 high columns of block
+// ids, data, sky light and block light in XZY order, as Pocket Edition's
+// chunks.dat did, then its height map and biome colors.
+func bedrockLegacyTerrain(x, z int, value []byte) (io.ReadCloser, error) {
+	if len(value) < pocketChunkBlocks+pocketChunkBlocks/2 {
+		return nil, errors.New(fmt.Sprintf("Chunk %v,%v legacy terrain is only %d bytes", x, z, len(value)))
+	}
+
+	var chunk chunkNbtWriter
+	chunk.beginStruct("")
+	chunk.beginStruct("Level")
+	chunk.int32("xPos", x)
+	chunk.int32("zPos", z)
+	chunk.byteArray("Blocks", value[:pocketChunkBlocks])
+	chunk.byteArray("Data", value[pocketChunkBlocks:pocketChunkBlocks+pocketChunkBlocks/2])
+	chunk.endStruct()
+	chunk.endStruct()
+	return ioutil.NopCloser(bytes.NewReader(chunk.Bytes())), nil
+}
+
+func (w *BedrockWorld) chunkVersion(db *levelDB, x, z int) ([]byte, error) {
+	var version, err = db.Get(w.chunkKey(x, z, bedrockTagVersion))
+	if version == nil && err == nil {
+		version, err = db.Get(w.chunkKey(x, z, bedrockTagLegacyVersion))
+	}
+	return version, err
+}
+
+func (w *BedrockWorld) Level() (*nbt.Level, error) {
+	var file, err = w.fs.Open(filepath.Join(w.worldDir, "level.dat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return nbt.ReadBedrockLevelDat(file)
+}
+
+func (w *BedrockWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
+	var db, dbErr = w.open()
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	var pool = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox(), nil}
+	var eachErr = db.Each(func(key []byte) {
+		if len(key) < 9 {
+			return
+		}
+
+		// Each chunk of the dimension has a version key
+		var (
+			x   = int(int32(binary.LittleEndian.Uint32(key[0:])))
+			z   = int(int32(binary.LittleEndian.Uint32(key[4:])))
+			tag = key[len(key)-1]
+		)
+		if tag != bedrockTagVersion && tag != bedrockTagLegacyVersion || !bytes.Equal(key, w.chunkKey(x, z, tag)) {
+			return
+		}
+
+		if !mask.IsMasked(x, z) {
+			pool.chunkMap[betaChunkPoolKey(x, z)] = true
+			pool.box.Union(x, z)
+		}
+	})
+	if eachErr != nil {
+		return nil, eachErr
+	}
+
+	return pool, nil
+}
+
+// bedrockSection decodes a palette based sub-chunk (versions 1, 8 and 9)
+// into an encoded Java section struct.
+//
+// A sub-chunk is a version byte, a count of block storages (the first
+// holds the blocks, the second any water the blocks are logged with) and
+// for version 9 the sub-chunk's y index. Each storage is a header byte of
+// bits per block (shifted one left above a runtime palette flag), the
+// packed block indexes as little endian uint32 words, and the palette as
+// little endian NBT structs. Blocks never straddle words, and are in XZY
+// order.
+func bedrockSection(value []byte, y int) ([]byte, error) {
+	var r = nbt.NewLittleEndianReader(bytes.NewReader(value))
+
+	var version, versionErr = r.ReadInt8()
+	if versionErr != nil {
+		return nil, versionErr
+	}
+
+	var storages = 1
+	switch version {
+	case 1:
+	case 8, 9:
+		var count, countErr = r.ReadInt8()
+		if countErr != nil {
+			return nil, countErr
+		}
+		storages = count
+		if version == 9 {
+			var index, indexErr = r.ReadInt8()
+			if indexErr != nil {
+				return nil, indexErr
+			}
+			y = index
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("sub-chunk version %d not supported", version))
+	}
+
+	if storages < 1 {
+		return encodeJavaSection(y, []string{"minecraft:air"}, []map[string]interface{}{nil}, make([]int, bedrockSubChunkBlockCount)), nil
+	}
+
+	var header, headerErr = r.ReadInt8()
+	if headerErr != nil {
+		return nil, headerErr
+	}
+	if header&1 != bedrockPersistentPaletteId {
+		return nil, errors.New("runtime block palettes not supported")
+	}
+
+	var bits = (header & 0xff) >> 1
+	switch bits {
+	case 0, 1, 2, 3, 4, 5, 6, 8, 16:
+	default:
+		return nil, errors.New(fmt.Sprintf("%d bits per block not supported", bits))
+	}
+	var indexes = make([]int, bedrockSubChunkBlockCount)
+	if bits != 0 {
+		var (
+			perWord = 32 / bits
+			words   = (bedrockSubChunkBlockCount + perWord - 1) / perWord
+			mask    = uint32(1)<<uint(bits) - 1
+		)
+		if 4*words > len(value) {
+			return nil, errors.New(fmt.Sprintf("%d words of blocks in %d bytes", words, len(value)))
+		}
+		for i := 0; i < words; i++ {
+			var word, err = r.ReadInt32()
+			if err != nil {
+				return nil, err
+			}
+			for j := 0; j < perWord && i*perWord+j < bedrockSubChunkBlockCount; j++ {
+				indexes[i*perWord+j] = int(uint32(word) >> uint(j*bits) & mask)
+			}
+		}
+	}
+
+	var paletteSize, paletteSizeErr = r.ReadInt32()
+	if paletteSizeErr != nil {
+		return nil, paletteSizeErr
+	}
+	// Each palette entry takes a few bytes at least
+	if paletteSize < 0 || paletteSize > len(value) {
+		return nil, errors.New(fmt.Sprintf("palette of %d blocks in %d bytes", paletteSize, len(value)))
+	}
+
+	var (
+		names      = make([]string, paletteSize)
+		properties = make([]map[string]interface{}, paletteSize)
+	)
+	for i := 0; i < paletteSize; i++ {
+		var _, _, tagErr = r.ReadTag()
+		if tagErr != nil {
+			return nil, tagErr
+		}
+		var entry, entryErr = r.ReadStruct()
+		if entryErr != nil {
+			return nil, entryErr
+		}
+
+		var name, _ = entry["name"].(string)
+		var states, _ = entry["states"].(map[string]interface{})
+		names[i], properties[i] = bedrockBlockState(name, states)
+	}
+
+	// Swap XZY for the YZX used by Java sections
+	var javaIndexes = make([]int, bedrockSubChunkBlockCount)
+	for i, index := range indexes {
+		if index >= paletteSize {
+			index = 0
+		}
+		var (
+			bx = i >> 8
+			bz = (i >> 4) & 0xf
+			by = i & 0xf
+		)
+		javaIndexes[by<<8|bz<<4|bx] = index
+	}
+
+	return encodeJavaSection(y, names, properties, javaIndexes), nil
+}
+
+func encodeJavaSection(y int, names []string, properties []map[string]interface{}, indexes []int) []byte {
+	var bits = 4
+	for 1<<uint(bits) < len(names) {
+		bits++
+	}
+	var perLong = 64 / bits
+	var data = make([]int64, (len(indexes)+perLong-1)/perLong)
+	for i, index := range indexes {
+		data[i/perLong] |= int64(uint64(index) << uint((i%perLong)*bits))
+	}
+
+	var palette = make([][]byte, len(names))
+	for i, name := range names {
+		var entry = new(chunkNbtWriter)
+		entry.beginStruct("")
+		entry.string("Name", name)
+		if len(properties[i]) != 0 {
+			entry.beginStruct("Properties")
+			for key, value := range properties[i] {
+				entry.string(key, fmt.Sprint(value))
+			}
+			entry.endStruct()
+		}
+		entry.endStruct()
+		palette[i] = entry.Bytes()
+	}
+
+	var section = new(chunkNbtWriter)
+	section.beginStruct("")
+	section.int8("Y", y)
+	section.beginStruct("block_states")
+	section.structList("palette", palette)
+	section.longArray("data", data)
+	section.endStruct()
+	section.endStruct()
+	return section.Bytes()
+}
+
+// bedrockBlockState translates the Bedrock name and states of a block to
+// the Java Edition name and properties. Newer Bedrock versions have been
+// adopting the Java names, older ones share a name between variants and
+// pick between them with a state.
+func bedrockBlockState(name string, states map[string]interface{}) (string, map[string]interface{}) {
+	var stateString = func(key string) string {
+		if value, ok := states[key]; ok {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+	var stateInt = func(key string) int {
+		var i, _ = strconv.Atoi(stateString(key))
+		return i
+	}
+
+	name = strings.TrimPrefix(name, "minecraft:")
+	var properties = make(map[string]interface{})
+
+	var variant = func(key, defaultValue string, renames map[string]string) string {
+		var value = stateString(key)
+		if value == "" {
+			value = defaultValue
+		}
+		if renamed, ok := renames[value]; ok {
+			return renamed
+		}
+		return value
+	}
+
+	switch name {
+	case "grass":
+		name = "grass_block"
+	case "tallgrass":
+		name = variant("tall_grass_type", "tall", map[string]string{"tall": "grass", "default": "grass", "snow": "grass"})
+	case "log", "log2", "wood":
+		name = variant("old_log_type", variant("new_log_type", variant("wood_type", "oak", nil), nil), nil) + "_log"
+	case "leaves", "leaves2":
+		name = variant("old_leaf_type", variant("new_leaf_type", "oak", nil), nil) + "_leaves"
+	case "planks":
+		name = variant("wood_type", "oak", nil) + "_planks"
+	case "fence":
+		name = variant("wood_type", "oak", nil) + "_fence"
+	case "sapling":
+		name = variant("sapling_type", "oak", nil) + "_sapling"
+	case "wool", "concrete", "concrete_powder", "carpet", "stained_glass", "stained_glass_pane", "shulker_box":
+		name = variant("color", "white", map[string]string{"silver": "light_gray"}) + "_" + name
+	case "stained_hardened_clay":
+		name = variant("color", "white", map[string]string{"silver": "light_gray"}) + "_terracotta"
+	case "hardened_clay":
+		name = "terracotta"
+	case "stone":
+		name = variant("stone_type", "stone", map[string]string{"granite_smooth": "polished_granite", "diorite_smooth": "polished_diorite", "andesite_smooth": "polished_andesite"})
+	case "dirt":
+		name = variant("dirt_type", "dirt", map[string]string{"normal": "dirt", "coarse": "coarse_dirt"})
+	case "sand":
+		name = variant("sand_type", "sand", map[string]string{"normal": "sand", "red": "red_sand"})
+	case "sandstone", "red_sandstone":
+		var prefix = strings.TrimSuffix(name, "sandstone")
+		name = variant("sand_stone_type", "default", map[string]string{"default": prefix + "sandstone", "heiroglyphs": "chiseled_" + prefix + "sandstone", "cut": "cut_" + prefix + "sandstone", "smooth": "smooth_" + prefix + "sandstone"})
+	case "stonebrick":
+		name = variant("stone_brick_type", "default", map[string]string{"default": "stone_bricks", "mossy": "mossy_stone_bricks", "cracked": "cracked_stone_bricks", "chiseled": "chiseled_stone_bricks", "smooth": "stone_bricks"})
+	case "red_flower":
+		name = variant("flower_type", "poppy", map[string]string{"orchid": "blue_orchid", "houstonia": "azure_bluet", "tulip_red": "red_tulip", "tulip_orange": "orange_tulip", "tulip_white": "white_tulip", "tulip_pink": "pink_tulip", "oxeye": "oxeye_daisy"})
+	case "yellow_flower":
+		name = "dandelion"
+	case "double_plant":
+		name = variant("double_plant_type", "sunflower", map[string]string{"syringa": "lilac", "grass": "tall_grass", "fern": "large_fern", "rose": "rose_bush", "paeonia": "peony"})
+	case "snow_layer":
+		name = "snow"
+		properties["layers"] = stateInt("height") + 1
+	case "water", "flowing_water", "lava", "flowing_lava":
+		name = strings.TrimPrefix(name, "flowing_")
+		properties["level"] = stateInt("liquid_depth")
+	case "stone_slab", "double_stone_slab", "stone_slab2", "double_stone_slab2", "wooden_slab", "double_wooden_slab":
+		var double = strings.HasPrefix(name, "double_")
+		if strings.HasPrefix(name, "wooden") || strings.HasPrefix(name, "double_wooden") {
+			name = variant("wood_type", "oak", nil) + "_slab"
+		} else {
+			name = variant("stone_slab_type", variant("stone_slab_type_2", "smooth_stone", nil), map[string]string{"wood": "petrified_oak", "stone_brick": "stone_brick", "quartz": "quartz"}) + "_slab"
+		}
+		switch {
+		case double:
+			properties["type"] = "double"
+		case stateInt("top_slot_bit") == 1:
+			properties["type"] = "top"
+		}
+	case "lit_furnace", "lit_redstone_ore", "lit_redstone_lamp":
+		name = strings.TrimPrefix(name, "lit_")
+		properties["lit"] = "true"
+	case "unlit_redstone_torch":
+		name = "redstone_torch"
+		properties["lit"] = "false"
+	case "lit_pumpkin":
+		name = "jack_o_lantern"
+	case "brick_block":
+		name = "bricks"
+	case "nether_brick":
+		name = "nether_bricks"
+	case "red_nether_brick":
+		name = "red_nether_bricks"
+	case "mob_spawner":
+		name = "spawner"
+	case "web":
+		name = "cobweb"
+	case "quartz_ore":
+		name = "nether_quartz_ore"
+	case "golden_rail":
+		name = "powered_rail"
+	case "wooden_door":
+		name = "oak_door"
+	case "trapdoor":
+		name = "oak_trapdoor"
+	case "fence_gate":
+		name = "oak_fence_gate"
+	case "wooden_pressure_plate":
+		name = "oak_pressure_plate"
+	case "wooden_button":
+		name = "oak_button"
+	case "standing_sign":
+		name = "oak_sign"
+	case "wall_sign":
+		name = "oak_wall_sign"
+	case "monster_egg":
+		name = "infested_stone"
+	case "melon_block":
+		name = "melon"
+	case "waterlily":
+		name = "lily_pad"
+	case "reeds":
+		name = "sugar_cane"
+	case "portal":
+		name = "nether_portal"
+	case "magma":
+		name = "magma_block"
+	case "slime":
+		name = "slime_block"
+	case "grass_path":
+		name = "dirt_path"
+	case "snow":
+		name = "snow_block"
+	case "cobblestone_wall":
+		name = variant("wall_block_type", "cobblestone", map[string]string{"mossy_cobblestone": "mossy_cobblestone"}) + "_wall"
+	case "quartz_block":
+		name = variant("chisel_type", "default", map[string]string{"default": "quartz_block", "chiseled": "chiseled_quartz_block", "lines": "quartz_pillar", "smooth": "smooth_quartz"})
+	}
+
+	// States shared by many blocks
+	if _, ok := states["upper_block_bit"]; ok {
+		if stateInt("upper_block_bit") == 1 {
+			properties["half"] = "upper"
+		} else {
+			properties["half"] = "lower"
+		}
+	}
+	if _, ok := states["upside_down_bit"]; ok && stateInt("upside_down_bit") == 1 {
+		properties["half"] = "top"
+	}
+	if _, ok := states["weirdo_direction"]; ok {
+		properties["facing"] = []string{"east", "west", "south", "north"}[stateInt("weirdo_direction")&3]
+	}
+	if _, ok := states["head_piece_bit"]; ok {
+		if stateInt("head_piece_bit") == 1 {
+			properties["part"] = "head"
+		} else {
+			properties["part"] = "foot"
+		}
+		properties["facing"] = []string{"south", "west", "north", "east"}[stateInt("direction")&3]
+	}
+	if _, ok := states["open_bit"]; ok && stateInt("open_bit") == 1 {
+		properties["open"] = "true"
+	}
+	if _, ok := states["growth"]; ok {
+		properties["age"] = stateInt("growth")
+	}
+	if _, ok := states["rail_direction"]; ok {
+		properties["shape"] = []string{"north_south", "east_west", "ascending_east", "ascending_west", "ascending_north", "ascending_south", "south_east", "south_west", "north_west", "north_east"}[stateInt("rail_direction")%10]
+	}
+	if facing := stateString("torch_facing_direction"); facing != "" && facing != "top" && facing != "unknown" {
+		name = strings.Replace(name, "torch", "wall_torch", 1)
+		properties["facing"] = map[string]string{"west": "east", "east": "west", "north": "south", "south": "north"}[facing]
+	}
+	if layers := stateString("ground_sign_direction"); layers != "" {
+		properties["rotation"] = layers
+	}
+
+	return "minecraft:" + name, properties
+}
@@ -0,0 +1,118 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestBedrockSection(t *testing.T) {
+	// Version 8 sub-chunk, one storage of 1 bit blocks: stone at even XZY
+	// indexes, air at odd.
+	var buf bytes.Buffer
+	buf.Write([]byte{8, 1, 1 << 1})
+	for i := 0; i < 4096/32; i++ {
+		binary.Write(&buf, binary.LittleEndian, uint32(0xaaaaaaaa))
+	}
+	binary.Write(&buf, binary.LittleEndian, int32(2))
+	buf.Write(lePaletteEntry("minecraft:stone", "stone_type", "stone"))
+	buf.Write(lePaletteEntry("minecraft:air", "", ""))
+
+	var section, err = bedrockSection(buf.Bytes(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunk = new(chunkNbtWriter)
+	chunk.beginStruct("")
+	chunk.int32("xPos", 1)
+	chunk.int32("zPos", 2)
+	chunk.structList("sections", [][]byte{section})
+	chunk.endStruct()
+
+	c, err := nbt.ReadChunkNbt(bytes.NewReader(chunk.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bedrock index y=1 (odd) is air; y=2 (even) is stone
+	var at = func(x, y, z int) nbt.Block {
+		return c.Blocks[y-c.MinY+(z*len(c.Blocks)/256)+(x*len(c.Blocks)/16)]
+	}
+	if b := at(0, 48, 0); b != 1 {
+		t.Errorf("block at 0,48,0 is %d not stone", b)
+	}
+	if b := at(0, 49, 0); b != 0 {
+		t.Errorf("block at 0,49,0 is %d not air", b)
+	}
+}
+
+func lePaletteEntry(name, stateName, stateValue string) []byte {
+	var buf bytes.Buffer
+	var tag = func(typeId nbt.TypeId, name string) {
+		buf.WriteByte(byte(typeId))
+		binary.Write(&buf, binary.LittleEndian, uint16(len(name)))
+		buf.WriteString(name)
+	}
+	var str = func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(s)))
+		buf.WriteString(s)
+	}
+
+	tag(nbt.TagStruct, "")
+	tag(nbt.TagString, "name")
+	str(name)
+	tag(nbt.TagStruct, "states")
+	if stateName != "" {
+		tag(nbt.TagString, stateName)
+		str(stateValue)
+	}
+	buf.WriteByte(byte(nbt.TagStructEnd))
+	buf.WriteByte(byte(nbt.TagStructEnd))
+	return buf.Bytes()
+}
+
+func TestBedrockSectionRejectsBadHeaders(t *testing.T) {
+	for _, value := range [][]byte{
+		{1, 0x82, 0, 0, 0, 0},          // 65 bits per block
+		{1, 0, 0xff, 0xff, 0xff, 0xff}, // Palette of -1 blocks
+		{1, 0, 0xff, 0xff, 0xff, 0x7f}, // Palette of more blocks than bytes
+		{1, 1 << 1, 0, 0, 0, 0},        // Too few words of 1 bit blocks
+		{1, 7 << 1},                    // 7 bits per block
+	} {
+		if _, err := bedrockSection(value, 0); err == nil {
+			t.Errorf("bedrockSection(%v) didn't fail", value)
+		}
+	}
+}
+
+func TestBedrockLegacyTerrain(t *testing.T) {
+	var value = make([]byte, 83200)
+	var i = 1*2048 + 2*128 + 3 // x=1, z=2, y=3
+	value[i] = 35
+	value[pocketChunkBlocks+i/2] = 14 << 4 // i is odd: the high nibble
+
+	var w = &BedrockWorld{db: &levelDB{log: make(map[string]levelDBEntry)}}
+	w.db.log[string(w.chunkKey(4, 5, bedrockTagLegacyTerrain))] = levelDBEntry{value: value}
+	var r, err = w.OpenChunk(4, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	c, err := nbt.ReadChunkNbt(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.XPos != 4 || c.ZPos != 5 {
+		t.Errorf("chunk is at %d,%d not 4,5", c.XPos, c.ZPos)
+	}
+	if b := c.Blocks[i]; b != 35+14<<8 {
+		t.Errorf("block at 1,3,2 is %#x not red wool", b)
+	}
+
+	if _, err := bedrockLegacyTerrain(4, 5, value[:100]); err == nil {
+		t.Error("short legacy terrain didn't fail")
+	}
+}
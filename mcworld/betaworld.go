@@ -1,54 +1,93 @@
 package mcworld
 
 import (
-	"compress/zlib"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"github.com/quag/mcobj/nbt"
 	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
 	ChunkNotFoundError = errors.New("Chunk Missing")
 )
 
+// Chunks too big for a region file are written to a c.x.z.mcc file next to
+// it, with this bit set in the compression type left in the region file.
+const externalChunkFlag = 0x80
+
 type BetaWorld struct {
-	worldDir string
+	fs           FileSystem
+	worldDir     string
+	dimensionDir string // The folder holding region, which for the Nether and End is inside the world
+	regionDir    string // Where the region files are, if not in dimensionDir/region
+	regions      regionCache
+	index        *RegionIndex
+
+	formatOnce sync.Once
+	extensions []string // Region file extensions to look for, in order
 }
 
 type McrFile struct {
-	*os.File
+	File
 }
 
 func (w *BetaWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
-	mcaName := fmt.Sprintf("r.%v.%v.mca", x>>5, z>>5)
-	mcaPath := filepath.Join(w.worldDir, "region", mcaName)
+	return w.openRegionChunk("region", x, z)
+}
 
-	mcrName := fmt.Sprintf("r.%v.%v.mcr", x>>5, z>>5)
-	mcrPath := filepath.Join(w.worldDir, "region", mcrName)
+// OpenEntities opens the entities of a chunk, which from 1.17 are kept in
+// region files of their own in the entities folder.
+func (w *BetaWorld) OpenEntities(x, z int) (io.ReadCloser, error) {
+	return w.openRegionChunk("entities", x, z)
+}
 
-	var path string
-	if _, err := os.Stat(mcaPath); err == nil {
-		path = mcaPath
-	} else {
-		path = mcrPath
-	}
+// OpenPOI opens the points of interest (beds, portals, workstations and so
+// on) of a chunk, which from 1.14 are kept in region files in the poi
+// folder.
+func (w *BetaWorld) OpenPOI(x, z int) (io.ReadCloser, error) {
+	return w.openRegionChunk("poi", x, z)
+}
 
-	file, openErr := os.Open(path)
-	if openErr != nil {
-		return nil, openErr
+// openRegionChunk opens chunk x,z from the region files in folder. It is
+// safe to call from many goroutines, which share the open region files.
+// When there is no region file by any extension the error is
+// os.ErrNotExist naming the region, and otherwise the first that isn't.
+func (w *BetaWorld) openRegionChunk(folder string, x, z int) (io.ReadCloser, error) {
+	var regionName = fmt.Sprintf("r.%v.%v", x>>5, z>>5)
+	var firstErr error
+	for _, ext := range w.regionExtensions() {
+		var mcaName = regionName + "." + ext
+		var handle, openErr = w.regions.acquire(w.fs, filepath.Join(w.folderDir(folder), mcaName))
+		if openErr == nil {
+			return w.openHandleChunk(handle, folder, mcaName, x, z)
+		}
+		if firstErr == nil && !os.IsNotExist(openErr) {
+			firstErr = openErr
+		}
 	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, &os.PathError{Op: "open", Path: filepath.Join(w.folderDir(folder), regionName+".*"), Err: os.ErrNotExist}
+}
+
+// openHandleChunk opens chunk x,z from an acquired region file, which is
+// released when the chunk is closed, or straight away on errors.
+func (w *BetaWorld) openHandleChunk(handle *regionHandle, folder, mcaName string, x, z int) (io.ReadCloser, error) {
 	defer func() {
-		if file != nil {
-			file.Close()
+		if handle != nil {
+			handle.release()
 		}
 	}()
 
-	var mcr = &McrFile{file}
+	var mcr = &McrFile{handle.file}
 	var loc, readLocErr = mcr.ReadLocation(x, z)
 	if readLocErr != nil {
 		return nil, readLocErr
@@ -57,48 +96,65 @@ func (w *BetaWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
 	if loc == 0 {
 		return nil, errors.New(fmt.Sprintf("Chunk missing: %v,%v in %v. %v", x, z, mcaName, (x&31)+(z&31)*32))
 	}
+	if loc.Offset() < 2*4096 || loc.Sectors() == 0 {
+		return nil, errors.New(fmt.Sprintf("Chunk corrupt: %v,%v in %v has location %#x", x, z, mcaName, uint32(loc)))
+	}
+
+	var chunkHeader [5]byte
+	var _, headerErr = mcr.ReadAt(chunkHeader[:], int64(loc.Offset()))
+	if headerErr != nil {
+		return nil, headerErr
+	}
 
 	var (
-		length          uint32
-		compressionType byte
+		length          = binary.BigEndian.Uint32(chunkHeader[:])
+		compressionType = chunkHeader[4]
 	)
-
-	var _, seekErr = mcr.Seek(int64(loc.Offset()), 0)
-	if seekErr != nil {
-		return nil, seekErr
+	if length == 0 || int(length) > 4096*loc.Sectors() {
+		return nil, errors.New(fmt.Sprintf("Chunk corrupt: %v,%v in %v is %v bytes long in %v sectors", x, z, mcaName, length, loc.Sectors()))
 	}
 
-	var lengthReadErr = binary.Read(mcr, binary.BigEndian, &length)
-	if lengthReadErr != nil {
-		return nil, lengthReadErr
+	var payload io.Reader = io.NewSectionReader(mcr, int64(loc.Offset())+5, int64(length)-1)
+	var external File
+	if compressionType&externalChunkFlag != 0 {
+		var externalErr error
+		external, externalErr = w.fs.Open(filepath.Join(w.folderDir(folder), fmt.Sprintf("c.%v.%v.mcc", x, z)))
+		if externalErr != nil {
+			return nil, externalErr
+		}
+		handle.release()
+		handle = nil
+		payload = external
 	}
 
-	var compressionTypeErr = binary.Read(mcr, binary.BigEndian, &compressionType)
-	if compressionTypeErr != nil {
-		return nil, compressionTypeErr
+	var r, decompressErr = decompressChunk(payload, compressionType)
+	if decompressErr != nil {
+		if external != nil {
+			external.Close()
+		}
+		return nil, decompressErr
 	}
 
-	var r, zlibNewErr = zlib.NewReader(mcr)
-	if zlibNewErr != nil {
-		return nil, zlibNewErr
+	if external != nil {
+		return &ReadCloserPair{r, external}, nil
 	}
+	var reader = &regionChunkReader{r, r, handle}
+	handle = nil
+	return reader, nil
+}
 
-	var pair = &ReadCloserPair{r, file}
-	file = nil
-	return pair, nil
+// Close closes the region files kept open between chunks.
+func (w *BetaWorld) Close() error {
+	return w.regions.Close()
 }
 
 func (r McrFile) ReadLocation(x, z int) (ChunkLocation, error) {
-	var _, seekErr = r.Seek(int64(4*((x&31)+(z&31)*32)), 0)
-	if seekErr != nil {
-		return ChunkLocation(0), seekErr
-	}
-	var location uint32
-	var readErr = binary.Read(r, binary.BigEndian, &location)
+	var b [4]byte
+	var _, readErr = r.ReadAt(b[:], int64(4*((x&31)+(z&31)*32)))
 	if readErr != nil {
 		return ChunkLocation(0), readErr
 	}
-	return ChunkLocation(location), nil
+	return ChunkLocation(binary.BigEndian.Uint32(b[:])), nil
 }
 
 type ChunkLocation uint32
@@ -111,83 +167,181 @@ func (cl ChunkLocation) Sectors() int {
 	return (int(cl) & 0xff)
 }
 
+func (w *BetaWorld) Level() (*nbt.Level, error) {
+	return readJavaLevel(w.fs, w.worldDir)
+}
+
 func (w *BetaWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
-	var regionDirname = filepath.Join(w.worldDir, "region")
-	var dir, dirOpenErr = os.Open(regionDirname)
-	if dirOpenErr != nil {
-		return nil, dirOpenErr
+	var regions, readErr = w.regionFiles()
+	if readErr != nil {
+		return nil, readErr
 	}
-	defer dir.Close()
 
-	var pool = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox()}
+	var pool = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox(), nil}
 
-	for {
-		var filenames, readErr = dir.Readdirnames(1)
-		if readErr == io.EOF || len(filenames) == 0 {
-			break
+	for _, region := range regions {
+		var report = &RegionReport{Filename: region.filename}
+		var mcrErr = w.poolMcrChunks(region.filename, mask, pool, region.x, region.z, report)
+		if mcrErr != nil {
+			report.problem("unreadable: %v", mcrErr)
 		}
-		if readErr != nil {
-			return nil, readErr
+		if len(report.Problems) != 0 {
+			pool.reports = append(pool.reports, report)
 		}
+	}
 
-		var fields = strings.FieldsFunc(filenames[0], func(c rune) bool { return c == '.' })
+	return pool, nil
+}
 
-		if len(fields) == 4 {
-			var (
-				rx, rxErr = strconv.Atoi(fields[1])
-				rz, ryErr = strconv.Atoi(fields[2])
-			)
+type regionFile struct {
+	filename string
+	x, z     int
+}
 
-			if rxErr == nil && ryErr == nil {
-				var regionFilename = filepath.Join(regionDirname, filenames[0])
-				var mcrErr = w.poolMcrChunks(regionFilename, mask, pool, rx, rz)
-				if mcrErr != nil {
-					return nil, mcrErr
-				}
-			}
+// regionExtensions is mca then mcr, as worlds converted to Anvil still
+// have their McRegion files, unless level.dat says the world hasn't been
+// converted. Linear files, from servers that replace Anvil, come last.
+func (w *BetaWorld) regionExtensions() []string {
+	w.formatOnce.Do(func() {
+		w.extensions = []string{"mca", "mcr", "linear"}
+		if level, err := w.Level(); err == nil && level.Version == nbt.McRegionVersion {
+			w.extensions = []string{"mcr", "mca", "linear"}
 		}
+	})
+	return w.extensions
+}
+
+// folderDir is where the region files of folder (region, entities or poi)
+// are kept.
+func (w *BetaWorld) folderDir(folder string) string {
+	if folder == "region" && w.regionDir != "" {
+		return w.regionDir
+	}
+	return filepath.Join(w.dimensionDir, folder)
+}
+
+// parseRegionFilename reads the region position and extension from the
+// name of an r.x.z.mca, r.x.z.mcr or r.x.z.linear file.
+func parseRegionFilename(filename string) (rx, rz int, ext string, ok bool) {
+	var fields = strings.Split(filename, ".")
+	if len(fields) != 4 || fields[0] != "r" || (fields[3] != "mca" && fields[3] != "mcr" && fields[3] != "linear") {
+		return 0, 0, "", false
 	}
 
-	return pool, nil
+	var rxErr, rzErr error
+	rx, rxErr = strconv.Atoi(fields[1])
+	rz, rzErr = strconv.Atoi(fields[2])
+	return rx, rz, fields[3], rxErr == nil && rzErr == nil
 }
 
-func (w *BetaWorld) poolMcrChunks(regionFilename string, mask ChunkMask, pool *BetaChunkPool, rx, rz int) error {
-	var region, regionOpenErr = os.Open(regionFilename)
-	if regionOpenErr != nil {
-		return regionOpenErr
+// regionFiles lists the region files of the dimension in each format,
+// leaving out the ones OpenChunk doesn't read when there are both.
+func (w *BetaWorld) regionFiles() ([]regionFile, error) {
+	var regionDirname = w.folderDir("region")
+	var filenames, readErr = w.fs.ReadDirNames(regionDirname)
+	if readErr != nil {
+		return nil, readErr
 	}
-	defer region.Close()
 
-	for cz := 0; cz < 32; cz++ {
-		for cx := 0; cx < 32; cx++ {
-			var location uint32
-			var readErr = binary.Read(region, binary.BigEndian, &location)
-			if readErr == io.EOF {
-				continue
-			}
-			if readErr != nil {
-				return readErr
-			}
-			if location != 0 {
-				var (
-					x = rx*32 + cx
-					z = rz*32 + cz
-				)
-
-				if !mask.IsMasked(x, z) {
-					pool.chunkMap[betaChunkPoolKey(x, z)] = true
-					pool.box.Union(x, z)
-				}
+	var (
+		regions []regionFile
+		seen    = make(map[uint64]int)
+	)
+	for _, filename := range filenames {
+		var rx, rz, ext, ok = parseRegionFilename(filename)
+		if !ok {
+			continue
+		}
+
+		var region = regionFile{filepath.Join(regionDirname, filename), rx, rz}
+		var key = betaChunkPoolKey(rx, rz)
+		if i, ok := seen[key]; ok {
+			if ext == w.regionExtensions()[0] {
+				regions[i] = region
 			}
+			continue
+		}
+		seen[key] = len(regions)
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// poolMcrChunks adds the chunks of a region file to the pool.
+func (w *BetaWorld) poolMcrChunks(regionFilename string, mask ChunkMask, pool *BetaChunkPool, rx, rz int, report *RegionReport) error {
+	return w.eachMcrChunk(regionFilename, mask, rx, rz, report, func(x, z int) {
+		pool.chunkMap[betaChunkPoolKey(x, z)] = true
+		pool.box.Union(x, z)
+	})
+}
+
+// eachMcrChunk calls fn with each unmasked chunk of a region file, leaving
+// out any that the header doesn't place sensibly in the file.
+func (w *BetaWorld) eachMcrChunk(regionFilename string, mask ChunkMask, rx, rz int, report *RegionReport, fn func(x, z int)) error {
+	var entry, entryErr = w.regionEntry(regionFilename, rx, rz, report)
+	if entryErr != nil {
+		return entryErr
+	}
+
+	var modifiedMask, hasModified = mask.(ModifiedChunkMask)
+
+	for i, slot := range entry.Slots {
+		var (
+			x = rx*32 + int(slot)%32
+			z = rz*32 + int(slot)/32
+		)
+
+		var masked bool
+		if hasModified {
+			var modified = time.Unix(int64(entry.Modified[i]), 0)
+			masked = modifiedMask.IsMaskedModified(x, z, modified)
+		} else {
+			masked = mask.IsMasked(x, z)
+		}
+
+		if !masked {
+			fn(x, z)
 		}
 	}
 
 	return nil
 }
 
+// regionEntry finds the chunks of a region file in the region index, or
+// by reading its header when there's no index or the file has changed.
+func (w *BetaWorld) regionEntry(regionFilename string, rx, rz int, report *RegionReport) (*regionIndexEntry, error) {
+	if w.index != nil {
+		if info, err := w.fs.Stat(regionFilename); err == nil {
+			if entry := w.index.lookup(regionFilename, info); entry != nil {
+				report.Problems = append(report.Problems, entry.Problems...)
+				return entry, nil
+			}
+		}
+	}
+
+	var handle, regionOpenErr = w.regions.acquire(w.fs, regionFilename)
+	if regionOpenErr != nil {
+		return nil, regionOpenErr
+	}
+	defer handle.release()
+
+	var entry, err = readRegionEntry(handle.file, rx, rz, report)
+	if err == nil && w.index != nil {
+		w.index.store(regionFilename, entry)
+	}
+	return entry, err
+}
+
+// SetRegionIndex keeps which chunks each region file holds in index, for
+// pooling chunks again without reading the region headers.
+func (w *BetaWorld) SetRegionIndex(index *RegionIndex) {
+	w.index = index
+}
+
 type BetaChunkPool struct {
 	chunkMap map[uint64]bool
 	box      *BoundingBox
+	reports  []*RegionReport
 }
 
 func (p *BetaChunkPool) Pop(x, z int) bool {
@@ -205,6 +359,10 @@ func (p *BetaChunkPool) BoundingBox() *BoundingBox {
 	return p.box
 }
 
+func (p *BetaChunkPool) Reports() []*RegionReport {
+	return p.reports
+}
+
 func betaChunkPoolKey(x, z int) uint64 {
 	return uint64(x)<<32 + uint64(z)
 }
@@ -0,0 +1,274 @@
+package mcworld
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBetaChunkPoolModifiedSince(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "betaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var saved = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	var region = testRegion(31, 2)
+	binary.BigEndian.PutUint32(region[4096+4*(31+2*32):], uint32(saved.Unix()))
+
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mca"), region, 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	for _, test := range []struct {
+		since     time.Time
+		remaining int
+	}{
+		{saved.Add(-time.Hour), 1},
+		{saved, 1},
+		{saved.Add(time.Second), 0},
+	} {
+		var pool, err = world.ChunkPool(&ModifiedSinceMask{&AllChunksMask{}, test.since})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pool.Remaining() != test.remaining {
+			t.Errorf("%d chunks modified since %v, not %d", pool.Remaining(), test.since, test.remaining)
+		}
+	}
+}
+
+func TestBetaChunkPoolCorruptHeader(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "betaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Chunk 31,2 is fine; 0,0 starts in the header, 1,0 runs off the end,
+	// and 2,0 and 3,0 share a sector.
+	var region = append(testRegion(31, 2), make([]byte, 2*4096)...)
+	binary.BigEndian.PutUint32(region[0:], 1<<8|1)
+	binary.BigEndian.PutUint32(region[4:], 4<<8|2)
+	binary.BigEndian.PutUint32(region[8:], 3<<8|2)
+	binary.BigEndian.PutUint32(region[12:], 4<<8|1)
+
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mca"), region, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.0.0.mca"), make([]byte, 100), 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	var pool, poolErr = world.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Errorf("%d chunks pooled, not just -1,2", pool.Remaining())
+	}
+
+	var reports = pool.(RegionReporter).Reports()
+	if len(reports) != 2 {
+		t.Fatalf("%d region reports, not 2: %v", len(reports), reports)
+	}
+	if len(reports[0].Problems) != 3 {
+		t.Errorf("problems %q, not 3", reports[0].Problems)
+	}
+	if len(reports[1].Problems) != 1 {
+		t.Errorf("problems %q, not 1", reports[1].Problems)
+	}
+}
+
+func TestBetaWorldConcurrentOpenChunk(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "betaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// More regions than are kept open, each holding a copy of chunk -1,2
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	for rx := 0; rx < regionCacheSize+4; rx++ {
+		ioutil.WriteFile(filepath.Join(dir, "region", fmt.Sprintf("r.%d.0.mca", rx)), testRegion(31, 2), 0644)
+	}
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	defer world.Close()
+
+	var wg sync.WaitGroup
+	var errs = make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				var rx = (g + i) % (regionCacheSize + 4)
+				var r, err = world.OpenChunk(rx*32+31, 2)
+				if err != nil {
+					errs <- err
+					return
+				}
+				var chunk, nbtErr = nbt.ReadChunkNbt(r)
+				r.Close()
+				if nbtErr != nil {
+					errs <- nbtErr
+					return
+				}
+				if chunk.XPos != -1 || chunk.ZPos != 2 {
+					errs <- fmt.Errorf("chunk %d,%d read from region %d", chunk.XPos, chunk.ZPos, rx)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if len(world.regions.handles) > regionCacheSize {
+		t.Errorf("%d region files open, more than %d", len(world.regions.handles), regionCacheSize)
+	}
+}
+
+func TestBetaChunkIterator(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "betaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The .mcr left behind by the conversion to Anvil is ignored
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mca"), testRegion(31, 2), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mcr"), testRegion(30, 2), 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	var chunks, iterErr = world.ChunkIterator(&AllChunksMask{})
+	if iterErr != nil {
+		t.Fatal(iterErr)
+	}
+	if chunks.Remaining() != 1 {
+		t.Errorf("estimated %d chunks, not the 1 sector after the header", chunks.Remaining())
+	}
+	if box := chunks.BoundingBox(); *box != (BoundingBox{-32, 0, -1, 31}) {
+		t.Errorf("bounding box %v isn't region -1,0", *box)
+	}
+
+	var x, z, ok = chunks.Next()
+	if !ok || x != -1 || z != 2 {
+		t.Errorf("first chunk %d,%d,%v not -1,2", x, z, ok)
+	}
+	if _, _, ok = chunks.Next(); ok || chunks.Remaining() != 0 {
+		t.Errorf("more than one chunk, %d remaining", chunks.Remaining())
+	}
+}
+
+func TestBetaWorldMcRegionLevel(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "betaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// level.dat says the world is still McRegion, so the .mcr is read
+	// rather than the .mca
+	var level = new(chunkNbtWriter)
+	level.beginStruct("")
+	level.beginStruct("Data")
+	level.int32("SpawnX", 0)
+	level.int32("SpawnY", 64)
+	level.int32("SpawnZ", 0)
+	level.int32("version", nbt.McRegionVersion)
+	level.endStruct()
+	level.endStruct()
+	var file, _ = os.Create(filepath.Join(dir, "level.dat"))
+	var gz = gzip.NewWriter(file)
+	gz.Write(level.Bytes())
+	gz.Close()
+	file.Close()
+
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mca"), testRegion(31, 2), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mcr"), testRegion(30, 2), 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	var pool, poolErr = world.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-2, 2) {
+		t.Errorf("%d chunks pooled, not just -2,2 from the .mcr", pool.Remaining())
+	}
+	if r, err := world.OpenChunk(-2, 2); err != nil {
+		t.Error(err)
+	} else {
+		r.Close()
+	}
+}
+
+func TestLooseRegionFiles(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "betaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "r.-1.0.mca"), testRegion(31, 2), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "c.-1.2.mcc"), []byte("not a region"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "r.backup.txt"), []byte("not a region"), 0644)
+
+	var world, openErr = OpenWorld(dir)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	var pool, poolErr = world.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Errorf("%d chunks pooled, not just -1,2", pool.Remaining())
+	}
+	if reports := pool.(RegionReporter).Reports(); len(reports) != 0 {
+		t.Errorf("region reports %v", reports)
+	}
+
+	var r, chunkErr = world.OpenChunk(-1, 2)
+	if chunkErr != nil {
+		t.Fatal(chunkErr)
+	}
+	r.Close()
+	world.(*BetaWorld).Close()
+}
+
+func TestBetaWorldMissingRegion(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "betaworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.1.0.linear"), []byte("not a region"), 0644)
+
+	// No region file by any extension names the region, not the last
+	// extension tried
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	if _, err := world.OpenChunk(5*32, 5*32); !os.IsNotExist(err) || !strings.Contains(err.Error(), "r.5.5") || strings.Contains(err.Error(), ".linear") {
+		t.Errorf("missing region: %v", err)
+	}
+
+	// A region file that can't be read is the error, not those missing
+	if _, err := world.OpenChunk(32, 0); err == nil || os.IsNotExist(err) || !strings.Contains(err.Error(), "r.1.0.linear") {
+		t.Errorf("unreadable region: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package mcworld
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// blockCache keeps the most recently read blocks of remote files, so that
+// reading a chunk (a few small reads of the region header, then the
+// chunk's sectors) only costs a request or two.
+type blockCache struct {
+	fetch func(name string, offset int64, size int) ([]byte, error)
+
+	mutex  sync.Mutex
+	blocks map[blockKey][]byte
+	order  []blockKey
+}
+
+const (
+	remoteBlockSize  = 64 * 1024
+	remoteBlockCount = 256
+)
+
+type blockKey struct {
+	name  string
+	index int64
+}
+
+func newBlockCache(fetch func(name string, offset int64, size int) ([]byte, error)) *blockCache {
+	return &blockCache{fetch: fetch, blocks: make(map[blockKey][]byte)}
+}
+
+func (c *blockCache) readBlock(name string, index int64) ([]byte, error) {
+	var key = blockKey{name, index}
+
+	c.mutex.Lock()
+	var block, cached = c.blocks[key]
+	c.mutex.Unlock()
+	if cached {
+		return block, nil
+	}
+
+	var err error
+	block, err = c.fetch(name, index*remoteBlockSize, remoteBlockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, exists := c.blocks[key]; !exists {
+		c.blocks[key] = block
+		c.order = append(c.order, key)
+		if len(c.order) > remoteBlockCount {
+			delete(c.blocks, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	return block, nil
+}
+
+// getRange makes a request for size bytes from offset.
+func getRange(client *http.Client, request *http.Request, name string, offset int64, size int) ([]byte, error) {
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+int64(size)-1))
+
+	var resp, err = client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return ioutil.ReadAll(resp.Body)
+	case http.StatusOK:
+		// The server ignored the range and sent the whole file
+		var all, readErr = ioutil.ReadAll(resp.Body)
+		if int64(len(all)) < offset {
+			return nil, io.EOF
+		}
+		var end = offset + int64(size)
+		if end > int64(len(all)) {
+			end = int64(len(all))
+		}
+		return all[offset:end], readErr
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, io.EOF
+	}
+	return nil, &os.PathError{Op: "read", Path: name, Err: errors.New(resp.Status)}
+}
+
+// blockFile is a remote File read through a blockCache.
+type blockFile struct {
+	blocks   *blockCache
+	name     string
+	info     os.FileInfo
+	position int64
+}
+
+func (f *blockFile) ReadAt(p []byte, off int64) (int, error) {
+	var n int
+	for n < len(p) {
+		var at = off + int64(n)
+		if at >= f.info.Size() {
+			return n, io.EOF
+		}
+
+		var block, err = f.blocks.readBlock(f.name, at/remoteBlockSize)
+		if err != nil {
+			return n, err
+		}
+		var within = at % remoteBlockSize
+		if within >= int64(len(block)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], block[within:])
+	}
+	return n, nil
+}
+
+func (f *blockFile) Read(p []byte) (int, error) {
+	if f.position >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if remaining := f.info.Size() - f.position; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	var n, err = f.ReadAt(p, f.position)
+	f.position += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *blockFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+		f.position = offset
+	case 1:
+		f.position += offset
+	case 2:
+		f.position = f.info.Size() + offset
+	}
+	if f.position < 0 {
+		f.position = 0
+		return 0, errors.New("Seek before the start of the file")
+	}
+	return f.position, nil
+}
+
+func (f *blockFile) Close() error {
+	return nil
+}
+
+func (f *blockFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+type remoteFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi *remoteFileInfo) Name() string       { return fi.name }
+func (fi *remoteFileInfo) Size() int64        { return fi.size }
+func (fi *remoteFileInfo) Mode() os.FileMode  { return 0444 }
+func (fi *remoteFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi *remoteFileInfo) IsDir() bool        { return false }
+func (fi *remoteFileInfo) Sys() interface{}   { return nil }
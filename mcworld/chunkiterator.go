@@ -0,0 +1,207 @@
+package mcworld
+
+// ChunkIterator yields the chunks of a world one at a time, without
+// holding every chunk in memory the way a ChunkPool does.
+type ChunkIterator interface {
+	// Next returns the next chunk, or false once there are no more.
+	Next() (x, z int, ok bool)
+	// Remaining is an estimate until the last region has been read.
+	Remaining() int
+	// BoundingBox covers every chunk, but may be larger than needed.
+	BoundingBox() *BoundingBox
+}
+
+// ChunkStreamer is implemented by worlds that can iterate over their
+// chunks lazily.
+type ChunkStreamer interface {
+	ChunkIterator(mask ChunkMask) (ChunkIterator, error)
+}
+
+// IterateChunks iterates over the unmasked chunks of a world, lazily if
+// the world supports it, and otherwise from a ChunkPool.
+func IterateChunks(world World, mask ChunkMask) (ChunkIterator, error) {
+	if streamer, ok := world.(ChunkStreamer); ok {
+		return streamer.ChunkIterator(mask)
+	}
+
+	var pool, err = world.ChunkPool(mask)
+	if err != nil {
+		return nil, err
+	}
+	var box = pool.BoundingBox()
+	return &poolIterator{pool: pool, x: box.X0, z: box.Z0}, nil
+}
+
+// poolIterator pops the chunks of a pool in rows across its bounding box.
+type poolIterator struct {
+	pool ChunkPool
+	x, z int
+}
+
+func (it *poolIterator) Next() (int, int, bool) {
+	var box = it.pool.BoundingBox()
+	for it.pool.Remaining() > 0 && it.x <= box.X1 {
+		var x, z = it.x, it.z
+		if it.z++; it.z > box.Z1 {
+			it.x, it.z = it.x+1, box.Z0
+		}
+		if it.pool.Pop(x, z) {
+			return x, z, true
+		}
+	}
+	return 0, 0, false
+}
+
+func (it *poolIterator) Remaining() int {
+	return it.pool.Remaining()
+}
+
+func (it *poolIterator) BoundingBox() *BoundingBox {
+	return it.pool.BoundingBox()
+}
+
+// betaChunkIterator reads the header of one region file at a time. Until
+// a region is read it is counted as having a chunk in every sector past
+// the header, up to the 1024 chunks a region can hold.
+type betaChunkIterator struct {
+	world   *BetaWorld
+	mask    ChunkMask
+	regions []regionFile
+	box     *BoundingBox
+
+	chunks    [][2]int // Coordinates from the current region
+	estimates []int    // Estimated chunk counts of the regions not yet read
+	remaining int
+	reports   []*RegionReport
+}
+
+func (w *BetaWorld) ChunkIterator(mask ChunkMask) (ChunkIterator, error) {
+	var regions, err = w.regionFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var it = &betaChunkIterator{world: w, mask: mask, regions: regions, box: EmptyBoundingBox()}
+	for _, region := range regions {
+		var estimate = 32 * 32
+		if info, err := w.fs.Stat(region.filename); err == nil {
+			if sectors := int(info.Size()/4096) - 2; sectors < estimate {
+				estimate = sectors
+			}
+			if estimate < 0 {
+				estimate = 0
+			}
+		}
+		it.estimates = append(it.estimates, estimate)
+		it.remaining += estimate
+
+		it.box.Union(region.x*32, region.z*32)
+		it.box.Union(region.x*32+31, region.z*32+31)
+	}
+	return it, nil
+}
+
+func (it *betaChunkIterator) Next() (int, int, bool) {
+	for len(it.chunks) == 0 {
+		if len(it.regions) == 0 {
+			return 0, 0, false
+		}
+
+		var region = it.regions[0]
+		it.remaining -= it.estimates[0]
+		it.regions, it.estimates = it.regions[1:], it.estimates[1:]
+
+		var report = &RegionReport{Filename: region.filename}
+		var err = it.world.eachMcrChunk(region.filename, it.mask, region.x, region.z, report, func(x, z int) {
+			it.chunks = append(it.chunks, [2]int{x, z})
+		})
+		if err != nil {
+			report.problem("unreadable: %v", err)
+		}
+		if len(report.Problems) != 0 {
+			it.reports = append(it.reports, report)
+		}
+		it.remaining += len(it.chunks)
+	}
+
+	var chunk = it.chunks[0]
+	it.chunks = it.chunks[1:]
+	it.remaining--
+	return chunk[0], chunk[1], true
+}
+
+func (it *betaChunkIterator) Remaining() int {
+	return it.remaining
+}
+
+func (it *betaChunkIterator) BoundingBox() *BoundingBox {
+	return it.box
+}
+
+func (it *betaChunkIterator) Reports() []*RegionReport {
+	return it.reports
+}
+
+// SpiralChunks pops the chunks of a pool in a spiral out from cx,cz, so
+// the chunks nearest the center come first.
+func SpiralChunks(pool ChunkPool, cx, cz int) ChunkIterator {
+	return &spiralIterator{pool: pool, cx: cx, cz: cz}
+}
+
+// spiralIterator walks square rings around the center, each ring r
+// starting on its east side and going anticlockwise.
+type spiralIterator struct {
+	pool   ChunkPool
+	cx, cz int
+	ring   int
+	step   int // Position around the ring, of 8*ring
+}
+
+func (it *spiralIterator) Next() (int, int, bool) {
+	var box = it.pool.BoundingBox()
+	var maxRing = maxInt(maxInt(it.cx-box.X0, box.X1-it.cx), maxInt(it.cz-box.Z0, box.Z1-it.cz))
+
+	for it.pool.Remaining() > 0 && it.ring <= maxRing {
+		var x, z = it.position()
+		if it.step++; it.step >= 8*it.ring {
+			it.ring, it.step = it.ring+1, 0
+		}
+		if it.pool.Pop(x, z) {
+			return x, z, true
+		}
+	}
+	return 0, 0, false
+}
+
+func (it *spiralIterator) position() (int, int) {
+	var r, k = it.ring, it.step
+	if r == 0 {
+		return it.cx, it.cz
+	}
+
+	var side, along = k / (2 * r), k % (2 * r)
+	switch side {
+	case 0:
+		return it.cx + r, it.cz - r + 1 + along
+	case 1:
+		return it.cx + r - 1 - along, it.cz + r
+	case 2:
+		return it.cx - r, it.cz + r - 1 - along
+	}
+	return it.cx - r + 1 + along, it.cz - r
+}
+
+func (it *spiralIterator) Remaining() int {
+	return it.pool.Remaining()
+}
+
+func (it *spiralIterator) BoundingBox() *BoundingBox {
+	return it.pool.BoundingBox()
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
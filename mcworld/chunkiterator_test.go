@@ -0,0 +1,61 @@
+package mcworld
+
+import (
+	"testing"
+)
+
+func TestSpiralChunks(t *testing.T) {
+	var pool = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox(), nil}
+	for x := -3; x <= 5; x++ {
+		for z := -2; z <= 1; z++ {
+			pool.chunkMap[betaChunkPoolKey(x, z)] = true
+			pool.box.Union(x, z)
+		}
+	}
+	var total = pool.Remaining()
+
+	var (
+		chunks = SpiralChunks(pool, 1, 0)
+		seen   = make(map[[2]int]bool)
+		ring   = 0
+	)
+	for x, z, ok := chunks.Next(); ok; x, z, ok = chunks.Next() {
+		var r = maxInt(abs(x-1), abs(z))
+		if r < ring {
+			t.Errorf("chunk %d,%d of ring %d after ring %d", x, z, r, ring)
+		}
+		ring = r
+		if seen[[2]int{x, z}] {
+			t.Errorf("chunk %d,%d twice", x, z)
+		}
+		seen[[2]int{x, z}] = true
+	}
+
+	if len(seen) != total || chunks.Remaining() != 0 {
+		t.Errorf("%d of %d chunks, %d remaining", len(seen), total, chunks.Remaining())
+	}
+}
+
+func TestPoolIterator(t *testing.T) {
+	var world = NewClassicWorld(osFileSystem{}, "")
+	world.level = &flatLevel{width: 40, length: 20}
+
+	var chunks, err = IterateChunks(world, &AllChunksMask{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	for _, _, ok := chunks.Next(); ok; _, _, ok = chunks.Next() {
+		count++
+	}
+	if count != 3*2 {
+		t.Errorf("%d chunks, not 6", count)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
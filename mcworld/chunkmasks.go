@@ -1,9 +1,22 @@
 package mcworld
 
+import (
+	"github.com/quag/mcobj/nbt"
+	"time"
+)
+
 type ChunkMask interface {
 	IsMasked(x, z int) bool
 }
 
+// ModifiedChunkMask is a ChunkMask that can also mask chunks by when they
+// were last saved. Worlds that record it call IsMaskedModified when pooling
+// chunks, rather than IsMasked.
+type ModifiedChunkMask interface {
+	ChunkMask
+	IsMaskedModified(x, z int, modified time.Time) bool
+}
+
 type RectangleChunkMask struct {
 	X0, Z0, X1, Z1 int
 }
@@ -17,3 +30,93 @@ type AllChunksMask struct{}
 func (m *AllChunksMask) IsMasked(x, z int) bool {
 	return false
 }
+
+// GeneratedChunkMask masks the proto-chunks that the world generator hasn't
+// finished, as well as everything Mask masks. Each chunk is read once to
+// find its status.
+type GeneratedChunkMask struct {
+	Mask      ChunkMask
+	Opener    ChunkOpener
+	generated map[uint64]bool
+}
+
+func (m *GeneratedChunkMask) IsMasked(x, z int) bool {
+	if m.Mask.IsMasked(x, z) {
+		return true
+	}
+
+	if m.generated == nil {
+		m.generated = make(map[uint64]bool)
+	}
+	return !readChunkCondition(m.generated, m.Opener, x, z, (*nbt.Chunk).IsFullyGenerated)
+}
+
+// InhabitedChunkMask masks the chunks that players have spent less than
+// MinTicks near, as well as everything Mask masks, so that explored but
+// untouched terrain is left out. Each chunk is read once to find its
+// InhabitedTime.
+type InhabitedChunkMask struct {
+	Mask      ChunkMask
+	Opener    ChunkOpener
+	MinTicks  int
+	inhabited map[uint64]bool
+}
+
+func (m *InhabitedChunkMask) IsMasked(x, z int) bool {
+	if m.Mask.IsMasked(x, z) {
+		return true
+	}
+
+	if m.inhabited == nil {
+		m.inhabited = make(map[uint64]bool)
+	}
+	return !readChunkCondition(m.inhabited, m.Opener, x, z, func(chunk *nbt.Chunk) bool {
+		return chunk.Inhabited >= m.MinTicks
+	})
+}
+
+// readChunkCondition reads a chunk to test it, remembering the result.
+// Chunks that can't be read pass, and are left for the export to report.
+func readChunkCondition(results map[uint64]bool, opener ChunkOpener, x, z int, condition func(*nbt.Chunk) bool) bool {
+	var key = betaChunkPoolKey(x, z)
+	if result, ok := results[key]; ok {
+		return result
+	}
+
+	var result = true
+	if r, err := opener.OpenChunk(x, z); err == nil {
+		if chunk, err := nbt.ReadChunkNbt(r); err == nil {
+			result = condition(chunk)
+		}
+		r.Close()
+	}
+	results[key] = result
+	return result
+}
+
+// ModifiedSinceMask masks the chunks saved before Since, as well as
+// everything Mask masks, so that only the chunks changed since an earlier
+// export are pooled. Unchanged chunks are still unmasked as neighbours.
+type ModifiedSinceMask struct {
+	Mask  ChunkMask
+	Since time.Time
+}
+
+func (m *ModifiedSinceMask) IsMasked(x, z int) bool {
+	return m.Mask.IsMasked(x, z)
+}
+
+func (m *ModifiedSinceMask) IsMaskedModified(x, z int, modified time.Time) bool {
+	return modified.Before(m.Since) || m.Mask.IsMasked(x, z)
+}
+
+// CircleChunkMask masks the chunks whose centers are further than Radius
+// chunks from the center of chunk X,Z.
+type CircleChunkMask struct {
+	X, Z, Radius int
+}
+
+func (m *CircleChunkMask) IsMasked(x, z int) bool {
+	var dx, dz = x - m.X, z - m.Z
+	return dx*dx+dz*dz > m.Radius*m.Radius
+}
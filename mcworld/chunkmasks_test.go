@@ -0,0 +1,60 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/quag/mcobj/nbt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// inhabitedOpener opens chunks with an InhabitedTime of x*1000 ticks, and
+// fails to open chunks with a negative x.
+type inhabitedOpener struct {
+	opened int
+}
+
+func (o *inhabitedOpener) OpenChunk(x, z int) (io.ReadCloser, error) {
+	if x < 0 {
+		return nil, errors.New("no chunk")
+	}
+	o.opened++
+
+	var w chunkNbtWriter
+	w.beginStruct("")
+	w.beginStruct("Level")
+	w.int32("xPos", x)
+	w.int32("zPos", z)
+	w.tag(nbt.TagInt64, "InhabitedTime")
+	binary.Write(&w, binary.BigEndian, int64(x*1000))
+	w.endStruct()
+	w.endStruct()
+	return ioutil.NopCloser(bytes.NewReader(w.Bytes())), nil
+}
+
+func TestInhabitedChunkMask(t *testing.T) {
+	var opener = &inhabitedOpener{}
+	var mask = &InhabitedChunkMask{Mask: &RectangleChunkMask{-10, 0, 10, 1}, Opener: opener, MinTicks: 3000}
+
+	for _, test := range []struct {
+		x, z   int
+		masked bool
+	}{
+		{0, 0, true},
+		{2, 0, true},
+		{3, 0, false},
+		{9, 0, false},
+		{-1, 0, false},
+		{3, 1, true},
+		{3, 0, false},
+	} {
+		if mask.IsMasked(test.x, test.z) != test.masked {
+			t.Errorf("chunk %d,%d masked is %v", test.x, test.z, !test.masked)
+		}
+	}
+	if opener.opened != 4 {
+		t.Errorf("%d chunks opened not 4", opener.opened)
+	}
+}
@@ -0,0 +1,150 @@
+package mcworld
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ClassicWorld reads a Classic level (.mine or level.dat). There are three
+// generations of these files, all gzipped:
+//
+//   - The earliest are just the 256x256x64 blocks.
+//   - Version 1 starts with a magic number and version byte, then the name,
+//     creator and creation time, three shorts of dimensions and the blocks.
+//   - Version 2 follows the magic number with a Java serialized Level
+//     object.
+//
+// Classic calls the vertical axis depth, and the z axis height.
+type ClassicWorld struct {
+	flatWorld
+}
+
+const (
+	classicMagic      = 0x271bb788
+	classicRawWidth   = 256
+	classicRawLength  = 256
+	classicRawHeight  = 64
+	classicLevelClass = "com.mojang.minecraft.level.Level"
+)
+
+func NewClassicWorld(fs FileSystem, path string) *ClassicWorld {
+	return &ClassicWorld{flatWorld{fs: fs, path: path, read: readClassicLevel}}
+}
+
+func readClassicLevel(fs FileSystem, path string) (*flatLevel, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var gz, gzipErr = gzip.NewReader(file)
+	if gzipErr != nil {
+		return nil, gzipErr
+	}
+	defer gz.Close()
+
+	var r = bufio.NewReader(gz)
+	var magic, peekErr = r.Peek(4)
+	if peekErr != nil {
+		return nil, peekErr
+	}
+
+	var level *flatLevel
+	var err error
+	if binary.BigEndian.Uint32(magic) != classicMagic {
+		level, err = readClassicRawLevel(r)
+	} else {
+		r.Discard(4)
+
+		var version, versionErr = r.ReadByte()
+		if versionErr != nil {
+			return nil, versionErr
+		}
+		switch version {
+		case 1:
+			level, err = readClassicV1Level(r)
+		case 2:
+			level, err = readClassicV2Level(r)
+		default:
+			return nil, errors.New(fmt.Sprintf("Classic level version %d not supported", version))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if level.spawnX == 0 && level.spawnZ == 0 {
+		level.spawnX, level.spawnY, level.spawnZ = level.width/2, level.height, level.length/2
+	}
+	return level, level.check()
+}
+
+func readClassicRawLevel(r io.Reader) (*flatLevel, error) {
+	var blocks, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &flatLevel{width: classicRawWidth, length: classicRawLength, height: classicRawHeight, blocks: blocks}, nil
+}
+
+func readClassicV1Level(r io.Reader) (*flatLevel, error) {
+	var skipUtf = func() error {
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return err
+		}
+		_, err := io.CopyN(ioutil.Discard, r, int64(length))
+		return err
+	}
+
+	// Name and creator
+	for i := 0; i < 2; i++ {
+		if err := skipUtf(); err != nil {
+			return nil, err
+		}
+	}
+
+	var header struct {
+		CreateTime            int64
+		Width, Length, Height int16
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+
+	var level = &flatLevel{width: int(header.Width), length: int(header.Length), height: int(header.Height)}
+	if level.width <= 0 || level.length <= 0 || level.height <= 0 {
+		return nil, errors.New("Bad Classic level dimensions")
+	}
+	level.blocks = make([]byte, level.width*level.length*level.height)
+	if _, err := io.ReadFull(r, level.blocks); err != nil {
+		return nil, err
+	}
+	return level, nil
+}
+
+func readClassicV2Level(r io.Reader) (*flatLevel, error) {
+	var object, err = readJavaObject(r)
+	if err != nil {
+		return nil, err
+	}
+	if object.className != classicLevelClass {
+		return nil, errors.New(fmt.Sprintf("Classic level holds a %s not a %s", object.className, classicLevelClass))
+	}
+
+	var level = new(flatLevel)
+	level.width, _ = object.fields["width"].(int)
+	level.length, _ = object.fields["height"].(int)
+	level.height, _ = object.fields["depth"].(int)
+	level.blocks, _ = object.fields["blocks"].([]byte)
+	level.spawnX, _ = object.fields["xSpawn"].(int)
+	level.spawnY, _ = object.fields["ySpawn"].(int)
+	level.spawnZ, _ = object.fields["zSpawn"].(int)
+	return level, nil
+}
@@ -0,0 +1,266 @@
+package mcworld
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	compressionGzip         = 1
+	compressionZlib         = 2
+	compressionUncompressed = 3
+	compressionLz4          = 4
+)
+
+// Decompressors and their read buffers are kept for the next chunk once a
+// chunk is closed, as an export opens tens of thousands of chunks and each
+// new zlib reader allocates tens of kilobytes.
+var (
+	bufferPool = sync.Pool{New: func() interface{} { return bufio.NewReaderSize(nil, 4096) }}
+	gzipPool   sync.Pool
+	zlibPool   sync.Pool
+)
+
+// decompressChunk wraps a region file chunk's payload in a decompressor for
+// the compression type from the chunk's header. The decompressor is reused
+// once it's closed.
+func decompressChunk(r io.Reader, compressionType byte) (io.ReadCloser, error) {
+	switch compressionType &^ externalChunkFlag {
+	case compressionGzip:
+		var buffered = bufferPool.Get().(*bufio.Reader)
+		buffered.Reset(r)
+
+		var gz, pooled = gzipPool.Get().(*gzip.Reader)
+		var err error
+		if !pooled {
+			gz, err = gzip.NewReader(buffered)
+		} else if err = gz.Reset(buffered); err != nil {
+			gzipPool.Put(gz)
+		}
+		if err != nil {
+			putBuffer(buffered)
+			return nil, err
+		}
+		return &pooledDecompressor{gz, buffered, &gzipPool}, nil
+	case compressionZlib:
+		var buffered = bufferPool.Get().(*bufio.Reader)
+		buffered.Reset(r)
+
+		var zr, pooled = zlibPool.Get().(io.ReadCloser)
+		var err error
+		if !pooled {
+			zr, err = zlib.NewReader(buffered)
+		} else if err = zr.(zlib.Resetter).Reset(buffered, nil); err != nil {
+			zlibPool.Put(zr)
+		}
+		if err != nil {
+			putBuffer(buffered)
+			return nil, err
+		}
+		return &pooledDecompressor{zr, buffered, &zlibPool}, nil
+	case compressionUncompressed:
+		return ioutil.NopCloser(r), nil
+	case compressionLz4:
+		return ioutil.NopCloser(&lz4BlockReader{r: r}), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown chunk compression type %d", compressionType))
+}
+
+// pooledDecompressor puts its decompressor and buffer back in their pools
+// when it's closed.
+type pooledDecompressor struct {
+	io.ReadCloser
+	buffered *bufio.Reader
+	pool     *sync.Pool
+}
+
+func (d *pooledDecompressor) Close() error {
+	if d.ReadCloser == nil {
+		return nil
+	}
+	var err = d.ReadCloser.Close()
+	d.pool.Put(d.ReadCloser)
+	putBuffer(d.buffered)
+	d.ReadCloser, d.buffered = nil, nil
+	return err
+}
+
+func (d *pooledDecompressor) Read(p []byte) (int, error) {
+	if d.ReadCloser == nil {
+		return 0, errors.New("Read from a closed chunk")
+	}
+	return d.ReadCloser.Read(p)
+}
+
+// putBuffer drops the buffer's reader, so it isn't kept alive by the pool.
+func putBuffer(buffered *bufio.Reader) {
+	buffered.Reset(nil)
+	bufferPool.Put(buffered)
+}
+
+var lz4BlockMagic = []byte("LZ4Block")
+
+const (
+	lz4BlockRaw        = 0x10
+	lz4BlockCompressed = 0x20
+)
+
+// lz4BlockReader reads the LZ4 stream format written by lz4-java's
+// LZ4BlockOutputStream, which newer servers use for chunks. Each block
+// has a header of the magic, a method byte, the compressed and
+// decompressed lengths and a checksum (which is not checked), and the
+// stream ends with an empty block.
+type lz4BlockReader struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+func (z *lz4BlockReader) Read(p []byte) (int, error) {
+	for len(z.buf) == 0 {
+		if z.eof {
+			return 0, io.EOF
+		}
+		if err := z.readBlock(); err != nil {
+			return 0, err
+		}
+	}
+
+	var n = copy(p, z.buf)
+	z.buf = z.buf[n:]
+	return n, nil
+}
+
+func (z *lz4BlockReader) readBlock() error {
+	var header [21]byte
+	if _, err := io.ReadFull(z.r, header[:]); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:8], lz4BlockMagic) {
+		return errors.New("LZ4 block magic not found")
+	}
+
+	// The low bits of the method byte are the log of the block size the
+	// stream was written with, less 10, which no block is bigger than
+	var (
+		method           = header[8] & 0xf0
+		blockSize        = 1 << (10 + header[8]&0xf)
+		compressedSize   = int(int32(binary.LittleEndian.Uint32(header[9:])))
+		decompressedSize = int(int32(binary.LittleEndian.Uint32(header[13:])))
+	)
+	if compressedSize < 0 || decompressedSize < 0 {
+		return errors.New("Negative LZ4 block size")
+	}
+	if decompressedSize > blockSize || compressedSize > decompressedSize ||
+		method == lz4BlockRaw && compressedSize != decompressedSize {
+		return errLz4Corrupt
+	}
+	if decompressedSize == 0 {
+		z.eof = true
+		return nil
+	}
+
+	var compressed = make([]byte, compressedSize)
+	if _, err := io.ReadFull(z.r, compressed); err != nil {
+		return err
+	}
+
+	switch method {
+	case lz4BlockRaw:
+		z.buf = compressed
+	case lz4BlockCompressed:
+		var decompressed, err = lz4Decompress(compressed, decompressedSize)
+		if err != nil {
+			return err
+		}
+		z.buf = decompressed
+	default:
+		return errors.New(fmt.Sprintf("Unknown LZ4 block method %#x", method))
+	}
+	return nil
+}
+
+var errLz4Corrupt = errors.New("Corrupt LZ4 block")
+
+// lz4Decompress decodes an LZ4 block: a series of sequences of literals
+// followed by a back reference into the output, the last being literals
+// alone.
+func lz4Decompress(src []byte, size int) ([]byte, error) {
+	var dst = make([]byte, 0, size)
+
+	var readLength = func(i, length int) (int, int, error) {
+		if length != 15 {
+			return i, length, nil
+		}
+		for {
+			if i >= len(src) {
+				return i, 0, errLz4Corrupt
+			}
+			var b = src[i]
+			i++
+			length += int(b)
+			if b != 255 {
+				return i, length, nil
+			}
+		}
+	}
+
+	for i := 0; i < len(src); {
+		var token = src[i]
+		i++
+
+		var literals int
+		var err error
+		i, literals, err = readLength(i, int(token>>4))
+		if err != nil {
+			return nil, err
+		}
+		if i+literals > len(src) || len(dst)+literals > size {
+			return nil, errLz4Corrupt
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+
+		if i == len(src) {
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, errLz4Corrupt
+		}
+		var offset = int(src[i]) | int(src[i+1])<<8
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errLz4Corrupt
+		}
+
+		var match int
+		i, match, err = readLength(i, int(token&0xf))
+		if err != nil {
+			return nil, err
+		}
+		match += 4
+		if len(dst)+match > size {
+			return nil, errLz4Corrupt
+		}
+
+		// Matches may overlap the bytes they produce, so copy one at a time
+		var start = len(dst) - offset
+		for j := 0; j < match; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, errLz4Corrupt
+	}
+	return dst, nil
+}
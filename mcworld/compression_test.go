@@ -0,0 +1,131 @@
+package mcworld
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLz4Decompress(t *testing.T) {
+	// "abc" then 6 bytes from 3 back, then a final "!"
+	var block = []byte{0x32, 'a', 'b', 'c', 3, 0, 0x10, '!'}
+
+	var out, err = lz4Decompress(block, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "abcabcabc!" {
+		t.Errorf("%q not %q", out, "abcabcabc!")
+	}
+
+	if _, err := lz4Decompress(block[:5], 10); err != errLz4Corrupt {
+		t.Errorf("truncated block error %v not %v", err, errLz4Corrupt)
+	}
+	for _, size := range []int{9, 11} {
+		if _, err := lz4Decompress(block, size); err != errLz4Corrupt {
+			t.Errorf("size %d error %v not %v", size, err, errLz4Corrupt)
+		}
+	}
+}
+
+func TestDecompressCorruptLz4Chunk(t *testing.T) {
+	var tests = []struct {
+		method                           byte
+		compressedSize, decompressedSize int32
+	}{
+		{lz4BlockCompressed, 1<<31 - 1, 10},   // Bigger than it decompresses to
+		{lz4BlockCompressed, 8, 1<<31 - 1},    // Bigger than the block size
+		{lz4BlockCompressed | 15, 8, 1 << 26}, // Bigger than the largest block size
+		{lz4BlockRaw, 3, 10},                  // Raw, but not its size
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		buf.Write(lz4BlockMagic)
+		buf.WriteByte(test.method)
+		binary.Write(&buf, binary.LittleEndian, test.compressedSize)
+		binary.Write(&buf, binary.LittleEndian, test.decompressedSize)
+		binary.Write(&buf, binary.LittleEndian, int32(0))
+		buf.Write([]byte{0x32, 'a', 'b', 'c', 3, 0, 0x10, '!'})
+
+		var r, err = decompressChunk(&buf, compressionLz4)
+		if err == nil {
+			_, err = ioutil.ReadAll(r)
+		}
+		if err != errLz4Corrupt {
+			t.Errorf("%+v: error %v not %v", test, err, errLz4Corrupt)
+		}
+	}
+}
+
+func TestDecompressLz4Chunk(t *testing.T) {
+	var buf bytes.Buffer
+	var block = func(method byte, data []byte, size int) {
+		buf.Write(lz4BlockMagic)
+		buf.WriteByte(method)
+		binary.Write(&buf, binary.LittleEndian, int32(len(data)))
+		binary.Write(&buf, binary.LittleEndian, int32(size))
+		binary.Write(&buf, binary.LittleEndian, int32(0))
+		buf.Write(data)
+	}
+	block(lz4BlockCompressed, []byte{0x32, 'a', 'b', 'c', 3, 0, 0x10, '!'}, 10)
+	block(lz4BlockRaw, []byte("raw"), 3)
+	block(lz4BlockRaw, nil, 0)
+
+	var r, err = decompressChunk(&buf, compressionLz4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "abcabcabc!raw" {
+		t.Errorf("%q not %q", out, "abcabcabc!raw")
+	}
+
+	if _, err := decompressChunk(&buf, 9); err == nil {
+		t.Error("no error for compression type 9")
+	}
+}
+
+func TestDecompressChunkReused(t *testing.T) {
+	var compress = func(compressionType byte, data string) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		if compressionType == compressionGzip {
+			w = gzip.NewWriter(&buf)
+		} else {
+			w = zlib.NewWriter(&buf)
+		}
+		io.WriteString(w, data)
+		w.Close()
+		return buf.Bytes()
+	}
+
+	for _, compressionType := range []byte{compressionGzip, compressionZlib} {
+		for i := 0; i < 3; i++ {
+			var data = fmt.Sprintf("chunk %d", i)
+			var r, err = decompressChunk(bytes.NewReader(compress(compressionType, data)), compressionType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out, readErr = ioutil.ReadAll(r)
+			r.Close()
+			if readErr != nil || string(out) != data {
+				t.Errorf("type %d: %q, %v not %q", compressionType, out, readErr, data)
+			}
+			if _, err := r.Read(out); err == nil {
+				t.Errorf("type %d: read after close", compressionType)
+			}
+		}
+
+		if _, err := decompressChunk(bytes.NewReader([]byte("corrupt")), compressionType); err == nil {
+			t.Errorf("type %d: corrupt chunk opened", compressionType)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Cubic Chunks region files are made of 512 byte sectors. The location
+// table at the start has an entry per column or cube, of the first sector
+// in the top three bytes and the number of sectors in the low byte, and
+// each entry's data starts with its length.
+const cubicSectorSize = 512
+
+// CubicWorld is a world saved by the Cubic Chunks mod, which splits the
+// world into 16x16x16 cubes with no limit on height. Columns are in
+// region2d/x.z.2dr files of 32x32 columns, and cubes in
+// region3d/x.y.z.3dr files of 16x16x16 cubes.
+type CubicWorld struct {
+	fs           FileSystem
+	worldDir     string
+	dimensionDir string
+
+	mutex   sync.Mutex
+	regions map[uint64][]int // Y of the 3D regions above each 16x16 column of cubes
+}
+
+func (w *CubicWorld) Level() (*nbt.Level, error) {
+	return readJavaLevel(w.fs, w.worldDir)
+}
+
+// OpenChunk gathers every cube above column x,z into one chunk with a
+// section per cube. Chunks only allow section Y in a byte, so cubes above
+// 2048 or below -2048 are left out.
+func (w *CubicWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
+	var regions, regionsErr = w.cubeRegions(x>>4, z>>4)
+	if regionsErr != nil {
+		return nil, regionsErr
+	}
+
+	var sections [][]byte
+	for _, ry := range regions {
+		var path = filepath.Join(w.dimensionDir, "region3d", fmt.Sprintf("%d.%d.%d.3dr", x>>4, ry, z>>4))
+		var file, openErr = w.fs.Open(path)
+		if openErr != nil {
+			return nil, openErr
+		}
+
+		for ly := 0; ly < 16; ly++ {
+			var y = ry*16 + ly
+			if y < -128 || y > 127 {
+				continue
+			}
+
+			var cube, readErr = readCubicEntry(file, (x&15)<<8|ly<<4|z&15)
+			if readErr != nil {
+				file.Close()
+				return nil, errors.New(fmt.Sprintf("Cube %d,%d,%d in %s: %v", x, y, z, path, readErr))
+			}
+			if cube == nil {
+				continue
+			}
+
+			var section, sectionErr = cubeSection(cube, y)
+			if sectionErr != nil {
+				file.Close()
+				return nil, errors.New(fmt.Sprintf("Cube %d,%d,%d in %s: %v", x, y, z, path, sectionErr))
+			}
+			if section != nil {
+				sections = append(sections, section)
+			}
+		}
+		file.Close()
+	}
+
+	if len(sections) == 0 {
+		// A column of nothing but sky
+		var air = new(chunkNbtWriter)
+		air.beginStruct("")
+		air.int8("Y", 0)
+		air.byteArray("Blocks", make([]byte, 4096))
+		air.byteArray("Data", make([]byte, 2048))
+		air.endStruct()
+		sections = append(sections, air.Bytes())
+	}
+
+	var chunk = new(chunkNbtWriter)
+	chunk.beginStruct("")
+	chunk.beginStruct("Level")
+	chunk.int32("xPos", x)
+	chunk.int32("zPos", z)
+	chunk.structList("Sections", sections)
+	chunk.endStruct()
+	chunk.endStruct()
+	return ioutil.NopCloser(bytes.NewReader(chunk.Bytes())), nil
+}
+
+// cubeSection re-encodes the blocks of a cube as a chunk section at y.
+func cubeSection(cube []byte, y int) ([]byte, error) {
+	var root, parseErr = nbt.Parse(bytes.NewReader(cube))
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	var level, _ = root["Level"].(map[string]interface{})
+	var cubeSections, _ = level["Sections"].([]interface{})
+	if len(cubeSections) == 0 {
+		return nil, nil // Nothing but air
+	}
+	var cubeSection, _ = cubeSections[0].(map[string]interface{})
+	var (
+		blocks, _ = cubeSection["Blocks"].([]byte)
+		data, _   = cubeSection["Data"].([]byte)
+	)
+	if len(blocks) != 4096 || len(data) != 2048 {
+		return nil, errors.New(fmt.Sprintf("%d blocks and %d data, not 4096 and 2048", len(blocks), len(data)))
+	}
+
+	var section = new(chunkNbtWriter)
+	section.beginStruct("")
+	section.int8("Y", y)
+	section.byteArray("Blocks", blocks)
+	section.byteArray("Data", data)
+	section.endStruct()
+	return section.Bytes(), nil
+}
+
+// readCubicEntry reads entry i of a Cubic Chunks region file, returning
+// nil if there isn't one. Entries are gzipped NBT.
+func readCubicEntry(file io.ReaderAt, i int) ([]byte, error) {
+	var b [4]byte
+	if _, err := file.ReadAt(b[:], int64(4*i)); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var location = binary.BigEndian.Uint32(b[:])
+	if location == 0 {
+		return nil, nil
+	}
+	var offset = int64(location>>8) * cubicSectorSize
+
+	if _, err := file.ReadAt(b[:], offset); err != nil {
+		return nil, err
+	}
+	var length = binary.BigEndian.Uint32(b[:])
+	if length == 0 || length > (location&0xff)*cubicSectorSize {
+		return nil, errors.New(fmt.Sprintf("%d bytes long in %d sectors", length, location&0xff))
+	}
+
+	var compressed = make([]byte, length)
+	if _, err := file.ReadAt(compressed, offset+4); err != nil {
+		return nil, err
+	}
+
+	var r, err = decompressChunk(bytes.NewReader(compressed), compressionGzip)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// cubeRegions returns the Y of the 3D regions above region column rx,rz,
+// listing region3d the first time it's needed.
+func (w *CubicWorld) cubeRegions(rx, rz int) ([]int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.regions == nil {
+		var names, err = w.fs.ReadDirNames(filepath.Join(w.dimensionDir, "region3d"))
+		if err != nil {
+			return nil, err
+		}
+
+		w.regions = make(map[uint64][]int)
+		for _, name := range names {
+			var fields = strings.Split(name, ".")
+			if len(fields) != 4 || fields[3] != "3dr" {
+				continue
+			}
+			var (
+				x, xErr = strconv.Atoi(fields[0])
+				y, yErr = strconv.Atoi(fields[1])
+				z, zErr = strconv.Atoi(fields[2])
+			)
+			if xErr == nil && yErr == nil && zErr == nil {
+				var key = betaChunkPoolKey(x, z)
+				w.regions[key] = append(w.regions[key], y)
+			}
+		}
+	}
+
+	return w.regions[betaChunkPoolKey(rx, rz)], nil
+}
+
+// ChunkPool pools a chunk for each column in the 2D regions.
+func (w *CubicWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
+	var regionDirname = filepath.Join(w.dimensionDir, "region2d")
+	var names, readErr = w.fs.ReadDirNames(regionDirname)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var pool = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox(), nil}
+	for _, name := range names {
+		var fields = strings.Split(name, ".")
+		if len(fields) != 3 || fields[2] != "2dr" {
+			continue
+		}
+		var (
+			rx, rxErr = strconv.Atoi(fields[0])
+			rz, rzErr = strconv.Atoi(fields[1])
+		)
+		if rxErr != nil || rzErr != nil {
+			continue
+		}
+
+		var file, openErr = w.fs.Open(filepath.Join(regionDirname, name))
+		if openErr != nil {
+			return nil, openErr
+		}
+		var header = make([]byte, 4*32*32)
+		var _, headerErr = io.ReadFull(file, header)
+		file.Close()
+		if headerErr != nil && headerErr != io.EOF && headerErr != io.ErrUnexpectedEOF {
+			return nil, headerErr
+		}
+
+		for i := 0; i < 32*32; i++ {
+			if binary.BigEndian.Uint32(header[4*i:]) == 0 {
+				continue
+			}
+			var (
+				x = rx*32 + i>>5
+				z = rz*32 + i&31
+			)
+			if !mask.IsMasked(x, z) {
+				pool.chunkMap[betaChunkPoolKey(x, z)] = true
+				pool.box.Union(x, z)
+			}
+		}
+	}
+	return pool, nil
+}
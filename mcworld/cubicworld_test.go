@@ -0,0 +1,97 @@
+package mcworld
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCubicWorld(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "cubicworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "region2d"), 0755)
+	os.Mkdir(filepath.Join(dir, "region3d"), 0755)
+
+	// Column 1,2 and the cube at y=-3 above it, which is stone at its
+	// lowest 0,0,0 and air everywhere else.
+	var column = new(chunkNbtWriter)
+	column.beginStruct("")
+	column.beginStruct("Level")
+	column.int32("x", 1)
+	column.int32("z", 2)
+	column.endStruct()
+	column.endStruct()
+	ioutil.WriteFile(filepath.Join(dir, "region2d", "0.0.2dr"), testCubicRegion(1024, 1<<5|2, column.Bytes()), 0644)
+
+	var blocks = make([]byte, 4096)
+	blocks[0] = 1
+	var cube = new(chunkNbtWriter)
+	cube.beginStruct("")
+	cube.beginStruct("Level")
+	var section = new(chunkNbtWriter)
+	section.beginStruct("")
+	section.byteArray("Blocks", blocks)
+	section.byteArray("Data", make([]byte, 2048))
+	section.endStruct()
+	cube.structList("Sections", [][]byte{section.Bytes()})
+	cube.endStruct()
+	cube.endStruct()
+	ioutil.WriteFile(filepath.Join(dir, "region3d", "0.-1.0.3dr"), testCubicRegion(4096, 1<<8|13<<4|2, cube.Bytes()), 0644)
+
+	var world = openWorld(osFileSystem{}, dir, dir)
+	if _, ok := world.(*CubicWorld); !ok {
+		t.Fatalf("%T not a Cubic Chunks world", world)
+	}
+
+	var pool, poolErr = world.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(1, 2) {
+		t.Errorf("%d chunks pooled, not 1,2", pool.Remaining())
+	}
+
+	var r, openErr = world.OpenChunk(1, 2)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	var chunk, chunkErr = nbt.ReadChunkNbt(r)
+	r.Close()
+	if chunkErr != nil {
+		t.Fatal(chunkErr)
+	}
+	if chunk.MinY != -48 {
+		t.Errorf("MinY %d not -48", chunk.MinY)
+	}
+	var height = len(chunk.Blocks) / 256
+	if chunk.Blocks[0] != 1 || chunk.Blocks[1] != 0 || chunk.Blocks[height] != 0 {
+		t.Errorf("blocks %v, not stone then air", chunk.Blocks[:2])
+	}
+}
+
+// testCubicRegion makes a Cubic Chunks region file of entries entries,
+// with entry i holding data gzipped.
+func testCubicRegion(entries, i int, data []byte) []byte {
+	var payload bytes.Buffer
+	var zw = gzip.NewWriter(&payload)
+	zw.Write(data)
+	zw.Close()
+
+	var (
+		first   = 4 * entries / cubicSectorSize
+		sectors = (payload.Len() + 4 + cubicSectorSize - 1) / cubicSectorSize
+		region  = make([]byte, (first+sectors)*cubicSectorSize)
+	)
+	binary.BigEndian.PutUint32(region[4*i:], uint32(first<<8|sectors))
+	binary.BigEndian.PutUint32(region[first*cubicSectorSize:], uint32(payload.Len()))
+	copy(region[first*cubicSectorSize+4:], payload.Bytes())
+	return region
+}
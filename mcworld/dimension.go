@@ -0,0 +1,93 @@
+package mcworld
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Dimension names understood by OpenDimension, besides dimension numbers
+// and the namespace:name of datapack dimensions.
+var dimensionNumbers = map[string]int{
+	"":                     0,
+	"overworld":            0,
+	"minecraft:overworld":  0,
+	"nether":               -1,
+	"the_nether":           -1,
+	"minecraft:the_nether": -1,
+	"end":                  1,
+	"the_end":              1,
+	"minecraft:the_end":    1,
+}
+
+// OpenDimension opens one dimension of a world. The Nether and End of Java
+// worlds are kept in the DIM-1 and DIM1 folders, and datapack dimensions
+// (from 1.16) in dimensions/namespace/name. Bedrock worlds keep every
+// dimension in the one database.
+func OpenDimension(worldDir, dimension string) (World, error) {
+	return openDimension(worldDir, dimension, nil)
+}
+
+// OpenLiveDimension opens one dimension of a world that a server is still
+// saving to, reading each file whole as it's opened so chunks aren't read
+// half written.
+func OpenLiveDimension(worldDir, dimension string) (World, error) {
+	return openDimension(worldDir, dimension, func(fs FileSystem) FileSystem {
+		return snapshotFileSystem{fs}
+	})
+}
+
+// OpenMappedDimension opens one dimension of a world, memory mapping the
+// files of worlds on the local disk rather than reading them piece by
+// piece. The files mustn't be changed while the world is open.
+func OpenMappedDimension(worldDir, dimension string) (World, error) {
+	return openDimension(worldDir, dimension, mappedFileSystem)
+}
+
+// openDimension opens a dimension of the world in worldDir, with wrap (if
+// not nil) wrapping the file system the world is on.
+func openDimension(worldDir, dimension string, wrap func(FileSystem) FileSystem) (World, error) {
+	var number, named = dimensionNumbers[strings.ToLower(dimension)]
+	if !named {
+		if n, err := strconv.Atoi(dimension); err == nil {
+			number, named = n, true
+		}
+	}
+
+	var fs, dir, fsErr = openFileSystem(worldDir)
+	if fsErr != nil {
+		return nil, fsErr
+	}
+	if wrap != nil {
+		fs = wrap(fs)
+	}
+
+	var world = openWorld(fs, dir, dir)
+	if bedrock, ok := world.(*BedrockWorld); ok {
+		if !named || number < -1 || number > 1 {
+			return nil, errors.New(fmt.Sprintf("Unknown Bedrock dimension %q", dimension))
+		}
+		// Bedrock numbers the Nether 1 and the End 2
+		bedrock.dimension = []int{1, 0, 2}[number+1]
+		return bedrock, nil
+	}
+
+	var _, flat = world.(flatWorldLevel)
+	var _, pocket = world.(*PocketWorld)
+	if (flat || pocket) && !(named && number == 0) {
+		return nil, errors.New(fmt.Sprintf("%s only has one dimension", worldDir))
+	}
+
+	switch {
+	case named && number == 0:
+		return world, nil
+	case named:
+		return openWorld(fs, dir, filepath.Join(dir, fmt.Sprintf("DIM%d", number))), nil
+	case strings.Contains(dimension, ":"):
+		var parts = strings.SplitN(dimension, ":", 2)
+		return openWorld(fs, dir, filepath.Join(dir, "dimensions", parts[0], parts[1])), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown dimension %q", dimension))
+}
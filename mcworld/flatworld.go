@@ -0,0 +1,140 @@
+package mcworld
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"io"
+	"io/ioutil"
+)
+
+// flatWorld is a world saved as one file holding a single block array,
+// which is cut into virtual 16x16 chunks for the rest of the exporter.
+type flatWorld struct {
+	fs   FileSystem
+	path string
+	read func(fs FileSystem, path string) (*flatLevel, error)
+
+	level    *flatLevel
+	levelErr error
+}
+
+// flatLevel is a level stored as one block array, in YZX order, with
+// chunk 0,0 at its corner. The low nibble of data (if there is any) is
+// each block's metadata.
+type flatLevel struct {
+	width, length, height  int
+	blocks, data           []byte
+	spawnX, spawnY, spawnZ int
+}
+
+func (w *flatWorld) open() (*flatLevel, error) {
+	if w.level == nil && w.levelErr == nil {
+		w.level, w.levelErr = w.read(w.fs, w.path)
+	}
+	return w.level, w.levelErr
+}
+
+func (w *flatWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
+	var level, err = w.open()
+	if err != nil {
+		return nil, err
+	}
+	return level.openChunk(x, z)
+}
+
+func (w *flatWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
+	var level, err = w.open()
+	if err != nil {
+		return nil, err
+	}
+	return level.chunkPool(mask), nil
+}
+
+// flatWorldLevel is implemented by worlds saved as a single flat level.
+type flatWorldLevel interface {
+	flatLevelFile() string
+}
+
+func (w *flatWorld) flatLevelFile() string {
+	return w.path
+}
+
+func (w *flatWorld) Level() (*nbt.Level, error) {
+	var level, err = w.open()
+	if err != nil {
+		return nil, err
+	}
+	return &nbt.Level{SpawnX: level.spawnX, SpawnY: level.spawnY, SpawnZ: level.spawnZ}, nil
+}
+
+// setBlocks fills the block and data arrays from blocks in YZX order, for
+// levels read from formats with block state palettes.
+func (level *flatLevel) setBlocks(blocks []nbt.Block) {
+	level.blocks = make([]byte, len(blocks))
+	level.data = make([]byte, len(blocks))
+	for i, block := range blocks {
+		level.blocks[i] = byte(block)
+		level.data[i] = byte(block >> 8)
+	}
+}
+
+func (level *flatLevel) check() error {
+	if level.width <= 0 || level.length <= 0 || level.height <= 0 || len(level.blocks) != level.width*level.length*level.height {
+		return errors.New(fmt.Sprintf("Blocks don't fill a %dx%dx%d level", level.width, level.length, level.height))
+	}
+	return nil
+}
+
+func (level *flatLevel) chunkPool(mask ChunkMask) ChunkPool {
+	var pool = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox(), nil}
+	for x := 0; x*16 < level.width; x++ {
+		for z := 0; z*16 < level.length; z++ {
+			if !mask.IsMasked(x, z) {
+				pool.chunkMap[betaChunkPoolKey(x, z)] = true
+				pool.box.Union(x, z)
+			}
+		}
+	}
+	return pool
+}
+
+// openChunk encodes the 16x16 column at chunk x,z as a pre-Anvil chunk,
+// with air past the edges of the level.
+func (level *flatLevel) openChunk(x, z int) (io.ReadCloser, error) {
+	if x < 0 || z < 0 || x*16 >= level.width || z*16 >= level.length {
+		return nil, errors.New(fmt.Sprintf("Chunk missing: %v,%v", x, z))
+	}
+
+	var (
+		blocks = make([]byte, 16*16*level.height)
+		data   = make([]byte, len(blocks)/2)
+	)
+	for cx := 0; cx < 16 && x*16+cx < level.width; cx++ {
+		for cz := 0; cz < 16 && z*16+cz < level.length; cz++ {
+			for y := 0; y < level.height; y++ {
+				var (
+					i = (y*level.length+z*16+cz)*level.width + x*16 + cx
+					j = y + level.height*(cz+16*cx)
+				)
+				blocks[j] = level.blocks[i]
+				if i < len(level.data) {
+					data[j/2] |= (level.data[i] & 0xf) << uint(4*(j&1))
+				}
+			}
+		}
+	}
+
+	var chunk = new(chunkNbtWriter)
+	chunk.beginStruct("")
+	chunk.beginStruct("Level")
+	chunk.int32("xPos", x)
+	chunk.int32("zPos", z)
+	chunk.byteArray("Blocks", blocks)
+	chunk.byteArray("Data", data)
+	chunk.endStruct()
+	chunk.endStruct()
+
+	return ioutil.NopCloser(bytes.NewReader(chunk.Bytes())), nil
+}
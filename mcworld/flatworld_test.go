@@ -0,0 +1,337 @@
+package mcworld
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFlatLevelChunks(t *testing.T) {
+	// A 20x4x18 level with stone:3 at the top of its far corner
+	var level = &flatLevel{width: 20, length: 18, height: 4}
+	level.blocks = make([]byte, 20*18*4)
+	level.data = make([]byte, len(level.blocks))
+	var corner = (3*18+17)*20 + 19
+	level.blocks[corner] = 1
+	level.data[corner] = 0xf3
+
+	var pool = level.chunkPool(&AllChunksMask{})
+	if pool.Remaining() != 4 {
+		t.Errorf("%d chunks not 4", pool.Remaining())
+	}
+
+	var r, err = level.openChunk(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := nbt.ReadChunkNbt(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.XPos != 1 || chunk.ZPos != 1 || len(chunk.Blocks) != 16*16*4 {
+		t.Fatalf("chunk %d,%d of %d blocks", chunk.XPos, chunk.ZPos, len(chunk.Blocks))
+	}
+	if b := chunk.Blocks[3+4*(1+16*3)]; b != 1+3<<8 {
+		t.Errorf("block at 3,3,1 is %d:%d not 1:3", b&0xff, b>>8)
+	}
+
+	if _, err := level.openChunk(2, 0); err == nil {
+		t.Error("no error for chunk 2,0 outside the level")
+	}
+}
+
+func TestClassicV1Level(t *testing.T) {
+	var buf bytes.Buffer
+	var gz = gzip.NewWriter(&buf)
+	binary.Write(gz, binary.BigEndian, uint32(classicMagic))
+	gz.Write([]byte{1, 0, 1, 'a', 0, 1, 'b'})
+	binary.Write(gz, binary.BigEndian, []int64{1234})
+	binary.Write(gz, binary.BigEndian, []int16{32, 16, 8})
+	var blocks = make([]byte, 32*16*8)
+	blocks[(7*16+3)*32+17] = 4
+	gz.Write(blocks)
+	gz.Close()
+
+	var file, err = ioutil.TempFile("", "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.Write(buf.Bytes())
+	file.Close()
+
+	level, err := readClassicLevel(osFileSystem{}, file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.width != 32 || level.length != 16 || level.height != 8 {
+		t.Errorf("level is %dx%dx%d not 32x16x8", level.width, level.length, level.height)
+	}
+	if level.blocks[(7*16+3)*32+17] != 4 {
+		t.Error("cobblestone at 17,7,3 missing")
+	}
+	if level.spawnX != 16 || level.spawnZ != 8 {
+		t.Errorf("spawn %d,%d not the middle of the level", level.spawnX, level.spawnZ)
+	}
+}
+
+func TestSchematicLevel(t *testing.T) {
+	var blocks, data = make([]byte, 5*3*2), make([]byte, 5*3*2)
+	blocks[(1*2+1)*5+4], data[(1*2+1)*5+4] = 35, 14
+
+	var schematic = new(chunkNbtWriter)
+	schematic.beginStruct("Schematic")
+	schematic.int16("Width", 5)
+	schematic.int16("Height", 3)
+	schematic.int16("Length", 2)
+	schematic.string("Materials", "Alpha")
+	schematic.byteArray("Blocks", blocks)
+	schematic.byteArray("Data", data)
+	schematic.endStruct()
+
+	var file, err = ioutil.TempFile("", "schematic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	var gz = gzip.NewWriter(file)
+	gz.Write(schematic.Bytes())
+	gz.Close()
+	file.Close()
+
+	level, err := readSchematicLevel(osFileSystem{}, file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.width != 5 || level.height != 3 || level.length != 2 {
+		t.Errorf("schematic is %dx%dx%d not 5x3x2", level.width, level.height, level.length)
+	}
+
+	r, err := level.openChunk(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := nbt.ReadChunkNbt(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var height = len(chunk.Blocks) / 256
+	if b := chunk.Blocks[1+height*(1+16*4)]; b != 35+14<<8 {
+		t.Errorf("block at 4,1,1 is %d:%d not red wool", b&0xff, b>>8)
+	}
+}
+
+func TestSpongeLevel(t *testing.T) {
+	for _, version := range []int{2, 3} {
+		// A 200x1x1 schematic of air, whose palette index of 150 takes
+		// two bytes, with a top oak slab at x=150
+		var data []byte
+		for x := 0; x < 200; x++ {
+			switch x {
+			case 150:
+				data = append(data, 2)
+			default:
+				data = append(data, 150&0x7f|0x80, 150>>7)
+			}
+		}
+
+		var schematic = new(chunkNbtWriter)
+		schematic.beginStruct("")
+		if version == 3 {
+			schematic.beginStruct("Schematic")
+		}
+		schematic.int32("Version", version)
+		schematic.int16("Width", 200)
+		schematic.int16("Height", 1)
+		schematic.int16("Length", 1)
+		if version == 3 {
+			schematic.beginStruct("Blocks")
+		}
+		schematic.beginStruct("Palette")
+		schematic.int32("minecraft:air", 150)
+		schematic.int32("minecraft:oak_slab[type=top,waterlogged=false]", 2)
+		schematic.endStruct()
+		if version == 3 {
+			schematic.byteArray("Data", data)
+			schematic.endStruct()
+			schematic.endStruct()
+		} else {
+			schematic.byteArray("BlockData", data)
+		}
+		schematic.endStruct()
+
+		var file, err = ioutil.TempFile("", "sponge")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(file.Name())
+		var gz = gzip.NewWriter(file)
+		gz.Write(schematic.Bytes())
+		gz.Close()
+		file.Close()
+
+		level, err := readSpongeLevel(osFileSystem{}, file.Name())
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if level.blocks[150] != 126 || level.data[150] != 8 || level.blocks[149] != 0 {
+			t.Errorf("version %d: block 150 is %d:%d not a top oak slab", version, level.blocks[150], level.data[150])
+		}
+	}
+}
+
+func TestLitematicLevel(t *testing.T) {
+	var litematic = new(chunkNbtWriter)
+	litematic.beginStruct("")
+	litematic.int32("Version", 6)
+	litematic.beginStruct("Regions")
+	for _, r := range []struct {
+		name     string
+		position [3]int
+		size     [3]int
+		block    string
+		states   int64
+	}{
+		{"a", [3]int{0, 0, 0}, [3]int{2, 1, 1}, "minecraft:stone", 1 << 2},
+		{"b", [3]int{5, 0, 2}, [3]int{-2, 1, -1}, "minecraft:red_wool", 1 | 1<<2},
+	} {
+		litematic.beginStruct(r.name)
+		for i, vector := range [][3]int{r.position, r.size} {
+			litematic.beginStruct([]string{"Position", "Size"}[i])
+			litematic.int32("x", vector[0])
+			litematic.int32("y", vector[1])
+			litematic.int32("z", vector[2])
+			litematic.endStruct()
+		}
+		var palette [][]byte
+		for _, name := range []string{"minecraft:air", r.block} {
+			var state = new(chunkNbtWriter)
+			state.beginStruct("")
+			state.string("Name", name)
+			state.endStruct()
+			palette = append(palette, state.Bytes())
+		}
+		litematic.structList("BlockStatePalette", palette)
+		litematic.longArray("BlockStates", []int64{r.states})
+		litematic.endStruct()
+	}
+	litematic.endStruct()
+	litematic.endStruct()
+
+	var file, err = ioutil.TempFile("", "litematic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	var gz = gzip.NewWriter(file)
+	gz.Write(litematic.Bytes())
+	gz.Close()
+	file.Close()
+
+	level, err := readLitematicLevel(osFileSystem{}, file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.width != 6 || level.height != 1 || level.length != 3 {
+		t.Fatalf("litematic is %dx%dx%d not 6x1x3", level.width, level.height, level.length)
+	}
+	for i, expected := range []byte{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 35, 35} {
+		if level.blocks[i] != expected {
+			t.Errorf("block %d is %d not %d", i, level.blocks[i], expected)
+		}
+	}
+	if level.data[17] != 14 {
+		t.Errorf("wool is color %d not red", level.data[17])
+	}
+}
+
+func TestStructureLevel(t *testing.T) {
+	var structure = new(chunkNbtWriter)
+	structure.beginStruct("")
+	structure.int32("DataVersion", 3465)
+	structure.int32List("size", []int{3, 2, 4})
+
+	var palette [][]byte
+	for _, name := range []string{"minecraft:stone", "minecraft:lime_wool"} {
+		var state = new(chunkNbtWriter)
+		state.beginStruct("")
+		state.string("Name", name)
+		state.endStruct()
+		palette = append(palette, state.Bytes())
+	}
+	structure.structList("palette", palette)
+
+	var blocks [][]byte
+	for i, pos := range [][]int{{0, 0, 0}, {2, 1, 3}} {
+		var block = new(chunkNbtWriter)
+		block.beginStruct("")
+		block.int32List("pos", pos)
+		block.int32("state", i)
+		block.endStruct()
+		blocks = append(blocks, block.Bytes())
+	}
+	structure.structList("blocks", blocks)
+	structure.endStruct()
+
+	var file, err = ioutil.TempFile("", "structure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	var gz = gzip.NewWriter(file)
+	gz.Write(structure.Bytes())
+	gz.Close()
+	file.Close()
+
+	level, err := readStructureLevel(osFileSystem{}, file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.width != 3 || level.height != 2 || level.length != 4 {
+		t.Fatalf("structure is %dx%dx%d not 3x2x4", level.width, level.height, level.length)
+	}
+	var top = (1*4+3)*3 + 2
+	if level.blocks[0] != 1 || level.blocks[top] != 35 || level.data[top] != 5 || level.blocks[1] != 0 {
+		t.Errorf("blocks %v, not stone and lime wool in opposite corners", level.blocks)
+	}
+}
+
+func TestReadJavaObject(t *testing.T) {
+	var buf bytes.Buffer
+	var utf = func(s string) {
+		binary.Write(&buf, binary.BigEndian, uint16(len(s)))
+		buf.WriteString(s)
+	}
+	buf.Write([]byte{0xac, 0xed, 0, 5, tcObject, tcClassDesc})
+	utf(classicLevelClass)
+	binary.Write(&buf, binary.BigEndian, int64(1))
+	buf.Write([]byte{scSerializable, 0, 3, 'I'})
+	utf("depth")
+	buf.WriteByte('I')
+	utf("width")
+	buf.WriteByte('[')
+	utf("blocks")
+	buf.WriteByte(tcString)
+	utf("[B")
+	buf.Write([]byte{tcEndBlockData, tcNull})
+	binary.Write(&buf, binary.BigEndian, []int32{64, 256})
+	buf.Write([]byte{tcArray, tcClassDesc})
+	utf("[B")
+	binary.Write(&buf, binary.BigEndian, int64(2))
+	buf.Write([]byte{scSerializable, 0, 0, tcEndBlockData, tcNull, 0, 0, 0, 3, 7, 8, 9})
+
+	var object, err = readJavaObject(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.className != classicLevelClass || object.fields["depth"] != 64 || object.fields["width"] != 256 {
+		t.Errorf("%s %v", object.className, object.fields)
+	}
+	if blocks, _ := object.fields["blocks"].([]byte); !bytes.Equal(blocks, []byte{7, 8, 9}) {
+		t.Errorf("blocks %v not [7 8 9]", object.fields["blocks"])
+	}
+}
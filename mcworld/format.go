@@ -0,0 +1,106 @@
+package mcworld
+
+import (
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"path/filepath"
+	"strings"
+)
+
+// Data versions of Java Edition releases, which level.dat records from
+// 1.9.
+var dataVersionReleases = []struct {
+	dataVersion int
+	release     string
+}{
+	{169, "1.9"},
+	{510, "1.10"},
+	{819, "1.11"},
+	{1139, "1.12"},
+	{1519, "1.13"},
+	{1952, "1.14"},
+	{2225, "1.15"},
+	{2566, "1.16"},
+	{2724, "1.17"},
+	{2860, "1.18"},
+	{3105, "1.19"},
+	{3463, "1.20"},
+	{3953, "1.21"},
+}
+
+// Describe names the format a world was opened as, and for Java worlds the
+// version of Minecraft that saved it.
+func Describe(world World) string {
+	switch w := world.(type) {
+	case *BetaWorld:
+		return w.describe()
+	case *AlphaWorld:
+		return "Alpha"
+	case *CubicWorld:
+		return "Cubic Chunks"
+	case *BedrockWorld:
+		return "Bedrock"
+	case *PocketWorld:
+		return "Pocket Edition chunks.dat"
+	case *IndevWorld:
+		return "Indev"
+	case *ClassicWorld:
+		return "Classic"
+	case *SchematicWorld:
+		return "MCEdit schematic"
+	case *SpongeWorld:
+		return "Sponge schematic"
+	case *LitematicWorld:
+		return "Litematica schematic"
+	case *StructureWorld:
+		return "Structure"
+	case *OverlayWorld:
+		var formats = make([]string, len(w.worlds))
+		for i, world := range w.worlds {
+			formats[i] = Describe(world)
+		}
+		return "Overlay of " + strings.Join(formats, ", ")
+	}
+	return fmt.Sprintf("%T", world)
+}
+
+// describe tells Anvil and McRegion apart by level.dat, or by the region
+// files if level.dat doesn't say.
+func (w *BetaWorld) describe() string {
+	var format = "Anvil"
+	var level, _ = w.Level()
+	switch {
+	case level != nil && level.Version == nbt.McRegionVersion:
+		format = "McRegion"
+	case level != nil && level.Version == nbt.AnvilVersion:
+	default:
+		if regions, err := w.regionFiles(); err == nil && len(regions) != 0 {
+			switch filepath.Ext(regions[0].filename) {
+			case ".mcr":
+				format = "McRegion"
+			case ".linear":
+				format = "Linear"
+			}
+		}
+	}
+
+	if level != nil && level.DataVersion != 0 {
+		return fmt.Sprintf("%s (data version %d, Minecraft %s)", format, level.DataVersion, DataVersionRelease(level.DataVersion))
+	}
+	return format
+}
+
+// DataVersionRelease names the release of Java Edition a data version
+// belongs to, such as "1.20", or "1.21 or later" past the last one known.
+func DataVersionRelease(dataVersion int) string {
+	var release = "before 1.9"
+	for _, r := range dataVersionReleases {
+		if dataVersion >= r.dataVersion {
+			release = r.release
+		}
+	}
+	if last := dataVersionReleases[len(dataVersionReleases)-1]; release == last.release {
+		release += " or later"
+	}
+	return release
+}
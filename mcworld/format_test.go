@@ -0,0 +1,27 @@
+package mcworld
+
+import (
+	"testing"
+)
+
+func TestDataVersionRelease(t *testing.T) {
+	for dataVersion, expected := range map[int]string{
+		0:    "before 1.9",
+		1343: "1.12",
+		1519: "1.13",
+		3465: "1.20",
+		4189: "1.21 or later",
+	} {
+		if release := DataVersionRelease(dataVersion); release != expected {
+			t.Errorf("data version %d is %q not %q", dataVersion, release, expected)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	var terrain = NewClassicWorld(osFileSystem{}, "terrain.mine")
+	var build = NewSpongeWorld(osFileSystem{}, "build.schem")
+	if d := Describe(NewOverlayWorld(terrain, build)); d != "Overlay of Classic, Sponge schematic" {
+		t.Errorf("described as %q", d)
+	}
+}
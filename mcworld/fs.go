@@ -0,0 +1,85 @@
+package mcworld
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileSystem opens the files of a world, so that worlds can be read from
+// somewhere other than a folder on the local disk. Names are built with
+// filepath.Join, as for the local disk.
+type FileSystem interface {
+	Open(name string) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDirNames(name string) ([]string, error) // Sorted
+}
+
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+type osFileSystem struct{}
+
+func (osFileSystem) Open(name string) (File, error) {
+	var file, err = os.Open(name)
+	if err != nil {
+		// Don't return a nil *os.File as a non-nil File
+		return nil, err
+	}
+	return file, nil
+}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) ReadDirNames(name string) ([]string, error) {
+	var dir, err = os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	var names, readErr = dir.Readdirnames(-1)
+	sort.Strings(names)
+	return names, readErr
+}
+
+func isDir(fs FileSystem, name string) bool {
+	var fi, err = fs.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// walkFiles calls fn for dir and everything inside it, like filepath.Walk.
+// fn can return filepath.SkipDir to skip a folder.
+func walkFiles(fs FileSystem, dir string, fn func(path string, info os.FileInfo) error) error {
+	var info, statErr = fs.Stat(dir)
+	if statErr != nil {
+		return statErr
+	}
+
+	var err = fn(dir, info)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	if err != nil || !info.IsDir() {
+		return err
+	}
+
+	var names, readErr = fs.ReadDirNames(dir)
+	if readErr != nil {
+		return readErr
+	}
+	for _, name := range names {
+		if err := walkFiles(fs, filepath.Join(dir, name), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
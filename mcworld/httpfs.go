@@ -0,0 +1,123 @@
+package mcworld
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// httpFileSystem reads a world from a web server. Files are read with
+// Range requests, a block at a time, so only the region headers and the
+// sectors of the chunks being exported are downloaded. Folders are listed
+// by reading the links out of the server's index pages.
+type httpFileSystem struct {
+	base   *url.URL
+	client *http.Client
+	blocks *blockCache
+}
+
+func newHTTPFileSystem(base *url.URL) *httpFileSystem {
+	var u = *base
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	var fs = &httpFileSystem{base: &u, client: http.DefaultClient}
+	fs.blocks = newBlockCache(fs.readRange)
+	return fs
+}
+
+func (fs *httpFileSystem) url(name string) *url.URL {
+	var p = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+	if p == "." {
+		p = ""
+	}
+	return fs.base.ResolveReference(&url.URL{Path: p})
+}
+
+func (fs *httpFileSystem) Stat(name string) (os.FileInfo, error) {
+	var resp, err = fs.client.Head(fs.url(name).String())
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: errors.New(resp.Status)}
+	}
+
+	// Servers redirect folders to their index, with a trailing slash
+	if strings.HasSuffix(resp.Request.URL.Path, "/") {
+		return dirInfo(path.Base(filepath.ToSlash(name))), nil
+	}
+	return &remoteFileInfo{path.Base(filepath.ToSlash(name)), resp.ContentLength}, nil
+}
+
+func (fs *httpFileSystem) Open(name string) (File, error) {
+	var info, err = fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	return &blockFile{blocks: fs.blocks, name: name, info: info}, nil
+}
+
+func (fs *httpFileSystem) readRange(name string, offset int64, size int) ([]byte, error) {
+	var request, err = http.NewRequest("GET", fs.url(name).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return getRange(fs.client, request, name, offset, size)
+}
+
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*"([^"]*)"`)
+
+func (fs *httpFileSystem) ReadDirNames(name string) ([]string, error) {
+	var u = fs.url(name)
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	var resp, err = fs.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New(resp.Status)}
+	}
+
+	var page, readErr = ioutil.ReadAll(resp.Body)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	// Keep the links to the folder's own files and folders
+	var seen = make(map[string]bool)
+	var names []string
+	for _, match := range hrefPattern.FindAllStringSubmatch(string(page), -1) {
+		var link, err = url.Parse(match[1])
+		if err != nil || link.IsAbs() || link.Host != "" || strings.HasPrefix(link.Path, "/") || link.Path == "" {
+			continue
+		}
+		var child = strings.TrimSuffix(link.Path, "/")
+		if child == "" || child == "." || child == ".." || strings.Contains(child, "/") || seen[child] {
+			continue
+		}
+		seen[child] = true
+		names = append(names, child)
+	}
+	sort.Strings(names)
+	return names, nil
+}
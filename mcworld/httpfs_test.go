@@ -0,0 +1,64 @@
+package mcworld
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenHTTPWorld(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "httpworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "World 1", "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "World 1", "level.dat"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "World 1", "region", "r.-1.0.mca"), testRegion(31, 2), 0644)
+
+	var requests int
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.FileServer(http.Dir(dir)).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	world, err := OpenWorld(server.URL + "/World%201")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := world.ChunkPool(&AllChunksMask{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Fatalf("%d chunks, not just -1,2", pool.Remaining())
+	}
+
+	r, err := world.OpenChunk(-1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := nbt.ReadChunkNbt(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.XPos != -1 || chunk.ZPos != 2 {
+		t.Errorf("chunk %d,%d not -1,2", chunk.XPos, chunk.ZPos)
+	}
+
+	// The region file is small enough to be one cached block
+	var before = requests
+	if _, err := world.OpenChunk(-1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if requests-before > 2 {
+		t.Errorf("%d requests to reopen a cached chunk", requests-before)
+	}
+}
@@ -0,0 +1,64 @@
+package mcworld
+
+import (
+	"image"
+	"image/png"
+	"os"
+)
+
+// ImageChunkMask masks chunks by the pixels of an image, each of which
+// covers Scale by Scale chunks, with the top left pixel covering chunk
+// X0,Z0 (x to the right, z down). Light pixels are exported, and dark or
+// transparent pixels and everything off the edge of the image are masked,
+// as is everything Mask masks.
+type ImageChunkMask struct {
+	Mask   ChunkMask
+	Image  image.Image
+	X0, Z0 int
+	Scale  int
+}
+
+// LoadImageChunkMask reads a PNG for an ImageChunkMask.
+func LoadImageChunkMask(filename string, mask ChunkMask, x0, z0, scale int) (*ImageChunkMask, error) {
+	var file, err = os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var img, decodeErr = png.Decode(file)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return &ImageChunkMask{mask, img, x0, z0, scale}, nil
+}
+
+func (m *ImageChunkMask) IsMasked(x, z int) bool {
+	if m.Mask.IsMasked(x, z) {
+		return true
+	}
+
+	var scale = m.Scale
+	if scale < 1 {
+		scale = 1
+	}
+	var (
+		bounds = m.Image.Bounds()
+		px     = bounds.Min.X + floorDiv(x-m.X0, scale)
+		py     = bounds.Min.Y + floorDiv(z-m.Z0, scale)
+	)
+	if !(image.Point{px, py}.In(bounds)) {
+		return true
+	}
+
+	// Compare the premultiplied brightness against half of full white
+	var r, g, b, _ = m.Image.At(px, py).RGBA()
+	return 299*r+587*g+114*b < 1000*0xffff/2
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
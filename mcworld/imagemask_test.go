@@ -0,0 +1,32 @@
+package mcworld
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestImageChunkMask(t *testing.T) {
+	// A 2x2 image, white on the diagonal from the top left
+	var img = image.NewGray(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.White)
+	img.Set(1, 1, color.White)
+
+	var mask = &ImageChunkMask{&AllChunksMask{}, img, -4, 10, 2}
+	for _, test := range []struct {
+		x, z   int
+		masked bool
+	}{
+		{-4, 10, false},
+		{-3, 11, false},
+		{-2, 10, true},
+		{-1, 13, false},
+		{-5, 10, true},
+		{0, 12, true},
+		{-4, 9, true},
+	} {
+		if mask.IsMasked(test.x, test.z) != test.masked {
+			t.Errorf("chunk %d,%d masked is %v", test.x, test.z, !test.masked)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package mcworld
+
+import (
+	"errors"
+	"github.com/quag/mcobj/nbt"
+)
+
+// IndevWorld reads an Indev .mclevel file, a gzipped NBT file whose Map
+// struct holds the dimensions, blocks and spawn point of the level.
+type IndevWorld struct {
+	flatWorld
+}
+
+var IndevMapNotFound = errors.New("Indev 'Map' struct not found")
+
+func NewIndevWorld(fs FileSystem, path string) *IndevWorld {
+	return &IndevWorld{flatWorld{fs: fs, path: path, read: readIndevLevel}}
+}
+
+func readIndevLevel(fs FileSystem, path string) (*flatLevel, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var r, decompressErr = nbt.NewDecompressor(file)
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	defer r.Close()
+
+	var root, parseErr = nbt.Parse(r)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	var m, ok = root["Map"].(map[string]interface{})
+	if !ok {
+		return nil, IndevMapNotFound
+	}
+
+	var level = new(flatLevel)
+	level.width, _ = m["Width"].(int)
+	level.length, _ = m["Length"].(int)
+	level.height, _ = m["Height"].(int)
+	level.blocks, _ = m["Blocks"].([]byte)
+	level.data, _ = m["Data"].([]byte) // The high nibble is light
+
+	if spawn, ok := m["Spawn"].([]int); ok && len(spawn) == 3 {
+		level.spawnX, level.spawnY, level.spawnZ = spawn[0], spawn[1], spawn[2]
+	}
+
+	return level, level.check()
+}
@@ -0,0 +1,417 @@
+package mcworld
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A reader for the Java object serialization stream format, which is just
+// enough to pull the fields out of a Classic level (and step over the
+// entities and everything else saved with it).
+
+const (
+	javaStreamMagic   = 0xaced
+	javaStreamVersion = 5
+	javaBaseHandle    = 0x7e0000
+
+	tcNull           = 0x70
+	tcReference      = 0x71
+	tcClassDesc      = 0x72
+	tcObject         = 0x73
+	tcString         = 0x74
+	tcArray          = 0x75
+	tcClass          = 0x76
+	tcBlockData      = 0x77
+	tcEndBlockData   = 0x78
+	tcReset          = 0x79
+	tcBlockDataLong  = 0x7a
+	tcException      = 0x7b
+	tcLongString     = 0x7c
+	tcProxyClassDesc = 0x7d
+	tcEnum           = 0x7e
+
+	scWriteMethod    = 0x01
+	scBlockData      = 0x08
+	scSerializable   = 0x02
+	scExternalizable = 0x04
+)
+
+type javaClassDesc struct {
+	name   string
+	flags  byte
+	fields []javaField
+	super  *javaClassDesc
+}
+
+type javaField struct {
+	typeCode byte
+	name     string
+}
+
+// javaObject holds the fields of every class in the object's hierarchy.
+type javaObject struct {
+	className string
+	fields    map[string]interface{}
+}
+
+type javaEndBlockData struct{}
+
+type javaReader struct {
+	r       *bufio.Reader
+	handles []interface{}
+}
+
+// readJavaObject reads a serialization stream and returns its first object.
+func readJavaObject(r io.Reader) (*javaObject, error) {
+	var jr = &javaReader{r: bufio.NewReader(r)}
+
+	var header struct{ Magic, Version uint16 }
+	if err := binary.Read(jr.r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Magic != javaStreamMagic || header.Version != javaStreamVersion {
+		return nil, errors.New("Not a Java serialization stream")
+	}
+
+	var content, err = jr.readContent()
+	if err != nil {
+		return nil, err
+	}
+	var object, ok = content.(*javaObject)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Java stream holds %T not an object", content))
+	}
+	return object, nil
+}
+
+func (jr *javaReader) newHandle(value interface{}) int {
+	jr.handles = append(jr.handles, value)
+	return len(jr.handles) - 1
+}
+
+func (jr *javaReader) readUint8() (byte, error) {
+	return jr.r.ReadByte()
+}
+
+func (jr *javaReader) readInt(size int) (int64, error) {
+	var b = make([]byte, size)
+	if _, err := io.ReadFull(jr.r, b); err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	// Sign extend
+	var shift = uint(64 - 8*size)
+	return int64(u<<shift) >> shift, nil
+}
+
+func (jr *javaReader) readUtf(lengthSize int) (string, error) {
+	var length, err = jr.readInt(lengthSize)
+	if err != nil {
+		return "", err
+	}
+	if lengthSize == 2 {
+		length &= 0xffff
+	}
+	if length < 0 || length > math.MaxInt32 {
+		return "", errors.New("Bad Java string length")
+	}
+	var b = make([]byte, length)
+	if _, err := io.ReadFull(jr.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (jr *javaReader) readContent() (interface{}, error) {
+	var tc, err = jr.readUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tc {
+	case tcNull:
+		return nil, nil
+	case tcReference:
+		return jr.readReference()
+	case tcClassDesc, tcProxyClassDesc:
+		return jr.readNewClassDesc(tc)
+	case tcObject:
+		return jr.readNewObject()
+	case tcString, tcLongString:
+		var lengthSize = 2
+		if tc == tcLongString {
+			lengthSize = 8
+		}
+		var s, err = jr.readUtf(lengthSize)
+		if err != nil {
+			return nil, err
+		}
+		jr.newHandle(s)
+		return s, nil
+	case tcArray:
+		return jr.readNewArray()
+	case tcClass:
+		var desc, err = jr.readClassDesc()
+		if err != nil {
+			return nil, err
+		}
+		jr.newHandle(desc)
+		return desc, nil
+	case tcEnum:
+		var _, descErr = jr.readClassDesc()
+		if descErr != nil {
+			return nil, descErr
+		}
+		var handle = jr.newHandle(nil)
+		var name, err = jr.readContent()
+		jr.handles[handle] = name
+		return name, err
+	case tcBlockData, tcBlockDataLong:
+		var lengthSize = 1
+		if tc == tcBlockDataLong {
+			lengthSize = 4
+		}
+		var length, err = jr.readInt(lengthSize)
+		if err != nil {
+			return nil, err
+		}
+		if lengthSize == 1 {
+			length &= 0xff
+		}
+		if length < 0 {
+			return nil, errors.New("Bad Java block data length")
+		}
+		var b = make([]byte, length)
+		_, err = io.ReadFull(jr.r, b)
+		return b, err
+	case tcEndBlockData:
+		return javaEndBlockData{}, nil
+	case tcReset:
+		jr.handles = nil
+		return jr.readContent()
+	}
+
+	return nil, errors.New(fmt.Sprintf("Java stream type %#x not supported", tc))
+}
+
+func (jr *javaReader) readReference() (interface{}, error) {
+	var handle, err = jr.readInt(4)
+	if err != nil {
+		return nil, err
+	}
+	handle -= javaBaseHandle
+	if handle < 0 || int(handle) >= len(jr.handles) {
+		return nil, errors.New(fmt.Sprintf("Bad Java handle %d", handle))
+	}
+	return jr.handles[handle], nil
+}
+
+func (jr *javaReader) readClassDesc() (*javaClassDesc, error) {
+	var content, err = jr.readContent()
+	if err != nil || content == nil {
+		return nil, err
+	}
+	var desc, ok = content.(*javaClassDesc)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Java class description expected, not %T", content))
+	}
+	return desc, nil
+}
+
+func (jr *javaReader) readNewClassDesc(tc byte) (*javaClassDesc, error) {
+	var desc = new(javaClassDesc)
+
+	if tc == tcProxyClassDesc {
+		jr.newHandle(desc)
+		var count, err = jr.readInt(4)
+		if err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < count; i++ {
+			if _, err := jr.readUtf(2); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		var name, nameErr = jr.readUtf(2)
+		if nameErr != nil {
+			return nil, nameErr
+		}
+		desc.name = name
+		if _, err := jr.readInt(8); err != nil { // serialVersionUID
+			return nil, err
+		}
+		jr.newHandle(desc)
+
+		var flagsErr error
+		desc.flags, flagsErr = jr.readUint8()
+		if flagsErr != nil {
+			return nil, flagsErr
+		}
+
+		var count, countErr = jr.readInt(2)
+		if countErr != nil {
+			return nil, countErr
+		}
+		desc.fields = make([]javaField, count)
+		for i := range desc.fields {
+			var field = &desc.fields[i]
+			var err error
+			if field.typeCode, err = jr.readUint8(); err != nil {
+				return nil, err
+			}
+			if field.name, err = jr.readUtf(2); err != nil {
+				return nil, err
+			}
+			if field.typeCode == '[' || field.typeCode == 'L' {
+				if _, err = jr.readContent(); err != nil { // Field class name
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := jr.skipAnnotation(); err != nil {
+		return nil, err
+	}
+
+	var superErr error
+	desc.super, superErr = jr.readClassDesc()
+	return desc, superErr
+}
+
+func (jr *javaReader) skipAnnotation() error {
+	for {
+		var content, err = jr.readContent()
+		if err != nil {
+			return err
+		}
+		if _, end := content.(javaEndBlockData); end {
+			return nil
+		}
+	}
+}
+
+func (jr *javaReader) readNewObject() (*javaObject, error) {
+	var desc, descErr = jr.readClassDesc()
+	if descErr != nil {
+		return nil, descErr
+	}
+	if desc == nil {
+		return nil, errors.New("Java object without a class")
+	}
+
+	var object = &javaObject{desc.name, make(map[string]interface{})}
+	jr.newHandle(object)
+
+	// Class data is written from the topmost superclass down
+	var hierarchy []*javaClassDesc
+	for c := desc; c != nil; c = c.super {
+		hierarchy = append([]*javaClassDesc{c}, hierarchy...)
+	}
+
+	for _, c := range hierarchy {
+		switch {
+		case c.flags&scExternalizable != 0:
+			if c.flags&scBlockData == 0 {
+				return nil, errors.New(fmt.Sprintf("Old externalizable %s not supported", c.name))
+			}
+			if err := jr.skipAnnotation(); err != nil {
+				return nil, err
+			}
+		case c.flags&scSerializable != 0:
+			for _, field := range c.fields {
+				var value, err = jr.readFieldValue(field.typeCode)
+				if err != nil {
+					return nil, err
+				}
+				object.fields[field.name] = value
+			}
+			if c.flags&scWriteMethod != 0 {
+				if err := jr.skipAnnotation(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return object, nil
+}
+
+func (jr *javaReader) readNewArray() (interface{}, error) {
+	var desc, descErr = jr.readClassDesc()
+	if descErr != nil {
+		return nil, descErr
+	}
+	var handle = jr.newHandle(nil)
+
+	var length, lengthErr = jr.readInt(4)
+	if lengthErr != nil {
+		return nil, lengthErr
+	}
+	if desc == nil || len(desc.name) < 2 || length < 0 {
+		return nil, errors.New("Bad Java array")
+	}
+
+	var typeCode = desc.name[1]
+	if typeCode == 'B' {
+		var b = make([]byte, length)
+		if _, err := io.ReadFull(jr.r, b); err != nil {
+			return nil, err
+		}
+		jr.handles[handle] = b
+		return b, nil
+	}
+
+	var values = make([]interface{}, length)
+	jr.handles[handle] = values
+	for i := range values {
+		var value, err = jr.readFieldValue(typeCode)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// readFieldValue reads a field of the given type code. Integers are read
+// as int, and objects and arrays as whatever readContent gives.
+func (jr *javaReader) readFieldValue(typeCode byte) (interface{}, error) {
+	var size int
+	switch typeCode {
+	case 'B', 'Z':
+		size = 1
+	case 'C', 'S':
+		size = 2
+	case 'I', 'F':
+		size = 4
+	case 'J', 'D':
+		size = 8
+	case 'L', '[':
+		return jr.readContent()
+	default:
+		return nil, errors.New(fmt.Sprintf("Java field type %q not supported", typeCode))
+	}
+
+	var value, err = jr.readInt(size)
+	if err != nil {
+		return nil, err
+	}
+	switch typeCode {
+	case 'C':
+		return int(uint16(value)), nil
+	case 'F':
+		return math.Float32frombits(uint32(value)), nil
+	case 'D':
+		return math.Float64frombits(uint64(value)), nil
+	}
+	return int(value), nil
+}
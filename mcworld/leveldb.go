@@ -0,0 +1,485 @@
+package mcworld
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A read-only reader for the LevelDB databases Bedrock Edition keeps its
+// worlds in. It only supports what is needed to pull values out of a
+// database that no-one else is writing to: the sorted table files (.ldb or
+// .sst) and the write-ahead log files. Rather than replaying the MANIFEST,
+// every table and log in the directory is consulted and the entry with the
+// highest sequence number wins.
+//
+// Mojang's fork of LevelDB compresses blocks with zlib (type 2) or raw
+// deflate (type 4) rather than snappy.
+
+var (
+	LevelDBCorruptError = errors.New("LevelDB file is corrupt")
+)
+
+const (
+	levelDBTableMagic = 0xdb4775248b80fb57
+	levelDBFooterSize = 48
+
+	levelDBLogBlockSize = 32768
+
+	levelDBTypeDeletion = 0
+	levelDBTypeValue    = 1
+
+	levelDBMaxSequence = 1<<56 - 1
+)
+
+type levelDB struct {
+	fs     FileSystem
+	tables []*levelDBTable
+	log    map[string]levelDBEntry
+}
+
+type levelDBEntry struct {
+	sequence uint64
+	deleted  bool
+	value    []byte
+}
+
+type levelDBTable struct {
+	fs                FileSystem
+	path              string
+	index             []levelDBBlockIndex
+	smallest, largest []byte // user keys
+}
+
+type levelDBBlockIndex struct {
+	key    []byte // internal key at or after the last key in the block
+	handle levelDBBlockHandle
+}
+
+type levelDBBlockHandle struct {
+	offset, size uint64
+}
+
+func openLevelDB(fs FileSystem, dir string) (*levelDB, error) {
+	var names, readErr = fs.ReadDirNames(dir)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var db = &levelDB{fs: fs, log: make(map[string]levelDBEntry)}
+	for _, name := range names {
+		var path = filepath.Join(dir, name)
+		switch filepath.Ext(name) {
+		case ".ldb", ".sst":
+			var table, err = openLevelDBTable(fs, path)
+			if err != nil {
+				return nil, err
+			}
+			if table != nil {
+				db.tables = append(db.tables, table)
+			}
+		case ".log":
+			if !strings.HasPrefix(name, "LOG") {
+				var err = db.readLog(path)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return db, nil
+}
+
+// Get returns the newest value stored for key, or nil if there isn't one.
+func (db *levelDB) Get(key []byte) ([]byte, error) {
+	var best, found = db.log[string(key)]
+
+	for _, table := range db.tables {
+		if bytes.Compare(key, table.smallest) < 0 || bytes.Compare(key, table.largest) > 0 {
+			continue
+		}
+		var entry, ok, err = table.get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok && (!found || entry.sequence > best.sequence) {
+			best, found = entry, true
+		}
+	}
+
+	if !found || best.deleted {
+		return nil, nil
+	}
+	return best.value, nil
+}
+
+// Each calls fn with the newest version of every key in the database.
+// Values aren't kept while scanning, so fn only sees the keys.
+func (db *levelDB) Each(fn func(key []byte)) error {
+	var newest = make(map[string]levelDBEntry)
+	var keep = func(key []byte, sequence uint64, deleted bool) {
+		var current, present = newest[string(key)]
+		if !present || sequence > current.sequence {
+			newest[string(key)] = levelDBEntry{sequence, deleted, nil}
+		}
+	}
+
+	for key, entry := range db.log {
+		keep([]byte(key), entry.sequence, entry.deleted)
+	}
+
+	for _, table := range db.tables {
+		for _, block := range table.index {
+			var data, err = readLevelDBBlock(table.fs, table.path, block.handle)
+			if err != nil {
+				return err
+			}
+			err = eachLevelDBBlockEntry(data, func(internalKey, value []byte) {
+				var userKey, sequence, kind, ok = parseInternalKey(internalKey)
+				if ok {
+					keep(userKey, sequence, kind == levelDBTypeDeletion)
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, entry := range newest {
+		if !entry.deleted {
+			fn([]byte(key))
+		}
+	}
+	return nil
+}
+
+func openLevelDBTable(fs FileSystem, path string) (*levelDBTable, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var info, statErr = file.Stat()
+	if statErr != nil {
+		return nil, statErr
+	}
+	if info.Size() < levelDBFooterSize {
+		return nil, nil
+	}
+
+	var footer = make([]byte, levelDBFooterSize)
+	var _, readErr = file.ReadAt(footer, info.Size()-levelDBFooterSize)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if binary.LittleEndian.Uint64(footer[40:]) != levelDBTableMagic {
+		return nil, errors.New(fmt.Sprintf("%v: not a LevelDB table", path))
+	}
+
+	var rest = footer
+	var _, metaErr = readBlockHandle(&rest)
+	var indexHandle, indexErr = readBlockHandle(&rest)
+	if metaErr != nil || indexErr != nil {
+		return nil, LevelDBCorruptError
+	}
+
+	var indexData, blockErr = readLevelDBBlock(fs, path, indexHandle)
+	if blockErr != nil {
+		return nil, blockErr
+	}
+
+	var table = &levelDBTable{fs: fs, path: path}
+	var handleErr error
+	var eachErr = eachLevelDBBlockEntry(indexData, func(key, value []byte) {
+		var handle, err = readBlockHandle(&value)
+		if err != nil {
+			handleErr = err
+		}
+		table.index = append(table.index, levelDBBlockIndex{append([]byte(nil), key...), handle})
+	})
+	if eachErr != nil {
+		return nil, eachErr
+	}
+	if handleErr != nil {
+		return nil, handleErr
+	}
+	if len(table.index) == 0 {
+		return nil, nil
+	}
+
+	// The index only gives an upper bound for each block, so the smallest
+	// key comes from the start of the first block.
+	var first, firstErr = readLevelDBBlock(fs, path, table.index[0].handle)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	eachLevelDBBlockEntry(first, func(key, value []byte) {
+		if table.smallest == nil {
+			table.smallest, _, _, _ = parseInternalKey(append([]byte(nil), key...))
+		}
+	})
+	table.largest, _, _, _ = parseInternalKey(table.index[len(table.index)-1].key)
+
+	return table, nil
+}
+
+func (t *levelDBTable) get(key []byte) (levelDBEntry, bool, error) {
+	var target = makeInternalKey(key, levelDBMaxSequence, levelDBTypeValue)
+
+	var i = sort.Search(len(t.index), func(i int) bool {
+		return compareInternalKeys(t.index[i].key, target) >= 0
+	})
+	if i == len(t.index) {
+		return levelDBEntry{}, false, nil
+	}
+
+	var data, err = readLevelDBBlock(t.fs, t.path, t.index[i].handle)
+	if err != nil {
+		return levelDBEntry{}, false, err
+	}
+
+	var entry levelDBEntry
+	var found bool
+	err = eachLevelDBBlockEntry(data, func(internalKey, value []byte) {
+		var userKey, sequence, kind, ok = parseInternalKey(internalKey)
+		if ok && bytes.Equal(userKey, key) && (!found || sequence > entry.sequence) {
+			entry = levelDBEntry{sequence, kind == levelDBTypeDeletion, append([]byte(nil), value...)}
+			found = true
+		}
+	})
+	return entry, found, err
+}
+
+func readBlockHandle(b *[]byte) (levelDBBlockHandle, error) {
+	var offset, n1 = binary.Uvarint(*b)
+	if n1 <= 0 {
+		return levelDBBlockHandle{}, LevelDBCorruptError
+	}
+	var size, n2 = binary.Uvarint((*b)[n1:])
+	if n2 <= 0 {
+		return levelDBBlockHandle{}, LevelDBCorruptError
+	}
+	*b = (*b)[n1+n2:]
+	return levelDBBlockHandle{offset, size}, nil
+}
+
+func readLevelDBBlock(fs FileSystem, path string, handle levelDBBlockHandle) ([]byte, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var info, statErr = file.Stat()
+	if statErr != nil {
+		return nil, statErr
+	}
+	var size = uint64(info.Size())
+	if handle.offset > size || handle.size > size-handle.offset || size-handle.offset-handle.size < 5 {
+		return nil, LevelDBCorruptError
+	}
+
+	// Each block is followed by a compression type byte and a checksum
+	var raw = make([]byte, handle.size+5)
+	var _, readErr = file.ReadAt(raw, int64(handle.offset))
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var data = raw[:handle.size]
+	switch raw[handle.size] {
+	case 0:
+		return data, nil
+	case 2:
+		var r, err = zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	case 4:
+		var r = flate.NewReader(bytes.NewReader(data))
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+
+	return nil, errors.New(fmt.Sprintf("%v: unsupported LevelDB block compression %d", path, raw[handle.size]))
+}
+
+func eachLevelDBBlockEntry(data []byte, fn func(key, value []byte)) error {
+	if len(data) < 4 {
+		return LevelDBCorruptError
+	}
+	var restarts = int(binary.LittleEndian.Uint32(data[len(data)-4:]))
+	var end = len(data) - 4 - 4*restarts
+	if end < 0 {
+		return LevelDBCorruptError
+	}
+
+	var key []byte
+	for pos := 0; pos < end; {
+		var lengths [3]uint64
+		for i := range lengths {
+			var length, n = binary.Uvarint(data[pos:end])
+			if n <= 0 {
+				return LevelDBCorruptError
+			}
+			lengths[i] = length
+			pos += n
+		}
+		var shared, unshared, valueLength = lengths[0], lengths[1], lengths[2]
+
+		if shared > uint64(len(key)) || unshared > uint64(end-pos) || valueLength > uint64(end-pos)-unshared {
+			return LevelDBCorruptError
+		}
+		key = append(key[:shared], data[pos:pos+int(unshared)]...)
+		pos += int(unshared)
+		fn(key, data[pos:pos+int(valueLength)])
+		pos += int(valueLength)
+	}
+
+	return nil
+}
+
+// Internal keys are the user key followed by a little endian 64 bit number
+// holding the sequence number (high 56 bits) and the entry type.
+func parseInternalKey(internalKey []byte) (userKey []byte, sequence uint64, kind byte, ok bool) {
+	if len(internalKey) < 8 {
+		return nil, 0, 0, false
+	}
+	var n = len(internalKey) - 8
+	var tag = binary.LittleEndian.Uint64(internalKey[n:])
+	return internalKey[:n], tag >> 8, byte(tag), true
+}
+
+func makeInternalKey(userKey []byte, sequence uint64, kind byte) []byte {
+	var key = make([]byte, len(userKey)+8)
+	copy(key, userKey)
+	binary.LittleEndian.PutUint64(key[len(userKey):], sequence<<8|uint64(kind))
+	return key
+}
+
+// compareInternalKeys orders by user key, then newest (highest sequence)
+// first.
+func compareInternalKeys(a, b []byte) int {
+	var aKey, aSequence, _, _ = parseInternalKey(a)
+	var bKey, bSequence, _, _ = parseInternalKey(b)
+	if c := bytes.Compare(aKey, bKey); c != 0 {
+		return c
+	}
+	switch {
+	case aSequence > bSequence:
+		return -1
+	case aSequence < bSequence:
+		return 1
+	}
+	return 0
+}
+
+// readLog replays the write batches in a write-ahead log. Logs hold writes
+// that haven't been compacted into a table yet.
+func (db *levelDB) readLog(path string) error {
+	var file, openErr = db.fs.Open(path)
+	if openErr != nil {
+		return openErr
+	}
+	defer file.Close()
+
+	var data, err = ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	var record []byte
+	for block := 0; block < len(data); block += levelDBLogBlockSize {
+		var end = block + levelDBLogBlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		for pos := block; pos+7 <= end; {
+			var (
+				length     = int(binary.LittleEndian.Uint16(data[pos+4:]))
+				recordType = data[pos+6]
+			)
+			pos += 7
+			if recordType == 0 || pos+length > end {
+				break // Zero padding or a torn write at the end of the log
+			}
+
+			var fragment = data[pos : pos+length]
+			pos += length
+
+			switch recordType {
+			case 1: // Full
+				db.applyBatch(fragment)
+				record = nil
+			case 2: // First
+				record = append(record[:0], fragment...)
+			case 3: // Middle
+				record = append(record, fragment...)
+			case 4: // Last
+				record = append(record, fragment...)
+				db.applyBatch(record)
+				record = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (db *levelDB) applyBatch(batch []byte) {
+	if len(batch) < 12 {
+		return
+	}
+	var (
+		sequence = binary.LittleEndian.Uint64(batch)
+		count    = int(binary.LittleEndian.Uint32(batch[8:]))
+		rest     = batch[12:]
+	)
+
+	var readSlice = func() ([]byte, bool) {
+		var length, n = binary.Uvarint(rest)
+		if n <= 0 || length > uint64(len(rest)-n) {
+			return nil, false
+		}
+		var s = rest[n : n+int(length)]
+		rest = rest[n+int(length):]
+		return s, true
+	}
+
+	for i := 0; i < count && len(rest) != 0; i, sequence = i+1, sequence+1 {
+		var kind = rest[0]
+		rest = rest[1:]
+
+		var key, ok = readSlice()
+		if !ok {
+			return
+		}
+
+		var entry = levelDBEntry{sequence: sequence, deleted: kind == levelDBTypeDeletion}
+		if kind == levelDBTypeValue {
+			var value, ok = readSlice()
+			if !ok {
+				return
+			}
+			entry.value = append([]byte(nil), value...)
+		}
+
+		if current, present := db.log[string(key)]; !present || sequence > current.sequence {
+			db.log[string(key)] = entry
+		}
+	}
+}
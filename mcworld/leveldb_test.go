@@ -0,0 +1,100 @@
+package mcworld
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLevelDBApplyBatch(t *testing.T) {
+	var batch = make([]byte, 12)
+	binary.LittleEndian.PutUint64(batch, 7)
+	binary.LittleEndian.PutUint32(batch[8:], 2)
+	batch = append(batch, levelDBTypeValue, 1, 'a', 2, 'v', '1')
+	batch = append(batch, levelDBTypeDeletion, 1, 'b')
+
+	var db = &levelDB{log: make(map[string]levelDBEntry)}
+	db.applyBatch(batch)
+	if entry := db.log["a"]; entry.sequence != 7 || entry.deleted || string(entry.value) != "v1" {
+		t.Errorf("a: %+v", entry)
+	}
+	if entry := db.log["b"]; entry.sequence != 8 || !entry.deleted {
+		t.Errorf("b: %+v", entry)
+	}
+}
+
+func TestLevelDBApplyCorruptBatch(t *testing.T) {
+	var header = make([]byte, 12)
+	binary.LittleEndian.PutUint32(header[8:], 1)
+
+	var lengths = []uint64{1 << 63, 1<<64 - 1, 100}
+	for _, length := range lengths {
+		var batch = append([]byte(nil), header...)
+		batch = append(batch, levelDBTypeValue)
+		batch = append(batch, make([]byte, binary.MaxVarintLen64)...)
+		var n = binary.PutUvarint(batch[13:], length)
+		batch = append(batch[:13+n], 'k')
+
+		var db = &levelDB{log: make(map[string]levelDBEntry)}
+		db.applyBatch(batch)
+		if len(db.log) != 0 {
+			t.Errorf("length %d: got %v", length, db.log)
+		}
+	}
+}
+
+func TestLevelDBBlockEntries(t *testing.T) {
+	// Two entries, the second sharing a byte of the first's key, and one
+	// restart point
+	var block = []byte{0, 2, 1, 'a', 'b', '1', 1, 1, 1, 'c', '2', 0, 0, 0, 0, 1, 0, 0, 0}
+	var entries []string
+	var err = eachLevelDBBlockEntry(block, func(key, value []byte) {
+		entries = append(entries, string(key)+"="+string(value))
+	})
+	if err != nil || len(entries) != 2 || entries[0] != "ab=1" || entries[1] != "ac=2" {
+		t.Errorf("got %v, %v", entries, err)
+	}
+}
+
+func TestLevelDBCorruptBlockEntries(t *testing.T) {
+	var blocks = [][]byte{
+		// A varint that runs past ten bytes
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0},
+		// An unshared length of 2^64-1
+		{0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 0, 0, 0, 0, 0},
+		// A value length of 2^63
+		{0, 1, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 1, 'k', 0, 0, 0, 0},
+		// A varint cut off by the restart points
+		{0, 0x80, 0, 0, 0, 0},
+	}
+	for i, block := range blocks {
+		var err = eachLevelDBBlockEntry(block, func(key, value []byte) {})
+		if err != LevelDBCorruptError {
+			t.Errorf("block %d: got %v", i, err)
+		}
+	}
+}
+
+func TestLevelDBBlockPastEnd(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "leveldb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var path = filepath.Join(dir, "000001.ldb")
+	if err := ioutil.WriteFile(path, make([]byte, 64), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var handles = []levelDBBlockHandle{{0, 1 << 40}, {1 << 40, 1}, {60, 0}, {0, 1<<64 - 1}}
+	for _, handle := range handles {
+		if _, err := readLevelDBBlock(osFileSystem{}, path, handle); err != LevelDBCorruptError {
+			t.Errorf("%+v: got %v", handle, err)
+		}
+	}
+	if _, err := readLevelDBBlock(osFileSystem{}, path, levelDBBlockHandle{0, 59}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,128 @@
+package mcworld
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Linear region files, written by some servers in place of Anvil, hold all
+// 1024 chunks of a region as one zstd compressed stream:
+//
+//	int64  signature
+//	int8   version
+//	int64  newest timestamp
+//	int8   compression level
+//	int16  chunk count
+//	int32  compressed length
+//	int64  reserved
+//	       compressed data
+//	int64  signature
+//
+// The data starts with the size and timestamp of each chunk followed by
+// the uncompressed NBT of the chunks, one after another.
+
+const (
+	linearSignature  = 0xc3ff13183cca9d9a
+	linearHeaderSize = 32
+)
+
+// openRegionFile opens a region file, reading Linear files as the Anvil
+// region they'd be, so everything else handles one format.
+func openRegionFile(fs FileSystem, path string) (File, error) {
+	if !strings.HasSuffix(path, ".linear") {
+		return fs.Open(path)
+	}
+
+	var file, err = fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var info, statErr = file.Stat()
+	if statErr != nil {
+		return nil, statErr
+	}
+	var data = make([]byte, info.Size())
+	if _, err := file.ReadAt(data, 0); err != nil {
+		return nil, err
+	}
+
+	var region, convertErr = linearToAnvil(data)
+	if convertErr != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", path, convertErr))
+	}
+	return &memoryFile{bytes.NewReader(region), info}, nil
+}
+
+// linearToAnvil decompresses a Linear file and lays its chunks out as an
+// Anvil region.
+func linearToAnvil(data []byte) ([]byte, error) {
+	if len(data) < linearHeaderSize+8 ||
+		binary.BigEndian.Uint64(data) != linearSignature ||
+		binary.BigEndian.Uint64(data[len(data)-8:]) != linearSignature {
+		return nil, errors.New("Not a Linear region file")
+	}
+	if version := data[8]; version != 1 {
+		return nil, errors.New(fmt.Sprintf("Linear version %d isn't supported", version))
+	}
+	var compressedLength = int(binary.BigEndian.Uint32(data[20:]))
+	if compressedLength > len(data)-linearHeaderSize-8 {
+		return nil, errors.New("Linear region file cut short")
+	}
+
+	var raw, err = zstdDecompress(data[linearHeaderSize : linearHeaderSize+compressedLength])
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 1024*8 {
+		return nil, errors.New("Linear chunk table cut short")
+	}
+
+	var (
+		region = make([]byte, 2*4096, 2*4096+len(raw))
+		chunks = raw[1024*8:]
+	)
+	for i := 0; i < 1024; i++ {
+		var size = int(binary.BigEndian.Uint32(raw[i*8:]))
+		var timestamp = binary.BigEndian.Uint32(raw[i*8+4:])
+		if size == 0 {
+			continue
+		}
+		if size > len(chunks) {
+			return nil, errors.New(fmt.Sprintf("Linear chunk %d cut short", i))
+		}
+		var chunk = chunks[:size]
+		chunks = chunks[size:]
+
+		// Chunks too big for a region slot are compressed, as Anvil would
+		var compressionType = byte(compressionUncompressed)
+		if 5+len(chunk) > 255*4096 {
+			var compressed bytes.Buffer
+			var zw = zlib.NewWriter(&compressed)
+			zw.Write(chunk)
+			zw.Close()
+			if 5+compressed.Len() > 255*4096 {
+				return nil, errors.New(fmt.Sprintf("Linear chunk %d is too big", i))
+			}
+			chunk = compressed.Bytes()
+			compressionType = compressionZlib
+		}
+
+		var sector = len(region) / 4096
+		var sectors = (5 + len(chunk) + 4095) / 4096
+		var start = len(region)
+		region = append(region, make([]byte, sectors*4096)...)
+		binary.BigEndian.PutUint32(region[start:], uint32(len(chunk)+1))
+		region[start+4] = compressionType
+		copy(region[start+5:], chunk)
+
+		binary.BigEndian.PutUint32(region[i*4:], uint32(sector<<8|sectors))
+		binary.BigEndian.PutUint32(region[4096+i*4:], timestamp)
+	}
+	return region, nil
+}
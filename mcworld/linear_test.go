@@ -0,0 +1,98 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinearRegion(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "linear")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var regionDir = filepath.Join(dir, "region")
+	os.Mkdir(regionDir, 0755)
+	ioutil.WriteFile(filepath.Join(regionDir, "r.-1.0.linear"), testLinearRegion(31, 2), 0644)
+
+	var world, openErr = OpenWorld(dir)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer world.(*BetaWorld).Close()
+
+	var pool, poolErr = world.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Errorf("%d chunks pooled, not just -1,2", pool.Remaining())
+	}
+	if reports := pool.(RegionReporter).Reports(); len(reports) != 0 {
+		t.Errorf("region reports %v", reports)
+	}
+
+	var r, chunkErr = world.OpenChunk(-1, 2)
+	if chunkErr != nil {
+		t.Fatal(chunkErr)
+	}
+	r.Close()
+
+	if format := Describe(world); format != "Linear" {
+		t.Errorf("format %q not Linear", format)
+	}
+}
+
+func TestLinearRegionCorrupt(t *testing.T) {
+	var region = testLinearRegion(0, 0)
+	if _, err := linearToAnvil(region[:len(region)-1]); err == nil {
+		t.Errorf("no error for a missing footer")
+	}
+
+	region[8] = 2
+	if _, err := linearToAnvil(region); err == nil {
+		t.Errorf("no error for version 2")
+	}
+}
+
+// testLinearRegion makes a Linear region file holding the one chunk at x,z
+// within the region, stored in a zstd frame of one uncompressed block.
+func testLinearRegion(x, z int) []byte {
+	var chunk = new(chunkNbtWriter)
+	chunk.beginStruct("")
+	chunk.beginStruct("Level")
+	chunk.int32("xPos", x-32)
+	chunk.int32("zPos", z)
+	chunk.endStruct()
+	chunk.endStruct()
+
+	var raw = make([]byte, 1024*8)
+	binary.BigEndian.PutUint32(raw[8*(x+z*32):], uint32(chunk.Len()))
+	binary.BigEndian.PutUint32(raw[8*(x+z*32)+4:], 1600000000)
+	raw = append(raw, chunk.Bytes()...)
+
+	var frame bytes.Buffer
+	binary.Write(&frame, binary.LittleEndian, uint32(zstdMagic))
+	frame.WriteByte(0xa0) // Single segment with a 4 byte content size
+	binary.Write(&frame, binary.LittleEndian, uint32(len(raw)))
+	var blockHeader = uint32(len(raw))<<3 | 1 // Last block, uncompressed
+	frame.Write([]byte{byte(blockHeader), byte(blockHeader >> 8), byte(blockHeader >> 16)})
+	frame.Write(raw)
+
+	var file bytes.Buffer
+	binary.Write(&file, binary.BigEndian, uint64(linearSignature))
+	file.WriteByte(1)
+	binary.Write(&file, binary.BigEndian, int64(1600000000))
+	file.WriteByte(6)
+	binary.Write(&file, binary.BigEndian, int16(1))
+	binary.Write(&file, binary.BigEndian, int32(frame.Len()))
+	binary.Write(&file, binary.BigEndian, int64(0))
+	file.Write(frame.Bytes())
+	binary.Write(&file, binary.BigEndian, uint64(linearSignature))
+	return file.Bytes()
+}
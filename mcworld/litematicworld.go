@@ -0,0 +1,178 @@
+package mcworld
+
+import (
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"sort"
+)
+
+// LitematicWorld reads a Litematica .litematic file. It holds any number
+// of named regions, each with a position, a size (negative along an axis
+// the selection was made in reverse), a block state palette and the
+// palette indexes packed into longs, end to end across long boundaries.
+// Every region is pasted into one level at its place in the whole.
+type LitematicWorld struct {
+	flatWorld
+}
+
+func NewLitematicWorld(fs FileSystem, path string) *LitematicWorld {
+	return &LitematicWorld{flatWorld{fs: fs, path: path, read: readLitematicLevel}}
+}
+
+type litematicRegion struct {
+	x, y, z             int // Minimum corner
+	sizeX, sizeY, sizeZ int
+	palette             []nbt.Block
+	states              []int64
+}
+
+func readLitematicLevel(fs FileSystem, path string) (*flatLevel, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var r, decompressErr = nbt.NewDecompressor(file)
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	defer r.Close()
+
+	var root, parseErr = nbt.Parse(r)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	var regionStructs, _ = root["Regions"].(map[string]interface{})
+	if len(regionStructs) == 0 {
+		return nil, errors.New("Litematic has no regions")
+	}
+
+	var (
+		regions = make([]*litematicRegion, 0, len(regionStructs))
+		box     = [6]int{} // Min and max corners of every region
+	)
+	// Regions overlapping each other are pasted in name order
+	var names = make([]string, 0, len(regionStructs))
+	for name := range regionStructs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var region, err = readLitematicRegion(regionStructs[name])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Litematic region %q: %v", name, err))
+		}
+
+		var corners = [6]int{region.x, region.y, region.z, region.x + region.sizeX, region.y + region.sizeY, region.z + region.sizeZ}
+		if len(regions) == 0 {
+			box = corners
+		}
+		for i := 0; i < 3; i++ {
+			if corners[i] < box[i] {
+				box[i] = corners[i]
+			}
+			if corners[i+3] > box[i+3] {
+				box[i+3] = corners[i+3]
+			}
+		}
+		regions = append(regions, region)
+	}
+
+	var level = new(flatLevel)
+	level.width, level.height, level.length = box[3]-box[0], box[4]-box[1], box[5]-box[2]
+	var blocks = make([]nbt.Block, level.width*level.height*level.length)
+	for _, region := range regions {
+		region.paste(blocks, region.x-box[0], region.y-box[1], region.z-box[2], level.width, level.length)
+	}
+	level.setBlocks(blocks)
+	level.spawnX, level.spawnY, level.spawnZ = level.width/2, level.height, level.length/2
+
+	return level, level.check()
+}
+
+func readLitematicRegion(value interface{}) (*litematicRegion, error) {
+	var s, ok = value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a struct")
+	}
+
+	var region = new(litematicRegion)
+	var (
+		positionStruct, _ = s["Position"].(map[string]interface{})
+		sizeStruct, _     = s["Size"].(map[string]interface{})
+		position, size    [3]int
+	)
+	for i, axis := range []string{"x", "y", "z"} {
+		position[i], _ = positionStruct[axis].(int)
+		size[i], _ = sizeStruct[axis].(int)
+
+		// A negative size runs back from the position
+		if size[i] < 0 {
+			size[i] = -size[i]
+			position[i] -= size[i] - 1
+		}
+	}
+	region.x, region.y, region.z = position[0], position[1], position[2]
+	region.sizeX, region.sizeY, region.sizeZ = size[0], size[1], size[2]
+
+	var palette, _ = s["BlockStatePalette"].([]interface{})
+	region.palette = make([]nbt.Block, len(palette))
+	for i, entry := range palette {
+		var state, _ = entry.(map[string]interface{})
+		var name, _ = state["Name"].(string)
+		var properties, _ = state["Properties"].(map[string]interface{})
+		region.palette[i] = nbt.BlockState(name, properties)
+	}
+	region.states, _ = s["BlockStates"].([]int64)
+
+	var count = region.sizeX * region.sizeY * region.sizeZ
+	if count != 0 && len(region.states)*64 < count*region.bits() {
+		return nil, errors.New(fmt.Sprintf("%d longs can't hold %d blocks", len(region.states), count))
+	}
+	return region, nil
+}
+
+// bits is how many bits each palette index takes, which is never fewer
+// than two.
+func (region *litematicRegion) bits() int {
+	var bits = 2
+	for 1<<uint(bits) < len(region.palette) {
+		bits++
+	}
+	return bits
+}
+
+// paste copies the region's blocks (other than air) into a YZX ordered
+// level of width and length, with the region's corner at x,y,z.
+func (region *litematicRegion) paste(blocks []nbt.Block, x, y, z, width, length int) {
+	var (
+		bits = region.bits()
+		mask = uint64(1)<<uint(bits) - 1
+	)
+	for i := 0; i < region.sizeX*region.sizeY*region.sizeZ; i++ {
+		var (
+			bit    = i * bits
+			word   = bit / 64
+			offset = uint(bit % 64)
+			index  = uint64(region.states[word]) >> offset
+		)
+		if int(offset)+bits > 64 {
+			index |= uint64(region.states[word+1]) << (64 - offset)
+		}
+		index &= mask
+		if int(index) >= len(region.palette) || region.palette[index] == 0 {
+			continue
+		}
+
+		var (
+			bx = i % region.sizeX
+			bz = (i / region.sizeX) % region.sizeZ
+			by = i / (region.sizeX * region.sizeZ)
+		)
+		blocks[((y+by)*length+z+bz)*width+x+bx] = region.palette[index]
+	}
+}
@@ -0,0 +1,83 @@
+package mcworld
+
+import (
+	"time"
+)
+
+// AndChunkMask unmasks only the chunks that every one of its masks unmasks.
+type AndChunkMask []ChunkMask
+
+func (m AndChunkMask) IsMasked(x, z int) bool {
+	for _, mask := range m {
+		if mask.IsMasked(x, z) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m AndChunkMask) IsMaskedModified(x, z int, modified time.Time) bool {
+	for _, mask := range m {
+		if isMaskedModified(mask, x, z, modified) {
+			return true
+		}
+	}
+	return false
+}
+
+// OrChunkMask unmasks the chunks that any of its masks unmasks.
+type OrChunkMask []ChunkMask
+
+func (m OrChunkMask) IsMasked(x, z int) bool {
+	for _, mask := range m {
+		if !mask.IsMasked(x, z) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m OrChunkMask) IsMaskedModified(x, z int, modified time.Time) bool {
+	for _, mask := range m {
+		if !isMaskedModified(mask, x, z, modified) {
+			return false
+		}
+	}
+	return true
+}
+
+// NotChunkMask unmasks the chunks that Mask masks, and the other way round.
+type NotChunkMask struct {
+	Mask ChunkMask
+}
+
+func (m *NotChunkMask) IsMasked(x, z int) bool {
+	return !m.Mask.IsMasked(x, z)
+}
+
+func (m *NotChunkMask) IsMaskedModified(x, z int, modified time.Time) bool {
+	return !isMaskedModified(m.Mask, x, z, modified)
+}
+
+// DifferenceChunkMask unmasks the chunks that Mask unmasks, less the ones
+// that Minus unmasks.
+type DifferenceChunkMask struct {
+	Mask, Minus ChunkMask
+}
+
+func (m *DifferenceChunkMask) IsMasked(x, z int) bool {
+	return m.Mask.IsMasked(x, z) || !m.Minus.IsMasked(x, z)
+}
+
+func (m *DifferenceChunkMask) IsMaskedModified(x, z int, modified time.Time) bool {
+	return isMaskedModified(m.Mask, x, z, modified) || !isMaskedModified(m.Minus, x, z, modified)
+}
+
+// isMaskedModified passes the save time on to masks that use it, so that
+// combining a ModifiedSinceMask keeps it working.
+func isMaskedModified(mask ChunkMask, x, z int, modified time.Time) bool {
+	if m, ok := mask.(ModifiedChunkMask); ok {
+		return m.IsMaskedModified(x, z, modified)
+	}
+	return mask.IsMasked(x, z)
+}
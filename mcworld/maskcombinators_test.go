@@ -0,0 +1,53 @@
+package mcworld
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaskCombinators(t *testing.T) {
+	var box = &RectangleChunkMask{-4, -4, 4, 4}
+	var circle = &CircleChunkMask{0, 0, 2}
+	var corner = &RectangleChunkMask{3, 3, 10, 10}
+
+	for _, test := range []struct {
+		name   string
+		mask   ChunkMask
+		x, z   int
+		masked bool
+	}{
+		{"and", AndChunkMask{box, corner}, 3, 3, false},
+		{"and", AndChunkMask{box, corner}, 4, 4, true},
+		{"and", AndChunkMask{box, corner}, 0, 0, true},
+		{"or", OrChunkMask{box, corner}, 0, 0, false},
+		{"or", OrChunkMask{box, corner}, 9, 9, false},
+		{"or", OrChunkMask{box, corner}, -5, 0, true},
+		{"not", &NotChunkMask{circle}, 0, 0, true},
+		{"not", &NotChunkMask{circle}, 3, 0, false},
+		{"difference", &DifferenceChunkMask{box, circle}, 0, 0, true},
+		{"difference", &DifferenceChunkMask{box, circle}, -3, -3, false},
+		{"difference", &DifferenceChunkMask{box, circle}, 5, 0, true},
+		{"empty and", AndChunkMask{}, 0, 0, false},
+		{"empty or", OrChunkMask{}, 0, 0, true},
+	} {
+		if test.mask.IsMasked(test.x, test.z) != test.masked {
+			t.Errorf("%s: chunk %d,%d masked is %v", test.name, test.x, test.z, !test.masked)
+		}
+	}
+}
+
+func TestMaskCombinatorsModified(t *testing.T) {
+	var since = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var changed = &ModifiedSinceMask{&AllChunksMask{}, since}
+	var mask ModifiedChunkMask = AndChunkMask{&RectangleChunkMask{0, 0, 1, 1}, changed}
+
+	if !mask.IsMaskedModified(0, 0, since.Add(-time.Hour)) {
+		t.Errorf("chunk saved before %v isn't masked", since)
+	}
+	if mask.IsMaskedModified(0, 0, since.Add(time.Hour)) {
+		t.Errorf("chunk saved after %v is masked", since)
+	}
+	if !(&NotChunkMask{changed}).IsMaskedModified(0, 0, since.Add(time.Hour)) {
+		t.Errorf("inverted chunk saved after %v isn't masked", since)
+	}
+}
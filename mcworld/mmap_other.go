@@ -0,0 +1,17 @@
+//go:build !unix
+
+package mcworld
+
+import (
+	"errors"
+	"os"
+)
+
+// Without mmap, files are opened as usual.
+func mapFile(file *os.File, size int) ([]byte, error) {
+	return nil, errors.New("mmap isn't supported")
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package mcworld
+
+import (
+	"bytes"
+	"os"
+)
+
+// mmapFileSystem is the local disk with files memory mapped as they're
+// opened, so reading chunks needs no system calls and the pages are shared
+// with the OS cache. Folders, empty files and files that can't be mapped
+// are opened as usual.
+type mmapFileSystem struct {
+	osFileSystem
+}
+
+// mappedFileSystem maps the files of fs if it's the local disk.
+func mappedFileSystem(fs FileSystem) FileSystem {
+	if _, ok := fs.(osFileSystem); ok {
+		return mmapFileSystem{}
+	}
+	return fs
+}
+
+func (fs mmapFileSystem) Open(name string) (File, error) {
+	var file, err = os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var info, statErr = file.Stat()
+	if statErr != nil || info.IsDir() || info.Size() == 0 || int64(int(info.Size())) != info.Size() {
+		return file, nil
+	}
+
+	var data, mapErr = mapFile(file, int(info.Size()))
+	if mapErr != nil {
+		return file, nil
+	}
+	file.Close()
+	return &mappedFile{memoryFile{bytes.NewReader(data), info}, data}, nil
+}
+
+// mappedFile is a memory mapped file, which is unmapped on Close.
+type mappedFile struct {
+	memoryFile
+	data []byte
+}
+
+func (f *mappedFile) Close() error {
+	if f.data == nil {
+		return nil
+	}
+	var data = f.data
+	f.data = nil
+	f.Reader = bytes.NewReader(nil)
+	return unmapFile(data)
+}
@@ -0,0 +1,52 @@
+package mcworld
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMappedWorld(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "mmapfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mca"), testRegion(31, 2), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.0.0.mca"), nil, 0644)
+
+	var world, openErr = OpenMappedDimension(dir, "")
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	var beta = world.(*BetaWorld)
+	defer beta.Close()
+	if _, ok := beta.fs.(mmapFileSystem); !ok {
+		t.Errorf("%T isn't mapped", beta.fs)
+	}
+
+	var pool, poolErr = world.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Errorf("%d chunks pooled, not just -1,2", pool.Remaining())
+	}
+
+	var r, chunkErr = world.OpenChunk(-1, 2)
+	if chunkErr != nil {
+		t.Fatal(chunkErr)
+	}
+	var chunk, nbtErr = nbt.ReadChunkNbt(r)
+	r.Close()
+	if nbtErr != nil {
+		t.Fatal(nbtErr)
+	}
+	if chunk.XPos != -1 || chunk.ZPos != 2 {
+		t.Errorf("chunk %d,%d not -1,2", chunk.XPos, chunk.ZPos)
+	}
+}
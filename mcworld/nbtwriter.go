@@ -0,0 +1,80 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/quag/mcobj/nbt"
+)
+
+// chunkNbtWriter is the least amount of big endian NBT encoding needed to
+// hand chunks from other formats to nbt.ReadChunkNbt.
+type chunkNbtWriter struct {
+	bytes.Buffer
+}
+
+func (w *chunkNbtWriter) tag(typeId nbt.TypeId, name string) {
+	w.WriteByte(byte(typeId))
+	binary.Write(w, binary.BigEndian, uint16(len(name)))
+	w.WriteString(name)
+}
+
+func (w *chunkNbtWriter) beginStruct(name string) {
+	w.tag(nbt.TagStruct, name)
+}
+
+func (w *chunkNbtWriter) endStruct() {
+	w.WriteByte(byte(nbt.TagStructEnd))
+}
+
+func (w *chunkNbtWriter) int8(name string, value int) {
+	w.tag(nbt.TagInt8, name)
+	w.WriteByte(byte(value))
+}
+
+func (w *chunkNbtWriter) int16(name string, value int) {
+	w.tag(nbt.TagInt16, name)
+	binary.Write(w, binary.BigEndian, int16(value))
+}
+
+func (w *chunkNbtWriter) int32(name string, value int) {
+	w.tag(nbt.TagInt32, name)
+	binary.Write(w, binary.BigEndian, int32(value))
+}
+
+func (w *chunkNbtWriter) string(name, value string) {
+	w.tag(nbt.TagString, name)
+	binary.Write(w, binary.BigEndian, uint16(len(value)))
+	w.WriteString(value)
+}
+
+func (w *chunkNbtWriter) byteArray(name string, values []byte) {
+	w.tag(nbt.TagByteArray, name)
+	binary.Write(w, binary.BigEndian, int32(len(values)))
+	w.Write(values)
+}
+
+func (w *chunkNbtWriter) longArray(name string, values []int64) {
+	w.tag(nbt.TagLongArray, name)
+	binary.Write(w, binary.BigEndian, int32(len(values)))
+	binary.Write(w, binary.BigEndian, values)
+}
+
+func (w *chunkNbtWriter) int32List(name string, values []int) {
+	w.tag(nbt.TagList, name)
+	w.WriteByte(byte(nbt.TagInt32))
+	binary.Write(w, binary.BigEndian, int32(len(values)))
+	for _, value := range values {
+		binary.Write(w, binary.BigEndian, int32(value))
+	}
+}
+
+// structList writes a list of structs, each already encoded as a struct
+// named "", whose header is dropped as list items are unnamed.
+func (w *chunkNbtWriter) structList(name string, items [][]byte) {
+	w.tag(nbt.TagList, name)
+	w.WriteByte(byte(nbt.TagStruct))
+	binary.Write(w, binary.BigEndian, int32(len(items)))
+	for _, item := range items {
+		w.Write(item[3:])
+	}
+}
@@ -0,0 +1,97 @@
+package mcworld
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"io"
+	"sync"
+)
+
+// OverlayWorld merges several worlds into one, with each chunk read from
+// the last world that has it. The spawn point and such come from the
+// first world.
+type OverlayWorld struct {
+	worlds []World
+
+	mutex  sync.Mutex
+	owners map[uint64]int // Which world each pooled chunk is read from
+}
+
+func NewOverlayWorld(worlds ...World) *OverlayWorld {
+	return &OverlayWorld{worlds: worlds}
+}
+
+func (w *OverlayWorld) Level() (*nbt.Level, error) {
+	return w.worlds[0].Level()
+}
+
+// OpenChunk reads a pooled chunk from the world it was pooled from, and
+// any other chunk from the last world that can open it.
+func (w *OverlayWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
+	w.mutex.Lock()
+	var owner, owned = w.owners[betaChunkPoolKey(x, z)]
+	w.mutex.Unlock()
+	if owned {
+		return w.worlds[owner].OpenChunk(x, z)
+	}
+
+	var firstErr error
+	for i := len(w.worlds) - 1; i >= 0; i-- {
+		var r, err = w.worlds[i].OpenChunk(x, z)
+		if err == nil {
+			return r, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+func (w *OverlayWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
+	var (
+		pool   = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox(), nil}
+		owners = make(map[uint64]int)
+	)
+	for i, world := range w.worlds {
+		var chunks, err = IterateChunks(world, mask)
+		if err != nil {
+			return nil, err
+		}
+		for x, z, ok := chunks.Next(); ok; x, z, ok = chunks.Next() {
+			var key = betaChunkPoolKey(x, z)
+			pool.chunkMap[key] = true
+			pool.box.Union(x, z)
+			owners[key] = i
+		}
+		if reporter, ok := chunks.(RegionReporter); ok {
+			pool.reports = append(pool.reports, reporter.Reports()...)
+		}
+	}
+
+	w.mutex.Lock()
+	w.owners = owners
+	w.mutex.Unlock()
+	return pool, nil
+}
+
+// Close closes the worlds that hold files open.
+// SetRegionIndex shares the index between the worlds that can use one.
+func (w *OverlayWorld) SetRegionIndex(index *RegionIndex) {
+	for _, world := range w.worlds {
+		if indexer, ok := world.(RegionIndexer); ok {
+			indexer.SetRegionIndex(index)
+		}
+	}
+}
+
+func (w *OverlayWorld) Close() error {
+	var firstErr error
+	for _, world := range w.worlds {
+		if closer, ok := world.(io.Closer); ok {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,45 @@
+package mcworld
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"testing"
+)
+
+func TestOverlayWorld(t *testing.T) {
+	// Terrain of two chunks of air, with a chunk of stone on top of 0,0
+	var terrain = NewClassicWorld(osFileSystem{}, "terrain")
+	terrain.level = &flatLevel{width: 32, length: 16, height: 1, blocks: make([]byte, 32*16)}
+	var build = NewClassicWorld(osFileSystem{}, "build")
+	build.level = &flatLevel{width: 16, length: 16, height: 1, blocks: make([]byte, 16*16), spawnX: 99}
+	for i := range build.level.blocks {
+		build.level.blocks[i] = 1
+	}
+
+	var world = NewOverlayWorld(terrain, build)
+	var pool, err = world.ChunkPool(&AllChunksMask{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Remaining() != 2 {
+		t.Errorf("%d chunks not 2", pool.Remaining())
+	}
+
+	for x, expected := range []nbt.Block{1, 0} {
+		var r, err = world.OpenChunk(x, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var chunk, chunkErr = nbt.ReadChunkNbt(r)
+		r.Close()
+		if chunkErr != nil {
+			t.Fatal(chunkErr)
+		}
+		if chunk.Blocks[0] != expected {
+			t.Errorf("chunk %d,0 is made of %d not %d", x, chunk.Blocks[0], expected)
+		}
+	}
+
+	if level, err := world.Level(); err != nil || level.SpawnX != 0 {
+		t.Errorf("level %v, %v isn't the terrain's", level, err)
+	}
+}
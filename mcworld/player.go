@@ -0,0 +1,39 @@
+package mcworld
+
+import (
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"path/filepath"
+)
+
+// ReadPlayer reads where a player of a Java world is. player is the UUID
+// of a playerdata file (or a name from players, before 1.7), or "" for the
+// player of a single player world, who is kept in level.dat.
+func ReadPlayer(worldDir, player string) (*nbt.Player, error) {
+	var fs, dir, fsErr = openFileSystem(worldDir)
+	if fsErr != nil {
+		return nil, fsErr
+	}
+
+	if player == "" {
+		var level, err = readJavaLevel(fs, dir)
+		if err != nil {
+			return nil, err
+		}
+		if level.Player == nil {
+			return nil, errors.New(fmt.Sprintf("%s has no single player", worldDir))
+		}
+		return level.Player, nil
+	}
+
+	for _, folder := range []string{"playerdata", "players"} {
+		var file, err = fs.Open(filepath.Join(dir, folder, player+".dat"))
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+		return nbt.ReadPlayerDat(file)
+	}
+	return nil, errors.New(fmt.Sprintf("Player %s not found in %s", player, worldDir))
+}
@@ -0,0 +1,106 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// PocketWorld reads the worlds of Pocket Edition before 0.9, which keep
+// every chunk of their 256x256 block world uncompressed in chunks.dat.
+//
+// chunks.dat starts with a 4KB location table like a region file's, but
+// little endian: the low byte of each entry is the chunk's length in 4KB
+// sectors and the rest its first sector. Each chunk is a length, then 128
+// high columns of block ids, data, sky light and block light in the XZY
+// order of McRegion chunks, then a dirty flag for each column.
+type PocketWorld struct {
+	fs       FileSystem
+	worldDir string
+}
+
+const (
+	pocketChunkHeight = 128
+	pocketChunkBlocks = 16 * 16 * pocketChunkHeight
+	pocketWorldChunks = 16 // Chunks across the world
+)
+
+func (w *PocketWorld) OpenChunk(x, z int) (io.ReadCloser, error) {
+	if x < 0 || z < 0 || x >= 32 || z >= 32 {
+		return nil, ChunkNotFoundError
+	}
+
+	var file, err = w.fs.Open(filepath.Join(w.worldDir, "chunks.dat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entry [4]byte
+	if _, err := file.ReadAt(entry[:], int64(4*(x+z*32))); err != nil {
+		return nil, err
+	}
+	var location = binary.LittleEndian.Uint32(entry[:])
+	if location == 0 {
+		return nil, ChunkNotFoundError
+	}
+	if location>>8 == 0 {
+		return nil, errors.New(fmt.Sprintf("Chunk corrupt: %v,%v in chunks.dat has location %#x", x, z, location))
+	}
+
+	// Skip the length, which is always the same
+	var data = make([]byte, pocketChunkBlocks+pocketChunkBlocks/2)
+	if _, err := file.ReadAt(data, int64(location>>8)*4096+4); err != nil {
+		return nil, err
+	}
+
+	var chunk chunkNbtWriter
+	chunk.beginStruct("")
+	chunk.beginStruct("Level")
+	chunk.int32("xPos", x)
+	chunk.int32("zPos", z)
+	chunk.byteArray("Blocks", data[:pocketChunkBlocks])
+	chunk.byteArray("Data", data[pocketChunkBlocks:])
+	chunk.endStruct()
+	chunk.endStruct()
+	return ioutil.NopCloser(bytes.NewReader(chunk.Bytes())), nil
+}
+
+func (w *PocketWorld) Level() (*nbt.Level, error) {
+	var file, err = w.fs.Open(filepath.Join(w.worldDir, "level.dat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return nbt.ReadBedrockLevelDat(file)
+}
+
+func (w *PocketWorld) ChunkPool(mask ChunkMask) (ChunkPool, error) {
+	var file, err = w.fs.Open(filepath.Join(w.worldDir, "chunks.dat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var locations [4096]byte
+	if _, err := io.ReadFull(file, locations[:]); err != nil {
+		return nil, err
+	}
+
+	var pool = &BetaChunkPool{make(map[uint64]bool), EmptyBoundingBox(), nil}
+	for z := 0; z < pocketWorldChunks; z++ {
+		for x := 0; x < pocketWorldChunks; x++ {
+			var location = binary.LittleEndian.Uint32(locations[4*(x+z*32):])
+			if location>>8 != 0 && !mask.IsMasked(x, z) {
+				pool.chunkMap[betaChunkPoolKey(x, z)] = true
+				pool.box.Union(x, z)
+			}
+		}
+	}
+	return pool, nil
+}
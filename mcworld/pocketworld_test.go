@@ -0,0 +1,66 @@
+package mcworld
+
+import (
+	"encoding/binary"
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPocketWorld(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "pocketworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Chunk 3,5 in sectors 1 to 21, with wool (and orange data) at 1,2,0
+	var chunksDat = make([]byte, 22*4096)
+	binary.LittleEndian.PutUint32(chunksDat[4*(3+5*32):], 1<<8|21)
+	binary.LittleEndian.PutUint32(chunksDat[4096:], 82180)
+	var (
+		blocks = chunksDat[4096+4:]
+		i      = 2 + 128*(0+16*1)
+	)
+	blocks[i] = 35
+	blocks[pocketChunkBlocks+i/2] = 1
+	ioutil.WriteFile(filepath.Join(dir, "chunks.dat"), chunksDat, 0644)
+
+	var world, openErr = OpenWorld(dir)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	if _, ok := world.(*PocketWorld); !ok {
+		t.Fatalf("%T isn't a PocketWorld", world)
+	}
+
+	var pool, poolErr = world.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(3, 5) {
+		t.Errorf("%d chunks pooled, not just 3,5", pool.Remaining())
+	}
+
+	var r, chunkErr = world.OpenChunk(3, 5)
+	if chunkErr != nil {
+		t.Fatal(chunkErr)
+	}
+	var chunk, nbtErr = nbt.ReadChunkNbt(r)
+	r.Close()
+	if nbtErr != nil {
+		t.Fatal(nbtErr)
+	}
+	if chunk.XPos != 3 || chunk.ZPos != 5 || len(chunk.Blocks) != pocketChunkBlocks {
+		t.Fatalf("chunk %d,%d of %d blocks", chunk.XPos, chunk.ZPos, len(chunk.Blocks))
+	}
+	if block := chunk.Blocks[i]; block != 35|1<<8 {
+		t.Errorf("block at 1,2,0 is %d:%d not 35:1", block&0xff, block>>8)
+	}
+
+	if _, err := world.OpenChunk(4, 5); err != ChunkNotFoundError {
+		t.Errorf("missing chunk error %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package mcworld
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// PolygonChunkMask masks the chunks that no polygon overlaps, as well as
+// everything Mask masks. Each polygon is a list of rings of x,z block
+// coordinates, the first ring the outline and the rest holes.
+type PolygonChunkMask struct {
+	Mask     ChunkMask
+	Polygons [][][][2]float64
+}
+
+// LoadPolygonChunkMask reads the polygons of a GeoJSON file for a
+// PolygonChunkMask, with x as the first coordinate and z as the second.
+// The file can be a Polygon, a MultiPolygon, or a Feature or
+// FeatureCollection of them.
+func LoadPolygonChunkMask(filename string, mask ChunkMask) (*PolygonChunkMask, error) {
+	var data, readErr = ioutil.ReadFile(filename)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var polygons, err = readGeoJSONPolygons(data)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %v", filename, err))
+	}
+	if len(polygons) == 0 {
+		return nil, errors.New(fmt.Sprintf("%s has no polygons", filename))
+	}
+	return &PolygonChunkMask{mask, polygons}, nil
+}
+
+type geoJSON struct {
+	Type        string
+	Coordinates json.RawMessage
+	Geometry    *geoJSON
+	Features    []*geoJSON
+}
+
+func readGeoJSONPolygons(data []byte) ([][][][2]float64, error) {
+	var g geoJSON
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	return g.polygons()
+}
+
+func (g *geoJSON) polygons() ([][][][2]float64, error) {
+	switch g.Type {
+	case "Polygon":
+		var polygon [][][2]float64
+		var err = json.Unmarshal(g.Coordinates, &polygon)
+		return [][][][2]float64{polygon}, err
+	case "MultiPolygon":
+		var polygons [][][][2]float64
+		var err = json.Unmarshal(g.Coordinates, &polygons)
+		return polygons, err
+	case "Feature":
+		if g.Geometry == nil {
+			return nil, nil
+		}
+		return g.Geometry.polygons()
+	case "FeatureCollection":
+		var polygons [][][][2]float64
+		for _, feature := range g.Features {
+			var featurePolygons, err = feature.polygons()
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, featurePolygons...)
+		}
+		return polygons, nil
+	}
+	return nil, errors.New(fmt.Sprintf("GeoJSON type %q isn't a polygon", g.Type))
+}
+
+func (m *PolygonChunkMask) IsMasked(x, z int) bool {
+	if m.Mask.IsMasked(x, z) {
+		return true
+	}
+
+	var x0, z0 = float64(x * 16), float64(z * 16)
+	var x1, z1 = x0 + 16, z0 + 16
+	for _, polygon := range m.Polygons {
+		if polygonOverlaps(polygon, x0, z0, x1, z1) {
+			return false
+		}
+	}
+	return true
+}
+
+// polygonOverlaps reports whether a polygon overlaps the rectangle from
+// x0,z0 to x1,z1, either by covering its center or by an edge crossing
+// into it.
+func polygonOverlaps(polygon [][][2]float64, x0, z0, x1, z1 float64) bool {
+	var cx, cz = (x0 + x1) / 2, (z0 + z1) / 2
+	var inside = false
+	for _, ring := range polygon {
+		for i := range ring {
+			var a, b = ring[i], ring[(i+1)%len(ring)]
+			if segmentCrossesRect(a, b, x0, z0, x1, z1) {
+				return true
+			}
+			// Even-odd rule, so holes cut out of the outline
+			if (a[1] > cz) != (b[1] > cz) && cx < a[0]+(cz-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// segmentCrossesRect clips the segment from a to b to the rectangle, by
+// Liang-Barsky, and reports whether any of it is left. Segments that only
+// touch the rectangle's edges don't count.
+func segmentCrossesRect(a, b [2]float64, x0, z0, x1, z1 float64) bool {
+	var (
+		dx, dz = b[0] - a[0], b[1] - a[1]
+		t0, t1 = 0.0, 1.0
+	)
+	for _, edge := range [][2]float64{{-dx, a[0] - x0}, {dx, x1 - a[0]}, {-dz, a[1] - z0}, {dz, z1 - a[1]}} {
+		var p, q = edge[0], edge[1]
+		if p == 0 {
+			if q <= 0 {
+				return false
+			}
+			continue
+		}
+		var t = q / p
+		if p < 0 {
+			if t > t1 {
+				return false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+	}
+	return t0 < t1
+}
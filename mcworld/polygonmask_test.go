@@ -0,0 +1,47 @@
+package mcworld
+
+import (
+	"testing"
+)
+
+func TestPolygonChunkMask(t *testing.T) {
+	// A 10 chunk square with a 2x2 chunk hole in the middle, and a sliver
+	// of a triangle that only clips a corner of chunk 20,0
+	var polygons, err = readGeoJSONPolygons([]byte(`{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [
+				[[0, 0], [160, 0], [160, 160], [0, 160], [0, 0]],
+				[[64, 64], [96, 64], [96, 96], [64, 96], [64, 64]]
+			]}},
+			{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [
+				[[318, 14], [321, 14], [318, 18], [318, 14]]
+			]}}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mask = &PolygonChunkMask{&AllChunksMask{}, polygons}
+	for _, test := range []struct {
+		x, z   int
+		masked bool
+	}{
+		{0, 0, false},
+		{9, 9, false},
+		{10, 0, true},
+		{-1, 0, true},
+		{4, 4, true},
+		{5, 5, true},
+		{3, 4, false},
+		{20, 0, false},
+		{20, 1, true},
+		{19, 0, false},
+		{19, 2, true},
+	} {
+		if mask.IsMasked(test.x, test.z) != test.masked {
+			t.Errorf("chunk %d,%d masked is %v", test.x, test.z, !test.masked)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package mcworld
+
+import (
+	"io"
+	"sync"
+)
+
+// Region files kept open between chunks. A region holds 1024 chunks, so
+// an exporter working outward from a point only needs a handful at a time.
+const regionCacheSize = 16
+
+// regionCache shares open region files between chunks and goroutines.
+// Chunks are read with ReadAt, so a handle is never seeked and any number
+// of readers can use it at once. The least recently used files are closed
+// once there are more than regionCacheSize, as soon as nothing is reading
+// them.
+type regionCache struct {
+	mutex   sync.Mutex
+	handles map[string]*regionHandle
+	clock   int
+}
+
+type regionHandle struct {
+	cache    *regionCache
+	file     File
+	refs     int  // Readers still using the file
+	lastUsed int  // Value of the cache's clock
+	evicted  bool // Out of the cache, closed by the last reader
+}
+
+// acquire returns the open handle for path, opening it with fs if need be.
+// Each handle must be released once.
+func (c *regionCache) acquire(fs FileSystem, path string) (*regionHandle, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.clock++
+	if h, ok := c.handles[path]; ok {
+		h.refs++
+		h.lastUsed = c.clock
+		return h, nil
+	}
+
+	var file, err = openRegionFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.handles == nil {
+		c.handles = make(map[string]*regionHandle)
+	}
+	var h = &regionHandle{cache: c, file: file, refs: 1, lastUsed: c.clock}
+	c.handles[path] = h
+
+	for len(c.handles) > regionCacheSize {
+		var (
+			oldestPath string
+			oldest     *regionHandle
+		)
+		for p, other := range c.handles {
+			if oldest == nil || other.lastUsed < oldest.lastUsed {
+				oldestPath, oldest = p, other
+			}
+		}
+		delete(c.handles, oldestPath)
+		oldest.evicted = true
+		if oldest.refs == 0 {
+			oldest.file.Close()
+		}
+	}
+
+	return h, nil
+}
+
+func (h *regionHandle) release() {
+	var c = h.cache
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	h.refs--
+	if h.refs == 0 && h.evicted {
+		h.file.Close()
+	}
+}
+
+// Close closes every file that isn't being read, and the rest as soon as
+// they're released.
+func (c *regionCache) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var firstErr error
+	for path, h := range c.handles {
+		delete(c.handles, path)
+		h.evicted = true
+		if h.refs == 0 {
+			if err := h.file.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// regionChunkReader releases the region file once a chunk has been read.
+type regionChunkReader struct {
+	io.Reader
+	closer io.Closer
+	handle *regionHandle
+}
+
+func (r *regionChunkReader) Close() error {
+	var err error
+	if r.closer != nil {
+		err = r.closer.Close()
+	}
+	if r.handle != nil {
+		r.handle.release()
+		r.handle = nil
+	}
+	return err
+}
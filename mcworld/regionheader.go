@@ -0,0 +1,76 @@
+package mcworld
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// RegionReport lists the problems found in a region file. The chunks with
+// problems are left out of the pool rather than failing the whole world.
+type RegionReport struct {
+	Filename string
+	Problems []string
+}
+
+func (r *RegionReport) problem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+func (r *RegionReport) String() string {
+	return r.Filename + ": " + strings.Join(r.Problems, "; ")
+}
+
+// checkRegionHeader checks the location table at the start of a region
+// file that is size bytes long, reporting the chunks that start inside the
+// header, run past the end of the file, or share sectors with another
+// chunk. It returns which of the 1024 chunks are safe to read.
+func checkRegionHeader(header []byte, size int64, rx, rz int, report *RegionReport) []bool {
+	var (
+		valid   = make([]bool, 32*32)
+		sectors = int((size + 4095) / 4096)
+		owners  = make([]int, sectors)
+	)
+	for i := range owners {
+		owners[i] = -1
+	}
+
+	for i := range valid {
+		var location = ChunkLocation(binary.BigEndian.Uint32(header[4*i:]))
+		if location == 0 {
+			continue
+		}
+
+		var (
+			x     = rx*32 + i%32
+			z     = rz*32 + i/32
+			first = location.Offset() / 4096
+			last  = first + location.Sectors()
+		)
+		switch {
+		case first < 2:
+			report.problem("chunk %d,%d starts inside the header at sector %d", x, z, first)
+			continue
+		case location.Sectors() == 0:
+			report.problem("chunk %d,%d has no sectors", x, z)
+			continue
+		case last > sectors:
+			report.problem("chunk %d,%d runs past the end of the file to sector %d of %d", x, z, last, sectors)
+			continue
+		}
+
+		valid[i] = true
+		for sector := first; sector < last; sector++ {
+			if owner := owners[sector]; owner != -1 {
+				if valid[owner] {
+					report.problem("chunks %d,%d and %d,%d overlap at sector %d", rx*32+owner%32, rz*32+owner/32, x, z, sector)
+				}
+				valid[owner] = false
+				valid[i] = false
+			}
+			owners[sector] = i
+		}
+	}
+
+	return valid
+}
@@ -0,0 +1,143 @@
+package mcworld
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"io"
+	"os"
+	"sync"
+)
+
+// RegionIndex remembers which chunks each region file holds, so that
+// pooling the chunks of a big world again needn't read every region
+// header. Regions are looked up by filename and only trusted while their
+// size and modification time are unchanged.
+type RegionIndex struct {
+	filename string
+	mutex    sync.Mutex
+	regions  map[string]*regionIndexEntry
+	changed  bool
+}
+
+const regionIndexVersion = 1
+
+type regionIndexFile struct {
+	Version int
+	Regions map[string]*regionIndexEntry
+}
+
+type regionIndexEntry struct {
+	Size     int64
+	ModTime  int64    // Unix nanoseconds
+	Slots    []uint16 // x+z*32 of each chunk in the header safe to read
+	Modified []uint32 // When each of those chunks was saved
+	Problems []string
+}
+
+// OpenRegionIndex reads the index kept in filename, starting an empty one
+// if there isn't one yet or it can't be read.
+func OpenRegionIndex(filename string) (*RegionIndex, error) {
+	var index = &RegionIndex{filename: filename, regions: make(map[string]*regionIndexEntry)}
+
+	var file, err = os.Open(filename)
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var saved regionIndexFile
+	if gob.NewDecoder(file).Decode(&saved) == nil && saved.Version == regionIndexVersion && saved.Regions != nil {
+		index.regions = saved.Regions
+	}
+	return index, nil
+}
+
+// Save writes the index back to its file if any regions were added or
+// rescanned.
+func (index *RegionIndex) Save() error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	if !index.changed {
+		return nil
+	}
+
+	var tmpFilename = index.filename + ".tmp"
+	var file, err = os.Create(tmpFilename)
+	if err != nil {
+		return err
+	}
+	var encodeErr = gob.NewEncoder(file).Encode(&regionIndexFile{regionIndexVersion, index.regions})
+	var closeErr = file.Close()
+	if encodeErr == nil {
+		encodeErr = closeErr
+	}
+	if encodeErr != nil {
+		os.Remove(tmpFilename)
+		return encodeErr
+	}
+	if err := os.Rename(tmpFilename, index.filename); err != nil {
+		return err
+	}
+	index.changed = false
+	return nil
+}
+
+func (index *RegionIndex) lookup(filename string, info os.FileInfo) *regionIndexEntry {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	var entry = index.regions[filename]
+	if entry == nil || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return nil
+	}
+	return entry
+}
+
+func (index *RegionIndex) store(filename string, entry *regionIndexEntry) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.regions[filename] = entry
+	index.changed = true
+}
+
+// readRegionEntry reads the header of a region file into an index entry,
+// reporting its problems.
+func readRegionEntry(region File, rx, rz int, report *RegionReport) (*regionIndexEntry, error) {
+	var info, statErr = region.Stat()
+	if statErr != nil {
+		return nil, statErr
+	}
+
+	// Linear regions are read as an Anvil image of a different size
+	var size = info.Size()
+	if sized, ok := region.(interface {
+		Size() int64
+	}); ok {
+		size = sized.Size()
+	}
+
+	// The location table is followed by a table of when each chunk was
+	// last saved. Region files cut short are missing the chunks after the
+	// end.
+	var header [2 * 4096]byte
+	var _, readErr = io.ReadFull(io.NewSectionReader(region, 0, int64(len(header))), header[:])
+	if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+		return nil, readErr
+	}
+	if size != 0 && size < 2*4096 {
+		report.problem("header cut short at %d bytes", size)
+	}
+
+	var (
+		valid = checkRegionHeader(header[:], size, rx, rz, report)
+		entry = &regionIndexEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Problems: report.Problems}
+	)
+	for i := range valid {
+		if valid[i] && binary.BigEndian.Uint32(header[4*i:]) != 0 {
+			entry.Slots = append(entry.Slots, uint16(i))
+			entry.Modified = append(entry.Modified, binary.BigEndian.Uint32(header[4096+4*i:]))
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,65 @@
+package mcworld
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegionIndex(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "regionindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		regionFilename = filepath.Join(dir, "region", "r.-1.0.mca")
+		indexFilename  = filepath.Join(dir, "index")
+		saved          = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	)
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(regionFilename, testRegion(31, 2), 0644)
+	os.Chtimes(regionFilename, saved, saved)
+
+	var pooled = func(index *RegionIndex) int {
+		var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+		world.SetRegionIndex(index)
+		defer world.Close()
+		var pool, err = world.ChunkPool(&AllChunksMask{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pool.Remaining()
+	}
+
+	var index, indexErr = OpenRegionIndex(indexFilename)
+	if indexErr != nil {
+		t.Fatal(indexErr)
+	}
+	if n := pooled(index); n != 1 {
+		t.Fatalf("%d chunks pooled, not 1", n)
+	}
+	if err := index.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Blanking the region without changing its size or time goes unnoticed
+	ioutil.WriteFile(regionFilename, make([]byte, 3*4096), 0644)
+	os.Chtimes(regionFilename, saved, saved)
+	index, indexErr = OpenRegionIndex(indexFilename)
+	if indexErr != nil {
+		t.Fatal(indexErr)
+	}
+	if n := pooled(index); n != 1 {
+		t.Errorf("%d chunks pooled from the index, not 1", n)
+	}
+
+	// A new time has it read again
+	os.Chtimes(regionFilename, saved.Add(time.Minute), saved.Add(time.Minute))
+	if n := pooled(index); n != 0 {
+		t.Errorf("%d chunks pooled from the changed region, not 0", n)
+	}
+}
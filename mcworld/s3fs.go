@@ -0,0 +1,338 @@
+package mcworld
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3FileSystem reads a world from an S3 (or S3 compatible) bucket, from
+// s3://bucket/prefix. Objects are read with Range requests like
+// httpFileSystem, and folders listed with ListObjectsV2.
+//
+// Credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN, and the region from AWS_REGION or AWS_DEFAULT_REGION.
+// Without credentials requests are unsigned, which is enough for public
+// buckets. AWS_ENDPOINT_URL points at other S3 compatible services, whose
+// buckets are addressed by path.
+type s3FileSystem struct {
+	endpoint *url.URL // With the bucket in the host or the path
+	region   string
+	client   *http.Client
+	blocks   *blockCache
+	now      func() time.Time
+
+	accessKey, secretKey, sessionToken string
+}
+
+const s3EmptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func newS3FileSystem(u *url.URL) (*s3FileSystem, string, error) {
+	if u.Host == "" {
+		return nil, "", errors.New(fmt.Sprintf("No bucket in %v", u))
+	}
+
+	var region = os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	var endpoint *url.URL
+	if custom := os.Getenv("AWS_ENDPOINT_URL"); custom != "" {
+		var parsed, err = url.Parse(custom)
+		if err != nil {
+			return nil, "", err
+		}
+		endpoint = parsed
+		endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + u.Host
+	} else {
+		endpoint = &url.URL{Scheme: "https", Host: u.Host + ".s3." + region + ".amazonaws.com"}
+	}
+
+	var fs = &s3FileSystem{
+		endpoint:     endpoint,
+		region:       region,
+		client:       http.DefaultClient,
+		now:          time.Now,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	fs.blocks = newBlockCache(fs.readRange)
+
+	var prefix = strings.Trim(u.Path, "/")
+	if prefix == "" {
+		prefix = "."
+	}
+	return fs, filepath.FromSlash(prefix), nil
+}
+
+// s3Key turns a name into an object key, which has no leading slash.
+func s3Key(name string) string {
+	var key = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+	if key == "." {
+		return ""
+	}
+	return key
+}
+
+func (fs *s3FileSystem) request(method, key string, query url.Values) (*http.Request, error) {
+	var u = *fs.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	u.RawQuery = s3CanonicalQuery(query)
+	var request, err = http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Send the path escaped the same way it is signed
+	request.URL.RawPath = s3EscapePath(request.URL.Path)
+	return request, nil
+}
+
+func (fs *s3FileSystem) do(request *http.Request) (*http.Response, error) {
+	fs.sign(request)
+	return fs.client.Do(request)
+}
+
+func (fs *s3FileSystem) Stat(name string) (os.FileInfo, error) {
+	var key = s3Key(name)
+	if key != "" {
+		var request, err = fs.request("HEAD", key, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := fs.do(request)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return &remoteFileInfo{path.Base(key), resp.ContentLength}, nil
+		case http.StatusNotFound, http.StatusForbidden:
+			// Forbidden is what's returned for missing objects without
+			// permission to list the bucket
+		default:
+			return nil, &os.PathError{Op: "stat", Path: name, Err: errors.New(resp.Status)}
+		}
+	}
+
+	// Folders only exist as the prefix of other keys
+	var list, err = fs.list(key, 1, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Contents) == 0 && len(list.CommonPrefixes) == 0 {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return dirInfo(path.Base(key)), nil
+}
+
+func (fs *s3FileSystem) Open(name string) (File, error) {
+	var info, err = fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	return &blockFile{blocks: fs.blocks, name: name, info: info}, nil
+}
+
+func (fs *s3FileSystem) ReadDirNames(name string) ([]string, error) {
+	var prefix = s3Key(name)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var names []string
+	var token string
+	for {
+		var list, err = fs.list(s3Key(name), 1000, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range list.Contents {
+			if child := strings.TrimPrefix(object.Key, prefix); child != "" {
+				names = append(names, child)
+			}
+		}
+		for _, common := range list.CommonPrefixes {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(common.Prefix, prefix), "/"))
+		}
+		if !list.IsTruncated || list.NextContinuationToken == "" {
+			break
+		}
+		token = list.NextContinuationToken
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// list lists the keys in the folder key with ListObjectsV2.
+func (fs *s3FileSystem) list(key string, maxKeys int, token string) (*s3ListBucketResult, error) {
+	var prefix = key
+	if prefix != "" {
+		prefix += "/"
+	}
+	var query = url.Values{
+		"list-type": {"2"},
+		"prefix":    {prefix},
+		"delimiter": {"/"},
+		"max-keys":  {fmt.Sprint(maxKeys)},
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+
+	var request, err = fs.request("GET", "", query)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := fs.do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &os.PathError{Op: "list", Path: key, Err: errors.New(resp.Status)}
+	}
+
+	var result = new(s3ListBucketResult)
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (fs *s3FileSystem) readRange(name string, offset int64, size int) ([]byte, error) {
+	var request, err = fs.request("GET", s3Key(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+int64(size)-1))
+	fs.sign(request)
+	return getRange(fs.client, request, name, offset, size)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header, signing the
+// host, any range and the x-amz headers.
+func (fs *s3FileSystem) sign(request *http.Request) {
+	if fs.accessKey == "" || fs.secretKey == "" {
+		return
+	}
+
+	var (
+		now       = fs.now().UTC()
+		amzDate   = now.Format("20060102T150405Z")
+		date      = now.Format("20060102")
+		scope     = date + "/" + fs.region + "/s3/aws4_request"
+		headers   = map[string]string{"host": request.URL.Host}
+		names     []string
+		canonical []string
+	)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", s3EmptyHash)
+	if fs.sessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", fs.sessionToken)
+	}
+	for name, values := range request.Header {
+		var lower = strings.ToLower(name)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		canonical = append(canonical, name+":"+headers[name]+"\n")
+	}
+	var signedHeaders = strings.Join(names, ";")
+
+	var canonicalRequest = strings.Join([]string{
+		request.Method,
+		s3EscapePath(request.URL.Path),
+		s3CanonicalQuery(request.URL.Query()),
+		strings.Join(canonical, ""),
+		signedHeaders,
+		s3EmptyHash,
+	}, "\n")
+	var requestHash = sha256.Sum256([]byte(canonicalRequest))
+	var stringToSign = "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	var key = []byte("AWS4" + fs.secretKey)
+	for _, part := range []string{date, fs.region, "s3", "aws4_request"} {
+		key = s3Hmac(key, part)
+	}
+	var signature = hex.EncodeToString(s3Hmac(key, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+fs.accessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func s3Hmac(key []byte, data string) []byte {
+	var h = hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape percent encodes everything except the unreserved characters,
+// as Signature Version 4 requires.
+func s3Escape(s string, keepSlash bool) string {
+	var escaped []byte
+	for i := 0; i < len(s); i++ {
+		var c = s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			escaped = append(escaped, c)
+		default:
+			escaped = append(escaped, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+	return string(escaped)
+}
+
+func s3EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return s3Escape(p, true)
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key, false)+"="+s3Escape(value, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
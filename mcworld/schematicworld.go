@@ -0,0 +1,53 @@
+package mcworld
+
+import (
+	"errors"
+	"github.com/quag/mcobj/nbt"
+)
+
+// SchematicWorld reads an MCEdit .schematic file, a gzipped NBT file whose
+// root Schematic struct holds the size and the YZX ordered Blocks and Data
+// arrays of a copied region, in the same layout as an Indev level.
+type SchematicWorld struct {
+	flatWorld
+}
+
+var SchematicBlocksNotFound = errors.New("Schematic 'Blocks' not found")
+
+func NewSchematicWorld(fs FileSystem, path string) *SchematicWorld {
+	return &SchematicWorld{flatWorld{fs: fs, path: path, read: readSchematicLevel}}
+}
+
+func readSchematicLevel(fs FileSystem, path string) (*flatLevel, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var r, decompressErr = nbt.NewDecompressor(file)
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	defer r.Close()
+
+	var root, parseErr = nbt.Parse(r)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	var level = new(flatLevel)
+	var ok bool
+	if level.blocks, ok = root["Blocks"].([]byte); !ok {
+		return nil, SchematicBlocksNotFound
+	}
+	level.data, _ = root["Data"].([]byte)
+	level.width, _ = root["Width"].(int)
+	level.length, _ = root["Length"].(int)
+	level.height, _ = root["Height"].(int)
+
+	// Schematics have no spawn, so look at the middle from above
+	level.spawnX, level.spawnY, level.spawnZ = level.width/2, level.height, level.length/2
+
+	return level, level.check()
+}
@@ -0,0 +1,212 @@
+package mcworld
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LoadSelectionChunkMask reads a WorldEdit selection and masks the chunks
+// outside it, as well as everything mask masks.
+//
+// The selection is either the CUI messages WorldEdit sends for it (s|cuboid
+// followed by p|0|x|y|z and p|1|x|y|z, with p2 points for polygon2d, e for
+// ellipsoid and cyl for cylinder selections), or JSON such as a session
+// file holding a cuboid's corners as pos1 and pos2 (or position1 and
+// position2, or min and max), each as [x, y, z] or {"x", "y", "z"}.
+func LoadSelectionChunkMask(filename string, mask ChunkMask) (ChunkMask, error) {
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var selection, selectionErr = readSelection(data)
+	if selectionErr != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %v", filename, selectionErr))
+	}
+	return AndChunkMask{mask, selection}, nil
+}
+
+func readSelection(data []byte) (ChunkMask, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '{' {
+		return readJSONSelection(trimmed)
+	}
+	return readCUISelection(data)
+}
+
+func readJSONSelection(data []byte) (ChunkMask, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if a, b, ok := findSelectionCorners(v); ok {
+		return cuboidSelectionMask(a, b), nil
+	}
+	return nil, errors.New("no selection corners found")
+}
+
+var selectionCornerNames = [][2]string{
+	{"pos1", "pos2"},
+	{"position1", "position2"},
+	{"min", "max"},
+}
+
+// findSelectionCorners searches depth first for a pair of corners.
+func findSelectionCorners(v interface{}) (a, b [2]float64, ok bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, names := range selectionCornerNames {
+			var a, aOk = selectionPoint(v[names[0]])
+			var b, bOk = selectionPoint(v[names[1]])
+			if aOk && bOk {
+				return a, b, true
+			}
+		}
+		for _, child := range v {
+			if a, b, ok := findSelectionCorners(child); ok {
+				return a, b, true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if a, b, ok := findSelectionCorners(child); ok {
+				return a, b, true
+			}
+		}
+	}
+	return
+}
+
+// selectionPoint reads the x and z of a block position.
+func selectionPoint(v interface{}) (p [2]float64, ok bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) == 3 {
+			var x, xOk = v[0].(float64)
+			var z, zOk = v[2].(float64)
+			return [2]float64{x, z}, xOk && zOk
+		}
+	case map[string]interface{}:
+		var x, xOk = v["x"].(float64)
+		var z, zOk = v["z"].(float64)
+		return [2]float64{x, z}, xOk && zOk
+	}
+	return
+}
+
+func readCUISelection(data []byte) (ChunkMask, error) {
+	var (
+		shape   string
+		corners = make(map[int][2]float64)
+		points  = make(map[int][2]float64)
+		center  [2]float64
+		radius  [2]float64
+		rounded bool
+	)
+
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(line, "CUI ")
+		var fields = strings.Split(line, "|")
+		if len(fields) < 2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var numbers = make([]float64, len(fields)-1)
+		for i, field := range fields[1:] {
+			numbers[i], _ = strconv.ParseFloat(field, 64)
+		}
+
+		switch fields[0] {
+		case "s":
+			shape = fields[1]
+		case "p":
+			if len(numbers) >= 4 {
+				corners[int(numbers[0])] = [2]float64{numbers[1], numbers[3]}
+			}
+		case "p2":
+			if len(numbers) >= 3 {
+				points[int(numbers[0])] = [2]float64{numbers[1], numbers[2]}
+			}
+		case "e":
+			if len(numbers) >= 4 && numbers[0] == 0 {
+				center = [2]float64{numbers[1], numbers[3]}
+			} else if len(numbers) >= 4 && numbers[0] == 1 {
+				radius = [2]float64{numbers[1], numbers[3]}
+				rounded = true
+			}
+		case "cyl":
+			if len(numbers) >= 5 {
+				center = [2]float64{numbers[0], numbers[2]}
+				radius = [2]float64{numbers[3], numbers[4]}
+				rounded = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	switch shape {
+	case "cuboid", "":
+		var a, aOk = corners[0]
+		var b, bOk = corners[1]
+		if !aOk || !bOk {
+			return nil, errors.New("cuboid selection without both corners")
+		}
+		return cuboidSelectionMask(a, b), nil
+	case "polygon2d":
+		if len(points) == 0 {
+			return nil, errors.New("polygon2d selection without points")
+		}
+		var ring = make([][2]float64, 0, len(points))
+		for i := 0; i < len(points); i++ {
+			var p, ok = points[i]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("polygon2d selection without point %d", i))
+			}
+			ring = append(ring, [2]float64{p[0] + 0.5, p[1] + 0.5})
+		}
+		return &PolygonChunkMask{&AllChunksMask{}, [][][][2]float64{{ring}}}, nil
+	case "ellipsoid", "cylinder":
+		if !rounded {
+			return nil, errors.New(fmt.Sprintf("%s selection without a radius", shape))
+		}
+		return &ellipseChunkMask{center[0] + 0.5, center[1] + 0.5, radius[0] + 0.5, radius[1] + 0.5}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported %s selection", shape))
+}
+
+// cuboidSelectionMask masks the chunks outside the blocks from corner a to
+// corner b inclusive.
+func cuboidSelectionMask(a, b [2]float64) ChunkMask {
+	var (
+		x0, x1 = int(math.Floor(math.Min(a[0], b[0]))), int(math.Floor(math.Max(a[0], b[0])))
+		z0, z1 = int(math.Floor(math.Min(a[1], b[1]))), int(math.Floor(math.Max(a[1], b[1])))
+	)
+	return &RectangleChunkMask{floorDiv(x0, 16), floorDiv(z0, 16), floorDiv(x1, 16) + 1, floorDiv(z1, 16) + 1}
+}
+
+// ellipseChunkMask masks the chunks that don't overlap an ellipse in block
+// coordinates.
+type ellipseChunkMask struct {
+	cx, cz, rx, rz float64
+}
+
+func (m *ellipseChunkMask) IsMasked(x, z int) bool {
+	// The chunk's closest point to the center
+	var (
+		px = math.Max(float64(x*16), math.Min(m.cx, float64(x*16+16)))
+		pz = math.Max(float64(z*16), math.Min(m.cz, float64(z*16+16)))
+		dx = (px - m.cx) / m.rx
+		dz = (pz - m.cz) / m.rz
+	)
+	return dx*dx+dz*dz >= 1
+}
@@ -0,0 +1,50 @@
+package mcworld
+
+import (
+	"testing"
+)
+
+func TestSelectionMasks(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		selection string
+		unmasked  [][2]int
+		masked    [][2]int
+	}{
+		{"cuboid", "s|cuboid\np|0|-1|64|20|0\np|1|31|70|5|2346\n",
+			[][2]int{{-1, 0}, {1, 1}, {0, 0}},
+			[][2]int{{2, 0}, {-2, 0}, {0, 2}, {0, -1}}},
+		{"polygon2d", "s|polygon2d\np2|0|0|0|0\np2|1|100|0|0\np2|2|0|100|0\nmm|0|255\n",
+			[][2]int{{0, 0}, {6, 0}, {0, 6}, {3, 2}},
+			[][2]int{{6, 6}, {-1, 0}, {7, 0}}},
+		{"cylinder", "s|cylinder\ncyl|0|64|0|20|20\nmm|0|255\n",
+			[][2]int{{0, 0}, {-1, -1}, {1, 0}, {-2, 0}},
+			[][2]int{{2, 2}, {-2, -2}, {-3, 0}}},
+		{"ellipsoid", "CUI s|ellipsoid\nCUI e|0|0|64|0\nCUI e|1|40|10|8\n",
+			[][2]int{{2, 0}, {-3, 0}, {0, -1}},
+			[][2]int{{3, 0}, {0, 1}, {0, -2}}},
+		{"session", `{"wandItem": "minecraft:wooden_axe", "selection": {"pos1": [32, 64, -16], "pos2": {"x": 47, "y": 70, "z": -1}}}`,
+			[][2]int{{2, -1}},
+			[][2]int{{1, -1}, {3, -1}, {2, 0}, {2, -2}}},
+	} {
+		var mask, err = readSelection([]byte(test.selection))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		for _, chunk := range test.unmasked {
+			if mask.IsMasked(chunk[0], chunk[1]) {
+				t.Errorf("%s: chunk %d,%d is masked", test.name, chunk[0], chunk[1])
+			}
+		}
+		for _, chunk := range test.masked {
+			if !mask.IsMasked(chunk[0], chunk[1]) {
+				t.Errorf("%s: chunk %d,%d isn't masked", test.name, chunk[0], chunk[1])
+			}
+		}
+	}
+
+	if _, err := readSelection([]byte("s|polyhedron\n")); err == nil {
+		t.Errorf("polyhedron selection read")
+	}
+}
@@ -0,0 +1,657 @@
+package mcworld
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sftpFileSystem reads a world over SFTP. Rather than carrying an SSH
+// implementation, each session runs the ssh command with the sftp
+// subsystem, so the user's keys, agent and ~/.ssh/config all just work,
+// and speaks version 3 of the SFTP protocol over its stdin and stdout.
+//
+// Requests on a session are made one at a time, so up to sftpMaxSessions
+// sessions are started as more files are read at once. A session whose
+// connection fails is dropped, and the request tried once more on another.
+type sftpFileSystem struct {
+	dial func() (*sftpSession, error)
+
+	mutex    sync.Mutex
+	sessions []*sftpSession
+	next     int
+}
+
+const (
+	sftpMaxSessions = 4
+	sftpMaxRead     = 32 * 1024
+	sftpBlockSize   = 64 * 1024
+
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpOpendir  = 11
+	sftpReaddir  = 12
+	sftpStat     = 17
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpName     = 104
+	sftpAttrs    = 105
+	sftpOpenRead = 1
+
+	sftpStatusOk         = 0
+	sftpStatusEOF        = 1
+	sftpStatusNoSuchFile = 2
+
+	sftpAttrSize        = 0x1
+	sftpAttrUidGid      = 0x2
+	sftpAttrPermissions = 0x4
+	sftpAttrAcModTime   = 0x8
+	sftpAttrExtended    = 0x80000000
+
+	sftpModeType = 0170000
+	sftpModeDir  = 0040000
+)
+
+// newSFTPFileSystem connects to sftp://user@host:port/path. The path is
+// absolute, unless it starts with /~/ for the user's home folder.
+func newSFTPFileSystem(u *url.URL) (*sftpFileSystem, string, error) {
+	if u.Host == "" {
+		return nil, "", errors.New(fmt.Sprintf("No host in %v", u))
+	}
+
+	var args []string
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	var destination = u.Hostname()
+	if u.User != nil {
+		destination = u.User.Username() + "@" + destination
+	}
+	args = append(args, "-s", destination, "sftp")
+
+	var fs = &sftpFileSystem{dial: func() (*sftpSession, error) {
+		return startSFTPCommand(exec.Command("ssh", args...))
+	}}
+
+	var dir = u.Path
+	if strings.HasPrefix(dir, "/~/") {
+		dir = dir[3:]
+	}
+	if dir == "" || dir == "/~" {
+		dir = "."
+	}
+
+	// Connect now so that bad hosts and logins are reported up front
+	if _, err := fs.session(); err != nil {
+		return nil, "", err
+	}
+	return fs, filepath.FromSlash(dir), nil
+}
+
+// session picks the session for the next request, starting a new one
+// while there are fewer than the maximum and the others are busy.
+func (fs *sftpFileSystem) session() (*sftpSession, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	for _, s := range fs.sessions {
+		if s.idle() {
+			return s, nil
+		}
+	}
+
+	if len(fs.sessions) < sftpMaxSessions {
+		var s, err = fs.dial()
+		if err != nil {
+			return nil, err
+		}
+		fs.sessions = append(fs.sessions, s)
+		return s, nil
+	}
+
+	fs.next = (fs.next + 1) % len(fs.sessions)
+	return fs.sessions[fs.next], nil
+}
+
+// drop forgets a session whose connection has failed.
+func (fs *sftpFileSystem) drop(s *sftpSession) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	for i, session := range fs.sessions {
+		if session == s {
+			fs.sessions = append(fs.sessions[:i], fs.sessions[i+1:]...)
+			s.w.Close()
+			break
+		}
+	}
+}
+
+// retry runs a request on a session, and if the session's connection
+// fails, once more on another.
+func (fs *sftpFileSystem) retry(request func(s *sftpSession) error) error {
+	var s, err = fs.session()
+	if err != nil {
+		return err
+	}
+	if err = request(s); err == nil || !s.failed() {
+		return err
+	}
+
+	fs.drop(s)
+	if s, err = fs.session(); err != nil {
+		return err
+	}
+	return request(s)
+}
+
+func sftpPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+func (fs *sftpFileSystem) Stat(name string) (os.FileInfo, error) {
+	var p = sftpPath(name)
+	var attrs sftpAttributes
+	var err = fs.retry(func(s *sftpSession) (statErr error) {
+		attrs, statErr = s.stat(p)
+		return
+	})
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return &sftpFileInfo{path.Base(p), attrs}, nil
+}
+
+func (fs *sftpFileSystem) Open(name string) (File, error) {
+	var f = &sftpFile{fs: fs, path: sftpPath(name)}
+	if err := fs.retry(f.open); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return f, nil
+}
+
+func (fs *sftpFileSystem) ReadDirNames(name string) ([]string, error) {
+	var names []string
+	var err = fs.retry(func(s *sftpSession) error {
+		var handle, openErr = s.handleRequest(sftpOpendir, sftpString(sftpPath(name)))
+		if openErr != nil {
+			return &os.PathError{Op: "open", Path: name, Err: openErr}
+		}
+		defer s.closeHandle(handle)
+
+		names = names[:0]
+		for {
+			var batch, readErr = s.readdir(handle)
+			if readErr == io.EOF {
+				return nil
+			}
+			if readErr != nil {
+				return readErr
+			}
+			for _, n := range batch {
+				if n != "." && n != ".." {
+					names = append(names, n)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// sftpSession is one running sftp subsystem.
+type sftpSession struct {
+	mutex  sync.Mutex // Guards busy, nextId and broken
+	busy   bool
+	nextId uint32
+	broken bool // Once a request fails to be sent or answered
+
+	// Only one request is in flight on a session at a time
+	requestMutex sync.Mutex
+	w            io.WriteCloser
+	r            *bufio.Reader
+}
+
+func startSFTPCommand(cmd *exec.Cmd) (*sftpSession, error) {
+	var w, inErr = cmd.StdinPipe()
+	if inErr != nil {
+		return nil, inErr
+	}
+	var r, outErr = cmd.StdoutPipe()
+	if outErr != nil {
+		return nil, outErr
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return newSFTPSession(r, w)
+}
+
+func newSFTPSession(r io.Reader, w io.WriteCloser) (*sftpSession, error) {
+	var s = &sftpSession{w: w, r: bufio.NewReader(r)}
+
+	if err := s.send(sftpInit, uint32(3)); err != nil {
+		return nil, err
+	}
+	var packetType, _, err = s.receive()
+	if err != nil {
+		return nil, err
+	}
+	if packetType != sftpVersion {
+		return nil, errors.New("SFTP server didn't send its version")
+	}
+	return s, nil
+}
+
+func (s *sftpSession) idle() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return !s.busy
+}
+
+// failed is whether the session's connection has failed, rather than the
+// server answering a request with an error.
+func (s *sftpSession) failed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.broken
+}
+
+func (s *sftpSession) fail() {
+	s.mutex.Lock()
+	s.broken = true
+	s.mutex.Unlock()
+}
+
+// sftpString marks a value to be sent as a length prefixed string.
+type sftpString string
+
+func (s *sftpSession) send(packetType byte, fields ...interface{}) error {
+	var packet = []byte{0, 0, 0, 0, packetType}
+	for _, field := range fields {
+		switch v := field.(type) {
+		case uint32:
+			packet = append(packet, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+		case uint64:
+			packet = append(packet, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.BigEndian.PutUint64(packet[len(packet)-8:], v)
+		case sftpString:
+			packet = append(packet, byte(len(v)>>24), byte(len(v)>>16), byte(len(v)>>8), byte(len(v)))
+			packet = append(packet, v...)
+		}
+	}
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	var _, err = s.w.Write(packet)
+	return err
+}
+
+func (s *sftpSession) receive() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	var length = binary.BigEndian.Uint32(header[:])
+	if length < 1 || length > 1<<24 {
+		return 0, nil, errors.New("Bad SFTP packet length")
+	}
+	var payload = make([]byte, length-1)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// request sends a request and waits for its response, returning the
+// response's type and the payload after the request id. Status responses
+// other than OK are returned as errors, with the end of a file or folder
+// as io.EOF.
+func (s *sftpSession) request(packetType byte, fields ...interface{}) (byte, *sftpReader, error) {
+	s.mutex.Lock()
+	s.busy = true
+	s.nextId++
+	var id = s.nextId
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		s.busy = false
+		s.mutex.Unlock()
+	}()
+
+	s.requestMutex.Lock()
+	defer s.requestMutex.Unlock()
+
+	if err := s.send(packetType, append([]interface{}{id}, fields...)...); err != nil {
+		s.fail()
+		return 0, nil, err
+	}
+	var responseType, payload, err = s.receive()
+	if err != nil {
+		s.fail()
+		return 0, nil, err
+	}
+
+	var r = &sftpReader{b: payload}
+	if r.uint32() != id {
+		s.fail()
+		return 0, nil, errors.New("SFTP response out of order")
+	}
+	if responseType == sftpStatus {
+		var code = r.uint32()
+		var message = r.string()
+		switch code {
+		case sftpStatusOk:
+		case sftpStatusEOF:
+			return 0, nil, io.EOF
+		case sftpStatusNoSuchFile:
+			return 0, nil, os.ErrNotExist
+		default:
+			return 0, nil, errors.New(fmt.Sprintf("SFTP error %d: %s", code, message))
+		}
+	}
+	return responseType, r, r.err
+}
+
+func (s *sftpSession) handleRequest(packetType byte, fields ...interface{}) (sftpString, error) {
+	var responseType, r, err = s.request(packetType, fields...)
+	if err != nil {
+		return "", err
+	}
+	if responseType != sftpHandle {
+		return "", errors.New("SFTP server didn't send a handle")
+	}
+	return sftpString(r.string()), r.err
+}
+
+func (s *sftpSession) closeHandle(handle sftpString) error {
+	var _, _, err = s.request(sftpClose, handle)
+	return err
+}
+
+func (s *sftpSession) stat(p string) (sftpAttributes, error) {
+	var responseType, r, err = s.request(sftpStat, sftpString(p))
+	if err != nil {
+		return sftpAttributes{}, err
+	}
+	if responseType != sftpAttrs {
+		return sftpAttributes{}, errors.New("SFTP server didn't send attributes")
+	}
+	var attrs = r.attrs()
+	return attrs, r.err
+}
+
+func (s *sftpSession) readdir(handle sftpString) ([]string, error) {
+	var responseType, r, err = s.request(sftpReaddir, handle)
+	if err != nil {
+		return nil, err
+	}
+	if responseType != sftpName {
+		return nil, errors.New("SFTP server didn't send names")
+	}
+	// Each name is at least its length, its long name's and its attributes'
+	// flags
+	var count = r.uint32()
+	if uint64(count) > uint64(len(r.b)/12) {
+		return nil, errors.New(fmt.Sprintf("SFTP server sent %d names in %d bytes", count, len(r.b)))
+	}
+	var names = make([]string, count)
+	for i := range names {
+		names[i] = r.string()
+		r.string() // Long name
+		r.attrs()
+	}
+	return names, r.err
+}
+
+func (s *sftpSession) read(handle sftpString, offset int64, length int) ([]byte, error) {
+	var responseType, r, err = s.request(sftpRead, handle, uint64(offset), uint32(length))
+	if err != nil {
+		return nil, err
+	}
+	if responseType != sftpData {
+		return nil, errors.New("SFTP server didn't send data")
+	}
+	var data = r.string()
+	return []byte(data), r.err
+}
+
+type sftpReader struct {
+	b []byte
+	// err is set once a read runs past the end of the payload
+	err error
+}
+
+// take is the next n bytes of the payload. Past its end, it's zeroes for
+// the numbers read, and nothing for strings, without allocating lengths
+// the server sent.
+func (r *sftpReader) take(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.b) {
+		r.err = errors.New("Short SFTP packet")
+		if n > 8 || n < 0 {
+			return nil
+		}
+		return make([]byte, n)
+	}
+	var taken = r.b[:n]
+	r.b = r.b[n:]
+	return taken
+}
+
+func (r *sftpReader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.take(4))
+}
+
+func (r *sftpReader) uint64() uint64 {
+	return binary.BigEndian.Uint64(r.take(8))
+}
+
+func (r *sftpReader) string() string {
+	var length = r.uint32()
+	if r.err != nil {
+		return ""
+	}
+	if uint64(length) > uint64(len(r.b)) {
+		r.err = errors.New("Short SFTP packet")
+		return ""
+	}
+	return string(r.take(int(length)))
+}
+
+type sftpAttributes struct {
+	size        int64
+	permissions uint32
+	modTime     time.Time
+}
+
+func (r *sftpReader) attrs() sftpAttributes {
+	var attrs sftpAttributes
+	var flags = r.uint32()
+	if flags&sftpAttrSize != 0 {
+		attrs.size = int64(r.uint64())
+	}
+	if flags&sftpAttrUidGid != 0 {
+		r.uint32()
+		r.uint32()
+	}
+	if flags&sftpAttrPermissions != 0 {
+		attrs.permissions = r.uint32()
+	}
+	if flags&sftpAttrAcModTime != 0 {
+		r.uint32()
+		attrs.modTime = time.Unix(int64(r.uint32()), 0)
+	}
+	if flags&sftpAttrExtended != 0 {
+		var count = int(r.uint32())
+		for i := 0; i < count && r.err == nil; i++ {
+			r.string()
+			r.string()
+		}
+	}
+	return attrs
+}
+
+type sftpFileInfo struct {
+	name  string
+	attrs sftpAttributes
+}
+
+func (fi *sftpFileInfo) Name() string       { return fi.name }
+func (fi *sftpFileInfo) Size() int64        { return fi.attrs.size }
+func (fi *sftpFileInfo) ModTime() time.Time { return fi.attrs.modTime }
+func (fi *sftpFileInfo) IsDir() bool        { return fi.attrs.permissions&sftpModeType == sftpModeDir }
+func (fi *sftpFileInfo) Sys() interface{}   { return nil }
+
+func (fi *sftpFileInfo) Mode() os.FileMode {
+	var mode = os.FileMode(fi.attrs.permissions & 0777)
+	if fi.IsDir() {
+		mode |= os.ModeDir
+	}
+	return mode
+}
+
+// sftpFile reads ahead a block at a time, as chunks are decompressed a
+// few bytes at a time and every read is a round trip.
+type sftpFile struct {
+	fs       *sftpFileSystem
+	path     string
+	session  *sftpSession
+	handle   sftpString
+	info     os.FileInfo
+	position int64
+
+	mutex       sync.Mutex // Guards the read ahead block, so ReadAt can be shared
+	blockOffset int64
+	block       []byte
+}
+
+// open opens the file on a session, again on another if the connection of
+// the one it was open on fails.
+func (f *sftpFile) open(s *sftpSession) error {
+	var attrs, statErr = s.stat(f.path)
+	if statErr != nil {
+		return statErr
+	}
+	var handle, openErr = s.handleRequest(sftpOpen, sftpString(f.path), uint32(sftpOpenRead), uint32(0))
+	if openErr != nil {
+		return openErr
+	}
+	f.session, f.handle, f.info = s, handle, &sftpFileInfo{path.Base(f.path), attrs}
+	return nil
+}
+
+func (f *sftpFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var n int
+	for n < len(p) {
+		var at = off + int64(n)
+		if at >= f.info.Size() {
+			return n, io.EOF
+		}
+
+		if at < f.blockOffset || at >= f.blockOffset+int64(len(f.block)) {
+			if err := f.readBlock(at); err != nil {
+				return n, err
+			}
+		}
+		n += copy(p[n:], f.block[at-f.blockOffset:])
+	}
+	return n, nil
+}
+
+func (f *sftpFile) readBlock(offset int64) error {
+	var err = f.readBlockOnce(offset)
+	if err == nil || !f.session.failed() {
+		return err
+	}
+	f.fs.drop(f.session)
+	var s, sessionErr = f.fs.session()
+	if sessionErr != nil {
+		return sessionErr
+	}
+	if err := f.open(s); err != nil {
+		return err
+	}
+	return f.readBlockOnce(offset)
+}
+
+func (f *sftpFile) readBlockOnce(offset int64) error {
+	var block []byte
+	for len(block) < sftpBlockSize {
+		var data, err = f.session.read(f.handle, offset+int64(len(block)), sftpMaxRead)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			break
+		}
+		block = append(block, data...)
+	}
+	if len(block) == 0 {
+		return io.EOF
+	}
+	f.blockOffset, f.block = offset, block
+	return nil
+}
+
+func (f *sftpFile) Read(p []byte) (int, error) {
+	if f.position >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if remaining := f.info.Size() - f.position; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	var n, err = f.ReadAt(p, f.position)
+	f.position += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *sftpFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+		f.position = offset
+	case 1:
+		f.position += offset
+	case 2:
+		f.position = f.info.Size() + offset
+	}
+	if f.position < 0 {
+		f.position = 0
+		return 0, errors.New("Seek before the start of the file")
+	}
+	return f.position, nil
+}
+
+func (f *sftpFile) Close() error {
+	return f.session.closeHandle(f.handle)
+}
+
+func (f *sftpFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
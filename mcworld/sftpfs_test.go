@@ -0,0 +1,230 @@
+package mcworld
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSFTPWorld(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "sftpworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mca"), testRegion(31, 2), 0644)
+
+	var fs = &sftpFileSystem{dial: func() (*sftpSession, error) {
+		var requests, requestWriter = io.Pipe()
+		var responses, responseWriter = io.Pipe()
+		go serveFakeSFTP(requests, responseWriter)
+		return newSFTPSession(responses, requestWriter)
+	}}
+
+	var world = openWorld(fs, dir, dir)
+	if _, beta := world.(*BetaWorld); !beta {
+		t.Fatalf("%T not a *BetaWorld", world)
+	}
+	pool, err := world.ChunkPool(&AllChunksMask{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Fatalf("%d chunks, not just -1,2", pool.Remaining())
+	}
+	r, err := world.OpenChunk(-1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Error(err)
+	}
+	r.Close()
+
+	if _, err := fs.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("stat of a missing file gave %v", err)
+	}
+}
+
+// serveFakeSFTP answers the requests sftpSession makes from the local disk.
+func serveFakeSFTP(requests io.Reader, w io.Writer) {
+	var r = bufio.NewReader(requests)
+	var handles = make(map[string]*os.File)
+	var listed = make(map[string]bool)
+
+	var send = func(packetType byte, fields ...interface{}) {
+		var packet = []byte{0, 0, 0, 0, packetType}
+		for _, field := range fields {
+			switch v := field.(type) {
+			case uint32:
+				packet = binary.BigEndian.AppendUint32(packet, v)
+			case uint64:
+				packet = binary.BigEndian.AppendUint64(packet, v)
+			case string:
+				packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+				packet = append(packet, v...)
+			}
+		}
+		binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+		w.Write(packet)
+	}
+	var attrs = func(fi os.FileInfo) []interface{} {
+		var mode = uint32(fi.Mode() & 0777)
+		if fi.IsDir() {
+			mode |= sftpModeDir
+		}
+		return []interface{}{uint32(sftpAttrSize | sftpAttrPermissions), uint64(fi.Size()), mode}
+	}
+
+	for {
+		var length uint32
+		if binary.Read(r, binary.BigEndian, &length) != nil {
+			return
+		}
+		var packet = make([]byte, length)
+		io.ReadFull(r, packet)
+		var p = &sftpReader{b: packet[1:]}
+		if packet[0] == sftpInit {
+			send(sftpVersion, uint32(3))
+			continue
+		}
+
+		var id = p.uint32()
+		switch packet[0] {
+		case sftpStat:
+			var fi, err = os.Stat(p.string())
+			if err != nil {
+				send(sftpStatus, id, uint32(sftpStatusNoSuchFile), "no such file", "")
+				continue
+			}
+			send(sftpAttrs, append([]interface{}{id}, attrs(fi)...)...)
+		case sftpOpen, sftpOpendir:
+			var name = p.string()
+			var file, err = os.Open(name)
+			if err != nil {
+				send(sftpStatus, id, uint32(sftpStatusNoSuchFile), "no such file", "")
+				continue
+			}
+			handles[name] = file
+			send(sftpHandle, id, name)
+		case sftpRead:
+			var file = handles[p.string()]
+			var offset, size = p.uint64(), p.uint32()
+			var data = make([]byte, size)
+			var n, _ = file.ReadAt(data, int64(offset))
+			if n == 0 {
+				send(sftpStatus, id, uint32(sftpStatusEOF), "eof", "")
+				continue
+			}
+			send(sftpData, id, string(data[:n]))
+		case sftpReaddir:
+			var handle = p.string()
+			if listed[handle] {
+				send(sftpStatus, id, uint32(sftpStatusEOF), "eof", "")
+				continue
+			}
+			listed[handle] = true
+			var infos, _ = handles[handle].Readdir(-1)
+			var fields = []interface{}{id, uint32(len(infos))}
+			for _, fi := range infos {
+				fields = append(fields, fi.Name(), fi.Name())
+				fields = append(fields, attrs(fi)...)
+			}
+			send(sftpName, fields...)
+		case sftpClose:
+			var handle = p.string()
+			handles[handle].Close()
+			delete(handles, handle)
+			send(sftpStatus, id, uint32(sftpStatusOk), "", "")
+		}
+	}
+}
+
+func TestSFTPReaderBounds(t *testing.T) {
+	// A string that's longer than the packet
+	var r = &sftpReader{b: []byte{0xff, 0xff, 0xff, 0xff, 'a'}}
+	if s := r.string(); s != "" || r.err == nil {
+		t.Errorf("string %q, error %v", s, r.err)
+	}
+	r = &sftpReader{b: []byte{0, 0}}
+	if n := r.uint32(); n != 0 || r.err == nil {
+		t.Errorf("uint32 %d, error %v", n, r.err)
+	}
+
+	// More names than the packet could hold
+	var requests, requestWriter = io.Pipe()
+	var responses, responseWriter = io.Pipe()
+	go func() {
+		var r = bufio.NewReader(requests)
+		var header [5]byte
+		for {
+			if _, err := io.ReadFull(r, header[:]); err != nil {
+				return
+			}
+			var packet = make([]byte, binary.BigEndian.Uint32(header[:])-1)
+			io.ReadFull(r, packet)
+			var response = []byte{0, 0, 0, 0, sftpVersion, 0, 0, 0, 3}
+			if header[4] != sftpInit {
+				response = append([]byte{0, 0, 0, 0, sftpName}, packet[:4]...)
+				response = append(response, 0x7f, 0xff, 0xff, 0xff)
+			}
+			binary.BigEndian.PutUint32(response, uint32(len(response)-4))
+			responseWriter.Write(response)
+		}
+	}()
+	s, err := newSFTPSession(responses, requestWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, err := s.readdir("handle"); err == nil {
+		t.Errorf("%d names and no error", len(names))
+	}
+	requestWriter.Close()
+}
+
+func TestSFTPRedial(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "sftpredial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "level.dat"), []byte("level"), 0644)
+
+	// The first connection drops after its init and the first stat, before
+	// the file is opened
+	var dials = 0
+	var fs = &sftpFileSystem{dial: func() (*sftpSession, error) {
+		dials++
+		var requests, requestWriter = io.Pipe()
+		var responses, responseWriter = io.Pipe()
+		if dials == 1 {
+			go func() {
+				serveFakeSFTP(io.LimitReader(requests, int64(9+13+len(dir)+len("/level.dat"))), responseWriter)
+				responseWriter.Close()
+				requests.Close()
+			}()
+		} else {
+			go serveFakeSFTP(requests, responseWriter)
+		}
+		return newSFTPSession(responses, requestWriter)
+	}}
+
+	file, err := fs.Open(filepath.Join(dir, "level.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil || string(data) != "level" {
+		t.Errorf("read %q, %v", data, err)
+	}
+	if dials != 2 {
+		t.Errorf("dialled %d times, not 2", dials)
+	}
+}
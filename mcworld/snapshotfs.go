@@ -0,0 +1,67 @@
+package mcworld
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// How many times a file that changes while it's read is read again, and
+// how long to wait for the server to finish writing before each retry.
+const (
+	snapshotRetries = 5
+	snapshotWait    = 200 * time.Millisecond
+)
+
+// snapshotFileSystem reads each file into memory as it's opened, so a
+// server saving chunks while the world is exported can't tear a read. A
+// read is only kept if the file's size and modification time are the same
+// before and after, and otherwise read again. The region cache keeps the
+// snapshots of the regions in use, so every chunk of a region is from the
+// same save.
+type snapshotFileSystem struct {
+	FileSystem
+}
+
+func (fs snapshotFileSystem) Open(name string) (File, error) {
+	for retry := 0; ; retry++ {
+		var data, info, changed, err = fs.snapshot(name)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			return &memoryFile{bytes.NewReader(data), info}, nil
+		}
+		if retry == snapshotRetries {
+			return nil, errors.New(fmt.Sprintf("%s kept changing while it was read", name))
+		}
+		time.Sleep(snapshotWait)
+	}
+}
+
+func (fs snapshotFileSystem) snapshot(name string) ([]byte, os.FileInfo, bool, error) {
+	var file, err = fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer file.Close()
+
+	var before, beforeErr = file.Stat()
+	if beforeErr != nil {
+		return nil, nil, false, beforeErr
+	}
+	var data, readErr = ioutil.ReadAll(file)
+	if readErr != nil {
+		return nil, nil, false, readErr
+	}
+	var after, afterErr = fs.FileSystem.Stat(name)
+	if afterErr != nil {
+		return nil, nil, false, afterErr
+	}
+
+	var changed = after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) || int64(len(data)) != after.Size()
+	return data, after, changed, nil
+}
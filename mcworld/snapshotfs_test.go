@@ -0,0 +1,38 @@
+package mcworld
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotFileSystem(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "r.0.0.mca")
+	ioutil.WriteFile(path, testRegion(1, 1), 0644)
+
+	var fs = snapshotFileSystem{osFileSystem{}}
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer file.Close()
+
+	// The snapshot doesn't see later writes
+	ioutil.WriteFile(path, nil, 0644)
+	var info, _ = file.Stat()
+	var b [4]byte
+	if _, err := file.ReadAt(b[:], 4*(1+32)); err != nil || info.Size() != 3*4096 {
+		t.Errorf("snapshot of %d bytes, %v", info.Size(), err)
+	}
+	if binary.BigEndian.Uint32(b[:]) != 2<<8|1 {
+		t.Errorf("location %x read from the rewritten file", b)
+	}
+}
@@ -0,0 +1,96 @@
+package mcworld
+
+import (
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+)
+
+// SpongeWorld reads a Sponge .schem file, as saved by WorldEdit since
+// 1.13. The blocks are varint indexes, in YZX order, into a palette of
+// block state strings. Version 2 has the Palette and BlockData at the top
+// of the root Schematic struct, and version 3 moves the root into a
+// Schematic struct and the blocks into a Blocks struct of Palette and
+// Data.
+type SpongeWorld struct {
+	flatWorld
+}
+
+func NewSpongeWorld(fs FileSystem, path string) *SpongeWorld {
+	return &SpongeWorld{flatWorld{fs: fs, path: path, read: readSpongeLevel}}
+}
+
+func readSpongeLevel(fs FileSystem, path string) (*flatLevel, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var r, decompressErr = nbt.NewDecompressor(file)
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	defer r.Close()
+
+	var root, parseErr = nbt.Parse(r)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if schematic, ok := root["Schematic"].(map[string]interface{}); ok {
+		root = schematic
+	}
+
+	var (
+		palette, _ = root["Palette"].(map[string]interface{})
+		data, _    = root["BlockData"].([]byte)
+	)
+	if blocks, ok := root["Blocks"].(map[string]interface{}); ok {
+		palette, _ = blocks["Palette"].(map[string]interface{})
+		data, _ = blocks["Data"].([]byte)
+	}
+	if palette == nil {
+		return nil, errors.New("Sponge schematic palette not found")
+	}
+
+	var states = make(map[int]nbt.Block)
+	for state, index := range palette {
+		if i, ok := index.(int); ok {
+			states[i] = nbt.ParseBlockState(state)
+		}
+	}
+
+	var level = new(flatLevel)
+	level.width, _ = root["Width"].(int)
+	level.length, _ = root["Length"].(int)
+	level.height, _ = root["Height"].(int)
+
+	// Sizes are unsigned shorts
+	level.width &= 0xffff
+	level.length &= 0xffff
+	level.height &= 0xffff
+
+	var blocks = make([]nbt.Block, 0, level.width*level.length*level.height)
+	for i := 0; i < len(data); {
+		var index, shift int
+		for {
+			if i >= len(data) {
+				return nil, errors.New("Sponge schematic block data ends inside a varint")
+			}
+			var b = data[i]
+			i++
+			index |= int(b&0x7f) << uint(shift)
+			if b&0x80 == 0 {
+				break
+			}
+			if shift += 7; shift > 28 {
+				return nil, errors.New(fmt.Sprintf("Sponge schematic varint too long at byte %d", i))
+			}
+		}
+		blocks = append(blocks, states[index])
+	}
+	level.setBlocks(blocks)
+	level.spawnX, level.spawnY, level.spawnZ = level.width/2, level.height, level.length/2
+
+	return level, level.check()
+}
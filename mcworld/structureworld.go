@@ -0,0 +1,96 @@
+package mcworld
+
+import (
+	"errors"
+	"fmt"
+	"github.com/quag/mcobj/nbt"
+)
+
+// StructureWorld reads a vanilla structure .nbt file, as saved by
+// structure blocks and used by data pack structures. Only the blocks that
+// aren't air are listed, each with its position and an index into the
+// palette. Structures with random variants (shipwrecks) keep several
+// palettes, of which the first is used.
+type StructureWorld struct {
+	flatWorld
+}
+
+func NewStructureWorld(fs FileSystem, path string) *StructureWorld {
+	return &StructureWorld{flatWorld{fs: fs, path: path, read: readStructureLevel}}
+}
+
+func readStructureLevel(fs FileSystem, path string) (*flatLevel, error) {
+	var file, openErr = fs.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer file.Close()
+
+	var r, decompressErr = nbt.NewDecompressor(file)
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	defer r.Close()
+
+	var root, parseErr = nbt.Parse(r)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	var size = intList(root["size"])
+	if len(size) != 3 {
+		return nil, errors.New("Structure size not found")
+	}
+
+	var paletteList, _ = root["palette"].([]interface{})
+	if palettes, ok := root["palettes"].([]interface{}); ok && len(palettes) != 0 {
+		paletteList, _ = palettes[0].([]interface{})
+	}
+	var palette = make([]nbt.Block, len(paletteList))
+	for i, entry := range paletteList {
+		var state, _ = entry.(map[string]interface{})
+		var name, _ = state["Name"].(string)
+		var properties, _ = state["Properties"].(map[string]interface{})
+		palette[i] = nbt.BlockState(name, properties)
+	}
+
+	var level = new(flatLevel)
+	level.width, level.height, level.length = size[0], size[1], size[2]
+	var blocks = make([]nbt.Block, level.width*level.height*level.length)
+
+	var blockList, _ = root["blocks"].([]interface{})
+	for _, item := range blockList {
+		var block, _ = item.(map[string]interface{})
+		var (
+			pos      = intList(block["pos"])
+			state, _ = block["state"].(int)
+		)
+		if len(pos) != 3 || state < 0 || state >= len(palette) {
+			return nil, errors.New(fmt.Sprintf("Structure block %v of state %d is out of range", pos, state))
+		}
+		var x, y, z = pos[0], pos[1], pos[2]
+		if x < 0 || y < 0 || z < 0 || x >= level.width || y >= level.height || z >= level.length {
+			return nil, errors.New(fmt.Sprintf("Structure block %v is outside its %v size", pos, size))
+		}
+		blocks[(y*level.length+z)*level.width+x] = palette[state]
+	}
+	level.setBlocks(blocks)
+	level.spawnX, level.spawnY, level.spawnZ = level.width/2, level.height, level.length/2
+
+	return level, level.check()
+}
+
+// intList returns an NBT list of int tags as ints.
+func intList(value interface{}) []int {
+	switch list := value.(type) {
+	case []int:
+		return list
+	case []interface{}:
+		var ints = make([]int, len(list))
+		for i, item := range list {
+			ints[i], _ = item.(int)
+		}
+		return ints
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package mcworld
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// TrimWorld writes the chunks of world that mask leaves unmasked to new
+// Anvil region files in outDir/region, along with their entities and
+// points of interest if the world keeps them apart, and the world's
+// level.dat. Chunks from other formats are written as the Java chunks
+// they're read as. Chunks that can't be read are left out, and passed to
+// skip unless it's nil. It returns how many chunks were written.
+//
+// outDir can't be the world's own folder, as its region files would be
+// written over while they're read.
+func TrimWorld(world World, mask ChunkMask, outDir string, skip func(x, z int, err error)) (int, error) {
+	if beta, ok := world.(*BetaWorld); ok {
+		for _, dir := range []string{beta.worldDir, beta.dimensionDir} {
+			if sameFolder(beta.fs, dir, outDir) {
+				return 0, errors.New(fmt.Sprintf("%v is the world's own folder", outDir))
+			}
+		}
+	}
+
+	var chunks, iterErr = IterateChunks(world, mask)
+	if iterErr != nil {
+		return 0, iterErr
+	}
+
+	var regions = make(map[[2]int][][2]int)
+	for {
+		var x, z, ok = chunks.Next()
+		if !ok {
+			break
+		}
+		var key = [2]int{x >> 5, z >> 5}
+		regions[key] = append(regions[key], [2]int{x, z})
+	}
+
+	var keys = make([][2]int, 0, len(regions))
+	for key := range regions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][1] < keys[j][1] || keys[i][1] == keys[j][1] && keys[i][0] < keys[j][0]
+	})
+
+	var folders = []trimFolder{{"region", world.OpenChunk, true}}
+	if opener, ok := world.(EntityOpener); ok {
+		folders = append(folders, trimFolder{"entities", opener.OpenEntities, false})
+	}
+	if opener, ok := world.(POIOpener); ok {
+		folders = append(folders, trimFolder{"poi", opener.OpenPOI, false})
+	}
+
+	var written int
+	for _, folder := range folders {
+		var dir = filepath.Join(outDir, folder.name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return written, err
+		}
+		for _, key := range keys {
+			var filename = filepath.Join(dir, fmt.Sprintf("r.%d.%d.mca", key[0], key[1]))
+			var n, err = writeRegion(filename, regions[key], folder.open, folder.required, skip)
+			if err != nil {
+				return written, err
+			}
+			if folder.required {
+				written += n
+			}
+		}
+		if !folder.required {
+			// Only stays if it isn't empty
+			os.Remove(dir)
+		}
+	}
+
+	if err := copyLevelDat(world, outDir); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// trimFolder is a folder of region files to write, and how to open their
+// chunks. Chunks are only required to open in the region folder, as most
+// chunks have no entities or points of interest.
+type trimFolder struct {
+	name     string
+	open     func(x, z int) (io.ReadCloser, error)
+	required bool
+}
+
+// sameFolder reports whether outDir is the folder dir of a world on the
+// local file system.
+func sameFolder(fs FileSystem, dir, outDir string) bool {
+	if _, ok := fs.(osFileSystem); !ok {
+		return false
+	}
+	var a, aErr = os.Stat(dir)
+	var b, bErr = os.Stat(outDir)
+	return aErr == nil && bErr == nil && os.SameFile(a, b)
+}
+
+// writeRegion writes chunks to a new region file, leaving out the ones
+// open can't open. Those that are required and can't be opened or read
+// are passed to skip. Chunks too big for the region file go in a c.x.z.mcc
+// file next to it. The region file is written under another name and only
+// renamed once all its chunks are read, and no file is left when there
+// are no chunks.
+func writeRegion(filename string, chunks [][2]int, open func(x, z int) (io.ReadCloser, error), required bool, skip func(x, z int, err error)) (int, error) {
+	var partFilename = filename + ".part"
+	var file, err = os.Create(partFilename)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(partFilename)
+	}()
+	var skipped = func(x, z int, err error) {
+		if required && skip != nil {
+			skip(x, z, err)
+		}
+	}
+
+	var (
+		header  [2 * 4096]byte
+		sector  = 2
+		written int
+		now     = uint32(time.Now().Unix())
+	)
+	for _, chunk := range chunks {
+		var x, z = chunk[0], chunk[1]
+		var r, openErr = open(x, z)
+		if openErr != nil {
+			skipped(x, z, openErr)
+			continue
+		}
+		var data, readErr = ioutil.ReadAll(r)
+		r.Close()
+		if readErr != nil {
+			skipped(x, z, readErr)
+			continue
+		}
+
+		var compressed bytes.Buffer
+		var zw = zlib.NewWriter(&compressed)
+		zw.Write(data)
+		zw.Close()
+
+		var payload bytes.Buffer
+		var compressionType = byte(compressionZlib)
+		if 5+compressed.Len() > 255*4096 {
+			var mccFilename = filepath.Join(filepath.Dir(filename), fmt.Sprintf("c.%d.%d.mcc", x, z))
+			if err := ioutil.WriteFile(mccFilename, compressed.Bytes(), 0644); err != nil {
+				return written, err
+			}
+			compressed.Reset()
+			compressionType |= externalChunkFlag
+		}
+		binary.Write(&payload, binary.BigEndian, uint32(compressed.Len()+1))
+		payload.WriteByte(compressionType)
+		payload.Write(compressed.Bytes())
+		var sectors = (payload.Len() + 4095) / 4096
+		payload.Write(make([]byte, sectors*4096-payload.Len()))
+
+		if _, err := file.WriteAt(payload.Bytes(), int64(sector)*4096); err != nil {
+			return written, err
+		}
+		var i = 4 * ((x & 31) + (z&31)*32)
+		binary.BigEndian.PutUint32(header[i:], uint32(sector<<8|sectors))
+		binary.BigEndian.PutUint32(header[4096+i:], now)
+		sector += sectors
+		written++
+	}
+
+	if written == 0 {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		return 0, nil
+	}
+	if _, err := file.WriteAt(header[:], 0); err != nil {
+		return written, err
+	}
+	if err := file.Close(); err != nil {
+		return written, err
+	}
+	return written, os.Rename(partFilename, filename)
+}
+
+// copyLevelDat copies the level.dat of a Java world, when it has one.
+func copyLevelDat(world World, outDir string) error {
+	var beta, ok = world.(*BetaWorld)
+	if !ok {
+		return nil
+	}
+
+	var file, err = beta.fs.Open(filepath.Join(beta.worldDir, "level.dat"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var data, readErr = ioutil.ReadAll(file)
+	if readErr != nil {
+		return readErr
+	}
+	return ioutil.WriteFile(filepath.Join(outDir, "level.dat"), data, 0644)
+}
@@ -0,0 +1,120 @@
+package mcworld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrimWorld(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "trim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var worldDir, outDir = filepath.Join(dir, "world"), filepath.Join(dir, "trimmed")
+	os.MkdirAll(filepath.Join(worldDir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(worldDir, "region", "r.-1.0.mca"), testRegion(31, 2), 0644)
+	ioutil.WriteFile(filepath.Join(worldDir, "region", "r.0.0.mca"), testRegion(32+3, 4), 0644)
+	ioutil.WriteFile(filepath.Join(worldDir, "level.dat"), []byte("level"), 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: worldDir, dimensionDir: worldDir}
+	defer world.Close()
+	var n, trimErr = TrimWorld(world, &RectangleChunkMask{-1, 0, 1, 10}, outDir, nil)
+	if trimErr != nil {
+		t.Fatal(trimErr)
+	}
+	if n != 1 {
+		t.Errorf("%d chunks written, not 1", n)
+	}
+
+	var files, _ = ioutil.ReadDir(filepath.Join(outDir, "region"))
+	if len(files) != 1 || files[0].Name() != "r.-1.0.mca" {
+		t.Errorf("trimmed regions %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "entities")); !os.IsNotExist(err) {
+		t.Errorf("empty entities folder left: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(outDir, "level.dat")); string(data) != "level" {
+		t.Errorf("level.dat is %q", data)
+	}
+
+	var trimmed = &BetaWorld{fs: osFileSystem{}, worldDir: outDir, dimensionDir: outDir}
+	defer trimmed.Close()
+	var pool, poolErr = trimmed.ChunkPool(&AllChunksMask{})
+	if poolErr != nil {
+		t.Fatal(poolErr)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Errorf("%d chunks in the trimmed world, not just -1,2", pool.Remaining())
+	}
+	var r, chunkErr = trimmed.OpenChunk(-1, 2)
+	if chunkErr != nil {
+		t.Fatal(chunkErr)
+	}
+	var chunk, nbtErr = nbt.ReadChunkNbt(r)
+	r.Close()
+	if nbtErr != nil || chunk.XPos != -1 || chunk.ZPos != 2 {
+		t.Errorf("trimmed chunk %v, %v", chunk, nbtErr)
+	}
+}
+
+func TestTrimWorldIntoItself(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "trim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var region = testRegion(32+3, 4)
+	os.MkdirAll(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.0.0.mca"), region, 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	defer world.Close()
+	if _, err := TrimWorld(world, &AllChunksMask{}, dir+string(filepath.Separator)+".", nil); err == nil {
+		t.Error("trimmed a world into its own folder")
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "region", "r.0.0.mca")); !bytes.Equal(data, region) {
+		t.Errorf("region file changed to %d bytes", len(data))
+	}
+}
+
+func TestTrimWorldSkipsUnreadable(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "trim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Chunk 3,4 as it should be, and 5,4 of an unknown compression type
+	var worldDir, outDir = filepath.Join(dir, "world"), filepath.Join(dir, "trimmed")
+	var region = append(testRegion(32+3, 4), make([]byte, 4096)...)
+	binary.BigEndian.PutUint32(region[4*(5+4*32):], 3<<8|1)
+	binary.BigEndian.PutUint32(region[3*4096:], 2)
+	region[3*4096+4] = 99
+	os.MkdirAll(filepath.Join(worldDir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(worldDir, "region", "r.0.0.mca"), region, 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: worldDir, dimensionDir: worldDir}
+	defer world.Close()
+	var skipped [][2]int
+	var n, trimErr = TrimWorld(world, &AllChunksMask{}, outDir, func(x, z int, err error) {
+		skipped = append(skipped, [2]int{x, z})
+	})
+	if trimErr != nil {
+		t.Fatal(trimErr)
+	}
+	if n != 1 || len(skipped) != 1 || skipped[0] != [2]int{5, 4} {
+		t.Errorf("%d chunks written, skipped %v", n, skipped)
+	}
+	var files, _ = ioutil.ReadDir(filepath.Join(outDir, "region"))
+	if len(files) != 1 || files[0].Name() != "r.0.0.mca" {
+		t.Errorf("trimmed regions %v", files)
+	}
+}
@@ -0,0 +1,91 @@
+package mcworld
+
+import (
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Validator is implemented by worlds that can check their files for damage
+// before they're exported.
+type Validator interface {
+	Validate() (*Validation, error)
+}
+
+// Validation is what Validate found, with a report for each region file
+// that has problems.
+type Validation struct {
+	Regions, Chunks int
+	Reports         []*RegionReport
+}
+
+// Validate reads every chunk of every region file, checking the header
+// places the chunks sensibly in the file, that each chunk decompresses
+// (which checks the zlib or gzip checksum) and parses, and that each chunk
+// is in the header slot for the position it says it's at.
+func (w *BetaWorld) Validate() (*Validation, error) {
+	var regions, readErr = w.regionFiles()
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var validation = &Validation{}
+	for _, region := range regions {
+		var report = &RegionReport{Filename: region.filename}
+		w.validateRegion(region, validation, report)
+		if len(report.Problems) != 0 {
+			validation.Reports = append(validation.Reports, report)
+		}
+	}
+	return validation, nil
+}
+
+func (w *BetaWorld) validateRegion(region regionFile, validation *Validation, report *RegionReport) {
+	var handle, openErr = w.regions.acquire(w.fs, region.filename)
+	if openErr != nil {
+		report.problem("unreadable: %v", openErr)
+		return
+	}
+	var entry, entryErr = readRegionEntry(handle.file, region.x, region.z, report)
+	handle.release()
+	if entryErr != nil {
+		report.problem("unreadable: %v", entryErr)
+		return
+	}
+	validation.Regions++
+
+	var mcaName = filepath.Base(region.filename)
+	for _, slot := range entry.Slots {
+		var (
+			x = region.x*32 + int(slot)%32
+			z = region.z*32 + int(slot)/32
+		)
+		validation.Chunks++
+
+		var chunkHandle, acquireErr = w.regions.acquire(w.fs, region.filename)
+		if acquireErr != nil {
+			report.problem("unreadable: %v", acquireErr)
+			return
+		}
+		var r, chunkErr = w.openHandleChunk(chunkHandle, "region", mcaName, x, z)
+		if chunkErr != nil {
+			report.problem("chunk %d,%d: %v", x, z, chunkErr)
+			continue
+		}
+		var data, decompressErr = ioutil.ReadAll(r)
+		r.Close()
+		if decompressErr != nil {
+			report.problem("chunk %d,%d doesn't decompress: %v", x, z, decompressErr)
+			continue
+		}
+
+		var chunk, nbtErr = nbt.ReadChunkBytes(data)
+		if nbtErr != nil {
+			report.problem("chunk %d,%d doesn't parse: %v", x, z, nbtErr)
+			continue
+		}
+		if chunk.XPos != x || chunk.ZPos != z {
+			report.problem("chunk %d,%d says it's chunk %d,%d", x, z, chunk.XPos, chunk.ZPos)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package mcworld
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBetaWorldValidate(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// -1,2 is fine (from testRegion). -32,0 says it's 5,0, -31,0 has a
+	// corrupt stream and -30,0 starts in the header.
+	var region = append(testRegion(31, 2), make([]byte, 2*4096)...)
+	var chunk = new(chunkNbtWriter)
+	chunk.beginStruct("")
+	chunk.int32("xPos", 5)
+	chunk.int32("zPos", 0)
+	chunk.endStruct()
+	var payload bytes.Buffer
+	var zw = zlib.NewWriter(&payload)
+	zw.Write(chunk.Bytes())
+	zw.Close()
+
+	binary.BigEndian.PutUint32(region[0:], 3<<8|1)
+	binary.BigEndian.PutUint32(region[3*4096:], uint32(payload.Len()+1))
+	region[3*4096+4] = compressionZlib
+	copy(region[3*4096+5:], payload.Bytes())
+
+	binary.BigEndian.PutUint32(region[4:], 4<<8|1)
+	binary.BigEndian.PutUint32(region[4*4096:], 10)
+	region[4*4096+4] = compressionZlib
+	copy(region[4*4096+5:], "corrupt!!")
+
+	binary.BigEndian.PutUint32(region[8:], 1<<8|1)
+
+	os.Mkdir(filepath.Join(dir, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "region", "r.-1.0.mca"), region, 0644)
+
+	var world = &BetaWorld{fs: osFileSystem{}, worldDir: dir, dimensionDir: dir}
+	defer world.Close()
+	var validation, validateErr = world.Validate()
+	if validateErr != nil {
+		t.Fatal(validateErr)
+	}
+	if validation.Regions != 1 || validation.Chunks != 3 {
+		t.Errorf("%d regions and %d chunks validated, not 1 and 3", validation.Regions, validation.Chunks)
+	}
+	if len(validation.Reports) != 1 {
+		t.Fatalf("%d reports, not 1", len(validation.Reports))
+	}
+
+	var problems = validation.Reports[0].Problems
+	for i, expected := range []string{
+		"chunk -30,0 starts inside the header",
+		"chunk -32,0 says it's chunk 5,0",
+		"chunk -31,0: zlib: invalid header",
+	} {
+		if i >= len(problems) || !strings.HasPrefix(problems[i], expected) {
+			t.Errorf("problems %q don't have %q", problems, expected)
+			break
+		}
+	}
+}
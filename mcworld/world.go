@@ -1,16 +1,30 @@
 package mcworld
 
 import (
+	"github.com/quag/mcobj/nbt"
 	"io"
 	"math"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type ChunkOpener interface {
 	OpenChunk(x, z int) (io.ReadCloser, error)
 }
 
+// EntityOpener is implemented by worlds that keep entities apart from the
+// blocks of their chunks.
+type EntityOpener interface {
+	OpenEntities(x, z int) (io.ReadCloser, error)
+}
+
+// POIOpener is implemented by worlds that keep points of interest.
+type POIOpener interface {
+	OpenPOI(x, z int) (io.ReadCloser, error)
+}
+
 type ChunkPooler interface {
 	ChunkPool(mask ChunkMask) (ChunkPool, error)
 }
@@ -18,6 +32,7 @@ type ChunkPooler interface {
 type World interface {
 	ChunkOpener
 	ChunkPooler
+	Level() (*nbt.Level, error)
 }
 
 type ChunkPool interface {
@@ -26,16 +41,121 @@ type ChunkPool interface {
 	BoundingBox() *BoundingBox
 }
 
+// RegionReporter is implemented by pools that left out damaged region files
+// or chunks rather than failing.
+type RegionReporter interface {
+	Reports() []*RegionReport
+}
+
+// RegionIndexer is implemented by worlds that can keep which chunks their
+// region files hold in a RegionIndex.
+type RegionIndexer interface {
+	SetRegionIndex(index *RegionIndex)
+}
+
 type BoundingBox struct {
 	X0, Z0, X1, Z1 int
 }
 
-func OpenWorld(worldDir string) World {
-	var _, err = os.Stat(filepath.Join(worldDir, "region"))
+// OpenWorld opens a world from a folder, an archive of one, a folder of
+// loose region files, one of the single file levels from before Alpha, or
+// a schematic or structure.
+func OpenWorld(worldDir string) (World, error) {
+	var fs, dir, err = openFileSystem(worldDir)
 	if err != nil {
-		return &AlphaWorld{worldDir}
+		return nil, err
+	}
+	return openWorld(fs, dir, dir), nil
+}
+
+// openFileSystem finds the file system a world is on, and where in it the
+// world is.
+func openFileSystem(worldDir string) (FileSystem, string, error) {
+	if u, err := url.Parse(worldDir); err == nil {
+		switch u.Scheme {
+		case "http", "https":
+			return newHTTPFileSystem(u), "", nil
+		case "sftp":
+			return newSFTPFileSystem(u)
+		case "s3":
+			return newS3FileSystem(u)
+		}
 	}
-	return &BetaWorld{worldDir}
+
+	if fi, err := os.Stat(worldDir); err == nil && !fi.IsDir() {
+		switch strings.ToLower(filepath.Ext(worldDir)) {
+		case ".zip", ".mcworld":
+			return openZipFileSystem(worldDir)
+		}
+	}
+	return osFileSystem{}, worldDir, nil
+}
+
+// openWorld picks the format of the world in worldDir, reading the chunks
+// of the dimension in dimensionDir (which is worldDir for the Overworld).
+func openWorld(fs FileSystem, worldDir, dimensionDir string) World {
+	if fi, err := fs.Stat(worldDir); err == nil && !fi.IsDir() {
+		switch strings.ToLower(filepath.Ext(worldDir)) {
+		case ".mclevel":
+			return NewIndevWorld(fs, worldDir)
+		case ".mine", ".dat":
+			return NewClassicWorld(fs, worldDir)
+		case ".schematic":
+			return NewSchematicWorld(fs, worldDir)
+		case ".schem":
+			return NewSpongeWorld(fs, worldDir)
+		case ".litematic":
+			return NewLitematicWorld(fs, worldDir)
+		case ".nbt":
+			return NewStructureWorld(fs, worldDir)
+		}
+	}
+
+	if isDir(fs, filepath.Join(worldDir, "db")) {
+		return &BedrockWorld{fs: fs, worldDir: worldDir}
+	}
+
+	if fi, err := fs.Stat(filepath.Join(worldDir, "chunks.dat")); err == nil && !fi.IsDir() {
+		return &PocketWorld{fs, worldDir}
+	}
+
+	if isDir(fs, filepath.Join(dimensionDir, "region2d")) {
+		return &CubicWorld{fs: fs, worldDir: worldDir, dimensionDir: dimensionDir}
+	}
+
+	if _, err := fs.Stat(filepath.Join(dimensionDir, "region")); err != nil {
+		if hasRegionFiles(fs, dimensionDir) {
+			return &BetaWorld{fs: fs, worldDir: worldDir, dimensionDir: dimensionDir, regionDir: dimensionDir}
+		}
+		return &AlphaWorld{fs, worldDir, dimensionDir}
+	}
+	return &BetaWorld{fs: fs, worldDir: worldDir, dimensionDir: dimensionDir}
+}
+
+// hasRegionFiles reports whether dir holds r.x.z.mca or r.x.z.mcr files
+// itself, rather than in a region folder.
+func hasRegionFiles(fs FileSystem, dir string) bool {
+	var names, err = fs.ReadDirNames(dir)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if _, _, _, ok := parseRegionFilename(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readJavaLevel reads the spawn point from the level.dat of a Java Edition
+// world.
+func readJavaLevel(fs FileSystem, worldDir string) (*nbt.Level, error) {
+	var file, err = fs.Open(filepath.Join(worldDir, "level.dat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return nbt.ReadLevelDat(file)
 }
 
 type ReadCloserPair struct {
@@ -66,13 +186,15 @@ func EmptyBoundingBox() *BoundingBox {
 func (b *BoundingBox) Union(x, z int) {
 	if x < b.X0 {
 		b.X0 = x
-	} else if x > b.X1 {
+	}
+	if x > b.X1 {
 		b.X1 = x
 	}
 
 	if z < b.Z0 {
 		b.Z0 = z
-	} else if z > b.Z1 {
+	}
+	if z > b.Z1 {
 		b.Z1 = z
 	}
 }
@@ -0,0 +1,183 @@
+package mcworld
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// zipFileSystem reads a world from a .zip of a save, or a Bedrock .mcworld
+// export (which is a zip too). Zip entries can't be seeked, so each file is
+// decompressed into memory when opened, and the last few are kept as a
+// region file is opened again for every chunk in it.
+type zipFileSystem struct {
+	files map[string]*zip.File
+	dirs  map[string][]string
+
+	mutex  sync.Mutex
+	recent []zipCacheEntry
+}
+
+const zipCacheSize = 4
+
+type zipCacheEntry struct {
+	name string
+	data []byte
+}
+
+// openZipFileSystem opens an archive and finds the world inside it, which
+// is the shallowest folder holding a level.dat.
+func openZipFileSystem(archivePath string) (*zipFileSystem, string, error) {
+	var archive, err = zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var fs = &zipFileSystem{files: make(map[string]*zip.File), dirs: make(map[string][]string)}
+	var root string
+	var rootDepth = -1
+	for _, f := range archive.File {
+		var name = path.Clean(strings.TrimPrefix(f.Name, "/"))
+		if strings.HasSuffix(f.Name, "/") {
+			fs.addDir(name)
+			continue
+		}
+		fs.files[name] = f
+		fs.addDir(path.Dir(name))
+		fs.addChild(path.Dir(name), path.Base(name))
+
+		if path.Base(name) == "level.dat" {
+			var depth = strings.Count(name, "/")
+			if rootDepth == -1 || depth < rootDepth {
+				root, rootDepth = path.Dir(name), depth
+			}
+		}
+	}
+	for _, children := range fs.dirs {
+		sort.Strings(children)
+	}
+
+	if rootDepth == -1 {
+		root = "."
+	}
+	return fs, filepath.FromSlash(root), nil
+}
+
+func (fs *zipFileSystem) addDir(name string) {
+	if _, exists := fs.dirs[name]; exists {
+		return
+	}
+	fs.dirs[name] = nil
+	if name != "." {
+		var parent = path.Dir(name)
+		fs.addDir(parent)
+		fs.addChild(parent, path.Base(name))
+	}
+}
+
+func (fs *zipFileSystem) addChild(dir, name string) {
+	for _, child := range fs.dirs[dir] {
+		if child == name {
+			return
+		}
+	}
+	fs.dirs[dir] = append(fs.dirs[dir], name)
+}
+
+func zipName(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+func (fs *zipFileSystem) Open(name string) (File, error) {
+	var f, ok = fs.files[zipName(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	var data, err = fs.read(f)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryFile{bytes.NewReader(data), f.FileInfo()}, nil
+}
+
+func (fs *zipFileSystem) read(f *zip.File) ([]byte, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	for i, entry := range fs.recent {
+		if entry.name == f.Name {
+			copy(fs.recent[1:i+1], fs.recent[:i])
+			fs.recent[0] = entry
+			return entry.data, nil
+		}
+	}
+
+	var r, openErr = f.Open()
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer r.Close()
+
+	var data, readErr = ioutil.ReadAll(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	fs.recent = append([]zipCacheEntry{{f.Name, data}}, fs.recent...)
+	if len(fs.recent) > zipCacheSize {
+		fs.recent = fs.recent[:zipCacheSize]
+	}
+	return data, nil
+}
+
+func (fs *zipFileSystem) Stat(name string) (os.FileInfo, error) {
+	var key = zipName(name)
+	if f, ok := fs.files[key]; ok {
+		return f.FileInfo(), nil
+	}
+	if _, ok := fs.dirs[key]; ok {
+		return dirInfo(path.Base(key)), nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *zipFileSystem) ReadDirNames(name string) ([]string, error) {
+	var children, ok = fs.dirs[zipName(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return append([]string(nil), children...), nil
+}
+
+// memoryFile is a File already read into memory.
+type memoryFile struct {
+	*bytes.Reader
+	info os.FileInfo
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+func (f *memoryFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// dirInfo is the os.FileInfo of a folder that only exists as part of the
+// names of other files.
+type dirInfo string
+
+func (d dirInfo) Name() string       { return string(d) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() interface{}   { return nil }
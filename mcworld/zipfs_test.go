@@ -0,0 +1,91 @@
+package mcworld
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"github.com/quag/mcobj/nbt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestOpenZippedWorld(t *testing.T) {
+	var file, err = ioutil.TempFile("", "world*.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	var archive = zip.NewWriter(file)
+	var add = func(name string, data []byte) {
+		var w, err = archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	add("saves/World1/level.dat", nil)
+	add("saves/World1/region/r.-1.0.mca", testRegion(31, 2))
+	add("saves/World1/DIM-1/region/r.0.0.mca", testRegion(0, 0))
+	archive.Close()
+	file.Close()
+
+	world, err := OpenWorld(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := world.ChunkPool(&AllChunksMask{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Remaining() != 1 || !pool.Pop(-1, 2) {
+		t.Fatalf("%d chunks, not just -1,2", pool.Remaining())
+	}
+
+	r, err := world.OpenChunk(-1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := nbt.ReadChunkNbt(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.XPos != -1 || chunk.ZPos != 2 {
+		t.Errorf("chunk %d,%d not -1,2", chunk.XPos, chunk.ZPos)
+	}
+
+	nether, err := OpenDimension(file.Name(), "nether")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool, err := nether.ChunkPool(&AllChunksMask{}); err != nil || pool.Remaining() != 1 {
+		t.Errorf("nether chunk pool %v, %v", pool, err)
+	}
+}
+
+// testRegion makes a region file holding the one zlib compressed chunk at
+// x,z within the region.
+func testRegion(x, z int) []byte {
+	var chunk = new(chunkNbtWriter)
+	chunk.beginStruct("")
+	chunk.beginStruct("Level")
+	chunk.int32("xPos", x-32)
+	chunk.int32("zPos", z)
+	chunk.endStruct()
+	chunk.endStruct()
+
+	var payload bytes.Buffer
+	var zw = zlib.NewWriter(&payload)
+	zw.Write(chunk.Bytes())
+	zw.Close()
+
+	var region = make([]byte, 3*4096)
+	binary.BigEndian.PutUint32(region[4*(x+z*32):], 2<<8|1)
+	binary.BigEndian.PutUint32(region[2*4096:], uint32(payload.Len()+1))
+	region[2*4096+4] = compressionZlib
+	copy(region[2*4096+5:], payload.Bytes())
+	return region
+}
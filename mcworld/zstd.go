@@ -0,0 +1,813 @@
+package mcworld
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// A Zstandard (RFC 8878) decoder, enough for whole frames in memory as
+// stored in Linear region files. Dictionaries aren't supported and the
+// content checksum isn't checked.
+
+var errZstdCorrupt = errors.New("Corrupt zstd data")
+
+const (
+	zstdMagic          = 0xfd2fb528
+	zstdSkippableMagic = 0x184d2a50 // Low four bits vary
+)
+
+// zstdDecompress decodes every frame of src one after another.
+func zstdDecompress(src []byte) ([]byte, error) {
+	var out []byte
+	for len(src) != 0 {
+		if len(src) < 4 {
+			return nil, errZstdCorrupt
+		}
+		var magic = binary.LittleEndian.Uint32(src)
+		if magic&^0xf == zstdSkippableMagic {
+			if len(src) < 8 {
+				return nil, errZstdCorrupt
+			}
+			var size = int(binary.LittleEndian.Uint32(src[4:]))
+			if size > len(src)-8 {
+				return nil, errZstdCorrupt
+			}
+			src = src[8+size:]
+			continue
+		}
+		if magic != zstdMagic {
+			return nil, errors.New("zstd magic not found")
+		}
+
+		var frame = &zstdFrame{out: out, rep: [3]int{1, 4, 8}}
+		var rest, err = frame.decode(src[4:])
+		if err != nil {
+			return nil, err
+		}
+		out, src = frame.out, rest
+	}
+	return out, nil
+}
+
+// zstdFrame is what carries over between the blocks of a frame: the output
+// so far (which matches refer back into), the repeated offsets and the
+// tables that later blocks can reuse.
+type zstdFrame struct {
+	out   []byte
+	start int // Where the frame's output starts in out
+	rep   [3]int
+
+	huffman        *zstdHuffmanTable
+	literalLengths *fseTable
+	offsets        *fseTable
+	matchLengths   *fseTable
+	literals       []byte
+}
+
+func (f *zstdFrame) decode(src []byte) ([]byte, error) {
+	f.start = len(f.out)
+	if len(src) < 1 {
+		return nil, errZstdCorrupt
+	}
+	var (
+		descriptor    = src[0]
+		contentSize   = descriptor >> 6
+		singleSegment = descriptor&0x20 != 0
+		checksum      = descriptor&0x04 != 0
+		dictionary    = descriptor & 0x03
+		i             = 1
+	)
+	if descriptor&0x08 != 0 {
+		return nil, errZstdCorrupt
+	}
+	if !singleSegment {
+		i++ // Window descriptor, not needed with all of the output kept
+	}
+	if dictionary != 0 {
+		// An id of zero means no dictionary
+		var size = []int{0, 1, 2, 4}[dictionary]
+		if i+size > len(src) {
+			return nil, errZstdCorrupt
+		}
+		var id uint32
+		for j := 0; j < size; j++ {
+			id |= uint32(src[i+j]) << uint(8*j)
+		}
+		if id != 0 {
+			return nil, errors.New("zstd dictionaries aren't supported")
+		}
+		i += size
+	}
+	switch {
+	case contentSize == 0 && singleSegment:
+		i++
+	case contentSize == 1:
+		i += 2
+	case contentSize == 2:
+		i += 4
+	case contentSize == 3:
+		i += 8
+	}
+	if i > len(src) {
+		return nil, errZstdCorrupt
+	}
+	src = src[i:]
+
+	for {
+		if len(src) < 3 {
+			return nil, errZstdCorrupt
+		}
+		var (
+			header = int(src[0]) | int(src[1])<<8 | int(src[2])<<16
+			last   = header&1 != 0
+			kind   = header >> 1 & 3
+			size   = header >> 3
+		)
+		src = src[3:]
+
+		switch kind {
+		case 0: // Raw
+			if size > len(src) {
+				return nil, errZstdCorrupt
+			}
+			f.out = append(f.out, src[:size]...)
+			src = src[size:]
+		case 1: // RLE
+			if len(src) < 1 {
+				return nil, errZstdCorrupt
+			}
+			for j := 0; j < size; j++ {
+				f.out = append(f.out, src[0])
+			}
+			src = src[1:]
+		case 2:
+			if size > len(src) {
+				return nil, errZstdCorrupt
+			}
+			if err := f.decodeBlock(src[:size]); err != nil {
+				return nil, err
+			}
+			src = src[size:]
+		default:
+			return nil, errZstdCorrupt
+		}
+
+		if last {
+			break
+		}
+	}
+
+	if checksum {
+		if len(src) < 4 {
+			return nil, errZstdCorrupt
+		}
+		src = src[4:]
+	}
+	return src, nil
+}
+
+func (f *zstdFrame) decodeBlock(block []byte) error {
+	var n, err = f.decodeLiterals(block)
+	if err != nil {
+		return err
+	}
+	return f.decodeSequences(block[n:])
+}
+
+// decodeLiterals decodes the literals section into f.literals, returning
+// its length.
+func (f *zstdFrame) decodeLiterals(block []byte) (int, error) {
+	if len(block) < 1 {
+		return 0, errZstdCorrupt
+	}
+	var (
+		kind       = block[0] & 3
+		sizeFormat = block[0] >> 2 & 3
+	)
+
+	if kind == 0 || kind == 1 { // Raw or RLE
+		var size, header int
+		switch sizeFormat {
+		case 0, 2:
+			size, header = int(block[0]>>3), 1
+		case 1:
+			if len(block) < 2 {
+				return 0, errZstdCorrupt
+			}
+			size, header = int(block[0]>>4)|int(block[1])<<4, 2
+		case 3:
+			if len(block) < 3 {
+				return 0, errZstdCorrupt
+			}
+			size, header = int(block[0]>>4)|int(block[1])<<4|int(block[2])<<12, 3
+		}
+
+		if kind == 0 {
+			if header+size > len(block) {
+				return 0, errZstdCorrupt
+			}
+			f.literals = append(f.literals[:0], block[header:header+size]...)
+			return header + size, nil
+		}
+		if header >= len(block) {
+			return 0, errZstdCorrupt
+		}
+		f.literals = f.literals[:0]
+		for i := 0; i < size; i++ {
+			f.literals = append(f.literals, block[header])
+		}
+		return header + 1, nil
+	}
+
+	// Huffman coded, with a new tree (2) or the last block's (3)
+	var regenerated, compressed, header int
+	var streams = 4
+	switch sizeFormat {
+	case 0, 1:
+		if len(block) < 3 {
+			return 0, errZstdCorrupt
+		}
+		if sizeFormat == 0 {
+			streams = 1
+		}
+		var v = int(block[0])>>4 | int(block[1])<<4 | int(block[2])<<12
+		regenerated, compressed, header = v&0x3ff, v>>10, 3
+	case 2:
+		if len(block) < 4 {
+			return 0, errZstdCorrupt
+		}
+		var v = int(block[0])>>4 | int(block[1])<<4 | int(block[2])<<12 | int(block[3])<<20
+		regenerated, compressed, header = v&0x3fff, v>>14, 4
+	case 3:
+		if len(block) < 5 {
+			return 0, errZstdCorrupt
+		}
+		var v = int(block[0])>>4 | int(block[1])<<4 | int(block[2])<<12 | int(block[3])<<20 | int(block[4])<<28
+		regenerated, compressed, header = v&0x3ffff, v>>18, 5
+	}
+	if header+compressed > len(block) {
+		return 0, errZstdCorrupt
+	}
+	var data = block[header : header+compressed]
+
+	if kind == 2 {
+		var table, n, err = readZstdHuffmanTable(data)
+		if err != nil {
+			return 0, err
+		}
+		f.huffman = table
+		data = data[n:]
+	} else if f.huffman == nil {
+		return 0, errZstdCorrupt
+	}
+
+	f.literals = f.literals[:0]
+	if streams == 1 {
+		var err error
+		f.literals, err = f.huffman.decode(f.literals, data, regenerated)
+		if err != nil {
+			return 0, err
+		}
+		return header + compressed, nil
+	}
+
+	if len(data) < 6 {
+		return 0, errZstdCorrupt
+	}
+	var (
+		sizes = [4]int{
+			int(binary.LittleEndian.Uint16(data[0:])),
+			int(binary.LittleEndian.Uint16(data[2:])),
+			int(binary.LittleEndian.Uint16(data[4:])),
+		}
+		streamSize = (regenerated + 3) / 4
+	)
+	data = data[6:]
+	sizes[3] = len(data) - sizes[0] - sizes[1] - sizes[2]
+	if sizes[3] < 0 {
+		return 0, errZstdCorrupt
+	}
+	for i, size := range sizes {
+		var n = streamSize
+		if i == 3 {
+			n = regenerated - 3*streamSize
+		}
+		if n < 0 {
+			return 0, errZstdCorrupt
+		}
+		var err error
+		f.literals, err = f.huffman.decode(f.literals, data[:size], n)
+		if err != nil {
+			return 0, err
+		}
+		data = data[size:]
+	}
+	return header + compressed, nil
+}
+
+// decodeSequences decodes the sequences section and carries them out,
+// each copying some literals then a match from earlier in the output.
+func (f *zstdFrame) decodeSequences(src []byte) error {
+	if len(src) < 1 {
+		return errZstdCorrupt
+	}
+	var count int
+	switch {
+	case src[0] < 128:
+		count, src = int(src[0]), src[1:]
+	case src[0] < 255:
+		if len(src) < 2 {
+			return errZstdCorrupt
+		}
+		count, src = int(src[0]-128)<<8|int(src[1]), src[2:]
+	default:
+		if len(src) < 3 {
+			return errZstdCorrupt
+		}
+		count, src = int(src[1])|int(src[2])<<8+0x7f00, src[3:]
+	}
+	if count == 0 {
+		f.out = append(f.out, f.literals...)
+		return nil
+	}
+
+	if len(src) < 1 {
+		return errZstdCorrupt
+	}
+	var modes = src[0]
+	src = src[1:]
+	for _, table := range []struct {
+		table      **fseTable
+		mode       byte
+		predefined *fseTable
+		maxSymbol  int
+		maxLog     int
+	}{
+		{&f.literalLengths, modes >> 6, predefinedLiteralLengths, 35, 9},
+		{&f.offsets, modes >> 4 & 3, predefinedOffsets, 31, 8},
+		{&f.matchLengths, modes >> 2 & 3, predefinedMatchLengths, 52, 9},
+	} {
+		switch table.mode {
+		case 0:
+			*table.table = table.predefined
+		case 1:
+			if len(src) < 1 || int(src[0]) > table.maxSymbol {
+				return errZstdCorrupt
+			}
+			*table.table = rleFSETable(src[0])
+			src = src[1:]
+		case 2:
+			var t, n, err = readFSETable(src, table.maxSymbol, table.maxLog)
+			if err != nil {
+				return err
+			}
+			*table.table = t
+			src = src[n:]
+		case 3:
+			if *table.table == nil {
+				return errZstdCorrupt
+			}
+		}
+	}
+
+	var bits, err = newBackwardBits(src)
+	if err != nil {
+		return err
+	}
+	var (
+		llState  = f.literalLengths.init(bits)
+		ofState  = f.offsets.init(bits)
+		mlState  = f.matchLengths.init(bits)
+		literals = f.literals
+	)
+	for i := 0; i < count; i++ {
+		var (
+			ofCode = int(f.offsets.states[ofState].symbol)
+			mlCode = int(f.matchLengths.states[mlState].symbol)
+			llCode = int(f.literalLengths.states[llState].symbol)
+		)
+		if ofCode > 31 {
+			return errZstdCorrupt
+		}
+		var offsetValue = 1<<uint(ofCode) + bits.read(ofCode)
+		var matchLength = zstdMatchLengths[mlCode][0] + bits.read(zstdMatchLengths[mlCode][1])
+		var literalLength = zstdLiteralLengths[llCode][0] + bits.read(zstdLiteralLengths[llCode][1])
+
+		var offset int
+		if offsetValue > 3 {
+			offset = offsetValue - 3
+			f.rep = [3]int{offset, f.rep[0], f.rep[1]}
+		} else {
+			var repeat = offsetValue - 1
+			if literalLength == 0 {
+				repeat++
+			}
+			switch repeat {
+			case 0:
+				offset = f.rep[0]
+			case 1:
+				offset = f.rep[1]
+				f.rep = [3]int{offset, f.rep[0], f.rep[2]}
+			case 2:
+				offset = f.rep[2]
+				f.rep = [3]int{offset, f.rep[0], f.rep[1]}
+			case 3:
+				offset = f.rep[0] - 1
+				f.rep = [3]int{offset, f.rep[0], f.rep[1]}
+			}
+		}
+
+		if i != count-1 {
+			llState = f.literalLengths.update(llState, bits)
+			mlState = f.matchLengths.update(mlState, bits)
+			ofState = f.offsets.update(ofState, bits)
+		}
+		if bits.overflowed() {
+			return errZstdCorrupt
+		}
+
+		if literalLength > len(literals) {
+			return errZstdCorrupt
+		}
+		f.out = append(f.out, literals[:literalLength]...)
+		literals = literals[literalLength:]
+
+		if offset <= 0 || offset > len(f.out)-f.start {
+			return errZstdCorrupt
+		}
+		// Matches may overlap the bytes they produce, so copy one at a time
+		var from = len(f.out) - offset
+		for j := 0; j < matchLength; j++ {
+			f.out = append(f.out, f.out[from+j])
+		}
+	}
+	f.out = append(f.out, literals...)
+	return nil
+}
+
+// Baselines and extra bits of the literal and match length codes.
+var (
+	zstdLiteralLengths = [36][2]int{
+		{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0},
+		{8, 0}, {9, 0}, {10, 0}, {11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0},
+		{16, 1}, {18, 1}, {20, 1}, {22, 1}, {24, 2}, {28, 2}, {32, 3}, {40, 3},
+		{48, 4}, {64, 6}, {128, 7}, {256, 8}, {512, 9}, {1024, 10}, {2048, 11}, {4096, 12},
+		{8192, 13}, {16384, 14}, {32768, 15}, {65536, 16},
+	}
+	zstdMatchLengths = [53][2]int{
+		{3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0},
+		{11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0}, {16, 0}, {17, 0}, {18, 0},
+		{19, 0}, {20, 0}, {21, 0}, {22, 0}, {23, 0}, {24, 0}, {25, 0}, {26, 0},
+		{27, 0}, {28, 0}, {29, 0}, {30, 0}, {31, 0}, {32, 0}, {33, 0}, {34, 0},
+		{35, 1}, {37, 1}, {39, 1}, {41, 1}, {43, 2}, {47, 2}, {51, 3}, {59, 3},
+		{67, 4}, {83, 4}, {99, 5}, {131, 7}, {259, 8}, {515, 9}, {1027, 10}, {2051, 11},
+		{4099, 12}, {8195, 13}, {16387, 14}, {32771, 15}, {65539, 16},
+	}
+)
+
+var (
+	predefinedLiteralLengths = buildFSETable([]int{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}, 6)
+	predefinedMatchLengths = buildFSETable([]int{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}, 6)
+	predefinedOffsets = buildFSETable([]int{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}, 5)
+)
+
+// backwardBits reads a bitstream backwards from its end, where the highest
+// set bit of the last byte marks the start. Reading past the beginning
+// gives zeros, and is only an error if the stream was meant to be used up.
+type backwardBits struct {
+	data []byte
+	pos  int // Bits left
+}
+
+func newBackwardBits(data []byte) (*backwardBits, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return nil, errZstdCorrupt
+	}
+	return &backwardBits{data, (len(data)-1)*8 + highBit(uint32(data[len(data)-1]))}, nil
+}
+
+func (b *backwardBits) read(n int) int {
+	b.pos -= n
+	return bitsAt(b.data, b.pos, n)
+}
+
+func (b *backwardBits) peek(n int) int {
+	return bitsAt(b.data, b.pos-n, n)
+}
+
+func (b *backwardBits) overflowed() bool {
+	return b.pos < 0
+}
+
+// bitsAt reads n (up to 56) bits starting at bit pos of data, with bit 0
+// the lowest of the first byte. Bits before the start are zeros.
+func bitsAt(data []byte, pos, n int) int {
+	if n == 0 {
+		return 0
+	}
+	if pos < 0 {
+		if n+pos <= 0 {
+			return 0
+		}
+		return bitsAt(data, 0, n+pos) << uint(-pos)
+	}
+
+	if pos>>3 >= len(data) {
+		return 0
+	}
+	var v uint64
+	for i, b := range data[pos>>3:] {
+		if i == 8 {
+			break
+		}
+		v |= uint64(b) << uint(8*i)
+	}
+	return int(v >> uint(pos&7) & (1<<uint(n) - 1))
+}
+
+func highBit(v uint32) int {
+	var n = -1
+	for v != 0 {
+		v >>= 1
+		n++
+	}
+	return n
+}
+
+// fseTable decodes a finite state entropy coded stream: each state gives
+// a symbol, and the next state is newState plus the next bits bits.
+type fseTable struct {
+	accuracyLog int
+	states      []fseState
+}
+
+type fseState struct {
+	symbol   byte
+	bits     byte
+	newState uint16
+}
+
+func (t *fseTable) init(bits *backwardBits) int {
+	return bits.read(t.accuracyLog)
+}
+
+func (t *fseTable) update(state int, bits *backwardBits) int {
+	var s = t.states[state]
+	return int(s.newState) + bits.read(int(s.bits))
+}
+
+func rleFSETable(symbol byte) *fseTable {
+	return &fseTable{0, []fseState{{symbol, 0, 0}}}
+}
+
+// readFSETable reads the normalized symbol counts that describe an FSE
+// table, returning the table and the number of bytes read.
+func readFSETable(src []byte, maxSymbol, maxLog int) (*fseTable, int, error) {
+	if len(src) < 1 {
+		return nil, 0, errZstdCorrupt
+	}
+	var (
+		accuracyLog = int(src[0]&0xf) + 5
+		pos         = 4
+		remaining   = 1<<uint(accuracyLog) + 1
+		threshold   = 1 << uint(accuracyLog)
+		nbBits      = accuracyLog + 1
+		counts      []int
+	)
+	if accuracyLog > maxLog {
+		return nil, 0, errZstdCorrupt
+	}
+
+	for remaining > 1 && len(counts) <= maxSymbol {
+		if pos > 8*len(src) {
+			return nil, 0, errZstdCorrupt
+		}
+		var max = 2*threshold - 1 - remaining
+		var count int
+		if v := bitsAt(src, pos, nbBits-1); v < max {
+			count = v
+			pos += nbBits - 1
+		} else {
+			count = bitsAt(src, pos, nbBits)
+			if count >= threshold {
+				count -= max
+			}
+			pos += nbBits
+		}
+		count-- // Stored as one more, so that -1 (less than one) fits
+
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		counts = append(counts, count)
+
+		if count == 0 {
+			// Followed by how many more zeros, two bits at a time
+			for {
+				var repeat = bitsAt(src, pos, 2)
+				pos += 2
+				for i := 0; i < repeat; i++ {
+					counts = append(counts, 0)
+				}
+				if repeat != 3 {
+					break
+				}
+			}
+		}
+
+		for remaining < threshold && threshold > 1 {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 || len(counts) > maxSymbol+1 || pos > 8*len(src) {
+		return nil, 0, errZstdCorrupt
+	}
+	return buildFSETable(counts, accuracyLog), (pos + 7) / 8, nil
+}
+
+// buildFSETable spreads the symbols over the states in proportion to their
+// counts, with the symbols of count -1 given one state each at the end.
+func buildFSETable(counts []int, accuracyLog int) *fseTable {
+	var (
+		size      = 1 << uint(accuracyLog)
+		states    = make([]fseState, size)
+		next      = make([]int, len(counts))
+		high      = size - 1
+		step      = size>>1 + size>>3 + 3
+		position  = 0
+		tableMask = size - 1
+	)
+	for s, count := range counts {
+		if count == -1 {
+			states[high].symbol = byte(s)
+			high--
+			next[s] = 1
+		} else {
+			next[s] = count
+		}
+	}
+	for s, count := range counts {
+		for i := 0; i < count; i++ {
+			states[position].symbol = byte(s)
+			position = (position + step) & tableMask
+			for position > high {
+				position = (position + step) & tableMask
+			}
+		}
+	}
+	for i := range states {
+		var s = states[i].symbol
+		var n = next[s]
+		next[s]++
+		var bits = accuracyLog - highBit(uint32(n))
+		states[i].bits = byte(bits)
+		states[i].newState = uint16(n<<uint(bits) - size)
+	}
+	return &fseTable{accuracyLog, states}
+}
+
+// zstdHuffmanTable decodes literals by looking up the next maxBits bits.
+type zstdHuffmanTable struct {
+	maxBits int
+	entries []zstdHuffmanEntry
+}
+
+type zstdHuffmanEntry struct {
+	symbol byte
+	bits   byte
+}
+
+// readZstdHuffmanTable reads the weights of the literal symbols, either
+// as four bit numbers or FSE coded, returning the table and the bytes read.
+func readZstdHuffmanTable(src []byte) (*zstdHuffmanTable, int, error) {
+	if len(src) < 1 {
+		return nil, 0, errZstdCorrupt
+	}
+	var (
+		header  = int(src[0])
+		weights []int
+		n       int
+	)
+	if header >= 128 {
+		var count = header - 127
+		n = 1 + (count+1)/2
+		if n > len(src) {
+			return nil, 0, errZstdCorrupt
+		}
+		for i := 0; i < count; i++ {
+			var b = src[1+i/2]
+			if i%2 == 0 {
+				weights = append(weights, int(b>>4))
+			} else {
+				weights = append(weights, int(b&0xf))
+			}
+		}
+	} else {
+		n = 1 + header
+		if n > len(src) {
+			return nil, 0, errZstdCorrupt
+		}
+		var err error
+		weights, err = readFSEWeights(src[1:n])
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// The last weight is whatever makes the total a power of two
+	var total int
+	for _, w := range weights {
+		if w > 11 {
+			return nil, 0, errZstdCorrupt
+		}
+		if w > 0 {
+			total += 1 << uint(w-1)
+		}
+	}
+	if total == 0 {
+		return nil, 0, errZstdCorrupt
+	}
+	var maxBits = highBit(uint32(total)) + 1
+	var rest = 1<<uint(maxBits) - total
+	if rest&(rest-1) != 0 || maxBits > 11 || len(weights) > 255 {
+		return nil, 0, errZstdCorrupt
+	}
+	weights = append(weights, highBit(uint32(rest))+1)
+
+	// Lowest weights (longest codes) first, symbols in order within a weight
+	var table = &zstdHuffmanTable{maxBits, make([]zstdHuffmanEntry, 1<<uint(maxBits))}
+	var position int
+	for w := 1; w <= maxBits; w++ {
+		for s, weight := range weights {
+			if weight != w {
+				continue
+			}
+			var entry = zstdHuffmanEntry{byte(s), byte(maxBits + 1 - w)}
+			for i := 0; i < 1<<uint(w-1); i++ {
+				table.entries[position] = entry
+				position++
+			}
+		}
+	}
+	return table, n, nil
+}
+
+// readFSEWeights decodes Huffman weights coded with two interleaved FSE
+// states.
+func readFSEWeights(src []byte) ([]int, error) {
+	var table, n, err = readFSETable(src, 255, 6)
+	if err != nil {
+		return nil, err
+	}
+	var bits, bitsErr = newBackwardBits(src[n:])
+	if bitsErr != nil {
+		return nil, bitsErr
+	}
+
+	var (
+		states  = [2]int{table.init(bits), table.init(bits)}
+		weights []int
+	)
+	for i := 0; len(weights) < 255; i ^= 1 {
+		weights = append(weights, int(table.states[states[i]].symbol))
+		states[i] = table.update(states[i], bits)
+		if bits.overflowed() {
+			weights = append(weights, int(table.states[states[i^1]].symbol))
+			break
+		}
+	}
+	return weights, nil
+}
+
+// decode appends n symbols read from a Huffman coded stream.
+func (t *zstdHuffmanTable) decode(out []byte, src []byte, n int) ([]byte, error) {
+	var bits, err = newBackwardBits(src)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		var entry = t.entries[bits.peek(t.maxBits)]
+		bits.pos -= int(entry.bits)
+		out = append(out, entry.symbol)
+	}
+	if bits.pos != 0 {
+		return nil, errors.New(fmt.Sprintf("zstd Huffman stream has %d bits left", bits.pos))
+	}
+	return out, nil
+}
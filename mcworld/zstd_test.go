@@ -0,0 +1,85 @@
+package mcworld
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// Made with zstd -19, the first with raw literals and the second with
+// Huffman coded literals and FSE coded sequences.
+var zstdHello = []byte{
+	0x28, 0xb5, 0x2f, 0xfd, 0x24, 0x31, 0xf5, 0x00, 0x00, 0xa8, 0x68, 0x65,
+	0x6c, 0x6c, 0x6f, 0x20, 0x2c, 0x20, 0x6c, 0x69, 0x6e, 0x65, 0x61, 0x72,
+	0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x20, 0x02, 0x00, 0x3d, 0x8d, 0x9a,
+	0xb9, 0xc0, 0x01, 0xa5, 0x55, 0x06, 0xdb,
+}
+
+var zstdChunks = []byte{
+	0x28, 0xb5, 0x2f, 0xfd, 0x64, 0x4d, 0x03, 0x7d, 0x09, 0x00, 0x26, 0x98,
+	0x32, 0x14, 0xb0, 0x57, 0xd2, 0x01, 0x84, 0x11, 0x61, 0x34, 0x2c, 0x4d,
+	0x49, 0x29, 0xa5, 0x4c, 0x29, 0x1d, 0xd3, 0x3b, 0x1c, 0x0f, 0x30, 0x00,
+	0x2a, 0x00, 0x29, 0x00, 0xa4, 0x63, 0x67, 0xab, 0x33, 0x3b, 0xed, 0xca,
+	0x39, 0x47, 0xbe, 0xb4, 0x40, 0x73, 0x45, 0x8a, 0x8e, 0x2e, 0xc4, 0x4d,
+	0x9d, 0x9c, 0x88, 0xbf, 0x88, 0x52, 0x77, 0x21, 0x3b, 0xd7, 0xb9, 0x7e,
+	0x46, 0x06, 0x00, 0xc2, 0x42, 0x60, 0x20, 0x40, 0x10, 0x02, 0x02, 0x05,
+	0x0e, 0x0c, 0x10, 0x10, 0xc3, 0x55, 0x43, 0x02, 0x47, 0x25, 0x6f, 0x1a,
+	0x62, 0x3a, 0xe9, 0x2b, 0x74, 0x51, 0xdf, 0xdc, 0xcb, 0x92, 0xb9, 0x7f,
+	0xf0, 0x5a, 0x29, 0x77, 0xac, 0x43, 0xb8, 0xd0, 0xb2, 0x56, 0x0c, 0xd3,
+	0x11, 0x6d, 0xe9, 0x86, 0x51, 0xc5, 0x09, 0x2b, 0xe5, 0x22, 0xfb, 0x5d,
+	0xed, 0x7c, 0x09, 0xae, 0x27, 0xd2, 0xf8, 0x9a, 0x0b, 0xa7, 0x47, 0x57,
+	0x16, 0x21, 0x8e, 0x2c, 0x44, 0x6e, 0x4c, 0x7e, 0xe2, 0x30, 0x75, 0x61,
+	0x0a, 0xbb, 0xbb, 0xe8, 0x9c, 0xdd, 0xce, 0xea, 0x47, 0x18, 0x4d, 0xf4,
+	0x2e, 0x78, 0x1a, 0x01, 0x80, 0xc0, 0x55, 0x08, 0x79, 0x14, 0x22, 0xd3,
+	0x4d, 0xd0, 0x43, 0x27, 0x51, 0x9b, 0x8b, 0x90, 0x3d, 0x24, 0x78, 0x0e,
+	0x97, 0xbb, 0x46, 0x90, 0x70, 0x8c, 0x9a, 0x5a, 0x88, 0x95, 0x74, 0x74,
+	0x4f, 0x71, 0xf3, 0x98, 0x72, 0xf2, 0x9a, 0x58, 0x3c, 0xa8, 0x1a, 0x73,
+	0xa8, 0x11, 0x50, 0x74, 0xa3, 0xf9, 0x7e, 0x06, 0x60, 0xb9, 0x34, 0x06,
+	0x10, 0x70, 0x81, 0x04, 0xdc, 0xc6, 0x01, 0xf0, 0x99, 0xab, 0x27, 0x0c,
+	0xe8, 0x31, 0x5d, 0x2c, 0x49, 0x36, 0x1a, 0x6c, 0x89, 0xb6, 0x81, 0x06,
+	0x72, 0xe4, 0x55, 0x9a, 0x95, 0x98, 0x81, 0xd6, 0xc4, 0x06, 0x3d, 0x5d,
+	0x83, 0x96, 0x10, 0x75, 0xae, 0x2e, 0x0a, 0x93, 0xb7, 0xbe, 0x43, 0x55,
+	0x99, 0x70, 0xa2, 0x4e, 0x38, 0x8b, 0xa5, 0xa0, 0xd1, 0xa5, 0xc5, 0xee,
+	0x40, 0x5b, 0x76, 0xc1, 0x6e, 0xaf, 0x3d, 0xc0, 0xcf, 0x7f, 0xd8, 0x8b,
+	0xf9, 0xd5, 0xab, 0x8d, 0x50, 0xea, 0xb9, 0xc2, 0x54, 0x96, 0x59, 0xbb,
+	0x0a, 0x2c, 0xdd, 0x96, 0x60,
+}
+
+func TestZstdDecompress(t *testing.T) {
+	var hello, err = zstdDecompress(zstdHello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hello) != "hello hello hello hello, linear world hello hello" {
+		t.Errorf("decompressed to %q", hello)
+	}
+
+	var expected bytes.Buffer
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&expected, "chunk %d at %d,%d; ", i*i%97, i, i*7%31)
+	}
+	chunks, err := zstdDecompress(zstdChunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(chunks, expected.Bytes()) {
+		t.Errorf("decompressed to %q", chunks)
+	}
+
+	// Frames follow one another
+	both, err := zstdDecompress(append(append([]byte(nil), zstdHello...), zstdChunks...))
+	if err != nil || len(both) != len(hello)+len(chunks) {
+		t.Errorf("two frames decompressed to %d bytes, %v", len(both), err)
+	}
+}
+
+func TestZstdDecompressCorrupt(t *testing.T) {
+	for i := 4; i < len(zstdChunks); i += 7 {
+		if _, err := zstdDecompress(zstdChunks[:i]); err == nil {
+			t.Errorf("cut short at %d bytes without an error", i)
+		}
+	}
+	if _, err := zstdDecompress([]byte("not zstd")); err == nil {
+		t.Errorf("no error for a missing magic number")
+	}
+}
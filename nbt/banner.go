@@ -0,0 +1,109 @@
+package nbt
+
+import (
+	"strings"
+)
+
+// Banner is the base color of a banner and the patterns layered over it,
+// first to last. Colors are indexes of the 16 dye colors, white first and
+// black last, as the data of wool.
+type Banner struct {
+	Base     int
+	Patterns []BannerPattern
+}
+
+type BannerPattern struct {
+	Pattern string // The name of the pattern from 1.20.5, such as "bricks"
+	Color   int
+}
+
+// bannerPatternCodes are the names of the patterns that block entities
+// gave by a short code before 1.20.5.
+var bannerPatternCodes = map[string]string{
+	"b": "base", "bl": "square_bottom_left", "br": "square_bottom_right", "tl": "square_top_left", "tr": "square_top_right",
+	"bs": "stripe_bottom", "ts": "stripe_top", "ls": "stripe_left", "rs": "stripe_right", "cs": "stripe_center", "ms": "stripe_middle",
+	"drs": "stripe_downright", "dls": "stripe_downleft", "ss": "small_stripes", "cr": "cross", "sc": "straight_cross",
+	"bt": "triangle_bottom", "tt": "triangle_top", "bts": "triangles_bottom", "tts": "triangles_top",
+	"ld": "diagonal_left", "rd": "diagonal_up_right", "lud": "diagonal_up_left", "rud": "diagonal_right",
+	"mc": "circle", "mr": "rhombus", "vh": "half_vertical", "hh": "half_horizontal", "vhr": "half_vertical_right", "hhb": "half_horizontal_bottom",
+	"bo": "border", "cbo": "curly_border", "gra": "gradient", "gru": "gradient_up", "bri": "bricks",
+	"glb": "globe", "cre": "creeper", "sku": "skull", "flo": "flower", "moj": "mojang", "pig": "piglin", "flw": "flow", "gus": "guster",
+}
+
+// bannerColor is the color of a banner block by its name, such as
+// red_banner or red_wall_banner, or -1 if it isn't one.
+func bannerColor(name string) int {
+	if i := strings.Index(name, ":"); i != -1 {
+		name = name[i+1:]
+	}
+	var color = strings.TrimSuffix(strings.TrimSuffix(name, "_banner"), "_wall")
+	if color == name {
+		return -1
+	}
+	for i, c := range colorNames {
+		if c == color {
+			return i
+		}
+	}
+	return -1
+}
+
+// setBanner reads the patterns of a banner block entity, and its base
+// color before 1.13, which kept colors as the data of dyes, black first.
+// From 1.13 the base color is the block's, and is set from the palette.
+func (e *BlockEntity) setBanner() {
+	if e.Id != "minecraft:banner" && e.Id != "Banner" {
+		return
+	}
+	e.Banner = new(Banner)
+
+	var base, legacy = e.Data["Base"].(int)
+	if legacy {
+		e.Banner.Base = 15 - base&15
+	}
+
+	var patterns, _ = e.Data["Patterns"].([]interface{})
+	if patterns == nil {
+		patterns, _ = e.Data["patterns"].([]interface{})
+	}
+	for _, item := range patterns {
+		var pattern, ok = item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var p BannerPattern
+		if code, ok := pattern["Pattern"].(string); ok {
+			p.Pattern = bannerPatternCodes[code]
+		} else if name, ok := pattern["pattern"].(string); ok {
+			p.Pattern = strings.TrimPrefix(name, "minecraft:")
+		}
+		if color, ok := pattern["Color"].(int); ok {
+			p.Color = color & 15
+			if legacy {
+				p.Color = 15 - p.Color
+			}
+		} else if name, ok := pattern["color"].(string); ok {
+			p.Color = bannerColor(name + "_banner")
+		}
+		if p.Pattern != "" && p.Color != -1 {
+			e.Banner.Patterns = append(e.Banner.Patterns, p)
+		}
+	}
+}
+
+// colorBanners sets the base color of the banners in a 1.13+ section from
+// the names of their blocks in the palette.
+func (section *sectionData) colorBanners(entities []BlockEntity) {
+	var index = section.paletteIndex()
+	for i := range entities {
+		var e = &entities[i]
+		if e.Banner == nil || e.Y>>4 != section.y {
+			continue
+		}
+		var j = index(e.X&15 + 16*(e.Z&15+16*(e.Y&15)))
+		if j < len(section.bannerColors) && section.bannerColors[j] != -1 {
+			e.Banner.Base = section.bannerColors[j]
+		}
+	}
+}
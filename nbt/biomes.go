@@ -0,0 +1,176 @@
+package nbt
+
+// BiomeMap is the biomes of a chunk. Up until 1.15 there is one biome per
+// block column, in Names indexed x+z*16. After that biomes are 3D, with one
+// per 4x4x4 cell, in Names indexed x/4+z/4*4+(y-MinY)/4*16.
+type BiomeMap struct {
+	Names []string // Such as minecraft:plains, or "" where unknown
+	Cells bool
+	MinY  int
+}
+
+// At is the biome at x,y,z within the chunk.
+func (b *BiomeMap) At(x, y, z int) string {
+	if !b.Cells {
+		if len(b.Names) != 16*16 {
+			return ""
+		}
+		return b.Names[x&15+(z&15)*16]
+	}
+
+	var layers = len(b.Names) / 16
+	if layers == 0 {
+		return ""
+	}
+	var layer = (y - b.MinY) >> 2
+	if layer < 0 {
+		layer = 0
+	} else if layer >= layers {
+		layer = layers - 1
+	}
+	return b.Names[(x&15)>>2+((z&15)>>2)<<2+layer<<4]
+}
+
+// Biome is the biome at the surface of column x,z, as found by the chunk's
+// height maps, or the top of the chunk without them.
+func (c *Chunk) Biome(x, z int) string {
+	for _, heightMap := range []string{MotionBlocking, WorldSurface, LegacyHeightMap} {
+		if y, ok := c.Height(heightMap, x, z); ok {
+			return c.Biomes.At(x, y-1, z)
+		}
+	}
+	return c.Biomes.At(x, c.MinY+len(c.Blocks)/(16*16)-1, z)
+}
+
+// biomesFromIds reads the biome ids saved before 1.18: a byte per column
+// up until 1.13, then an int instead, then from 1.15 an int per 4x4x4 cell.
+func biomesFromIds(ids []int) BiomeMap {
+	if len(ids) == 0 {
+		return BiomeMap{}
+	}
+	var biomes = BiomeMap{Names: make([]string, len(ids)), Cells: len(ids) != 16*16}
+	for i, id := range ids {
+		biomes.Names[i] = biomeNames[id]
+	}
+	return biomes
+}
+
+// unpackBiomes reads the 64 cells of a 1.18+ section from its palette and
+// packed indexes, which never straddle longs.
+func unpackBiomes(palette []string, data []int64) []string {
+	var cells = make([]string, 4*4*4)
+	if len(palette) == 0 {
+		return cells
+	}
+	if len(palette) == 1 || len(data) == 0 {
+		for i := range cells {
+			cells[i] = palette[0]
+		}
+		return cells
+	}
+
+	var bits = 1
+	for 1<<uint(bits) < len(palette) {
+		bits++
+	}
+	var (
+		mask    = uint64(1)<<uint(bits) - 1
+		perLong = 64 / bits
+	)
+	for i := range cells {
+		if i/perLong >= len(data) {
+			break
+		}
+		var index = int(uint64(data[i/perLong]) >> uint((i%perLong)*bits) & mask)
+		if index < len(palette) {
+			cells[i] = palette[index]
+		}
+	}
+	return cells
+}
+
+// biomeNames are the numeric biome ids used before 1.18, by the names they
+// had in 1.13 to 1.17.
+var biomeNames = map[int]string{
+	0:   "minecraft:ocean",
+	1:   "minecraft:plains",
+	2:   "minecraft:desert",
+	3:   "minecraft:mountains",
+	4:   "minecraft:forest",
+	5:   "minecraft:taiga",
+	6:   "minecraft:swamp",
+	7:   "minecraft:river",
+	8:   "minecraft:nether_wastes",
+	9:   "minecraft:the_end",
+	10:  "minecraft:frozen_ocean",
+	11:  "minecraft:frozen_river",
+	12:  "minecraft:snowy_tundra",
+	13:  "minecraft:snowy_mountains",
+	14:  "minecraft:mushroom_fields",
+	15:  "minecraft:mushroom_field_shore",
+	16:  "minecraft:beach",
+	17:  "minecraft:desert_hills",
+	18:  "minecraft:wooded_hills",
+	19:  "minecraft:taiga_hills",
+	20:  "minecraft:mountain_edge",
+	21:  "minecraft:jungle",
+	22:  "minecraft:jungle_hills",
+	23:  "minecraft:jungle_edge",
+	24:  "minecraft:deep_ocean",
+	25:  "minecraft:stone_shore",
+	26:  "minecraft:snowy_beach",
+	27:  "minecraft:birch_forest",
+	28:  "minecraft:birch_forest_hills",
+	29:  "minecraft:dark_forest",
+	30:  "minecraft:snowy_taiga",
+	31:  "minecraft:snowy_taiga_hills",
+	32:  "minecraft:giant_tree_taiga",
+	33:  "minecraft:giant_tree_taiga_hills",
+	34:  "minecraft:wooded_mountains",
+	35:  "minecraft:savanna",
+	36:  "minecraft:savanna_plateau",
+	37:  "minecraft:badlands",
+	38:  "minecraft:wooded_badlands_plateau",
+	39:  "minecraft:badlands_plateau",
+	40:  "minecraft:small_end_islands",
+	41:  "minecraft:end_midlands",
+	42:  "minecraft:end_highlands",
+	43:  "minecraft:end_barrens",
+	44:  "minecraft:warm_ocean",
+	45:  "minecraft:lukewarm_ocean",
+	46:  "minecraft:cold_ocean",
+	47:  "minecraft:deep_warm_ocean",
+	48:  "minecraft:deep_lukewarm_ocean",
+	49:  "minecraft:deep_cold_ocean",
+	50:  "minecraft:deep_frozen_ocean",
+	127: "minecraft:the_void",
+	129: "minecraft:sunflower_plains",
+	130: "minecraft:desert_lakes",
+	131: "minecraft:gravelly_mountains",
+	132: "minecraft:flower_forest",
+	133: "minecraft:taiga_mountains",
+	134: "minecraft:swamp_hills",
+	140: "minecraft:ice_spikes",
+	149: "minecraft:modified_jungle",
+	151: "minecraft:modified_jungle_edge",
+	155: "minecraft:tall_birch_forest",
+	156: "minecraft:tall_birch_hills",
+	157: "minecraft:dark_forest_hills",
+	158: "minecraft:snowy_taiga_mountains",
+	160: "minecraft:giant_spruce_taiga",
+	161: "minecraft:giant_spruce_taiga_hills",
+	162: "minecraft:modified_gravelly_mountains",
+	163: "minecraft:shattered_savanna",
+	164: "minecraft:shattered_savanna_plateau",
+	165: "minecraft:eroded_badlands",
+	166: "minecraft:modified_wooded_badlands_plateau",
+	167: "minecraft:modified_badlands_plateau",
+	168: "minecraft:bamboo_jungle",
+	169: "minecraft:bamboo_jungle_hills",
+	170: "minecraft:soul_sand_valley",
+	171: "minecraft:crimson_forest",
+	172: "minecraft:warped_forest",
+	173: "minecraft:basalt_deltas",
+	174: "minecraft:dripstone_caves",
+	175: "minecraft:lush_caves",
+}
@@ -1,3 +1,7 @@
 package nbt
 
 type Block uint16
+
+// Waterlogged is set, above the data, on blocks from 1.13 and later that
+// hold water as well.
+const Waterlogged Block = 0x8000
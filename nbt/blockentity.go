@@ -0,0 +1,41 @@
+package nbt
+
+// BlockEntity is the extra data of a block such as a chest, sign or banner,
+// which the block state alone doesn't hold.
+type BlockEntity struct {
+	Id      string // minecraft:chest, minecraft:sign... or Chest, Sign... before 1.11
+	X, Y, Z int
+	Data    map[string]interface{} // Every tag of the block entity
+
+	Banner *Banner // What's on a banner, or nil for other block entities
+}
+
+// BlockEntity is the block entity at x,y,z within the chunk, with y a
+// world height, or nil when the block has none.
+func (c *Chunk) BlockEntity(x, y, z int) *BlockEntity {
+	for i := range c.BlockEntities {
+		var e = &c.BlockEntities[i]
+		if e.X&15 == x&15 && e.Z&15 == z&15 && e.Y == y {
+			return e
+		}
+	}
+	return nil
+}
+
+// setBlockEntities reads the block_entities list of 1.18+ chunks, or the
+// TileEntities list of older ones.
+func (chunk *chunkData) setBlockEntities(list []interface{}) {
+	for _, item := range list {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var entity = BlockEntity{Data: data}
+		entity.Id, _ = data["id"].(string)
+		entity.X, _ = data["x"].(int)
+		entity.Y, _ = data["y"].(int)
+		entity.Z, _ = data["z"].(int)
+		chunk.blockEntities = append(chunk.blockEntities, entity)
+	}
+}
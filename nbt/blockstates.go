@@ -0,0 +1,776 @@
+package nbt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Minecraft 1.13 replaced numeric block ids and data values with named
+// block states. The rest of mcobj still works in terms of the numeric ids
+// (blocks.json, the material names, the PRT BlockID channel), so palette
+// entries are translated back to the id and data value the block had in
+// 1.12. Blocks added after the flattening are mapped onto the closest
+// looking pre-flattening block.
+
+type blockStateConverter func(properties map[string]interface{}) Block
+
+var (
+	colorNames = []string{"white", "orange", "magenta", "light_blue", "yellow", "lime", "pink", "gray", "light_gray", "cyan", "purple", "blue", "brown", "green", "red", "black"}
+	woodNames  = []string{"oak", "spruce", "birch", "jungle", "acacia", "dark_oak"}
+
+	blockStateIds        map[string]Block
+	blockStateConverters map[string]blockStateConverter
+)
+
+// BlockState returns the pre-flattening block (id and data value) for a
+// named block state. name may include the "minecraft:" namespace.
+func BlockState(name string, properties map[string]interface{}) Block {
+	if i := strings.Index(name, ":"); i != -1 {
+		name = name[i+1:]
+	}
+
+	var block Block
+	if convert, ok := blockStateConverters[name]; ok {
+		block = convert(properties)
+	} else if id, ok := blockStateIds[name]; ok {
+		block = id
+	} else {
+		block = similarBlockState(name, properties)
+	}
+
+	if stateProperty(properties, "waterlogged") == "true" {
+		block |= Waterlogged
+	}
+	return block
+}
+
+// IsBlockStateName reports whether BlockState knows a block by its name,
+// rather than guessing from names like it.
+func IsBlockStateName(name string) bool {
+	if i := strings.Index(name, ":"); i != -1 {
+		name = name[i+1:]
+	}
+	var _, converted = blockStateConverters[name]
+	var _, known = blockStateIds[name]
+	return converted || known
+}
+
+// ParseBlockState returns the pre-flattening block for a block state
+// written as a string, such as "minecraft:oak_stairs[facing=east,half=top]".
+func ParseBlockState(state string) Block {
+	var name, properties = state, map[string]interface{}(nil)
+	if i := strings.Index(state, "["); i != -1 && strings.HasSuffix(state, "]") {
+		name = state[:i]
+		properties = make(map[string]interface{})
+		for _, property := range strings.Split(state[i+1:len(state)-1], ",") {
+			if kv := strings.SplitN(property, "=", 2); len(kv) == 2 {
+				properties[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		}
+	}
+	return BlockState(name, properties)
+}
+
+// similarBlockState picks a stand-in for blocks that have no 1.12
+// equivalent, based on the families that names tend to follow.
+func similarBlockState(name string, properties map[string]interface{}) Block {
+	var suffixes = []struct {
+		suffix string
+		block  string
+	}{
+		{"_wall_torch", "wall_torch"},
+		{"_torch", "torch"},
+		{"_stairs", "oak_stairs"},
+		{"_slab", "smooth_stone_slab"},
+		{"_fence_gate", "oak_fence_gate"},
+		{"_fence", "oak_fence"},
+		{"_wall", "cobblestone_wall"},
+		{"_trapdoor", "oak_trapdoor"},
+		{"_door", "oak_door"},
+		{"_pressure_plate", "oak_pressure_plate"},
+		{"_button", "oak_button"},
+		{"_wall_sign", "oak_wall_sign"},
+		{"_sign", "oak_sign"},
+		{"_planks", "oak_planks"},
+		{"_leaves", "oak_leaves"},
+		{"_log", "oak_log"},
+		{"_wood", "oak_log"},
+		{"_stem", "oak_log"},
+		{"_hyphae", "oak_log"},
+		{"_sapling", "oak_sapling"},
+		{"_mushroom", "brown_mushroom"},
+		{"_fungus", "brown_mushroom"},
+		{"_roots", "grass"},
+		{"_ore", "coal_ore"},
+		{"_bricks", "stone_bricks"},
+		{"_tiles", "stone_bricks"},
+		{"_pane", "glass_pane"},
+		{"_glass", "glass"},
+		{"_carpet", "white_carpet"},
+		{"_bed", "red_bed"},
+		{"_wall_banner", "white_wall_banner"},
+		{"_banner", "white_banner"},
+		{"_shulker_box", "purple_shulker_box"},
+		{"_candle", "candle"},
+		{"_lantern", "lantern"},
+		{"_coral", "grass"},
+		{"_coral_fan", "grass"},
+		{"_coral_wall_fan", "grass"},
+		{"_coral_block", "pink_wool"},
+		{"_terracotta", "terracotta"},
+		{"_concrete", "gray_concrete"},
+	}
+
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return BlockState(s.block, properties)
+		}
+	}
+
+	switch {
+	case strings.Contains(name, "copper"):
+		return blockStateIds["terracotta"]
+	case strings.Contains(name, "deepslate"), strings.Contains(name, "blackstone"), strings.Contains(name, "basalt"):
+		return blockStateIds["cobblestone"]
+	case strings.Contains(name, "sandstone"):
+		return blockStateIds["sandstone"]
+	case strings.Contains(name, "quartz"):
+		return blockStateIds["quartz_block"]
+	case strings.Contains(name, "nylium"):
+		return blockStateIds["netherrack"]
+	}
+
+	return blockStateIds["stone"]
+}
+
+func stateProperty(properties map[string]interface{}, key string) string {
+	if properties == nil {
+		return ""
+	}
+	value, _ := properties[key].(string)
+	return value
+}
+
+func statePropertyInt(properties map[string]interface{}, key string) int {
+	value, err := strconv.Atoi(stateProperty(properties, key))
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func withData(block Block, data int) Block {
+	return block + Block(data&0xf)<<8
+}
+
+// Legacy facing values, depending on the block they are stored in.
+var (
+	facingSWNE  = map[string]int{"south": 0, "west": 1, "north": 2, "east": 3}
+	facingENSW  = map[string]int{"east": 0, "west": 1, "south": 2, "north": 3}
+	facingTorch = map[string]int{"east": 1, "west": 2, "south": 3, "north": 4}
+	facingDoor  = map[string]int{"east": 0, "south": 1, "west": 2, "north": 3}
+	facingTrap  = map[string]int{"north": 0, "south": 1, "west": 2, "east": 3}
+	facingIndex = map[string]int{"down": 0, "up": 1, "north": 2, "south": 3, "west": 4, "east": 5}
+	pillarAxes  = map[string]int{"y": 0, "x": 4, "z": 8}
+	stairShapes = map[string]int{"straight": 0, "inner_left": 1, "inner_right": 2, "outer_left": 3, "outer_right": 4}
+	chestTypes  = map[string]int{"single": 1, "left": 2, "right": 3}
+	railShapes  = map[string]int{"north_south": 0, "east_west": 1, "ascending_east": 2, "ascending_west": 3, "ascending_north": 4, "ascending_south": 5, "south_east": 6, "south_west": 7, "north_west": 8, "north_east": 9}
+)
+
+func fixed(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		return block
+	}
+}
+
+func fluidState(flowing, still Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		var level = statePropertyInt(properties, "level")
+		if level == 0 {
+			return still
+		}
+		return withData(flowing, level)
+	}
+}
+
+func litState(off, on Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		if stateProperty(properties, "lit") == "true" || stateProperty(properties, "powered") == "true" {
+			return on
+		}
+		return off
+	}
+}
+
+// facingState adds the direction a block faces to its data, as the index
+// 1.12 gave the direction: down, up, north, south, west then east. The
+// property named by flag adds 8 when it's true.
+func facingState(block Block, flag string) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		var data = facingIndex[stateProperty(properties, "facing")]
+		if flag != "" && stateProperty(properties, flag) == "true" {
+			data += 8
+		}
+		return block + Block(data)<<8
+	}
+}
+
+// chestState keeps which half of a double chest a chest is, past the
+// nibble, as its chestTypes. 1.12 worked it out from the chests beside it.
+func chestState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		return facingState(block, "")(properties) + Block(chestTypes[stateProperty(properties, "type")])<<12
+	}
+}
+
+// skullState is a skull on the floor, data 1, with the rotation 1.12 kept
+// in its block entity in the bits above, or on a wall by the way it faces.
+func skullState(wall bool) blockStateConverter {
+	if wall {
+		return facingState(144, "")
+	}
+	return func(properties map[string]interface{}) Block {
+		return 144 + Block(1|statePropertyInt(properties, "rotation")<<3)<<8
+	}
+}
+
+// cauldronState has the level of what's in a cauldron, from 0 to 3, and
+// past the nibble 16 for lava or 32 for powder snow rather than water. Lava
+// cauldrons are always full.
+func cauldronState(contents int) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		var level = 3
+		if contents != 16 {
+			level = statePropertyInt(properties, "level")
+		}
+		return 118 + Block(level|contents)<<8
+	}
+}
+
+func anvilState(damage int) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		return withData(145, damage<<2|facingSWNE[stateProperty(properties, "facing")])
+	}
+}
+
+func furnaceState(properties map[string]interface{}) Block {
+	return facingState(litState(61, 62)(properties), "")(properties)
+}
+
+func pistonHeadState(properties map[string]interface{}) Block {
+	var block = facingState(34, "")(properties)
+	if stateProperty(properties, "type") == "sticky" {
+		block += 8 << 8
+	}
+	return block
+}
+
+// axisState adds the axis of a log or pillar to its data: 0 for y, 4 for x
+// and 8 for z.
+func axisState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		return block + Block(pillarAxes[stateProperty(properties, "axis")])<<8
+	}
+}
+
+// quartzPillarState is 2 for y, 3 for x and 4 for z, as quartz pillars are
+// a kind of quartz block.
+func quartzPillarState(properties map[string]interface{}) Block {
+	return withData(155, 2+pillarAxes[stateProperty(properties, "axis")]/4)
+}
+
+// repeaterState keeps which way a repeater faces and its delay, less one,
+// in the bits above.
+func repeaterState(properties map[string]interface{}) Block {
+	var data = facingSWNE[stateProperty(properties, "facing")]
+	if delay := statePropertyInt(properties, "delay"); delay > 1 {
+		data += (delay - 1) << 2
+	}
+	return withData(litState(93, 94)(properties), data)
+}
+
+func redstoneWireState(properties map[string]interface{}) Block {
+	return withData(55, statePropertyInt(properties, "power"))
+}
+
+// vineSides are the bits of a vine's data for the sides it grows on, as in
+// 1.12, with up and down past the nibble. Vines from before 1.13 with none
+// of them set hang from the block above.
+var vineSides = []struct {
+	side string
+	bit  int
+}{{"south", 1}, {"west", 2}, {"north", 4}, {"east", 8}, {"up", 16}, {"down", 32}}
+
+// vineState is for vines and, as the closest looking block, glow lichen.
+func vineState(properties map[string]interface{}) Block {
+	var data = 0
+	for _, s := range vineSides {
+		if stateProperty(properties, s.side) == "true" {
+			data |= s.bit
+		}
+	}
+	return 106 + Block(data)<<8
+}
+
+func ageState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		return withData(block, statePropertyInt(properties, "age"))
+	}
+}
+
+// stairsState keeps the shape, which 1.12 worked out from the neighbouring
+// stairs each time, in the bits above the facing and half as its index in
+// stairShapes plus one.
+func stairsState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		var data = facingENSW[stateProperty(properties, "facing")]
+		if stateProperty(properties, "half") == "top" {
+			data += 4
+		}
+		if shape, ok := stairShapes[stateProperty(properties, "shape")]; ok {
+			data += 8 * (shape + 1)
+		}
+		return block + Block(data)<<8 // Past the nibble withData keeps
+	}
+}
+
+func slabState(single, double Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		switch stateProperty(properties, "type") {
+		case "double":
+			return double
+		case "top":
+			return single + 8<<8
+		}
+		return single
+	}
+}
+
+// bedState keeps the color of a bed, which 1.12 kept in its block entity,
+// past the nibble, with its top bit where 1.12 had whether the bed was
+// occupied. It's kept as the color's index in colorNames xor red's, so beds
+// from before 1.13 stay the red they were most likely to be.
+func bedState(color int) blockStateConverter {
+	var c = color ^ 14
+	var colorData = c&7<<4 | c&8>>1
+	return func(properties map[string]interface{}) Block {
+		var data = facingSWNE[stateProperty(properties, "facing")]
+		if stateProperty(properties, "part") == "head" {
+			data += 8
+		}
+		return 26 + Block(data|colorData)<<8
+	}
+}
+
+func doorState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		if stateProperty(properties, "half") == "upper" {
+			var data = 8
+			if stateProperty(properties, "hinge") == "right" {
+				data++
+			}
+			if stateProperty(properties, "powered") == "true" {
+				data += 2
+			}
+			return withData(block, data)
+		}
+
+		var data = facingDoor[stateProperty(properties, "facing")]
+		if stateProperty(properties, "open") == "true" {
+			data += 4
+		}
+		return withData(block, data)
+	}
+}
+
+func torchState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		if data, ok := facingTorch[stateProperty(properties, "facing")]; ok {
+			return withData(block, data)
+		}
+		return withData(block, 5)
+	}
+}
+
+// Lanterns and candles came after the flattening, and are torches whose
+// data is past that of the five ways a torch can be placed.
+const (
+	TorchLantern        = 6
+	TorchHangingLantern = 7
+	TorchCandles        = 8 // And up to 11 for four candles
+)
+
+func lanternState(properties map[string]interface{}) Block {
+	if stateProperty(properties, "hanging") == "true" {
+		return withData(50, TorchHangingLantern)
+	}
+	return withData(50, TorchLantern)
+}
+
+func candleState(properties map[string]interface{}) Block {
+	var candles = statePropertyInt(properties, "candles")
+	if candles < 1 || candles > 4 {
+		candles = 1
+	}
+	return withData(50, TorchCandles+candles-1)
+}
+
+func trapdoorState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		var data = facingTrap[stateProperty(properties, "facing")]
+		if stateProperty(properties, "open") == "true" {
+			data += 4
+		}
+		if stateProperty(properties, "half") == "top" {
+			data += 8
+		}
+		return withData(block, data)
+	}
+}
+
+func railState(block Block, powerable bool) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		var data = railShapes[stateProperty(properties, "shape")]
+		if powerable && stateProperty(properties, "powered") == "true" {
+			data += 8
+		}
+		return withData(block, data)
+	}
+}
+
+func redstoneTorchState(properties map[string]interface{}) Block {
+	if stateProperty(properties, "lit") == "false" {
+		return torchState(75)(properties)
+	}
+	return torchState(76)(properties)
+}
+
+func snowState(properties map[string]interface{}) Block {
+	var layers = statePropertyInt(properties, "layers")
+	if layers < 1 {
+		layers = 1
+	}
+	return withData(78, layers-1)
+}
+
+func signState(block Block) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		return withData(block, statePropertyInt(properties, "rotation"))
+	}
+}
+
+func init() {
+	blockStateIds = map[string]Block{
+		"air":                            0,
+		"cave_air":                       0,
+		"void_air":                       0,
+		"stone":                          1,
+		"granite":                        withData(1, 1),
+		"polished_granite":               withData(1, 2),
+		"diorite":                        withData(1, 3),
+		"polished_diorite":               withData(1, 4),
+		"andesite":                       withData(1, 5),
+		"polished_andesite":              withData(1, 6),
+		"grass_block":                    2,
+		"dirt":                           3,
+		"coarse_dirt":                    withData(3, 1),
+		"podzol":                         withData(3, 2),
+		"cobblestone":                    4,
+		"bedrock":                        7,
+		"sand":                           12,
+		"red_sand":                       withData(12, 1),
+		"gravel":                         13,
+		"gold_ore":                       14,
+		"iron_ore":                       15,
+		"coal_ore":                       16,
+		"sponge":                         19,
+		"wet_sponge":                     withData(19, 1),
+		"glass":                          20,
+		"lapis_ore":                      21,
+		"lapis_block":                    22,
+		"sandstone":                      24,
+		"chiseled_sandstone":             withData(24, 1),
+		"cut_sandstone":                  withData(24, 2),
+		"note_block":                     25,
+		"cobweb":                         30,
+		"dead_bush":                      32,
+		"grass":                          withData(31, 1),
+		"short_grass":                    withData(31, 1),
+		"fern":                           withData(31, 2),
+		"seagrass":                       withData(31, 1) | Waterlogged, // Always under water
+		"tall_seagrass":                  withData(31, 1) | Waterlogged,
+		"kelp":                           83 | Waterlogged,
+		"kelp_plant":                     83 | Waterlogged,
+		"moving_piston":                  34,
+		"dandelion":                      37,
+		"poppy":                          38,
+		"blue_orchid":                    withData(38, 1),
+		"allium":                         withData(38, 2),
+		"azure_bluet":                    withData(38, 3),
+		"red_tulip":                      withData(38, 4),
+		"orange_tulip":                   withData(38, 5),
+		"white_tulip":                    withData(38, 6),
+		"pink_tulip":                     withData(38, 7),
+		"oxeye_daisy":                    withData(38, 8),
+		"cornflower":                     withData(38, 3),
+		"lily_of_the_valley":             withData(38, 6),
+		"wither_rose":                    38,
+		"brown_mushroom":                 39,
+		"red_mushroom":                   40,
+		"gold_block":                     41,
+		"iron_block":                     42,
+		"smooth_stone":                   withData(43, 8),
+		"bricks":                         45,
+		"tnt":                            46,
+		"bookshelf":                      47,
+		"mossy_cobblestone":              48,
+		"obsidian":                       49,
+		"fire":                           51,
+		"soul_fire":                      51,
+		"spawner":                        52,
+		"diamond_ore":                    56,
+		"diamond_block":                  57,
+		"crafting_table":                 58,
+		"farmland":                       60,
+		"lever":                          69,
+		"stone_pressure_plate":           70,
+		"oak_pressure_plate":             72,
+		"stone_button":                   77,
+		"ice":                            79,
+		"snow_block":                     80,
+		"cactus":                         81,
+		"clay":                           82,
+		"sugar_cane":                     83,
+		"jukebox":                        84,
+		"oak_fence":                      85,
+		"pumpkin":                        86,
+		"carved_pumpkin":                 86,
+		"netherrack":                     87,
+		"soul_sand":                      88,
+		"soul_soil":                      88,
+		"glowstone":                      89,
+		"nether_portal":                  90,
+		"jack_o_lantern":                 91,
+		"cake":                           92,
+		"infested_stone":                 97,
+		"infested_cobblestone":           withData(97, 1),
+		"infested_stone_bricks":          withData(97, 2),
+		"infested_mossy_stone_bricks":    withData(97, 3),
+		"infested_cracked_stone_bricks":  withData(97, 4),
+		"infested_chiseled_stone_bricks": withData(97, 5),
+		"stone_bricks":                   98,
+		"mossy_stone_bricks":             withData(98, 1),
+		"cracked_stone_bricks":           withData(98, 2),
+		"chiseled_stone_bricks":          withData(98, 3),
+		"brown_mushroom_block":           99,
+		"red_mushroom_block":             100,
+		"mushroom_stem":                  withData(99, 10),
+		"iron_bars":                      101,
+		"glass_pane":                     102,
+		"melon":                          103,
+		"attached_pumpkin_stem":          withData(104, 7),
+		"attached_melon_stem":            withData(105, 7),
+		"oak_fence_gate":                 107,
+		"mycelium":                       110,
+		"lily_pad":                       111,
+		"nether_bricks":                  112,
+		"nether_brick_fence":             113,
+		"enchanting_table":               116,
+		"brewing_stand":                  117,
+		"end_portal":                     119,
+		"end_portal_frame":               120,
+		"end_stone":                      121,
+		"dragon_egg":                     122,
+		"cocoa":                          127,
+		"emerald_ore":                    129,
+		"tripwire_hook":                  131,
+		"tripwire":                       132,
+		"emerald_block":                  133,
+		"command_block":                  137,
+		"beacon":                         138,
+		"cobblestone_wall":               139,
+		"mossy_cobblestone_wall":         withData(139, 1),
+		"flower_pot":                     140,
+		"oak_button":                     143,
+		"light_weighted_pressure_plate":  147,
+		"heavy_weighted_pressure_plate":  148,
+		"daylight_detector":              151,
+		"redstone_block":                 152,
+		"nether_quartz_ore":              153,
+		"quartz_block":                   155,
+		"chiseled_quartz_block":          withData(155, 1),
+		"smooth_quartz":                  155,
+		"slime_block":                    165,
+		"barrier":                        166,
+		"prismarine":                     168,
+		"prismarine_bricks":              withData(168, 1),
+		"dark_prismarine":                withData(168, 2),
+		"sea_lantern":                    169,
+		"terracotta":                     172,
+		"coal_block":                     173,
+		"packed_ice":                     174,
+		"blue_ice":                       174,
+		"red_sandstone":                  179,
+		"chiseled_red_sandstone":         withData(179, 1),
+		"cut_red_sandstone":              withData(179, 2),
+		"chorus_plant":                   199,
+		"chorus_flower":                  200,
+		"purpur_block":                   201,
+		"end_stone_bricks":               206,
+		"dirt_path":                      208,
+		"grass_path":                     208,
+		"end_gateway":                    209,
+		"repeating_command_block":        210,
+		"chain_command_block":            211,
+		"frosted_ice":                    212,
+		"magma_block":                    213,
+		"nether_wart_block":              214,
+		"warped_wart_block":              214,
+		"red_nether_bricks":              215,
+		"structure_void":                 217,
+		"shulker_box":                    229,
+		"structure_block":                255,
+	}
+
+	for data, wood := range woodNames {
+		blockStateIds[wood+"_planks"] = withData(5, data)
+		blockStateIds[wood+"_sapling"] = withData(6, data)
+		blockStateIds[wood+"_fence"] = Block([]int{85, 188, 189, 190, 192, 191}[data])
+		blockStateIds[wood+"_fence_gate"] = Block([]int{107, 183, 184, 185, 187, 186}[data])
+		blockStateIds[wood+"_button"] = 143
+		blockStateIds[wood+"_pressure_plate"] = 72
+
+		var log, leaves = withData(17, data), withData(18, data)
+		if data >= 4 {
+			log, leaves = withData(162, data-4), withData(161, data-4)
+		}
+		blockStateIds[wood+"_log"] = log
+		blockStateIds["stripped_"+wood+"_log"] = log
+		blockStateIds[wood+"_wood"] = log + 12<<8 // Bark on all sides
+		blockStateIds["stripped_"+wood+"_wood"] = log + 12<<8
+		blockStateIds[wood+"_leaves"] = leaves
+	}
+
+	for i, color := range colorNames {
+		blockStateIds[color+"_wool"] = withData(35, i)
+		blockStateIds[color+"_stained_glass"] = withData(95, i)
+		blockStateIds[color+"_stained_glass_pane"] = withData(160, i)
+		blockStateIds[color+"_terracotta"] = withData(159, i)
+		blockStateIds[color+"_carpet"] = withData(171, i)
+		blockStateIds[color+"_shulker_box"] = Block(219 + i)
+		blockStateIds[color+"_glazed_terracotta"] = Block(235 + i)
+		blockStateIds[color+"_concrete"] = withData(251, i)
+		blockStateIds[color+"_concrete_powder"] = withData(252, i)
+	}
+
+	blockStateConverters = map[string]blockStateConverter{
+		"water":               fluidState(8, 9),
+		"lava":                fluidState(10, 11),
+		"bubble_column":       fixed(9),
+		"wheat":               ageState(59),
+		"carrots":             ageState(141),
+		"potatoes":            ageState(142),
+		"beetroots":           ageState(207),
+		"nether_wart":         ageState(115),
+		"pumpkin_stem":        ageState(104),
+		"melon_stem":          ageState(105),
+		"snow":                snowState,
+		"furnace":             furnaceState,
+		"redstone_ore":        litState(73, 74),
+		"redstone_lamp":       litState(123, 124),
+		"repeater":            repeaterState,
+		"redstone_wire":       redstoneWireState,
+		"comparator":          litState(149, 150),
+		"torch":               torchState(50),
+		"wall_torch":          torchState(50),
+		"lantern":             lanternState,
+		"soul_lantern":        lanternState,
+		"candle":              candleState,
+		"redstone_torch":      redstoneTorchState,
+		"redstone_wall_torch": redstoneTorchState,
+		"rail":                railState(66, false),
+		"powered_rail":        railState(27, true),
+		"detector_rail":       railState(28, true),
+		"activator_rail":      railState(157, true),
+		"oak_sign":            signState(63),
+		"oak_wall_sign":       facingState(68, ""),
+		"ladder":              facingState(65, ""),
+		"vine":                vineState,
+		"glow_lichen":         vineState,
+		"chest":               chestState(54),
+		"trapped_chest":       chestState(146),
+		"ender_chest":         facingState(130, ""),
+		"dispenser":           facingState(23, "triggered"),
+		"dropper":             facingState(158, "triggered"),
+		"piston":              facingState(33, "extended"),
+		"sticky_piston":       facingState(29, "extended"),
+		"piston_head":         pistonHeadState,
+		"observer":            facingState(218, "powered"),
+		"end_rod":             facingState(198, ""),
+		"hopper":              facingState(154, ""),
+		"cauldron":            cauldronState(0),
+		"water_cauldron":      cauldronState(0),
+		"lava_cauldron":       cauldronState(16),
+		"anvil":               anvilState(0),
+		"chipped_anvil":       anvilState(1),
+		"damaged_anvil":       anvilState(2),
+		"hay_block":           axisState(170),
+		"bone_block":          axisState(216),
+		"purpur_pillar":       axisState(202),
+		"quartz_pillar":       quartzPillarState,
+		"sunflower":           doublePlantState(0),
+		"lilac":               doublePlantState(1),
+		"tall_grass":          doublePlantState(2),
+		"large_fern":          doublePlantState(3),
+		"rose_bush":           doublePlantState(4),
+		"peony":               doublePlantState(5),
+	}
+
+	for name, block := range map[string]Block{
+		"oak": 53, "cobblestone": 67, "brick": 108, "stone_brick": 109, "nether_brick": 114,
+		"sandstone": 128, "spruce": 134, "birch": 135, "jungle": 136, "quartz": 156,
+		"acacia": 163, "dark_oak": 164, "red_sandstone": 180, "purpur": 203,
+	} {
+		blockStateConverters[name+"_stairs"] = stairsState(block)
+	}
+
+	for name, data := range map[string]int{
+		"smooth_stone": 0, "stone": 0, "sandstone": 1, "petrified_oak": 2, "cobblestone": 3,
+		"brick": 4, "stone_brick": 5, "nether_brick": 6, "quartz": 7,
+	} {
+		blockStateConverters[name+"_slab"] = slabState(withData(44, data), withData(43, data))
+	}
+	for data, wood := range woodNames {
+		blockStateConverters[wood+"_slab"] = slabState(withData(126, data), withData(125, data))
+		blockStateConverters[wood+"_door"] = doorState(Block([]int{64, 193, 194, 195, 196, 197}[data]))
+		blockStateConverters[wood+"_trapdoor"] = trapdoorState(96)
+		blockStateConverters[wood+"_log"] = axisState(blockStateIds[wood+"_log"])
+		blockStateConverters["stripped_"+wood+"_log"] = axisState(blockStateIds[wood+"_log"])
+	}
+	blockStateConverters["red_sandstone_slab"] = slabState(182, 181)
+	blockStateConverters["purpur_slab"] = slabState(205, 204)
+	blockStateConverters["iron_door"] = doorState(71)
+	blockStateConverters["iron_trapdoor"] = trapdoorState(167)
+	blockStateConverters["powder_snow_cauldron"] = cauldronState(32)
+
+	for _, skull := range []string{"skeleton_skull", "wither_skeleton_skull", "zombie_head", "player_head", "creeper_head", "dragon_head", "piglin_head"} {
+		blockStateConverters[skull] = skullState(false)
+		var i = strings.LastIndex(skull, "_")
+		blockStateConverters[skull[:i]+"_wall"+skull[i:]] = skullState(true)
+	}
+
+	for i, color := range colorNames {
+		blockStateConverters[color+"_bed"] = bedState(i)
+		blockStateConverters[color+"_banner"] = signState(176)
+		blockStateConverters[color+"_wall_banner"] = facingState(177, "")
+	}
+}
+
+func doublePlantState(data int) blockStateConverter {
+	return func(properties map[string]interface{}) Block {
+		if stateProperty(properties, "half") == "upper" {
+			return withData(175, 8)
+		}
+		return withData(175, data)
+	}
+}
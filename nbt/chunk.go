@@ -1,15 +1,23 @@
 package nbt
 
 import (
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 type Chunk struct {
-	XPos, ZPos int
-	Blocks     []Block
+	XPos, ZPos    int
+	MinY          int // World height of the bottom of Blocks
+	Blocks        []Block
+	Status        string // How far the world generator got, from 1.13
+	Inhabited     int    // Ticks players have spent nearby, from 1.0
+	HeightMaps    map[string][]int
+	Biomes        BiomeMap
+	BlockLight    []byte // 0 to 15 for each of Blocks, or nil when not saved
+	SkyLight      []byte
+	BlockEntities []BlockEntity // Chests, signs, banners...
 }
 
 var (
@@ -17,27 +25,125 @@ var (
 )
 
 func ReadChunkDat(reader io.Reader) (*Chunk, error) {
-	r, err := gzip.NewReader(reader)
-	defer r.Close()
+	r, err := NewDecompressor(reader)
 	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
 
 	return ReadChunkNbt(r)
 }
 
 func ReadChunkNbt(reader io.Reader) (*Chunk, error) {
+	return readChunk(NewReader(reader))
+}
+
+// ReadChunkBytes reads a chunk from its uncompressed NBT in memory, reading
+// its arrays in place rather than copying them. The chunk doesn't keep any
+// of data, which can be reused as soon as it returns.
+func ReadChunkBytes(data []byte) (*Chunk, error) {
+	return readChunk(NewBytesReader(data))
+}
+
+func readChunk(r *Reader) (*Chunk, error) {
 	chunkData := new(chunkData)
 	chunkData.sections = make([]*sectionData, 0)
-	if err := chunkData.parse(NewReader(reader), false); err != nil {
+	if err := chunkData.parse(r, false); err != nil {
 		return nil, err
 	}
+	return chunkData.chunk(), nil
+}
+
+// ReadChunkNbtLenient reads a chunk like ReadChunkNbt, but when the NBT is
+// truncated or otherwise breaks off partway it also returns the chunk made
+// from every tag read before then, along with the error. The section it
+// broke off in and those after it are left empty.
+func ReadChunkNbtLenient(reader io.Reader) (*Chunk, error) {
+	return readChunkLenient(NewReader(reader))
+}
+
+// ReadChunkBytesLenient is ReadChunkBytes made lenient like
+// ReadChunkNbtLenient.
+func ReadChunkBytesLenient(data []byte) (*Chunk, error) {
+	return readChunkLenient(NewBytesReader(data))
+}
+
+func readChunkLenient(r *Reader) (*Chunk, error) {
+	chunkData := new(chunkData)
+	chunkData.sections = make([]*sectionData, 0)
+	var err = chunkData.parse(r, false)
+	if err != nil && chunkData.section != nil {
+		// The section it broke off in may not have its Y yet
+		chunkData.sections = chunkData.sections[:len(chunkData.sections)-1]
+	}
+	return chunkData.chunk(), err
+}
+
+func (chunkData *chunkData) chunk() *Chunk {
+	chunk := &Chunk{chunkData.xPos, chunkData.zPos, 0, nil, chunkData.status, chunkData.inhabited, nil, BiomeMap{}, nil, nil, chunkData.blockEntities}
+
+	// Heights count up from the bottom of the world, which 1.18+ chunks
+	// give as the section yPos.
+	if len(chunkData.heightMaps) != 0 {
+		chunk.HeightMaps = make(map[string][]int)
+		for name, longs := range chunkData.heightMaps {
+			if heights := unpackHeightMap(longs, 16*chunkData.yPos); heights != nil {
+				chunk.HeightMaps[name] = heights
+			}
+		}
+	}
+	if len(chunkData.legacyHeightMap) == 16*16 {
+		chunk.HeightMaps = map[string][]int{LegacyHeightMap: chunkData.legacyHeightMap}
+	}
 
-	chunk := &Chunk{chunkData.xPos, chunkData.zPos, nil}
+	chunk.Biomes = chunkData.biomeMap()
+
+	for i := range chunk.BlockEntities {
+		chunk.BlockEntities[i].setBanner()
+	}
 
 	if len(chunkData.sections) != 0 {
-		chunk.Blocks = make([]Block, 256*16*16) // Hard coded height for now. TODO: Make variable height chunks.
+		// Chunks are at least the 0-255 of Anvil worlds, and from 1.18
+		// extend down below zero and up above 256.
+		var minY, maxY = 0, 256
 		for _, section := range chunkData.sections {
+			if section.blocks == nil && section.palette == nil {
+				continue // Lighting only
+			}
+			if 16*section.y < minY {
+				minY = 16 * section.y
+			}
+			if 16*(section.y+1) > maxY {
+				maxY = 16 * (section.y + 1)
+			}
+		}
+		var height = maxY - minY
+
+		chunk.MinY = minY
+		chunk.Blocks = make([]Block, height*16*16)
+		for _, section := range chunkData.sections {
+			var sectionBase = 16*section.y - minY
+			if sectionBase >= 0 && sectionBase < height {
+				chunk.BlockLight = spreadSectionLight(chunk.BlockLight, section.blockLight, sectionBase, height)
+				chunk.SkyLight = spreadSectionLight(chunk.SkyLight, section.skyLight, sectionBase, height)
+			}
+
+			// Note that the old format is XZY and the new format is YZX
+			if section.palette != nil {
+				for i, block := range section.paletteBlocks() {
+					x, z, y := indexToCoords(i, 16, 16)
+					chunk.Blocks[coordsToIndex(x, z, y+sectionBase, 16, height)] = block
+				}
+				if section.bannerColors != nil {
+					section.colorBanners(chunk.BlockEntities)
+				}
+				continue
+			}
+
+			if section.blocks == nil || section.data == nil {
+				continue
+			}
+
 			for i, blockId := range section.blocks {
 				var metadata byte
 				if i&1 == 1 {
@@ -45,9 +151,8 @@ func ReadChunkNbt(reader io.Reader) (*Chunk, error) {
 				} else {
 					metadata = section.data[i/2] & 0xf
 				}
-				// Note that the old format is XZY and the new format is YZX
 				x, z, y := indexToCoords(i, 16, 16)
-				chunk.Blocks[coordsToIndex(x, z, y+16*section.y, 16, 256)] = Block(blockId) + (Block(metadata) << 8)
+				chunk.Blocks[coordsToIndex(x, z, y+sectionBase, 16, height)] = Block(blockId) + (Block(metadata) << 8)
 			}
 		}
 	} else {
@@ -62,10 +167,24 @@ func ReadChunkNbt(reader io.Reader) (*Chunk, error) {
 				}
 				chunk.Blocks[i] = Block(blockId) + (Block(metadata) << 8)
 			}
+			chunk.BlockLight = unpackNibbles(chunkData.blockLight, len(chunk.Blocks))
+			chunk.SkyLight = unpackNibbles(chunkData.skyLight, len(chunk.Blocks))
 		}
 	}
 
-	return chunk, nil
+	return chunk
+}
+
+// IsFullyGenerated reports whether the world generator has finished the
+// chunk. Proto-chunks that stopped at an earlier status are missing their
+// terrain or features. Chunks from before 1.13 have no status and are
+// always complete.
+func (c *Chunk) IsFullyGenerated() bool {
+	switch strings.TrimPrefix(c.Status, "minecraft:") {
+	case "", "full", "fullchunk", "postprocessed":
+		return true
+	}
+	return false
 }
 
 func indexToCoords(i, aMax, bMax int) (a, b, c int) {
@@ -89,24 +208,224 @@ func yzxToXzy(yzx, xMax, zMax, yMax int) int {
 	return xzy
 }
 
+// chunkData holds what parse has read, which may be views of the Reader's
+// buffer until chunk copies it into a Chunk.
 type chunkData struct {
-	xPos, zPos int
-	blocks     []byte
-	data       []byte
-	section    *sectionData
-	sections   []*sectionData
+	xPos, zPos      int
+	yPos            int
+	status          string
+	inhabited       int
+	heightMaps      map[string][]int64
+	legacyHeightMap []int
+	legacyBiomes    []int
+	blockLight      []byte
+	skyLight        []byte
+	blockEntities   []BlockEntity
+	blocks          []byte
+	data            []byte
+	section         *sectionData
+	sections        []*sectionData
 }
 
 type sectionData struct {
 	y      int
 	blocks []byte
 	data   []byte
+
+	palette     []Block
+	blockStates LongView
+
+	// The color of each banner in the palette and -1 for other blocks, or
+	// nil if there are no banners
+	bannerColors []int
+
+	biomePalette []string
+	biomeData    []int64
+
+	blockLight []byte
+	skyLight   []byte
+}
+
+func (section *sectionData) setPalette(states []interface{}) {
+	section.palette = make([]Block, len(states))
+	for i, s := range states {
+		state, _ := s.(map[string]interface{})
+		name, _ := state["Name"].(string)
+		properties, _ := state["Properties"].(map[string]interface{})
+		section.palette[i] = BlockState(name, properties)
+
+		if color := bannerColor(name); color != -1 {
+			if section.bannerColors == nil {
+				section.bannerColors = make([]int, len(states))
+				for j := range section.bannerColors {
+					section.bannerColors[j] = -1
+				}
+			}
+			section.bannerColors[i] = color
+		}
+	}
+}
+
+// readBlockStates reads the 1.18+ block_states struct, which holds the
+// palette and the packed indexes that earlier versions put directly in the
+// section.
+func (section *sectionData) readBlockStates(r *Reader) error {
+	if err := r.enter(); err != nil {
+		return err
+	}
+	defer r.leave()
+	section.setPalette(nil)
+	for {
+		typeId, name, err := r.ReadTag()
+		if err != nil {
+			return err
+		}
+		switch {
+		case typeId == TagStructEnd:
+			return nil
+		case typeId == TagLongArray && name == "data":
+			if section.blockStates, err = r.ReadLongsView(); err != nil {
+				return err
+			}
+		case typeId == TagList && name == "palette":
+			palette, err := r.ReadValue(TagList)
+			if err != nil {
+				return err
+			}
+			states, _ := palette.([]interface{})
+			section.setPalette(states)
+		default:
+			if err := r.skipValue(typeId); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// paletteBlocks unpacks the BlockStates of a 1.13+ section into one block
+// per position, in the same YZX order as the Blocks byte array.
+//
+// Each entry is an index into the palette, using just enough bits to index
+// the whole palette (but never fewer than four). Up until 1.16 the entries
+// were packed end to end, and could straddle two longs. From 1.16 entries
+// never straddle and the unused high bits of each long are padding. The two
+// layouts only produce differently sized arrays, so the length tells them
+// apart.
+func (section *sectionData) paletteBlocks() []Block {
+	var blocks = make([]Block, 16*16*16)
+
+	if len(section.palette) == 0 {
+		return blocks
+	}
+
+	if len(section.palette) == 1 || section.blockStates.Len() == 0 {
+		for i := range blocks {
+			blocks[i] = section.palette[0]
+		}
+		return blocks
+	}
+
+	var index = section.paletteIndex()
+	for i := range blocks {
+		if j := index(i); j < len(section.palette) {
+			blocks[i] = section.palette[j]
+		}
+	}
+
+	return blocks
+}
+
+// paletteIndex is the index into the palette of the block at each position
+// of the section, or past the end of the palette where there is none.
+func (section *sectionData) paletteIndex() func(i int) int {
+	if len(section.palette) <= 1 || section.blockStates.Len() == 0 {
+		return func(i int) int { return 0 }
+	}
+
+	var bits = 4
+	for 1<<uint(bits) < len(section.palette) {
+		bits++
+	}
+
+	var (
+		mask     = uint64(1)<<uint(bits) - 1
+		perLong  = 64 / bits
+		straddle = section.blockStates.Len()*64 == 16*16*16*bits
+	)
+
+	return func(i int) int {
+		var index uint64
+		if straddle {
+			var (
+				bit    = i * bits
+				word   = bit / 64
+				offset = uint(bit % 64)
+			)
+			index = uint64(section.blockStates.At(word)) >> offset
+			if int(offset)+bits > 64 {
+				index |= uint64(section.blockStates.At(word+1)) << (64 - offset)
+			}
+		} else {
+			var word = i / perLong
+			if word >= section.blockStates.Len() {
+				return len(section.palette)
+			}
+			index = uint64(section.blockStates.At(word)) >> uint((i%perLong)*bits)
+		}
+		return int(index & mask)
+	}
+}
+
+// setBiomes reads the 1.18+ biomes struct of a section, a palette of names
+// and packed indexes like block_states.
+func (section *sectionData) setBiomes(biomes map[string]interface{}) {
+	palette, _ := biomes["palette"].([]interface{})
+	section.biomePalette = make([]string, len(palette))
+	for i, name := range palette {
+		section.biomePalette[i], _ = name.(string)
+	}
+	section.biomeData, _ = Longs(biomes["data"])
+}
+
+// biomeMap gathers the biomes of the sections, or the chunk's biome ids
+// from before 1.18.
+func (chunk *chunkData) biomeMap() BiomeMap {
+	var minY, maxY = 0, 0
+	var found = false
+	for _, section := range chunk.sections {
+		if section.biomePalette == nil {
+			continue
+		}
+		if !found || 16*section.y < minY {
+			minY = 16 * section.y
+		}
+		if !found || 16*(section.y+1) > maxY {
+			maxY = 16 * (section.y + 1)
+		}
+		found = true
+	}
+	if !found {
+		var biomes = biomesFromIds(chunk.legacyBiomes)
+		biomes.MinY = 16 * chunk.yPos
+		return biomes
+	}
+
+	var biomes = BiomeMap{Names: make([]string, 16*(maxY-minY)/4), Cells: true, MinY: minY}
+	for _, section := range chunk.sections {
+		if section.biomePalette != nil {
+			copy(biomes.Names[16*(16*section.y-minY)/4:], unpackBiomes(section.biomePalette, section.biomeData))
+		}
+	}
+	return biomes
 }
 
 func (chunk *chunkData) parse(r *Reader, listStruct bool) error {
 	structDepth := 0
 	if listStruct {
 		structDepth++
+		if err := r.enter(); err != nil {
+			return err
+		}
 	}
 
 	for {
@@ -120,14 +439,47 @@ func (chunk *chunkData) parse(r *Reader, listStruct bool) error {
 
 		switch typeId {
 		case TagStruct:
+			if chunk.section != nil && structDepth == 1 && listStruct {
+				// 1.18+ sections nest the palette and the packed states
+				if name == "block_states" {
+					if err := chunk.section.readBlockStates(r); err != nil {
+						return err
+					}
+					break
+				} else if name == "biomes" {
+					biomes, err := r.ReadStruct()
+					if err != nil {
+						return err
+					}
+					chunk.section.setBiomes(biomes)
+					break
+				}
+			}
+			if name == "Heightmaps" && !listStruct {
+				heightMaps, err := r.ReadStruct()
+				if err != nil {
+					return err
+				}
+				chunk.heightMaps = make(map[string][]int64)
+				for name, value := range heightMaps {
+					if longs, ok := Longs(value); ok && len(longs) != 0 {
+						chunk.heightMaps[name] = longs
+					}
+				}
+				break
+			}
 			structDepth++
+			if err := r.enter(); err != nil {
+				return err
+			}
 		case TagStructEnd:
+			r.leave()
 			structDepth--
 			if structDepth == 0 {
 				return nil
 			}
 		case TagByteArray:
-			bytes, err := r.ReadBytes()
+			bytes, err := r.ReadBytesView()
 			if err != nil {
 				return err
 			}
@@ -143,18 +495,48 @@ func (chunk *chunkData) parse(r *Reader, listStruct bool) error {
 				} else {
 					chunk.data = bytes
 				}
+			} else if name == "BlockLight" {
+				if chunk.section != nil {
+					chunk.section.blockLight = bytes
+				} else if !listStruct {
+					chunk.blockLight = bytes
+				}
+			} else if name == "SkyLight" {
+				if chunk.section != nil {
+					chunk.section.skyLight = bytes
+				} else if !listStruct {
+					chunk.skyLight = bytes
+				}
+			} else if name == "Biomes" && !listStruct {
+				chunk.legacyBiomes = make([]int, len(bytes))
+				for i, id := range bytes {
+					chunk.legacyBiomes[i] = int(id)
+				}
 			}
 		case TagIntArray:
-			_, err := r.ReadInts()
+			ints, err := r.ReadInts()
 			if err != nil {
 				return err
 			}
+			if name == "HeightMap" && !listStruct {
+				chunk.legacyHeightMap = ints
+			} else if name == "Biomes" && !listStruct {
+				chunk.legacyBiomes = ints
+			}
+		case TagLongArray:
+			longs, err := r.ReadLongsView()
+			if err != nil {
+				return err
+			}
+			if name == "BlockStates" && chunk.section != nil {
+				chunk.section.blockStates = longs
+			}
 		case TagInt8:
 			number, err := r.ReadInt8()
 			if err != nil {
 				return err
 			}
-			if name == "Y" {
+			if name == "Y" && chunk.section != nil {
 				chunk.section.y = int(number)
 			}
 		case TagInt16:
@@ -174,11 +556,17 @@ func (chunk *chunkData) parse(r *Reader, listStruct bool) error {
 			if name == "zPos" {
 				chunk.zPos = number
 			}
+			if name == "yPos" && !listStruct {
+				chunk.yPos = number
+			}
 		case TagInt64:
-			_, err := r.ReadInt64()
+			number, err := r.ReadInt64()
 			if err != nil {
 				return err
 			}
+			if name == "InhabitedTime" && !listStruct && structDepth <= 2 {
+				chunk.inhabited = number
+			}
 		case TagFloat32:
 			_, err := r.ReadFloat32()
 			if err != nil {
@@ -190,15 +578,22 @@ func (chunk *chunkData) parse(r *Reader, listStruct bool) error {
 				return err
 			}
 		case TagString:
-			_, err := r.ReadString()
+			str, err := r.ReadString()
 			if err != nil {
 				return err
 			}
+			// Under Level up until 1.18, and at the top level after
+			if name == "Status" && !listStruct && structDepth <= 2 {
+				chunk.status = str
+			}
 		case TagList:
 			itemTypeId, length, err := r.ReadListHeader()
 			if err != nil {
 				return err
 			}
+			if err := r.enter(); err != nil {
+				return err
+			}
 			switch itemTypeId {
 			case TagInt8:
 				for i := 0; i < length; i++ {
@@ -222,8 +617,26 @@ func (chunk *chunkData) parse(r *Reader, listStruct bool) error {
 					}
 				}
 			case TagStruct:
+				if name == "Palette" && chunk.section != nil {
+					states, err := r.readList(TagStruct, length)
+					if err != nil {
+						return err
+					}
+					chunk.section.setPalette(states.([]interface{}))
+					break
+				}
+				if (name == "block_entities" || name == "TileEntities") && chunk.section == nil {
+					list, err := r.readList(TagStruct, length)
+					if err != nil {
+						return err
+					}
+					chunk.setBlockEntities(list.([]interface{}))
+					break
+				}
+
 				for i := 0; i < length; i++ {
-					if name == "Sections" {
+					r.setIndex(i)
+					if name == "Sections" || name == "sections" {
 						chunk.section = new(sectionData)
 						chunk.sections = append(chunk.sections, chunk.section)
 					}
@@ -232,12 +645,20 @@ func (chunk *chunkData) parse(r *Reader, listStruct bool) error {
 						return err
 					}
 				}
+				if name == "Sections" || name == "sections" {
+					chunk.section = nil
+				}
 			default:
-				fmt.Printf("# %s list todo(%v) %v\n", name, itemTypeId, length)
-				return ErrListUnknown
+				for i := 0; i < length; i++ {
+					r.setIndex(i)
+					if err := r.skipValue(itemTypeId); err != nil {
+						return err
+					}
+				}
 			}
+			r.leave()
 		default:
-			fmt.Printf("# %s todo(%d)\n", name, typeId)
+			return errors.New(fmt.Sprintf("reading %s typeId %d not supported", name, typeId))
 		}
 	}
 
@@ -0,0 +1,539 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestPaletteBlocksStraddled(t *testing.T) {
+	checkPaletteBlocks(t, packBlockStates(17, true))
+}
+
+func TestPaletteBlocksPadded(t *testing.T) {
+	checkPaletteBlocks(t, packBlockStates(17, false))
+}
+
+func TestPaletteBlocksSingleEntry(t *testing.T) {
+	section := &sectionData{palette: []Block{3}}
+	for i, block := range section.paletteBlocks() {
+		if block != 3 {
+			t.Fatalf("block %d is %d not 3", i, block)
+		}
+	}
+}
+
+func TestBlockStateNames(t *testing.T) {
+	checkBlockState(t, "minecraft:air", nil, 0)
+	checkBlockState(t, "minecraft:stone", nil, 1)
+	checkBlockState(t, "minecraft:red_wool", nil, withData(35, 14))
+	checkBlockState(t, "minecraft:oak_slab", map[string]interface{}{"type": "top"}, withData(126, 8))
+	checkBlockState(t, "minecraft:stone_brick_slab", map[string]interface{}{"type": "double"}, withData(43, 5))
+	checkBlockState(t, "minecraft:snow", map[string]interface{}{"layers": "3"}, withData(78, 2))
+	checkBlockState(t, "minecraft:water", map[string]interface{}{"level": "0"}, 9)
+	checkBlockState(t, "minecraft:warped_planks", nil, withData(5, 0))
+	checkBlockState(t, "minecraft:soul_lantern", map[string]interface{}{"hanging": "true"}, withData(50, TorchHangingLantern))
+	checkBlockState(t, "minecraft:red_candle", map[string]interface{}{"candles": "3"}, withData(50, TorchCandles+2))
+	checkBlockState(t, "minecraft:mangrove_trapdoor", map[string]interface{}{"facing": "east", "half": "top", "open": "true"}, withData(96, 3+4+8))
+	checkBlockState(t, "minecraft:oak_slab", map[string]interface{}{"type": "top", "waterlogged": "true"}, withData(126, 8)|Waterlogged)
+	checkBlockState(t, "minecraft:brain_coral", map[string]interface{}{"waterlogged": "true"}, withData(31, 1)|Waterlogged)
+	checkBlockState(t, "minecraft:kelp", map[string]interface{}{"age": "3"}, 83|Waterlogged)
+	checkBlockState(t, "minecraft:spruce_log", map[string]interface{}{"axis": "x"}, withData(17, 1+4))
+	checkBlockState(t, "minecraft:dark_oak_wood", map[string]interface{}{"axis": "z"}, withData(162, 1+12))
+	checkBlockState(t, "minecraft:quartz_pillar", map[string]interface{}{"axis": "z"}, withData(155, 4))
+	checkBlockState(t, "minecraft:furnace", map[string]interface{}{"facing": "west", "lit": "true"}, withData(62, 4))
+	checkBlockState(t, "minecraft:sticky_piston", map[string]interface{}{"facing": "down", "extended": "true"}, withData(29, 8))
+	checkBlockState(t, "minecraft:piston_head", map[string]interface{}{"facing": "east", "type": "sticky"}, withData(34, 5+8))
+	checkBlockState(t, "minecraft:repeater", map[string]interface{}{"facing": "north", "delay": "3", "powered": "true"}, withData(94, 2+2<<2))
+	checkBlockState(t, "minecraft:vine", map[string]interface{}{"north": "true", "east": "true", "up": "true", "south": "false"}, 106+(4+8+16)<<8)
+	checkBlockState(t, "minecraft:glow_lichen", map[string]interface{}{"down": "true", "waterlogged": "true"}, 106+32<<8|Waterlogged)
+	checkBlockState(t, "minecraft:red_bed", map[string]interface{}{"facing": "south", "part": "foot"}, 26)
+	checkBlockState(t, "minecraft:white_bed", map[string]interface{}{"facing": "north", "part": "head"}, 26+(2+8+4+6<<4)<<8)
+	checkBlockState(t, "minecraft:trapped_chest", map[string]interface{}{"facing": "east", "type": "left"}, 146+(5+2<<4)<<8)
+	checkBlockState(t, "minecraft:ender_chest", map[string]interface{}{"facing": "south"}, withData(130, 3))
+	checkBlockState(t, "minecraft:blue_banner", map[string]interface{}{"rotation": "6"}, withData(176, 6))
+	checkBlockState(t, "minecraft:lime_wall_banner", map[string]interface{}{"facing": "west"}, withData(177, 4))
+	checkBlockState(t, "minecraft:player_head", map[string]interface{}{"rotation": "10"}, 144+(1+10<<3)<<8)
+	checkBlockState(t, "minecraft:wither_skeleton_wall_skull", map[string]interface{}{"facing": "east"}, withData(144, 5))
+	checkBlockState(t, "minecraft:end_rod", map[string]interface{}{"facing": "west"}, withData(198, 4))
+	checkBlockState(t, "minecraft:water_cauldron", map[string]interface{}{"level": "2"}, withData(118, 2))
+	checkBlockState(t, "minecraft:lava_cauldron", nil, 118+(3|16)<<8)
+	checkBlockState(t, "minecraft:chipped_anvil", map[string]interface{}{"facing": "east"}, withData(145, 4+3))
+	checkBlockState(t, "minecraft:hopper", map[string]interface{}{"facing": "north", "enabled": "true"}, withData(154, 2))
+	checkBlockState(t, "minecraft:oak_stairs", map[string]interface{}{"facing": "north", "half": "top", "shape": "outer_left"}, 53+(3+4+8*4)<<8)
+}
+
+func TestParseBlockState(t *testing.T) {
+	for state, expected := range map[string]Block{
+		"minecraft:stone":                  1,
+		"minecraft:oak_slab[type=top]":     withData(126, 8),
+		"snow[layers=3]":                   withData(78, 2),
+		"minecraft:water[level=0]":         9,
+		"minecraft:snow[layers=3,unknown]": withData(78, 2),
+	} {
+		if block := ParseBlockState(state); block != expected {
+			t.Errorf("%s is %d:%d not %d:%d", state, block&0xff, block>>8, expected&0xff, expected>>8)
+		}
+	}
+}
+
+func TestReadExtendedHeightChunk(t *testing.T) {
+	var states = packBlockStates(2, false)
+	var palette = [][]byte{
+		tagStruct("", tagString("Name", "minecraft:air")),
+		tagStruct("", tagString("Name", "minecraft:stone")),
+	}
+
+	chunk, err := ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagInt("DataVersion", 2975),
+		tagInt("xPos", -3),
+		tagInt("zPos", 7),
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", -5)), // lighting only
+			tagStruct("", tagByte("Y", -4),
+				tagStruct("block_states",
+					tagList("palette", TagStruct, palette...),
+					tagLongArray("data", states.blockStates.Longs())),
+				tagStruct("biomes",
+					tagList("palette", TagString, []byte{0, 6, 'p', 'l', 'a', 'i', 'n', 's'}))),
+			tagStruct("", tagByte("Y", 19),
+				tagStruct("block_states",
+					tagList("palette", TagStruct, palette[1]))),
+		),
+	)))
+
+	checkError(t, err, nil)
+	if chunk.XPos != -3 || chunk.ZPos != 7 {
+		t.Errorf("chunk position %d,%d not -3,7", chunk.XPos, chunk.ZPos)
+	}
+	if chunk.MinY != -64 {
+		t.Errorf("MinY %d not -64", chunk.MinY)
+	}
+	if len(chunk.Blocks) != 16*16*384 {
+		t.Fatalf("%d blocks not %d", len(chunk.Blocks), 16*16*384)
+	}
+
+	// (1,0,0) of the lowest section is stone, (0,0,0) is air and the top
+	// section is filled with stone.
+	if b := chunk.Blocks[coordsToIndex(1, 0, 0, 16, 384)]; b != 1 {
+		t.Errorf("block at 1,-64,0 is %d not stone", b)
+	}
+	if b := chunk.Blocks[coordsToIndex(0, 0, 0, 16, 384)]; b != 0 {
+		t.Errorf("block at 0,-64,0 is %d not air", b)
+	}
+	if b := chunk.Blocks[coordsToIndex(5, 9, 383, 16, 384)]; b != 1 {
+		t.Errorf("block at 5,319,9 is %d not stone", b)
+	}
+}
+
+func TestChunkStatus(t *testing.T) {
+	var proto, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagInt("DataVersion", 2975),
+		tagString("Status", "minecraft:noise"),
+		tagList("block_entities", TagStruct, tagStruct("", tagString("Status", "full"))))))
+	checkError(t, err, nil)
+	if proto.Status != "minecraft:noise" || proto.IsFullyGenerated() {
+		t.Errorf("status %q is fully generated", proto.Status)
+	}
+
+	old, err := ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagStruct("Level", tagString("Status", "full")))))
+	checkError(t, err, nil)
+	if old.Status != "full" || !old.IsFullyGenerated() {
+		t.Errorf("status %q isn't fully generated", old.Status)
+	}
+
+	if legacy := (&Chunk{}); !legacy.IsFullyGenerated() {
+		t.Errorf("chunk without a status isn't fully generated")
+	}
+}
+
+func TestInhabitedTime(t *testing.T) {
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagStruct("Level",
+			tagLong("InhabitedTime", 72000),
+			tagList("Entities", TagStruct, tagStruct("", tagLong("InhabitedTime", 5)))))))
+	checkError(t, err, nil)
+	if chunk.Inhabited != 72000 {
+		t.Errorf("inhabited for %d ticks not 72000", chunk.Inhabited)
+	}
+}
+
+func TestHeightMaps(t *testing.T) {
+	// 1.18 packs nine bit heights seven to a long, from y=-64
+	var padded = make([]int64, 37)
+	for i := 0; i < 256; i++ {
+		padded[i/7] |= int64(i) << uint(i%7*9)
+	}
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagInt("yPos", -4),
+		tagStruct("Heightmaps",
+			tagLongArray(MotionBlocking, padded),
+			tagLongArray(WorldSurface, make([]int64, 36))))))
+	checkError(t, err, nil)
+	if y, ok := chunk.Height(MotionBlocking, 3, 2); !ok || y != 2*16+3-64 {
+		t.Errorf("motion blocking height %d, %v not %d", y, ok, 2*16+3-64)
+	}
+	if y, ok := chunk.Height(WorldSurface, 15, 15); !ok || y != -64 {
+		t.Errorf("empty world surface height %d, %v not -64", y, ok)
+	}
+	if _, ok := chunk.Height(OceanFloor, 0, 0); ok {
+		t.Errorf("height from a missing height map")
+	}
+
+	// 1.14 straddles the longs, from y=0
+	var straddled = make([]int64, 36)
+	for i := 0; i < 256; i++ {
+		var bit = i * 9
+		straddled[bit/64] |= int64(uint64(i) << uint(bit%64))
+		if bit%64+9 > 64 {
+			straddled[bit/64+1] |= int64(uint64(i) >> uint(64-bit%64))
+		}
+	}
+	chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagStruct("Level", tagStruct("Heightmaps", tagLongArray(MotionBlocking, straddled))))))
+	checkError(t, err, nil)
+	if y, _ := chunk.Height(MotionBlocking, 13, 6); y != 6*16+13 {
+		t.Errorf("straddled height %d not %d", y, 6*16+13)
+	}
+}
+
+func TestLegacyHeightMap(t *testing.T) {
+	var heightMap bytes.Buffer
+	heightMap.Write(tagHeader(TagIntArray, "HeightMap"))
+	binary.Write(&heightMap, binary.BigEndian, int32(256))
+	for i := 0; i < 256; i++ {
+		binary.Write(&heightMap, binary.BigEndian, int32(64+i%16))
+	}
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("", tagStruct("Level", heightMap.Bytes()))))
+	checkError(t, err, nil)
+	if y, ok := chunk.Height(LegacyHeightMap, 5, 9); !ok || y != 69 {
+		t.Errorf("legacy height %d, %v not 69", y, ok)
+	}
+}
+
+func TestSectionBiomes(t *testing.T) {
+	// Two bits per cell, with the top half of the section a river
+	var data = []int64{0, 0x5555555555555555}
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", -4),
+				tagStruct("biomes",
+					tagList("palette", TagString, tagString("", "minecraft:plains")[3:]))),
+			tagStruct("", tagByte("Y", -3),
+				tagStruct("biomes",
+					tagList("palette", TagString,
+						tagString("", "minecraft:plains")[3:],
+						tagString("", "minecraft:river")[3:],
+						tagString("", "minecraft:beach")[3:]),
+					tagLongArray("data", data)))))))
+	checkError(t, err, nil)
+	if chunk.Biomes.MinY != -64 || len(chunk.Biomes.Names) != 2*64 {
+		t.Fatalf("%d biome cells from %d", len(chunk.Biomes.Names), chunk.Biomes.MinY)
+	}
+	for _, expected := range []struct {
+		y     int
+		biome string
+	}{{-64, "minecraft:plains"}, {-41, "minecraft:plains"}, {-40, "minecraft:river"}, {100, "minecraft:river"}} {
+		if biome := chunk.Biomes.At(7, expected.y, 3); biome != expected.biome {
+			t.Errorf("biome at y=%d is %q not %q", expected.y, biome, expected.biome)
+		}
+	}
+}
+
+func TestLegacyBiomes(t *testing.T) {
+	var columns = make([]byte, 256)
+	columns[5+9*16] = 21
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagStruct("Level", append(tagHeader(TagByteArray, "Biomes"), append([]byte{0, 0, 1, 0}, columns...)...)))))
+	checkError(t, err, nil)
+	if biome := chunk.Biome(5, 9); biome != "minecraft:jungle" {
+		t.Errorf("column biome %q not jungle", biome)
+	}
+	if biome := chunk.Biome(6, 9); biome != "minecraft:ocean" {
+		t.Errorf("column biome %q not ocean", biome)
+	}
+
+	// 1.15 has an int for each 4x4x4 cell, here desert above y=128
+	var cells bytes.Buffer
+	cells.Write(tagHeader(TagIntArray, "Biomes"))
+	binary.Write(&cells, binary.BigEndian, int32(1024))
+	for i := 0; i < 1024; i++ {
+		var id = int32(1)
+		if i >= 512 {
+			id = 2
+		}
+		binary.Write(&cells, binary.BigEndian, id)
+	}
+	chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("", tagStruct("Level", cells.Bytes()))))
+	checkError(t, err, nil)
+	if biome := chunk.Biomes.At(0, 127, 0); biome != "minecraft:plains" {
+		t.Errorf("biome below 128 %q not plains", biome)
+	}
+	if biome := chunk.Biomes.At(0, 128, 0); biome != "minecraft:desert" {
+		t.Errorf("biome above 128 %q not desert", biome)
+	}
+}
+
+func TestSectionLight(t *testing.T) {
+	var blockLight, skyLight = make([]byte, 2048), make([]byte, 2048)
+	blockLight[0] = 0x3e  // x=0 is 14 and x=1 is 3, at y=0 z=0
+	skyLight[2047] = 0xf0 // x=15 y=15 z=15 is 15
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", -4),
+				tagStruct("block_states",
+					tagList("palette", TagStruct, tagStruct("", tagString("Name", "minecraft:stone")))),
+				tagByteArray("BlockLight", blockLight),
+				tagByteArray("SkyLight", skyLight))))))
+	checkError(t, err, nil)
+	for _, expected := range []struct {
+		x, y, z    int
+		block, sky int
+	}{{0, -64, 0, 14, 0}, {1, -64, 0, 3, 0}, {15, -49, 15, 0, 15}, {2, -65, 2, 0, 0}, {2, 256, 2, 0, 15}} {
+		if block, sky := chunk.Light(expected.x, expected.y, expected.z); block != expected.block || sky != expected.sky {
+			t.Errorf("light at %d,%d,%d is %d,%d not %d,%d", expected.x, expected.y, expected.z, block, sky, expected.block, expected.sky)
+		}
+	}
+}
+
+func TestBlockEntities(t *testing.T) {
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagInt("xPos", -1),
+		tagList("block_entities", TagStruct,
+			tagStruct("", tagString("id", "minecraft:sign"), tagInt("x", -3), tagInt("y", -20), tagInt("z", 40),
+				tagString("Text1", "{\"text\":\"Hello\"}")),
+			tagStruct("", tagString("id", "minecraft:chest"), tagInt("x", -16), tagInt("y", 70), tagInt("z", 32))))))
+	checkError(t, err, nil)
+	if len(chunk.BlockEntities) != 2 {
+		t.Fatalf("%d block entities not 2", len(chunk.BlockEntities))
+	}
+	if e := chunk.BlockEntity(-3, -20, 40); e == nil || e.Id != "minecraft:sign" || e.Data["Text1"] != "{\"text\":\"Hello\"}" {
+		t.Errorf("sign %v", e)
+	}
+	if e := chunk.BlockEntity(0, 70, 0); e == nil || e.Id != "minecraft:chest" {
+		t.Errorf("chest %v", e)
+	}
+	if e := chunk.BlockEntity(0, 71, 0); e != nil {
+		t.Errorf("%s above the chest", e.Id)
+	}
+
+	chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagStruct("Level",
+			tagList("TileEntities", TagStruct,
+				tagStruct("", tagString("id", "Furnace"), tagInt("x", 5), tagInt("y", 12), tagInt("z", 6)))))))
+	checkError(t, err, nil)
+	if e := chunk.BlockEntity(5, 12, 6); e == nil || e.Id != "Furnace" {
+		t.Errorf("furnace %v", e)
+	}
+}
+
+func TestBanners(t *testing.T) {
+	// A red banner at 1,65,2 and a blue one on a wall at 3,66,0
+	var states = make([]int64, 256)
+	states[18] = 1 << 4
+	states[32] = 2 << 12
+	var chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", 4),
+				tagStruct("block_states",
+					tagList("palette", TagStruct,
+						tagStruct("", tagString("Name", "minecraft:air")),
+						tagStruct("", tagString("Name", "minecraft:red_banner"), tagStruct("Properties", tagString("rotation", "4"))),
+						tagStruct("", tagString("Name", "minecraft:blue_wall_banner"), tagStruct("Properties", tagString("facing", "west")))),
+					tagLongArray("data", states)))),
+		tagList("block_entities", TagStruct,
+			tagStruct("", tagString("id", "minecraft:banner"), tagInt("x", 1), tagInt("y", 65), tagInt("z", 2),
+				tagList("Patterns", TagStruct, tagStruct("", tagString("Pattern", "bri"), tagInt("Color", 11)))),
+			tagStruct("", tagString("id", "minecraft:banner"), tagInt("x", 3), tagInt("y", 66), tagInt("z", 0),
+				tagList("patterns", TagStruct, tagStruct("", tagString("pattern", "minecraft:creeper"), tagString("color", "lime"))))))))
+	checkError(t, err, nil)
+	if b := chunk.Blocks[coordsToIndex(1, 2, 65, 16, len(chunk.Blocks)/256)]; b != withData(176, 4) {
+		t.Errorf("block %#x at 1,65,2 isn't a banner", b)
+	}
+	if e := chunk.BlockEntity(1, 65, 2); e == nil || !reflect.DeepEqual(e.Banner, &Banner{14, []BannerPattern{{"bricks", 11}}}) {
+		t.Errorf("red banner %+v", e)
+	}
+	if e := chunk.BlockEntity(3, 66, 0); e == nil || !reflect.DeepEqual(e.Banner, &Banner{11, []BannerPattern{{"creeper", 5}}}) {
+		t.Errorf("blue banner %+v", e)
+	}
+
+	// Before 1.13, colors were the data of dyes, which count from black
+	chunk, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagStruct("Level",
+			tagList("TileEntities", TagStruct,
+				tagStruct("", tagString("id", "Banner"), tagInt("x", 5), tagInt("y", 12), tagInt("z", 6), tagInt("Base", 1),
+					tagList("Patterns", TagStruct, tagStruct("", tagString("Pattern", "cre"), tagInt("Color", 0)))))))))
+	checkError(t, err, nil)
+	if e := chunk.BlockEntity(5, 12, 6); e == nil || !reflect.DeepEqual(e.Banner, &Banner{14, []BannerPattern{{"creeper", 15}}}) {
+		t.Errorf("legacy banner %+v", e)
+	}
+}
+
+func TestReadChunkLenient(t *testing.T) {
+	var data = tagStruct("",
+		tagInt("xPos", 3),
+		tagString("Status", "minecraft:full"),
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", 0),
+				tagStruct("block_states",
+					tagList("palette", TagStruct, tagStruct("", tagString("Name", "minecraft:stone"))))),
+			tagStruct("", tagByte("Y", 1),
+				tagStruct("block_states",
+					tagList("palette", TagStruct, tagStruct("", tagString("Name", "minecraft:dirt")))))))
+
+	// Cut off partway through the second section
+	var truncated = data[:len(data)-20]
+	if _, err := ReadChunkNbt(bytes.NewReader(truncated)); err == nil {
+		t.Fatalf("no error reading a truncated chunk")
+	}
+	var chunk, err = ReadChunkNbtLenient(bytes.NewReader(truncated))
+	if err == nil || chunk == nil {
+		t.Fatalf("chunk %v with error %v", chunk, err)
+	}
+	if chunk.XPos != 3 || chunk.Status != "minecraft:full" {
+		t.Errorf("chunk at x %d with status %q", chunk.XPos, chunk.Status)
+	}
+	var stone = BlockState("minecraft:stone", nil)
+	if len(chunk.Blocks) != 256*256 || chunk.Blocks[coordsToIndex(2, 3, 4, 16, 256)] != stone || chunk.Blocks[coordsToIndex(2, 3, 20, 16, 256)] != 0 {
+		t.Errorf("salvaged %d blocks", len(chunk.Blocks))
+	}
+}
+
+// packBlockStates builds a section with a palette of paletteSize entries
+// where position i holds palette entry i%paletteSize.
+func packBlockStates(paletteSize int, straddle bool) *sectionData {
+	section := &sectionData{palette: make([]Block, paletteSize)}
+	for i := range section.palette {
+		section.palette[i] = Block(i + 1)
+	}
+
+	bits := 4
+	for 1<<uint(bits) < paletteSize {
+		bits++
+	}
+
+	var longs []int64
+	if straddle {
+		longs = make([]int64, 4096*bits/64)
+		for i := 0; i < 4096; i++ {
+			value := uint64(i % paletteSize)
+			bit := i * bits
+			longs[bit/64] |= int64(value << uint(bit%64))
+			if bit%64+bits > 64 {
+				longs[bit/64+1] |= int64(value >> uint(64-bit%64))
+			}
+		}
+	} else {
+		perLong := 64 / bits
+		longs = make([]int64, (4096+perLong-1)/perLong)
+		for i := 0; i < 4096; i++ {
+			value := uint64(i % paletteSize)
+			longs[i/perLong] |= int64(value << uint((i%perLong)*bits))
+		}
+	}
+
+	section.blockStates = make(LongView, 8*len(longs))
+	for i, x := range longs {
+		binary.BigEndian.PutUint64(section.blockStates[8*i:], uint64(x))
+	}
+	return section
+}
+
+func checkPaletteBlocks(t *testing.T, section *sectionData) {
+	blocks := section.paletteBlocks()
+	if len(blocks) != 4096 {
+		t.Fatalf("%d blocks not 4096", len(blocks))
+	}
+	for i, block := range blocks {
+		expected := section.palette[i%len(section.palette)]
+		if block != expected {
+			t.Fatalf("block %d is %d not %d", i, block, expected)
+		}
+	}
+}
+
+func TestIsBlockStateName(t *testing.T) {
+	for name, expected := range map[string]bool{"minecraft:oak_stairs": true, "stone": true, "mangrove_stairs": false, "mod:widget": false} {
+		if IsBlockStateName(name) != expected {
+			t.Errorf("IsBlockStateName(%q) isn't %v", name, expected)
+		}
+	}
+}
+
+func checkBlockState(t *testing.T, name string, properties map[string]interface{}, expected Block) {
+	if block := BlockState(name, properties); block != expected {
+		t.Errorf("%s %v is %d:%d not %d:%d", name, properties, block&0xff, block>>8, expected&0xff, expected>>8)
+	}
+}
+
+func tagHeader(typeId TypeId, name string) []byte {
+	var b = []byte{byte(typeId), byte(len(name) >> 8), byte(len(name))}
+	return append(b, name...)
+}
+
+func tagByte(name string, value int8) []byte {
+	return append(tagHeader(TagInt8, name), byte(value))
+}
+
+func tagInt(name string, value int32) []byte {
+	var buf bytes.Buffer
+	buf.Write(tagHeader(TagInt32, name))
+	binary.Write(&buf, binary.BigEndian, value)
+	return buf.Bytes()
+}
+
+func tagLong(name string, value int64) []byte {
+	var buf bytes.Buffer
+	buf.Write(tagHeader(TagInt64, name))
+	binary.Write(&buf, binary.BigEndian, value)
+	return buf.Bytes()
+}
+
+func tagString(name, value string) []byte {
+	var b = append(tagHeader(TagString, name), byte(len(value)>>8), byte(len(value)))
+	return append(b, value...)
+}
+
+func tagByteArray(name string, values []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(tagHeader(TagByteArray, name))
+	binary.Write(&buf, binary.BigEndian, int32(len(values)))
+	buf.Write(values)
+	return buf.Bytes()
+}
+
+func tagLongArray(name string, values []int64) []byte {
+	var buf bytes.Buffer
+	buf.Write(tagHeader(TagLongArray, name))
+	binary.Write(&buf, binary.BigEndian, int32(len(values)))
+	binary.Write(&buf, binary.BigEndian, values)
+	return buf.Bytes()
+}
+
+// tagList takes each item already encoded, without names; struct items
+// from tagStruct("") have their header stripped.
+func tagList(name string, itemTypeId TypeId, items ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(tagHeader(TagList, name))
+	buf.WriteByte(byte(itemTypeId))
+	binary.Write(&buf, binary.BigEndian, int32(len(items)))
+	for _, item := range items {
+		if itemTypeId == TagStruct {
+			item = item[3:]
+		}
+		buf.Write(item)
+	}
+	return buf.Bytes()
+}
+
+func tagStruct(name string, tags ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(tagHeader(TagStruct, name))
+	for _, tag := range tags {
+		buf.Write(tag)
+	}
+	buf.WriteByte(byte(TagStructEnd))
+	return buf.Bytes()
+}
@@ -0,0 +1,31 @@
+package nbt
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+)
+
+// NewDecompressor reads a loose NBT file, such as a level.dat, playerdata
+// file or chunk file, whether it's gzipped, zlib compressed or not
+// compressed at all, going by its first two bytes. Most are gzipped, but
+// tools and servers don't all agree.
+func NewDecompressor(reader io.Reader) (io.ReadCloser, error) {
+	var r = bufio.NewReader(reader)
+	var magic, err = r.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(r)
+	case len(magic) == 2 && magic[0]&0x0f == 8 && (int(magic[0])<<8|int(magic[1]))%31 == 0:
+		// A deflate zlib header, whose check bits make it a multiple of
+		// 31. Uncompressed NBT starts with a struct's type of 10 instead.
+		return zlib.NewReader(r)
+	}
+	return ioutil.NopCloser(r), nil
+}
@@ -0,0 +1,80 @@
+package nbt
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Difference is a tag that differs between two trees. Old is nil for a tag
+// only in the new tree and New is nil for a tag only in the old one.
+type Difference struct {
+	Path     string
+	Old, New interface{}
+}
+
+func (d Difference) String() string {
+	switch {
+	case d.Old == nil:
+		return fmt.Sprintf("+ %s: %s", d.Path, FormatSNBT(d.New))
+	case d.New == nil:
+		return fmt.Sprintf("- %s: %s", d.Path, FormatSNBT(d.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", d.Path, FormatSNBT(d.Old), FormatSNBT(d.New))
+}
+
+// Diff compares two parsed trees, such as the same chunk from two backups,
+// and gives every tag that was added, removed or changed, with paths that
+// ParsePath reads. Structs and lists of structs or other lists are compared
+// tag by tag and item by item, while lists of numbers and arrays, such as
+// a section's 4096 blocks, are compared whole.
+func Diff(old, new interface{}) []Difference {
+	return diff(old, new, "", nil)
+}
+
+func diff(old, new interface{}, path string, differences []Difference) []Difference {
+	oldStruct, oldIsStruct := old.(map[string]interface{})
+	newStruct, newIsStruct := new.(map[string]interface{})
+	if oldIsStruct && newIsStruct {
+		var names = make([]string, 0, len(oldStruct)+len(newStruct))
+		for name := range oldStruct {
+			names = append(names, name)
+		}
+		for name := range newStruct {
+			if _, ok := oldStruct[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			var namePath = Path{{name: name}}.String()
+			if path != "" {
+				namePath = path + "." + namePath
+			}
+			differences = diff(oldStruct[name], newStruct[name], namePath, differences)
+		}
+		return differences
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList {
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldList) {
+				oldItem = oldList[i]
+			}
+			if i < len(newList) {
+				newItem = newList[i]
+			}
+			differences = diff(oldItem, newItem, fmt.Sprintf("%s[%d]", path, i), differences)
+		}
+		return differences
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		differences = append(differences, Difference{path, old, new})
+	}
+	return differences
+}
@@ -0,0 +1,33 @@
+package nbt
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	var old, _ = ParseSNBT(`{DataVersion: 3465, Status: "minecraft:full", sections: [
+		{Y: 0b, data: [L; 1L, 2L]}, {Y: 1b}], "a.b": 1}`)
+	var new, _ = ParseSNBT(`{DataVersion: 3578, sections: [
+		{Y: 0b, data: [L; 1L, 3L]}, {Y: 1b}, {Y: 2b}], "a.b": 1, LastUpdate: 5L}`)
+
+	var differences = Diff(old, new)
+	var expected = []string{
+		"~ DataVersion: 3465 -> 3578",
+		"+ LastUpdate: 5",
+		"- Status: \"minecraft:full\"",
+		"~ sections[0].data: [L;1L,2L] -> [L;1L,3L]",
+		"+ sections[2]: {Y:2}",
+	}
+	if len(differences) != len(expected) {
+		t.Fatalf("differences %v", differences)
+	}
+	for i, d := range differences {
+		if d.String() != expected[i] {
+			t.Errorf("difference %q not %q", d, expected[i])
+		}
+	}
+
+	if differences := Diff(old, old); len(differences) != 0 {
+		t.Errorf("%d differences from itself", len(differences))
+	}
+}
@@ -0,0 +1,60 @@
+package nbt
+
+import (
+	"io"
+)
+
+type Entity struct {
+	Id      string
+	X, Y, Z float64
+	Data    map[string]interface{} // Every tag of the entity
+}
+
+// ReadEntitiesNbt reads the entities of a chunk, from either a 1.17+
+// entities region chunk, which has them at the top level, or an older
+// chunk, which has them in its Level struct. The blocks and everything else
+// of older chunks are skipped over rather than parsed.
+func ReadEntitiesNbt(reader io.Reader) ([]Entity, error) {
+	var stream = NewStream(NewReader(reader))
+	var list []interface{}
+	for {
+		token, err := stream.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if token.Kind != StartToken || stream.Depth() == 1 {
+			continue
+		}
+
+		if token.Name == "Entities" && token.Type == TagList {
+			value, err := stream.Value()
+			if err != nil {
+				return nil, err
+			}
+			list, _ = value.([]interface{})
+		} else if token.Name != "Level" || stream.Depth() != 2 {
+			if err := stream.Skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	entities := make([]Entity, 0, len(list))
+	for _, item := range list {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		entity := Entity{Data: data}
+		entity.Id, _ = data["id"].(string)
+		if pos, ok := data["Pos"].([]float64); ok && len(pos) == 3 {
+			entity.X, entity.Y, entity.Z = pos[0], pos[1], pos[2]
+		}
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
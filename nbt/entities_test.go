@@ -0,0 +1,56 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestReadEntities(t *testing.T) {
+	var pos = tagHeader(TagList, "Pos")
+	pos = append(pos, byte(TagFloat64), 0, 0, 0, 3)
+	for _, f := range []float64{1.5, -60, 8.25} {
+		pos = binary.BigEndian.AppendUint64(pos, math.Float64bits(f))
+	}
+
+	entities, err := ReadEntitiesNbt(bytes.NewReader(tagStruct("",
+		tagInt("DataVersion", 2975),
+		tagList("Entities", TagStruct,
+			tagStruct("", tagString("id", "minecraft:item_frame"), pos,
+				tagList("Tags", TagString, []byte{0, 1, 'a'}, []byte{0, 2, 'b', 'c'})),
+			tagStruct("", tagString("id", "minecraft:cow"))),
+	)))
+
+	checkError(t, err, nil)
+	if len(entities) != 2 {
+		t.Fatalf("%d entities not 2", len(entities))
+	}
+	if e := entities[0]; e.Id != "minecraft:item_frame" || e.X != 1.5 || e.Y != -60 || e.Z != 8.25 {
+		t.Errorf("entity %s at %v,%v,%v", e.Id, e.X, e.Y, e.Z)
+	}
+	if tags, _ := entities[0].Data["Tags"].([]interface{}); len(tags) != 2 || tags[1] != "bc" {
+		t.Errorf("tags %v not [a bc]", entities[0].Data["Tags"])
+	}
+	if entities[1].Id != "minecraft:cow" {
+		t.Errorf("entity %s not a cow", entities[1].Id)
+	}
+}
+
+func TestReadLegacyEntities(t *testing.T) {
+	entities, err := ReadEntitiesNbt(bytes.NewReader(tagStruct("",
+		tagStruct("Level",
+			tagList("Sections", TagStruct,
+				tagStruct("", tagByte("Y", 0), tagLongArray("BlockStates", make([]int64, 256)))),
+			tagList("Entities", TagStruct,
+				tagStruct("", tagString("id", "minecraft:pig"),
+					tagList("Passengers", TagStruct, tagStruct("", tagString("id", "minecraft:zombie")))))))))
+
+	checkError(t, err, nil)
+	if len(entities) != 1 || entities[0].Id != "minecraft:pig" {
+		t.Fatalf("entities %v not a pig", entities)
+	}
+	if passengers, _ := entities[0].Data["Passengers"].([]interface{}); len(passengers) != 1 {
+		t.Errorf("passengers %v", entities[0].Data["Passengers"])
+	}
+}
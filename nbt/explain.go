@@ -31,6 +31,9 @@ func (e *explainer) parseStruct(nr *Reader, listStruct bool) error {
 	structDepth := 0
 	if listStruct {
 		structDepth = 1
+		if err := nr.enter(); err != nil {
+			return err
+		}
 	}
 
 	for {
@@ -48,14 +51,22 @@ func (e *explainer) parseStruct(nr *Reader, listStruct bool) error {
 		case TagStruct:
 			e.RecordNoValue()
 			structDepth++
+			if err := nr.enter(); err != nil {
+				return err
+			}
 		case TagStructEnd:
 			e.RecordNoValue()
+			nr.leave()
 			structDepth--
 			if structDepth == 0 {
 				return nil
 			}
 		case TagByteArray:
 			e.RecordValue(nr.ReadBytes())
+		case TagIntArray:
+			e.RecordValue(nr.ReadInts())
+		case TagLongArray:
+			e.RecordValue(nr.ReadLongs())
 		case TagInt8:
 			e.RecordValue(nr.ReadInt8())
 		case TagInt16:
@@ -121,8 +132,18 @@ func (e *explainer) parseStruct(nr *Reader, listStruct bool) error {
 					e.stack.pop()
 				}
 			default:
-				return errors.New(fmt.Sprintf("reading lists of typeId %d not supported. length:%d", itemTypeId, length))
+				list := make([]interface{}, length)
+				for i := 0; i < length; i++ {
+					x, err := nr.ReadValue(itemTypeId)
+					list[i] = x
+					if err != nil {
+						return err
+					}
+				}
+				e.RecordValue(list, nil)
 			}
+		default:
+			return errors.New(fmt.Sprintf("reading typeId %d not supported", typeId))
 		}
 
 		if typeId != TagStruct {
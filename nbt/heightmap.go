@@ -0,0 +1,71 @@
+package nbt
+
+// Names of the height maps chunks keep, from 1.13. Chunks from before then
+// have the one LegacyHeightMap, of the blocks light stops at.
+const (
+	MotionBlocking  = "MOTION_BLOCKING"
+	WorldSurface    = "WORLD_SURFACE"
+	OceanFloor      = "OCEAN_FLOOR"
+	LegacyHeightMap = "HeightMap"
+)
+
+// Height is the world height just above the top block of column x,z in a
+// height map, or the bottom of the world when the column is empty. It
+// isn't ok when the chunk doesn't have the height map.
+func (c *Chunk) Height(heightMap string, x, z int) (y int, ok bool) {
+	var heights = c.HeightMaps[heightMap]
+	if len(heights) != 16*16 {
+		return 0, false
+	}
+	return heights[x&15+(z&15)*16], true
+}
+
+// unpackHeightMap reads the 256 heights of a 1.13+ height map, which are
+// packed into longs with just enough bits for the world height. Like block
+// states, the heights straddle longs up until 1.16 and after that leave
+// the unused high bits of each long as padding.
+func unpackHeightMap(longs []int64, minY int) []int {
+	var bits, straddle = heightMapBits(len(longs))
+	if bits == 0 {
+		return nil
+	}
+
+	var (
+		heights = make([]int, 16*16)
+		mask    = uint64(1)<<uint(bits) - 1
+		perLong = 64 / bits
+	)
+	for i := range heights {
+		var height uint64
+		if straddle {
+			var (
+				bit    = i * bits
+				word   = bit / 64
+				offset = uint(bit % 64)
+			)
+			height = uint64(longs[word]) >> offset
+			if int(offset)+bits > 64 {
+				height |= uint64(longs[word+1]) << (64 - offset)
+			}
+		} else {
+			height = uint64(longs[i/perLong]) >> uint((i%perLong)*bits)
+		}
+		heights[i] = int(height&mask) + minY
+	}
+	return heights
+}
+
+// heightMapBits works out the bits per height from how many longs hold
+// the 256 of them, as the world height that decides it isn't saved.
+func heightMapBits(longs int) (bits int, straddle bool) {
+	if longs%4 == 0 && longs/4 > 0 && longs/4 <= 32 {
+		return longs / 4, true
+	}
+	for bits = 1; bits <= 32; bits++ {
+		var perLong = 64 / bits
+		if (256+perLong-1)/perLong == longs {
+			return bits, false
+		}
+	}
+	return 0, false
+}
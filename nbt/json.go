@@ -0,0 +1,112 @@
+package nbt
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+)
+
+var typeNames = []string{"end", "byte", "short", "int", "long", "float", "double", "byte_array", "string", "list", "compound", "int_array", "long_array"}
+
+func (t TypeId) String() string {
+	if int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return "unknown"
+}
+
+// DumpJSON prints NBT as indented JSON for reading by eye. Every tag is an
+// object of its type and value, as ints, longs and bytes all look alike in
+// JSON. Lists also give the type of their items, which are plain values.
+//
+// For example a struct holding the byte Y and the list Pos becomes
+//
+//	{"type": "compound", "value": {
+//	  "Y": {"type": "byte", "value": -4},
+//	  "Pos": {"type": "list", "items": "double", "value": [1.5, -60, 8.25]}}}
+func DumpJSON(r io.Reader, w io.Writer) error {
+	var stream = NewStream(NewReader(r))
+	var token, err = stream.Next()
+	if err != nil {
+		return err
+	}
+	root, err := jsonTag(stream, token)
+	if err != nil {
+		return err
+	}
+
+	var encoder = json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(root)
+}
+
+func jsonTag(stream *Stream, token Token) (map[string]interface{}, error) {
+	var tag = map[string]interface{}{"type": token.Type.String()}
+
+	switch {
+	case token.Kind == ValueToken:
+		var value, err = stream.Value()
+		if err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case []byte:
+			// Rather than base64, and signed like the other numbers
+			var numbers = make([]int, len(v))
+			for i, b := range v {
+				numbers[i] = int(int8(b))
+			}
+			value = numbers
+		case float32:
+			value = jsonFloat(float64(v))
+		case float64:
+			value = jsonFloat(v)
+		}
+		tag["value"] = value
+	case token.Type == TagStruct:
+		var fields = make(map[string]interface{})
+		for {
+			var field, err = stream.Next()
+			if err != nil {
+				return nil, err
+			}
+			if field.Kind == EndToken {
+				break
+			}
+			if fields[field.Name], err = jsonTag(stream, field); err != nil {
+				return nil, err
+			}
+		}
+		tag["value"] = fields
+	case token.Type == TagList:
+		var items = make([]interface{}, 0, token.Length)
+		for {
+			var item, err = stream.Next()
+			if err != nil {
+				return nil, err
+			}
+			if item.Kind == EndToken {
+				break
+			}
+			itemTag, err := jsonTag(stream, item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, itemTag["value"])
+		}
+		tag["items"] = token.ItemType.String()
+		tag["value"] = items
+	}
+
+	return tag, nil
+}
+
+// jsonFloat gives NaN and the infinities, which JSON has no numbers for, as
+// strings.
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
@@ -0,0 +1,31 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDumpJSON(t *testing.T) {
+	var buf bytes.Buffer
+	checkError(t, DumpJSON(bytes.NewReader(tagStruct("",
+		tagByte("Y", -4),
+		tagLongArray("data", []int64{1 << 40}),
+		tagByteArray("SkyLight", []byte{0xff, 1}),
+		tagList("palette", TagStruct, tagStruct("", tagString("Name", "minecraft:stone"))))), &buf), nil)
+
+	var dumped interface{}
+	checkError(t, json.Unmarshal(buf.Bytes(), &dumped), nil)
+	var expected = map[string]interface{}{"type": "compound", "value": map[string]interface{}{
+		"Y":        map[string]interface{}{"type": "byte", "value": -4.0},
+		"data":     map[string]interface{}{"type": "long_array", "value": []interface{}{float64(1 << 40)}},
+		"SkyLight": map[string]interface{}{"type": "byte_array", "value": []interface{}{-1.0, 1.0}},
+		"palette": map[string]interface{}{"type": "list", "items": "compound", "value": []interface{}{
+			map[string]interface{}{"Name": map[string]interface{}{"type": "string", "value": "minecraft:stone"}},
+		}},
+	}}
+	if !reflect.DeepEqual(dumped, expected) {
+		t.Errorf("dumped %s", buf.String())
+	}
+}
@@ -1,7 +1,6 @@
 package nbt
 
 import (
-	"compress/gzip"
 	"errors"
 	"io"
 )
@@ -13,14 +12,27 @@ var (
 
 type Level struct {
 	SpawnX, SpawnY, SpawnZ int
+
+	Name        string
+	DataVersion int   // Version of the game that last saved, from 1.9
+	Version     int   // Storage format, 19132 for McRegion and 19133 for Anvil
+	Seed        int64 // From WorldGenSettings from 1.16
+	GameRules   map[string]string
+	Player      *Player // The player of a single player world
 }
 
+// Storage format versions in level.dat
+const (
+	McRegionVersion = 19132
+	AnvilVersion    = 19133
+)
+
 func ReadLevelDat(reader io.Reader) (*Level, error) {
-	r, err := gzip.NewReader(reader)
-	defer r.Close()
+	r, err := NewDecompressor(reader)
 	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
 
 	return ReadLevelNbt(r)
 }
@@ -41,6 +53,38 @@ func ReadLevelNbt(reader io.Reader) (*Level, error) {
 		return nil, DataStructNotFound
 	}
 
+	return readLevelData(data)
+}
+
+// ReadBedrockLevelDat reads the level.dat of Bedrock (and Pocket) Edition
+// worlds: an 8 byte header of version and length, then uncompressed little
+// endian NBT with the spawn at the top level.
+func ReadBedrockLevelDat(reader io.Reader) (*Level, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	nr := NewLittleEndianReader(reader)
+	typeId, _, err := nr.ReadTag()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := nr.ReadValue(typeId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, SpawnIntNotFound
+	}
+
+	return readLevelData(data)
+}
+
+func readLevelData(data map[string]interface{}) (*Level, error) {
 	xval, xok := data["SpawnX"]
 	yval, yok := data["SpawnY"]
 	zval, zok := data["SpawnZ"]
@@ -58,5 +102,32 @@ func ReadLevelNbt(reader io.Reader) (*Level, error) {
 		return nil, SpawnIntNotFound
 	}
 
+	level.Name, _ = data["LevelName"].(string)
+	level.DataVersion, _ = data["DataVersion"].(int)
+	level.Version, _ = data["version"].(int)
+
+	if seed, ok := data["RandomSeed"].(int); ok {
+		level.Seed = int64(seed)
+	} else if settings, ok := data["WorldGenSettings"].(map[string]interface{}); ok {
+		if seed, ok := settings["seed"].(int); ok {
+			level.Seed = int64(seed)
+		}
+	}
+
+	// Java keeps game rules as strings. Bedrock's are loose at the top
+	// level, as bytes and ints, and aren't read.
+	if rules, ok := data["GameRules"].(map[string]interface{}); ok {
+		level.GameRules = make(map[string]string)
+		for name, value := range rules {
+			if s, ok := value.(string); ok {
+				level.GameRules[name] = s
+			}
+		}
+	}
+
+	if player, ok := data["Player"].(map[string]interface{}); ok {
+		level.Player, _ = readPlayer(player)
+	}
+
 	return level, nil
 }
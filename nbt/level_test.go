@@ -3,6 +3,8 @@ package nbt
 import (
 	"bytes"
 	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
 	"io"
 	"testing"
 )
@@ -29,6 +31,50 @@ func TestReadSpawnXYZ(t *testing.T) {
 	}
 }
 
+func TestReadLevelMetadata(t *testing.T) {
+	level, err := ReadLevelNbt(bytes.NewReader(tagStruct("", tagStruct("Data",
+		tagInt("SpawnX", 1), tagInt("SpawnY", 64), tagInt("SpawnZ", -2),
+		tagString("LevelName", "New World"),
+		tagInt("DataVersion", 3465),
+		tagInt("version", AnvilVersion),
+		tagStruct("WorldGenSettings", tagLong("seed", -42)),
+		tagStruct("GameRules", tagString("doDaylightCycle", "false"))))))
+
+	checkError(t, err, nil)
+	if level.Name != "New World" || level.DataVersion != 3465 || level.Version != AnvilVersion {
+		t.Errorf("level %q, data version %d, version %d", level.Name, level.DataVersion, level.Version)
+	}
+	if level.Seed != -42 {
+		t.Errorf("seed %d not -42", level.Seed)
+	}
+	if level.GameRules["doDaylightCycle"] != "false" {
+		t.Errorf("game rules %v", level.GameRules)
+	}
+}
+
+func TestReadLevelPlayer(t *testing.T) {
+	var pos [][]byte
+	for _, f := range []float64{-20.5, 70, 33.25} {
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.BigEndian, f)
+		pos = append(pos, buf.Bytes())
+	}
+
+	level, err := ReadLevelNbt(bytes.NewReader(tagStruct("", tagStruct("Data",
+		tagInt("SpawnX", 1), tagInt("SpawnY", 64), tagInt("SpawnZ", -2),
+		tagStruct("Player",
+			tagList("Pos", TagFloat64, pos...),
+			tagString("Dimension", "minecraft:the_nether"))))))
+
+	checkError(t, err, nil)
+	if level.Player == nil {
+		t.Fatal("no player")
+	}
+	if p := level.Player; p.X != -20.5 || p.Y != 70 || p.Z != 33.25 || p.Dimension != "minecraft:the_nether" {
+		t.Errorf("player at %v,%v,%v in %q", p.X, p.Y, p.Z, p.Dimension)
+	}
+}
+
 func TestLevelParseError(t *testing.T) {
 	checkLevelReadError(t, io.EOF, 0xff)
 }
@@ -84,3 +130,23 @@ func checkLevelNil(t *testing.T, level *Level) {
 		t.Errorf("Level %v is not nil", level)
 	}
 }
+
+func TestReadLevelDatCompression(t *testing.T) {
+	var data = tagStruct("", tagStruct("Data", tagInt("SpawnX", 1), tagInt("SpawnY", 64), tagInt("SpawnZ", -2)))
+
+	var gzipped, zlibbed bytes.Buffer
+	var gz = gzip.NewWriter(&gzipped)
+	gz.Write(data)
+	gz.Close()
+	var zw = zlib.NewWriter(&zlibbed)
+	zw.Write(data)
+	zw.Close()
+
+	for name, file := range map[string][]byte{"gzip": gzipped.Bytes(), "zlib": zlibbed.Bytes(), "raw": data} {
+		level, err := ReadLevelDat(bytes.NewReader(file))
+		checkError(t, err, nil)
+		if level == nil || level.SpawnY != 64 {
+			t.Errorf("%s level %v", name, level)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package nbt
+
+// Light is the block light and sky light at x,y,z within the chunk, with
+// y a world height. Above the chunk there is full sky light, and below it
+// or where the chunk has no light saved it is dark.
+func (c *Chunk) Light(x, y, z int) (block, sky int) {
+	var height = len(c.Blocks) / (16 * 16)
+	if y-c.MinY >= height {
+		return 0, 15
+	}
+	if y < c.MinY {
+		return 0, 0
+	}
+
+	var i = coordsToIndex(x&15, z&15, y-c.MinY, 16, height)
+	if i < len(c.BlockLight) {
+		block = int(c.BlockLight[i])
+	}
+	if i < len(c.SkyLight) {
+		sky = int(c.SkyLight[i])
+	}
+	return block, sky
+}
+
+// unpackNibbles spreads the four bit light levels of a chunk from before
+// Anvil, which are in the same XZY order as its blocks, to a byte each.
+func unpackNibbles(nibbles []byte, n int) []byte {
+	if len(nibbles)*2 != n {
+		return nil
+	}
+	var levels = make([]byte, n)
+	for i := range levels {
+		levels[i] = nibble(nibbles, i)
+	}
+	return levels
+}
+
+// spreadSectionLight copies the light of a section, in YZX order, into the
+// levels of the whole chunk, making them on the first section with light.
+func spreadSectionLight(levels, nibbles []byte, sectionBase, height int) []byte {
+	if len(nibbles) != 16*16*16/2 {
+		return levels
+	}
+	if levels == nil {
+		levels = make([]byte, 16*16*height)
+	}
+	for i := 0; i < 16*16*16; i++ {
+		x, z, y := indexToCoords(i, 16, 16)
+		levels[coordsToIndex(x, z, y+sectionBase, 16, height)] = nibble(nibbles, i)
+	}
+	return levels
+}
+
+// nibble is the ith four bits of an array, the low four bits first.
+func nibble(nibbles []byte, i int) byte {
+	if i&1 == 1 {
+		return nibbles[i/2] >> 4
+	}
+	return nibbles[i/2] & 0xf
+}
@@ -0,0 +1,133 @@
+package nbt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Limits stop a corrupt or crafted file from making a Reader nest without
+// end or allocate more memory than it could possibly need. Zero means no
+// limit.
+type Limits struct {
+	MaxDepth      int   // Structs and lists inside each other
+	MaxListLength int   // Items in a list or array
+	MaxSize       int64 // Bytes read in all, after decompression
+}
+
+// DefaultLimits are the limits of every new Reader. The depth is the
+// game's own limit, and the rest are far beyond any chunk but leave room
+// for big schematics and levels from before Alpha.
+var DefaultLimits = Limits{
+	MaxDepth:      512,
+	MaxListLength: 1 << 26,
+	MaxSize:       1 << 30,
+}
+
+// LimitError is a Reader going over one of its Limits, at the path of the
+// tag it was reading, such as Level.Sections[3].BlockStates.
+type LimitError struct {
+	Path   string
+	Reason string
+}
+
+func (e *LimitError) Error() string {
+	if e.Path == "" {
+		return "NBT " + e.Reason
+	}
+	return fmt.Sprintf("NBT %s at %s", e.Reason, e.Path)
+}
+
+// SetLimits changes the limits from DefaultLimits.
+func (r *Reader) SetLimits(limits Limits) {
+	r.limits = limits
+}
+
+func (r *Reader) limitError(format string, args ...interface{}) error {
+	return &LimitError{r.Path(), fmt.Sprintf(format, args...)}
+}
+
+// Path is where the reader is, as the names of the tags and the indexes of
+// the list items it's inside.
+func (r *Reader) Path() string {
+	var b strings.Builder
+	for _, step := range r.path {
+		if step.index >= 0 {
+			fmt.Fprintf(&b, "[%d]", step.index)
+		} else if step.name != "" {
+			if b.Len() != 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(step.name)
+		}
+	}
+	return b.String()
+}
+
+type readerStep struct {
+	name  string
+	index int // Of a list item, or -1 for a named tag
+}
+
+// enter goes into a struct or list, whose tags or items then replace each
+// other at the end of the path.
+func (r *Reader) enter() error {
+	if r.limits.MaxDepth > 0 && len(r.path) > r.limits.MaxDepth {
+		return r.limitError("nested over %d deep", r.limits.MaxDepth)
+	}
+	r.path = append(r.path, readerStep{index: -1})
+	return nil
+}
+
+func (r *Reader) leave() {
+	if len(r.path) > 1 {
+		r.path = r.path[:len(r.path)-1]
+	}
+}
+
+func (r *Reader) setName(name string) {
+	r.path[len(r.path)-1] = readerStep{name, -1}
+}
+
+func (r *Reader) setIndex(i int) {
+	r.path[len(r.path)-1] = readerStep{index: i}
+}
+
+// checkLength checks the length of a list or array before anything is
+// made to hold it. Items that can't fit in what's left of the size limit
+// can't be there either.
+func (r *Reader) checkLength(length, itemSize int) error {
+	if length < 0 {
+		return ErrNegativeLength
+	}
+	if r.limits.MaxListLength > 0 && length > r.limits.MaxListLength {
+		return r.limitError("list of %d items is over the limit of %d", length, r.limits.MaxListLength)
+	}
+	// The buffer has read ahead of the tags
+	var left = r.limits.MaxSize - r.size.n + int64(r.r.Buffered())
+	if r.limits.MaxSize > 0 && int64(length)*int64(itemSize) > left {
+		return r.limitError("list of %d items is bigger than the %d bytes left of the size limit", length, left)
+	}
+	return nil
+}
+
+// sizeCounter counts the bytes read from under a Reader's buffer, stopping
+// at its size limit.
+type sizeCounter struct {
+	reader *Reader
+	src    io.Reader
+	n      int64
+}
+
+func (c *sizeCounter) Read(p []byte) (int, error) {
+	var limit = c.reader.limits.MaxSize
+	if limit > 0 && c.n >= limit {
+		return 0, c.reader.limitError("over the size limit of %d bytes", limit)
+	}
+	if limit > 0 && int64(len(p)) > limit-c.n {
+		p = p[:limit-c.n]
+	}
+	var n, err = c.src.Read(p)
+	c.n += int64(n)
+	return n, err
+}
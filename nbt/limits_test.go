@@ -0,0 +1,70 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestDepthLimit(t *testing.T) {
+	// Structs in structs, 600 deep
+	var nested = tagStruct("", tagInt("x", 1))
+	for i := 0; i < 600; i++ {
+		nested = tagStruct("", append(tagHeader(TagStruct, "a"), nested[3:]...))
+	}
+
+	var _, err = Parse(bytes.NewReader(nested))
+	if _, ok := err.(*LimitError); !ok || !strings.Contains(err.Error(), "nested over 512 deep") {
+		t.Errorf("error %v", err)
+	}
+	if _, err = ReadChunkNbt(bytes.NewReader(nested)); err == nil {
+		t.Errorf("no error reading a chunk nested 600 deep")
+	}
+
+	var r = NewReader(bytes.NewReader(nested))
+	r.SetLimits(Limits{MaxDepth: 1000})
+	typeId, _, _ := r.ReadTag()
+	if _, err = r.ReadValue(typeId); err != nil {
+		t.Errorf("error %v with a higher limit", err)
+	}
+}
+
+func TestListLengthLimit(t *testing.T) {
+	// A long array claiming a billion longs
+	var huge bytes.Buffer
+	huge.Write(tagHeader(TagLongArray, "BlockStates"))
+	binary.Write(&huge, binary.BigEndian, int32(1<<30))
+
+	var _, err = ReadChunkNbt(bytes.NewReader(tagStruct("",
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", 0)),
+			tagStruct("", huge.Bytes())))))
+	if err == nil || err.Error() != "NBT list of 1073741824 items is over the limit of 67108864 at sections[1].BlockStates" {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	var data = tagStruct("", tagByteArray("Blocks", make([]byte, 10000)))
+
+	var r = NewReader(bytes.NewReader(data))
+	r.SetLimits(Limits{MaxSize: 5000})
+	typeId, _, _ := r.ReadTag()
+	var _, err = r.ReadValue(typeId)
+	if err == nil || !strings.Contains(err.Error(), "bytes left of the size limit at Blocks") {
+		t.Errorf("error %v", err)
+	}
+
+	// Lists of small structs can only tell as they're read
+	var structs = make([][]byte, 1000)
+	for i := range structs {
+		structs[i] = tagStruct("", tagInt("x", int32(i)))
+	}
+	r = NewReader(bytes.NewReader(tagStruct("", tagList("Entities", TagStruct, structs...))))
+	r.SetLimits(Limits{MaxSize: 5000})
+	typeId, _, _ = r.ReadTag()
+	if _, err = r.ReadValue(typeId); err == nil || !strings.Contains(err.Error(), "over the size limit of 5000 bytes at Entities[") {
+		t.Errorf("error %v", err)
+	}
+}
@@ -23,10 +23,20 @@ const (
 	TagList      TypeId = 9  // { TAG_Byte tagId; TAG_Int length; A sequential list of Tags (not Named Tags), of type <typeId>. The length of this array is <length> Tags. } Notes: All tags share the same type.
 	TagStruct    TypeId = 10 // { A sequential list of Named Tags. This array keeps going until a TAG_End is found.; TAG_End end } Notes: If there's a nested TAG_Compound within this tag, that one will also have a TAG_End, so simply reading until the next TAG_End will not work. The names of the named tags have to be unique within each TAG_Compound The order of the tags is not guaranteed.
 	TagIntArray  TypeId = 11 // { TAG_Int length; An array of ints. The length of this array is <length> ints }
+	TagLongArray TypeId = 12 // { TAG_Int length; An array of longs. The length of this array is <length> longs }
+)
+
+var (
+	ErrNegativeLength = errors.New("Negative array length")
 )
 
 type Reader struct {
-	r *bufio.Reader
+	r            *bufio.Reader
+	littleEndian bool
+	limits       Limits
+	size         *sizeCounter
+	path         []readerStep
+	data         []byte // All of the NBT, when it's read from memory
 }
 
 func Parse(r io.Reader) (map[string]interface{}, error) {
@@ -35,6 +45,9 @@ func Parse(r io.Reader) (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if typeId != TagStruct {
+		return nil, errors.New(fmt.Sprintf("Root tag is %v, not a struct", typeId))
+	}
 
 	value, err := nr.ReadValue(typeId)
 	if err != nil {
@@ -44,7 +57,20 @@ func Parse(r io.Reader) (map[string]interface{}, error) {
 }
 
 func NewReader(r io.Reader) *Reader {
-	return &Reader{bufio.NewReader(r)}
+	return newReader(r, false)
+}
+
+// NewLittleEndianReader reads the little endian NBT used by Bedrock (and
+// Pocket) Edition.
+func NewLittleEndianReader(r io.Reader) *Reader {
+	return newReader(r, true)
+}
+
+func newReader(r io.Reader, littleEndian bool) *Reader {
+	var reader = &Reader{littleEndian: littleEndian, limits: DefaultLimits, path: []readerStep{{index: -1}}}
+	reader.size = &sizeCounter{reader: reader, src: r}
+	reader.r = bufio.NewReader(reader.size)
+	return reader
 }
 
 func (r *Reader) ReadTag() (typeId TypeId, name string, err error) {
@@ -57,6 +83,7 @@ func (r *Reader) ReadTag() (typeId TypeId, name string, err error) {
 	if err != nil {
 		return typeId, name, err
 	}
+	r.setName(name)
 
 	return typeId, name, nil
 }
@@ -69,11 +96,20 @@ func (r *Reader) ReadListHeader() (itemTypeId TypeId, length int, err error) {
 		length, err = r.ReadInt32()
 	}
 
+	// Empty lists are sometimes written with a negative length, and lists
+	// of nothing can't hold anything
+	if length < 0 || itemTypeId == TagStructEnd {
+		length = 0
+	}
+	if err == nil {
+		err = r.checkLength(length, 1)
+	}
+
 	return
 }
 
 func (r *Reader) ReadString() (string, error) {
-	var length, err1 = r.ReadInt16()
+	var length, err1 = r.readUintN(2)
 	if err1 != nil {
 		return "", err1
 	}
@@ -88,6 +124,9 @@ func (r *Reader) ReadBytes() ([]byte, error) {
 	if err1 != nil {
 		return nil, err1
 	}
+	if err := r.checkLength(length, 1); err != nil {
+		return nil, err
+	}
 
 	var bytes = make([]byte, length)
 	var _, err = io.ReadFull(r.r, bytes)
@@ -99,6 +138,9 @@ func (r *Reader) ReadInts() ([]int, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := r.checkLength(length, 4); err != nil {
+		return nil, err
+	}
 
 	ints := make([]int, length)
 	for i := 0; i < length; i++ {
@@ -110,6 +152,26 @@ func (r *Reader) ReadInts() ([]int, error) {
 	return ints, nil
 }
 
+func (r *Reader) ReadLongs() ([]int64, error) {
+	length, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkLength(length, 8); err != nil {
+		return nil, err
+	}
+
+	longs := make([]int64, length)
+	for i := 0; i < length; i++ {
+		x, err := r.readUintN(8)
+		if err != nil {
+			return nil, err
+		}
+		longs[i] = int64(x)
+	}
+	return longs, nil
+}
+
 func (r *Reader) ReadInt8() (int, error) {
 	return r.readIntN(1)
 }
@@ -142,17 +204,11 @@ func (r *Reader) readTypeId() (TypeId, error) {
 }
 
 func (r *Reader) readIntN(n int) (int, error) {
-	var a int = 0
+	var a, err = r.readUintN(n)
 
-	for i := 0; i < n; i++ {
-		var b, err = r.r.ReadByte()
-		if err != nil {
-			return a, err
-		}
-		a = a<<8 + int(b)
-	}
-
-	return a, nil
+	// Sign extend, as all the NBT integer types are signed
+	var shift = uint(64 - 8*n)
+	return int(int64(a<<shift) >> shift), err
 }
 
 func (r *Reader) readUintN(n int) (uint64, error) {
@@ -163,7 +219,11 @@ func (r *Reader) readUintN(n int) (uint64, error) {
 		if err != nil {
 			return a, err
 		}
-		a = a<<8 + uint64(b)
+		if r.littleEndian {
+			a += uint64(b) << uint(8*i)
+		} else {
+			a = a<<8 + uint64(b)
+		}
 	}
 
 	return a, nil
@@ -171,6 +231,10 @@ func (r *Reader) readUintN(n int) (uint64, error) {
 
 func (r *Reader) ReadStruct() (map[string]interface{}, error) {
 	s := make(map[string]interface{})
+	if err := r.enter(); err != nil {
+		return s, err
+	}
+	defer r.leave()
 	for {
 		typeId, name, err := r.ReadTag()
 		if err != nil {
@@ -196,6 +260,10 @@ func (r *Reader) ReadValue(typeId TypeId) (interface{}, error) {
 		return nil, nil
 	case TagByteArray:
 		return r.ReadBytes()
+	case TagIntArray:
+		return r.ReadInts()
+	case TagLongArray:
+		return r.ReadLongs()
 	case TagInt8:
 		return r.ReadInt8()
 	case TagInt16:
@@ -215,52 +283,150 @@ func (r *Reader) ReadValue(typeId TypeId) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
-		switch TypeId(itemTypeId) {
-		case TagInt8:
-			list := make([]int, length)
-			for i := 0; i < length; i++ {
-				x, err := r.ReadInt8()
-				list[i] = x
-				if err != nil {
-					return list, err
-				}
+		if err := r.enter(); err != nil {
+			return nil, err
+		}
+		defer r.leave()
+		return r.readList(itemTypeId, length)
+	}
+
+	return nil, errors.New(fmt.Sprintf("reading typeId %d not supported", typeId))
+}
+
+// readList reads the items of a list after its header. Lists of numbers are
+// slices of the number type and anything else is a []interface{}.
+func (r *Reader) readList(itemTypeId TypeId, length int) (interface{}, error) {
+	switch TypeId(itemTypeId) {
+	case TagInt8:
+		list := make([]int, length)
+		for i := 0; i < length; i++ {
+			x, err := r.ReadInt8()
+			list[i] = x
+			if err != nil {
+				return list, err
 			}
-			return list, nil
-		case TagFloat32:
-			list := make([]float32, length)
-			for i := 0; i < length; i++ {
-				x, err := r.ReadFloat32()
-				list[i] = x
-				if err != nil {
-					return list, err
-				}
+		}
+		return list, nil
+	case TagInt16:
+		list := make([]int, length)
+		for i := 0; i < length; i++ {
+			x, err := r.ReadInt16()
+			list[i] = x
+			if err != nil {
+				return list, err
 			}
-			return list, nil
-		case TagFloat64:
-			list := make([]float64, length)
-			for i := 0; i < length; i++ {
-				x, err := r.ReadFloat64()
-				list[i] = x
-				if err != nil {
-					return list, err
-				}
+		}
+		return list, nil
+	case TagInt32:
+		list := make([]int, length)
+		for i := 0; i < length; i++ {
+			x, err := r.ReadInt32()
+			list[i] = x
+			if err != nil {
+				return list, err
 			}
-			return list, nil
-		case TagStruct:
-			list := make([]interface{}, length)
-			for i := 0; i < length; i++ {
-				s := make(map[string]interface{})
-				s, err := r.ReadStruct()
-				list[i] = s
-				if err != nil {
-					return list, err
-				}
+		}
+		return list, nil
+	case TagInt64:
+		list := make([]int64, length)
+		for i := 0; i < length; i++ {
+			x, err := r.ReadInt64()
+			list[i] = int64(x)
+			if err != nil {
+				return list, err
 			}
-			return list, nil
-		default:
-			return nil, errors.New(fmt.Sprintf("reading lists of typeId %d not supported. length:%d", itemTypeId, length))
 		}
+		return list, nil
+	case TagFloat32:
+		list := make([]float32, length)
+		for i := 0; i < length; i++ {
+			x, err := r.ReadFloat32()
+			list[i] = x
+			if err != nil {
+				return list, err
+			}
+		}
+		return list, nil
+	case TagFloat64:
+		list := make([]float64, length)
+		for i := 0; i < length; i++ {
+			x, err := r.ReadFloat64()
+			list[i] = x
+			if err != nil {
+				return list, err
+			}
+		}
+		return list, nil
+	case TagStruct:
+		list := make([]interface{}, length)
+		for i := 0; i < length; i++ {
+			r.setIndex(i)
+			s := make(map[string]interface{})
+			s, err := r.ReadStruct()
+			list[i] = s
+			if err != nil {
+				return list, err
+			}
+		}
+		return list, nil
+	default:
+		list := make([]interface{}, length)
+		for i := 0; i < length; i++ {
+			r.setIndex(i)
+			x, err := r.ReadValue(itemTypeId)
+			list[i] = x
+			if err != nil {
+				return list, err
+			}
+		}
+		return list, nil
 	}
+}
 
-	return nil, errors.New(fmt.Sprintf("reading typeId %d not supported", typeId))
+// skipValue reads past a value without keeping it. Unlike ReadValue it
+// understands lists of every item type, so it can step over parts of a
+// file that nothing is interested in.
+func (r *Reader) skipValue(typeId TypeId) error {
+	switch typeId {
+	case TagStruct:
+		if err := r.enter(); err != nil {
+			return err
+		}
+		defer r.leave()
+		for {
+			itemTypeId, _, err := r.ReadTag()
+			if err != nil {
+				return err
+			}
+			if itemTypeId == TagStructEnd {
+				return nil
+			}
+			if err := r.skipValue(itemTypeId); err != nil {
+				return err
+			}
+		}
+	case TagList:
+		itemTypeId, length, err := r.ReadListHeader()
+		if err != nil {
+			return err
+		}
+		if err := r.enter(); err != nil {
+			return err
+		}
+		defer r.leave()
+		for i := 0; i < length; i++ {
+			r.setIndex(i)
+			if err := r.skipValue(itemTypeId); err != nil {
+				return err
+			}
+		}
+		return nil
+	case TagStructEnd:
+		return nil
+	case TagInt8, TagInt16, TagInt32, TagInt64, TagFloat32, TagFloat64, TagString, TagByteArray, TagIntArray, TagLongArray:
+		_, err := r.ReadValue(typeId)
+		return err
+	}
+
+	return errors.New(fmt.Sprintf("skipping typeId %d not supported", typeId))
 }
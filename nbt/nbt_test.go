@@ -0,0 +1,50 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseArraysAndLists(t *testing.T) {
+	var ints bytes.Buffer
+	ints.Write(tagHeader(TagList, "ints"))
+	ints.WriteByte(byte(TagInt32))
+	binary.Write(&ints, binary.BigEndian, int32(2))
+	binary.Write(&ints, binary.BigEndian, []int32{2, -3})
+
+	var longs bytes.Buffer
+	longs.Write(tagHeader(TagList, "longs"))
+	longs.WriteByte(byte(TagInt64))
+	binary.Write(&longs, binary.BigEndian, int32(3))
+	binary.Write(&longs, binary.BigEndian, []int64{2, 1 << 40, -1})
+
+	var intArray bytes.Buffer
+	intArray.Write(tagHeader(TagIntArray, "intArray"))
+	binary.Write(&intArray, binary.BigEndian, []int32{2, 1, 7})
+
+	var root, err = Parse(bytes.NewReader(tagStruct("",
+		ints.Bytes(), longs.Bytes(), intArray.Bytes(),
+		tagLongArray("longArray", []int64{-5}),
+		tagList("empty", TagStructEnd))))
+	checkError(t, err, nil)
+
+	if v, ok := Ints(root["ints"]); !ok || len(v) != 2 || v[1] != -3 {
+		t.Errorf("ints %v", root["ints"])
+	}
+	if v, ok := Ints(root["intArray"]); !ok || len(v) != 2 || v[1] != 7 {
+		t.Errorf("int array %v", root["intArray"])
+	}
+	if v, ok := Longs(root["longs"]); !ok || len(v) != 3 || v[1] != 1<<40 || v[2] != -1 {
+		t.Errorf("longs %v", root["longs"])
+	}
+	if v, ok := Longs(root["longArray"]); !ok || len(v) != 1 || v[0] != -5 {
+		t.Errorf("long array %v", root["longArray"])
+	}
+	if v, ok := Longs(root["empty"]); !ok || len(v) != 0 {
+		t.Errorf("empty list %v", root["empty"])
+	}
+	if _, ok := Longs(root["ints"]); ok {
+		t.Errorf("ints as longs")
+	}
+}
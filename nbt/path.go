@@ -0,0 +1,161 @@
+package nbt
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Path picks values out of parsed NBT, written like
+// Level.Sections[3].Palette[*].Name: names of struct tags separated by
+// dots, [n] for the nth item of a list or array, counting back from the end
+// when negative, and [*] for every item. Names with dots or brackets in
+// them can be quoted, as in Data."odd.name".
+type Path []pathStep
+
+type pathStep struct {
+	name  string
+	index int
+	kind  pathStepKind
+}
+
+type pathStepKind int
+
+const (
+	pathName pathStepKind = iota
+	pathIndex
+	pathEvery
+)
+
+func ParsePath(s string) (Path, error) {
+	var path Path
+	var i = 0
+	var expectName = true
+	for i < len(s) {
+		switch {
+		case s[i] == '[':
+			var end = strings.IndexByte(s[i:], ']')
+			if end == -1 {
+				return nil, errors.New(fmt.Sprintf("path %q has an unclosed [", s))
+			}
+			var inside = s[i+1 : i+end]
+			if inside == "*" {
+				path = append(path, pathStep{kind: pathEvery})
+			} else if n, err := strconv.Atoi(inside); err == nil {
+				path = append(path, pathStep{kind: pathIndex, index: n})
+			} else {
+				return nil, errors.New(fmt.Sprintf("path %q has [%s] rather than a number or *", s, inside))
+			}
+			i += end + 1
+			expectName = false
+		case s[i] == '.' && !expectName:
+			i++
+			expectName = true
+		case expectName && s[i] == '"':
+			var end = strings.IndexByte(s[i+1:], '"')
+			if end == -1 {
+				return nil, errors.New(fmt.Sprintf("path %q has an unclosed quote", s))
+			}
+			path = append(path, pathStep{name: s[i+1 : i+1+end]})
+			i += end + 2
+			expectName = false
+		case expectName:
+			var end = strings.IndexAny(s[i:], ".[")
+			if end == -1 {
+				end = len(s) - i
+			}
+			if end == 0 {
+				return nil, errors.New(fmt.Sprintf("path %q is missing a name at %d", s, i))
+			}
+			path = append(path, pathStep{name: s[i : i+end]})
+			i += end
+			expectName = false
+		default:
+			return nil, errors.New(fmt.Sprintf("path %q has %q where a . or [ should be", s, s[i]))
+		}
+	}
+	if expectName && len(s) != 0 {
+		return nil, errors.New(fmt.Sprintf("path %q ends without a name", s))
+	}
+	return path, nil
+}
+
+// Select is every value the path leads to from value, in order. Paths
+// that lead nowhere, such as to a missing name or past the end of a list,
+// select nothing.
+func (p Path) Select(value interface{}) []interface{} {
+	var values = []interface{}{value}
+	for _, step := range p {
+		var next []interface{}
+		for _, v := range values {
+			next = step.apply(v, next)
+		}
+		values = next
+	}
+	return values
+}
+
+// Select is the values path selects from value, after parsing the path.
+func Select(value interface{}, path string) ([]interface{}, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Select(value), nil
+}
+
+func (step pathStep) apply(value interface{}, selected []interface{}) []interface{} {
+	if step.kind == pathName {
+		if s, ok := value.(map[string]interface{}); ok {
+			if v, ok := s[step.name]; ok {
+				selected = append(selected, v)
+			}
+		}
+		return selected
+	}
+
+	// Lists and arrays are slices of several types
+	var list = reflect.ValueOf(value)
+	if value == nil || list.Kind() != reflect.Slice {
+		return selected
+	}
+	if step.kind == pathEvery {
+		for i := 0; i < list.Len(); i++ {
+			selected = append(selected, list.Index(i).Interface())
+		}
+		return selected
+	}
+
+	var i = step.index
+	if i < 0 {
+		i += list.Len()
+	}
+	if i >= 0 && i < list.Len() {
+		selected = append(selected, list.Index(i).Interface())
+	}
+	return selected
+}
+
+func (p Path) String() string {
+	var b strings.Builder
+	for i, step := range p {
+		switch step.kind {
+		case pathName:
+			if i != 0 {
+				b.WriteByte('.')
+			}
+			if strings.ContainsAny(step.name, ".[]\"") || step.name == "" {
+				b.WriteString(`"` + step.name + `"`)
+			} else {
+				b.WriteString(step.name)
+			}
+		case pathIndex:
+			fmt.Fprintf(&b, "[%d]", step.index)
+		case pathEvery:
+			b.WriteString("[*]")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	var root, err = ParseSNBT(`{Level: {Sections: [
+		{Y: 0b, Palette: [{Name: "minecraft:stone"}, {Name: "minecraft:dirt"}]},
+		{Y: 1b, Palette: [{Name: "minecraft:air"}]}],
+		Heights: [L; 1L, 2L, 3L], "odd.name": 5}}`)
+	checkError(t, err, nil)
+
+	for _, test := range []struct {
+		path     string
+		expected []interface{}
+	}{
+		{"Level.Sections[0].Palette[*].Name", []interface{}{"minecraft:stone", "minecraft:dirt"}},
+		{"Level.Sections[*].Palette[0].Name", []interface{}{"minecraft:stone", "minecraft:air"}},
+		{"Level.Sections[-1].Y", []interface{}{1}},
+		{"Level.Heights[1]", []interface{}{int64(2)}},
+		{`Level."odd.name"`, []interface{}{5}},
+		{"Level.Sections[2].Y", nil},
+		{"Level.Missing[*]", nil},
+	} {
+		var selected, err = Select(root, test.path)
+		checkError(t, err, nil)
+		if !reflect.DeepEqual(selected, test.expected) {
+			t.Errorf("%s selected %v not %v", test.path, selected, test.expected)
+		}
+	}
+
+	for _, bad := range []string{"Level.", "Level..Y", "Level[x]", "Level[0", `"open`, "Level[0]Y"} {
+		if _, err := ParsePath(bad); err == nil {
+			t.Errorf("no error parsing %q", bad)
+		}
+	}
+
+	var path, _ = ParsePath(`Level.Sections[-1].Palette[*]."a.b"`)
+	if path.String() != `Level.Sections[-1].Palette[*]."a.b"` {
+		t.Errorf("path %s", path)
+	}
+}
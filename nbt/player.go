@@ -0,0 +1,52 @@
+package nbt
+
+import (
+	"errors"
+	"io"
+	"strconv"
+)
+
+var (
+	PosListNotFound = errors.New("Pos list of three doubles not found")
+)
+
+// Player is where a player was when the world was last saved.
+type Player struct {
+	X, Y, Z   float64
+	Dimension string // A dimension name, or a number before 1.16
+}
+
+// ReadPlayerDat reads a playerdata/<uuid>.dat (or, before 1.7,
+// players/<name>.dat) file.
+func ReadPlayerDat(reader io.Reader) (*Player, error) {
+	r, err := NewDecompressor(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	root, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return readPlayer(root)
+}
+
+// readPlayer reads a player struct, which is also found as the Player
+// struct in the level.dat of single player worlds.
+func readPlayer(data map[string]interface{}) (*Player, error) {
+	pos, ok := data["Pos"].([]float64)
+	if !ok || len(pos) != 3 {
+		return nil, PosListNotFound
+	}
+
+	player := &Player{X: pos[0], Y: pos[1], Z: pos[2]}
+	switch dimension := data["Dimension"].(type) {
+	case string:
+		player.Dimension = dimension
+	case int:
+		player.Dimension = strconv.Itoa(dimension)
+	}
+	return player, nil
+}
@@ -0,0 +1,60 @@
+package nbt
+
+import (
+	"io"
+	"sort"
+	"strconv"
+)
+
+// PointOfInterest is a block villagers and the game keep track of, such as
+// a bed, nether portal or workstation.
+type PointOfInterest struct {
+	Type        string // minecraft:home, minecraft:nether_portal, minecraft:armorer...
+	X, Y, Z     int
+	FreeTickets int
+}
+
+// ReadPOINbt reads the points of interest of a chunk from a poi region
+// chunk. Each section of the chunk keyed by its y has a list of Records.
+func ReadPOINbt(reader io.Reader) ([]PointOfInterest, error) {
+	root, err := Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, _ := root["Sections"].(map[string]interface{})
+
+	// Keep the points in order, bottom to top
+	keys := make([]string, 0, len(sections))
+	for key := range sections {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i])
+		b, _ := strconv.Atoi(keys[j])
+		return a < b
+	})
+
+	points := make([]PointOfInterest, 0)
+	for _, key := range keys {
+		section, _ := sections[key].(map[string]interface{})
+		records, _ := section["Records"].([]interface{})
+		for _, item := range records {
+			record, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pos, ok := Ints(record["pos"])
+			if !ok || len(pos) != 3 {
+				continue
+			}
+
+			point := PointOfInterest{X: pos[0], Y: pos[1], Z: pos[2]}
+			point.Type, _ = record["type"].(string)
+			point.FreeTickets, _ = record["free_tickets"].(int)
+			points = append(points, point)
+		}
+	}
+
+	return points, nil
+}
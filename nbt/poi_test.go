@@ -0,0 +1,36 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadPOI(t *testing.T) {
+	var record = func(poiType string, x, y, z int32) []byte {
+		var pos = tagHeader(TagIntArray, "pos")
+		for _, i := range []int32{3, x, y, z} {
+			pos = binary.BigEndian.AppendUint32(pos, uint32(i))
+		}
+		return tagStruct("", tagString("type", poiType), pos, tagInt("free_tickets", 1))
+	}
+
+	points, err := ReadPOINbt(bytes.NewReader(tagStruct("",
+		tagInt("DataVersion", 2975),
+		tagStruct("Sections",
+			tagStruct("4", tagByte("Valid", 1), tagList("Records", TagStruct, record("minecraft:home", 10, 70, -3))),
+			tagStruct("-2", tagByte("Valid", 1), tagList("Records", TagStruct, record("minecraft:nether_portal", 9, -20, -4))),
+		),
+	)))
+
+	checkError(t, err, nil)
+	if len(points) != 2 {
+		t.Fatalf("%d points not 2", len(points))
+	}
+	if p := points[0]; p.Type != "minecraft:nether_portal" || p.X != 9 || p.Y != -20 || p.Z != -4 {
+		t.Errorf("first point %v not the portal at 9,-20,-4", p)
+	}
+	if p := points[1]; p.Type != "minecraft:home" || p.FreeTickets != 1 {
+		t.Errorf("second point %v not a bed", p)
+	}
+}
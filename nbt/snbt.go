@@ -0,0 +1,429 @@
+package nbt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseSNBT reads the stringified NBT of commands and data packs, such as
+// {Name:"minecraft:oak_stairs",Properties:{facing:east}}, into the same
+// values Parse gives: every integer is an int, lists of numbers are slices
+// of their number type, and true and false are the bytes 1 and 0.
+func ParseSNBT(s string) (interface{}, error) {
+	var p = &snbtParser{s: s}
+	value, _, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.i != len(p.s) {
+		return nil, p.errorf("unexpected %q after the value", p.s[p.i:])
+	}
+	return value, nil
+}
+
+// FormatSNBT prints a value as stringified NBT, with the types Write would
+// give it and struct names in order.
+func FormatSNBT(value interface{}) string {
+	var b strings.Builder
+	formatSNBT(&b, value)
+	return b.String()
+}
+
+type snbtParser struct {
+	s string
+	i int
+}
+
+func (p *snbtParser) errorf(format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("snbt at %d: ", p.i) + fmt.Sprintf(format, args...))
+}
+
+func (p *snbtParser) skipSpace() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) != -1 {
+		p.i++
+	}
+}
+
+// next skips spaces and reports whether the next character is c, taking
+// it if it is.
+func (p *snbtParser) next(c byte) bool {
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *snbtParser) parseValue() (interface{}, TypeId, error) {
+	p.skipSpace()
+	if p.i == len(p.s) {
+		return nil, TagStructEnd, p.errorf("missing value")
+	}
+
+	switch c := p.s[p.i]; {
+	case c == '{':
+		p.i++
+		value, err := p.parseStruct()
+		return value, TagStruct, err
+	case c == '[':
+		p.i++
+		if p.i+1 < len(p.s) && p.s[p.i+1] == ';' {
+			var arrayType = p.s[p.i]
+			p.i += 2
+			return p.parseArray(arrayType)
+		}
+		value, err := p.parseList()
+		return value, TagList, err
+	case c == '"' || c == '\'':
+		value, err := p.parseQuoted()
+		return value, TagString, err
+	}
+
+	var word = p.parseWord()
+	if word == "" {
+		return nil, TagStructEnd, p.errorf("unexpected %q", p.s[p.i])
+	}
+	value, typeId := snbtWord(word)
+	return value, typeId, nil
+}
+
+func (p *snbtParser) parseStruct() (map[string]interface{}, error) {
+	var s = make(map[string]interface{})
+	if p.next('}') {
+		return s, nil
+	}
+	for {
+		p.skipSpace()
+		var name string
+		if p.i < len(p.s) && (p.s[p.i] == '"' || p.s[p.i] == '\'') {
+			var err error
+			if name, err = p.parseQuoted(); err != nil {
+				return s, err
+			}
+		} else if name = p.parseWord(); name == "" {
+			return s, p.errorf("missing name")
+		}
+		if !p.next(':') {
+			return s, p.errorf("missing : after %s", name)
+		}
+
+		value, _, err := p.parseValue()
+		if err != nil {
+			return s, err
+		}
+		s[name] = value
+
+		if p.next('}') {
+			return s, nil
+		}
+		if !p.next(',') {
+			return s, p.errorf("missing , or }")
+		}
+	}
+}
+
+func (p *snbtParser) parseList() (interface{}, error) {
+	var items []interface{}
+	var itemTypeId = TagStructEnd
+	if p.next(']') {
+		return []interface{}{}, nil
+	}
+	for {
+		value, typeId, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if len(items) != 0 && typeId != itemTypeId {
+			return nil, ErrMixedList
+		}
+		items, itemTypeId = append(items, value), typeId
+
+		if p.next(']') {
+			break
+		}
+		if !p.next(',') {
+			return nil, p.errorf("missing , or ]")
+		}
+	}
+
+	// The same slices that readList makes
+	switch itemTypeId {
+	case TagInt8, TagInt16, TagInt32:
+		var list = make([]int, len(items))
+		for i, item := range items {
+			list[i] = item.(int)
+		}
+		return list, nil
+	case TagInt64:
+		var list = make([]int64, len(items))
+		for i, item := range items {
+			list[i] = int64(item.(int))
+		}
+		return list, nil
+	case TagFloat32:
+		var list = make([]float32, len(items))
+		for i, item := range items {
+			list[i] = item.(float32)
+		}
+		return list, nil
+	case TagFloat64:
+		var list = make([]float64, len(items))
+		for i, item := range items {
+			list[i] = item.(float64)
+		}
+		return list, nil
+	}
+	return items, nil
+}
+
+// parseArray reads the items of a [B;...], [I;...] or [L;...] array.
+func (p *snbtParser) parseArray(arrayType byte) (interface{}, TypeId, error) {
+	if strings.IndexByte("BIL", arrayType) == -1 {
+		return nil, TagStructEnd, p.errorf("unknown array type %c", arrayType)
+	}
+
+	var numbers []int
+	if !p.next(']') {
+		for {
+			p.skipSpace()
+			var word = p.parseWord()
+			if word == "" {
+				return nil, TagStructEnd, p.errorf("missing number in a %c array", arrayType)
+			}
+			value, typeId := snbtWord(word)
+			number, ok := value.(int)
+			if !ok || typeId == TagInt8 && arrayType != 'B' || typeId == TagInt64 && arrayType != 'L' {
+				return nil, TagStructEnd, p.errorf("%q in a %c array", word, arrayType)
+			}
+			numbers = append(numbers, number)
+
+			if p.next(']') {
+				break
+			}
+			if !p.next(',') {
+				return nil, TagStructEnd, p.errorf("missing , or ]")
+			}
+		}
+	}
+
+	switch arrayType {
+	case 'B':
+		var bytes = make([]byte, len(numbers))
+		for i, n := range numbers {
+			bytes[i] = byte(n)
+		}
+		return bytes, TagByteArray, nil
+	case 'I':
+		if numbers == nil {
+			numbers = []int{}
+		}
+		return numbers, TagIntArray, nil
+	case 'L':
+		var longs = make([]int64, len(numbers))
+		for i, n := range numbers {
+			longs[i] = int64(n)
+		}
+		return longs, TagLongArray, nil
+	}
+	return nil, TagStructEnd, p.errorf("unknown array type %c", arrayType)
+}
+
+func (p *snbtParser) parseQuoted() (string, error) {
+	var quote = p.s[p.i]
+	p.i++
+	var b strings.Builder
+	for p.i < len(p.s) {
+		var c = p.s[p.i]
+		p.i++
+		if c == quote {
+			return b.String(), nil
+		}
+		if c == '\\' && p.i < len(p.s) {
+			c = p.s[p.i]
+			p.i++
+		}
+		b.WriteByte(c)
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseWord reads an unquoted name, number or string.
+func (p *snbtParser) parseWord() string {
+	var start = p.i
+	for p.i < len(p.s) && isSNBTWordByte(p.s[p.i]) {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+func isSNBTWordByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("_-.+", c) != -1
+}
+
+// snbtWord is the number or boolean an unquoted word stands for, going by
+// its suffix, or else the word as a string.
+func snbtWord(word string) (interface{}, TypeId) {
+	switch word {
+	case "true":
+		return 1, TagInt8
+	case "false":
+		return 0, TagInt8
+	}
+
+	var digits, suffix = word, byte(0)
+	if last := word[len(word)-1]; strings.IndexByte("bBsSlLfFdD", last) != -1 {
+		digits, suffix = word[:len(word)-1], last|0x20
+	}
+
+	var bits, typeId = 32, TagInt32
+	switch suffix {
+	case 'b':
+		bits, typeId = 8, TagInt8
+	case 's':
+		bits, typeId = 16, TagInt16
+	case 'l':
+		bits, typeId = 64, TagInt64
+	case 'f':
+		if f, err := strconv.ParseFloat(digits, 32); err == nil {
+			return float32(f), TagFloat32
+		}
+		return word, TagString
+	case 'd':
+		if f, err := strconv.ParseFloat(digits, 64); err == nil {
+			return f, TagFloat64
+		}
+		return word, TagString
+	}
+
+	if n, err := strconv.ParseInt(digits, 10, bits); err == nil {
+		return int(n), typeId
+	}
+	// Without a suffix, numbers with a decimal point are doubles
+	if suffix == 0 && strings.ContainsAny(digits, ".eE") {
+		if f, err := strconv.ParseFloat(digits, 64); err == nil && !math.IsInf(f, 0) {
+			return f, TagFloat64
+		}
+	}
+	return word, TagString
+}
+
+func formatSNBT(b *strings.Builder, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var names = make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteByte('{')
+		for i, name := range names {
+			if i != 0 {
+				b.WriteByte(',')
+			}
+			formatSNBTName(b, name)
+			b.WriteByte(':')
+			formatSNBT(b, v[name])
+		}
+		b.WriteByte('}')
+	case int8:
+		fmt.Fprintf(b, "%db", v)
+	case int16:
+		fmt.Fprintf(b, "%ds", v)
+	case int32:
+		fmt.Fprintf(b, "%d", v)
+	case int64:
+		fmt.Fprintf(b, "%dL", v)
+	case int:
+		if typeId, _ := TypeOf(v); typeId == TagInt64 {
+			fmt.Fprintf(b, "%dL", v)
+		} else {
+			fmt.Fprintf(b, "%d", v)
+		}
+	case float32:
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32) + "f")
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, 64) + "d")
+	case string:
+		formatSNBTString(b, v)
+	case []byte:
+		b.WriteString("[B;")
+		for i, x := range v {
+			if i != 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%db", int8(x))
+		}
+		b.WriteByte(']')
+	case []int:
+		b.WriteString("[I;")
+		for i, x := range v {
+			if i != 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%d", x)
+		}
+		b.WriteByte(']')
+	case []int64:
+		b.WriteString("[L;")
+		for i, x := range v {
+			if i != 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%dL", x)
+		}
+		b.WriteByte(']')
+	case []int32:
+		formatSNBTList(b, len(v), func(i int) interface{} { return v[i] })
+	case []float32:
+		formatSNBTList(b, len(v), func(i int) interface{} { return v[i] })
+	case []float64:
+		formatSNBTList(b, len(v), func(i int) interface{} { return v[i] })
+	case []interface{}:
+		formatSNBTList(b, len(v), func(i int) interface{} { return v[i] })
+	default:
+		fmt.Fprintf(b, "%q", fmt.Sprint(v))
+	}
+}
+
+func formatSNBTList(b *strings.Builder, length int, item func(i int) interface{}) {
+	b.WriteByte('[')
+	for i := 0; i < length; i++ {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		formatSNBT(b, item(i))
+	}
+	b.WriteByte(']')
+}
+
+// formatSNBTName leaves names unquoted when they can be.
+func formatSNBTName(b *strings.Builder, name string) {
+	for i := 0; i < len(name); i++ {
+		if !isSNBTWordByte(name[i]) {
+			formatSNBTString(b, name)
+			return
+		}
+	}
+	if name == "" {
+		formatSNBTString(b, name)
+		return
+	}
+	b.WriteString(name)
+}
+
+func formatSNBTString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+}
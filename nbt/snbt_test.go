@@ -0,0 +1,62 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSNBT(t *testing.T) {
+	var value, err = ParseSNBT(`{Name: "minecraft:oak_stairs", Properties: {facing: east, waterlogged: false},
+		Count: 3b, Damage: -2s, Seed: 123L, Health: 20.5f, x: 1.25, y: 2d, Pos: [1.5d, -60d],
+		Tags: ['a', "b\"c"], Blocks: [B; 1b, -1b], HeightMap: [I; 64, 65], States: [L; 1L], "odd name": []}`)
+	checkError(t, err, nil)
+
+	var expected = map[string]interface{}{
+		"Name":       "minecraft:oak_stairs",
+		"Properties": map[string]interface{}{"facing": "east", "waterlogged": 0},
+		"Count":      3,
+		"Damage":     -2,
+		"Seed":       123,
+		"Health":     float32(20.5),
+		"x":          1.25,
+		"y":          2.0,
+		"Pos":        []float64{1.5, -60},
+		"Tags":       []interface{}{"a", `b"c`},
+		"Blocks":     []byte{1, 255},
+		"HeightMap":  []int{64, 65},
+		"States":     []int64{1},
+		"odd name":   []interface{}{},
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("parsed %v\nnot %v", value, expected)
+	}
+
+	for _, bad := range []string{"{a:1", "{a 1}", "[1, a]", "[I; 1b]", `"open`, "{} x", "[I;1,]", "[{;:1}]", "[B;]]", "[L;"} {
+		if _, err := ParseSNBT(bad); err == nil {
+			t.Errorf("no error parsing %q", bad)
+		}
+	}
+}
+
+func TestFormatSNBT(t *testing.T) {
+	var snbt = FormatSNBT(map[string]interface{}{
+		"Name":     "minecraft:chest",
+		"Count":    int8(3),
+		"Seed":     1 << 40,
+		"Pos":      []float64{1.5, -60},
+		"Blocks":   []byte{1, 255},
+		"States":   []int64{1},
+		"Items":    []interface{}{map[string]interface{}{"id": `say "hi"`}},
+		"odd name": int16(2),
+	})
+	var expected = `{Blocks:[B;1b,-1b],Count:3b,Items:[{id:"say \"hi\""}],Name:"minecraft:chest",Pos:[1.5d,-60d],Seed:1099511627776L,States:[L;1L],"odd name":2s}`
+	if snbt != expected {
+		t.Errorf("formatted %s\nnot %s", snbt, expected)
+	}
+
+	var parsed, err = ParseSNBT(snbt)
+	checkError(t, err, nil)
+	if FormatSNBT(parsed) != `{Blocks:[B;1b,-1b],Count:3,Items:[{id:"say \"hi\""}],Name:"minecraft:chest",Pos:[1.5d,-60d],Seed:1099511627776L,States:[L;1L],"odd name":2}` {
+		t.Errorf("reformatted %s", FormatSNBT(parsed))
+	}
+}
@@ -0,0 +1,171 @@
+package nbt
+
+import (
+	"errors"
+	"io"
+)
+
+type TokenKind int
+
+const (
+	ValueToken TokenKind = iota // A tag holding a number, string or array
+	StartToken                  // The start of a struct or list
+	EndToken                    // The end of the innermost struct or list
+)
+
+// Token is one event of a Stream. List items have no name, and a list's
+// token has the type and number of its items.
+type Token struct {
+	Kind     TokenKind
+	Type     TypeId // TagStruct or TagList for the start and end tokens
+	Name     string
+	ItemType TypeId
+	Length   int
+}
+
+var (
+	ErrNoValue = errors.New("No tag to read the value of")
+)
+
+// Stream reads NBT a tag at a time, without building the tree of structs
+// that Parse does, so that a reader can pick out just the tags it wants and
+// step over the rest.
+//
+// After a value token the value can be read with Value, and Next skips it
+// otherwise. After a start token Next goes into the struct or list, while
+// Skip steps over all of it and Value reads all of it.
+type Stream struct {
+	r       *Reader
+	frames  []streamFrame
+	pending *Token // The last token, until its value is read or skipped
+}
+
+type streamFrame struct {
+	list      bool
+	itemType  TypeId
+	length    int
+	remaining int
+}
+
+func NewStream(r *Reader) *Stream {
+	return &Stream{r: r}
+}
+
+// Depth is how many structs and lists the stream is inside. The root struct
+// is at depth 1 once it has started.
+func (s *Stream) Depth() int {
+	return len(s.frames)
+}
+
+// Next reads the next token, or io.EOF after the last tag.
+func (s *Stream) Next() (Token, error) {
+	if s.pending != nil && s.pending.Kind == ValueToken {
+		if err := s.Skip(); err != nil {
+			return Token{}, err
+		}
+	}
+	s.pending = nil
+
+	var token Token
+	if len(s.frames) == 0 || !s.frames[len(s.frames)-1].list {
+		typeId, name, err := s.r.ReadTag()
+		if err != nil {
+			return Token{}, err
+		}
+		if typeId == TagStructEnd {
+			if len(s.frames) == 0 {
+				return Token{}, io.EOF
+			}
+			s.frames = s.frames[:len(s.frames)-1]
+			s.r.leave()
+			return Token{Kind: EndToken, Type: TagStruct}, nil
+		}
+		token = Token{Type: typeId, Name: name}
+	} else {
+		var frame = &s.frames[len(s.frames)-1]
+		if frame.remaining == 0 {
+			s.frames = s.frames[:len(s.frames)-1]
+			s.r.leave()
+			return Token{Kind: EndToken, Type: TagList}, nil
+		}
+		s.r.setIndex(frame.length - frame.remaining)
+		frame.remaining--
+		token = Token{Type: frame.itemType}
+	}
+
+	switch token.Type {
+	case TagStruct:
+		if err := s.r.enter(); err != nil {
+			return Token{}, err
+		}
+		token.Kind = StartToken
+		s.frames = append(s.frames, streamFrame{})
+	case TagList:
+		itemTypeId, length, err := s.r.ReadListHeader()
+		if err != nil {
+			return Token{}, err
+		}
+		if err := s.r.enter(); err != nil {
+			return Token{}, err
+		}
+		token.Kind, token.ItemType, token.Length = StartToken, itemTypeId, length
+		s.frames = append(s.frames, streamFrame{true, itemTypeId, length, length})
+	default:
+		token.Kind = ValueToken
+	}
+
+	s.pending = &token
+	return token, nil
+}
+
+// Value reads the value of the last token: a number, string or array, or
+// the whole of a struct or list that has just started.
+func (s *Stream) Value() (interface{}, error) {
+	if s.pending == nil {
+		return nil, ErrNoValue
+	}
+	var token = s.pending
+	s.pending = nil
+
+	if token.Kind == ValueToken {
+		return s.r.ReadValue(token.Type)
+	}
+
+	var frame = s.frames[len(s.frames)-1]
+	s.frames = s.frames[:len(s.frames)-1]
+	if frame.list {
+		defer s.r.leave()
+		return s.r.readList(frame.itemType, frame.remaining)
+	}
+	s.r.leave() // ReadStruct goes back in
+	return s.r.ReadStruct()
+}
+
+// Skip steps over the value of the last token, or the whole of a struct or
+// list that has just started.
+func (s *Stream) Skip() error {
+	if s.pending == nil {
+		return ErrNoValue
+	}
+	var token = s.pending
+	s.pending = nil
+
+	if token.Kind == ValueToken {
+		return s.r.skipValue(token.Type)
+	}
+
+	var frame = s.frames[len(s.frames)-1]
+	s.frames = s.frames[:len(s.frames)-1]
+	if frame.list {
+		defer s.r.leave()
+		for i := 0; i < frame.remaining; i++ {
+			s.r.setIndex(i)
+			if err := s.r.skipValue(frame.itemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s.r.leave() // skipValue goes back in
+	return s.r.skipValue(TagStruct)
+}
@@ -0,0 +1,56 @@
+package nbt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	var stream = NewStream(NewReader(bytes.NewReader(tagStruct("",
+		tagInt("DataVersion", 3465),
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", 0)),
+			tagStruct("", tagByte("Y", 1))),
+		tagStruct("Heightmaps", tagLongArray("WORLD_SURFACE", []int64{1, 2})),
+		tagString("Status", "minecraft:full")))))
+
+	var seen []string
+	for {
+		token, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		checkError(t, err, nil)
+
+		switch {
+		case token.Name == "DataVersion":
+			if value, err := stream.Value(); err != nil || value != 3465 {
+				t.Errorf("data version %v %v", value, err)
+			}
+		case token.Name == "sections":
+			if token.ItemType != TagStruct || token.Length != 2 {
+				t.Errorf("sections list of %d %d", token.Length, token.ItemType)
+			}
+		case token.Name == "Y" && stream.Depth() == 3:
+			if value, _ := stream.Value(); value == 1 {
+				stream.Next() // End of the second section
+				checkError(t, stream.Skip(), ErrNoValue)
+			}
+		case token.Name == "Heightmaps":
+			checkError(t, stream.Skip(), nil)
+		}
+		seen = append(seen, token.Name)
+	}
+
+	// The value of Status is skipped, and the end tokens have no names
+	var expected = []string{"", "DataVersion", "sections", "", "Y", "", "", "Y", "", "Heightmaps", "Status", ""}
+	if len(seen) != len(expected) {
+		t.Fatalf("tokens %q not %q", seen, expected)
+	}
+	for i := range seen {
+		if seen[i] != expected[i] {
+			t.Fatalf("tokens %q not %q", seen, expected)
+		}
+	}
+}
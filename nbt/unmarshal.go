@@ -0,0 +1,158 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Unmarshal parses uncompressed NBT into the struct v points to. See
+// UnmarshalStruct.
+func Unmarshal(data []byte, v interface{}) error {
+	root, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return UnmarshalStruct(root, v)
+}
+
+// UnmarshalStruct fills in the struct v points to from a parsed struct.
+// Each exported field is read from the tag named by its nbt field tag, such
+// as `nbt:"DataVersion"`, or else from the tag with the field's own name.
+// Fields tagged `nbt:"-"` and fields without a tag in s are left alone.
+//
+// Numbers convert to any number or bool field, lists and arrays to slices,
+// structs to structs or to map[string] fields, and anything goes into an
+// interface{} field as it was parsed.
+func UnmarshalStruct(s map[string]interface{}, v interface{}) error {
+	var ptr = reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return errors.New(fmt.Sprintf("nbt: can't unmarshal into %T, only a pointer to a struct", v))
+	}
+	return unmarshalValue(s, ptr.Elem(), "")
+}
+
+func unmarshalValue(value interface{}, to reflect.Value, path string) error {
+	var mismatch = func() error {
+		return errors.New(fmt.Sprintf("nbt: can't unmarshal %T into %s of type %s", value, strings.TrimPrefix(path, "."), to.Type()))
+	}
+
+	switch to.Kind() {
+	case reflect.Ptr:
+		if to.IsNil() {
+			to.Set(reflect.New(to.Type().Elem()))
+		}
+		return unmarshalValue(value, to.Elem(), path)
+	case reflect.Interface:
+		if value == nil {
+			to.Set(reflect.Zero(to.Type()))
+		} else if reflect.TypeOf(value).AssignableTo(to.Type()) {
+			to.Set(reflect.ValueOf(value))
+		} else {
+			return mismatch()
+		}
+	case reflect.Struct:
+		var s, ok = value.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		var t = to.Type()
+		for i := 0; i < t.NumField(); i++ {
+			var field = t.Field(i)
+			var name = field.Tag.Get("nbt")
+			if field.PkgPath != "" || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if item, ok := s[name]; ok {
+				if err := unmarshalValue(item, to.Field(i), path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		var s, ok = value.(map[string]interface{})
+		if !ok || to.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		var m = reflect.MakeMapWithSize(to.Type(), len(s))
+		for name, item := range s {
+			var itemValue = reflect.New(to.Type().Elem()).Elem()
+			if err := unmarshalValue(item, itemValue, path+"."+name); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(name).Convert(to.Type().Key()), itemValue)
+		}
+		to.Set(m)
+	case reflect.Slice:
+		var from = reflect.ValueOf(value)
+		if value == nil || from.Kind() != reflect.Slice {
+			return mismatch()
+		}
+		var slice = reflect.MakeSlice(to.Type(), from.Len(), from.Len())
+		for i := 0; i < from.Len(); i++ {
+			if err := unmarshalValue(from.Index(i).Interface(), slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		to.Set(slice)
+	case reflect.String:
+		var str, ok = value.(string)
+		if !ok {
+			return mismatch()
+		}
+		to.SetString(str)
+	case reflect.Bool:
+		var n, ok = unmarshalInt(value)
+		if !ok {
+			return mismatch()
+		}
+		to.SetBool(n != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n, ok = unmarshalInt(value)
+		if !ok || to.OverflowInt(n) {
+			return mismatch()
+		}
+		to.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// Unsigned fields take the bits of the signed value, as a byte
+		// array's bytes are
+		var n, ok = unmarshalInt(value)
+		if !ok {
+			return mismatch()
+		}
+		to.SetUint(uint64(n) & (1<<uint(to.Type().Bits()) - 1))
+	case reflect.Float32, reflect.Float64:
+		switch f := value.(type) {
+		case float32:
+			to.SetFloat(float64(f))
+		case float64:
+			to.SetFloat(f)
+		default:
+			var n, ok = unmarshalInt(value)
+			if !ok {
+				return mismatch()
+			}
+			to.SetFloat(float64(n))
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+func unmarshalInt(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case byte:
+		return int64(int8(n)), true
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	type section struct {
+		Y      int8
+		Blocks []byte
+	}
+	var chunk struct {
+		X         int32 `nbt:"xPos"`
+		Status    string
+		Inhabited int64 `nbt:"InhabitedTime"`
+		Sections  []section
+		Heights   map[string][]int64 `nbt:"Heightmaps"`
+		Pos       [][]float64        `nbt:"-"`
+		Light     *section
+		Any       interface{}
+		Full      bool
+		hidden    int
+	}
+
+	var data = tagStruct("",
+		tagInt("xPos", -7),
+		tagString("Status", "minecraft:full"),
+		tagLong("InhabitedTime", 1<<40),
+		tagByte("Full", 1),
+		tagInt("hidden", 3),
+		tagList("Sections", TagStruct,
+			tagStruct("", tagByte("Y", -4), tagByteArray("Blocks", []byte{1, 255}))),
+		tagStruct("Heightmaps", tagLongArray("WORLD_SURFACE", []int64{5, 6})),
+		tagStruct("Light", tagByte("Y", 2)),
+		tagString("Any", "anything"))
+	checkError(t, Unmarshal(data, &chunk), nil)
+
+	if chunk.X != -7 || chunk.Status != "minecraft:full" || chunk.Inhabited != 1<<40 || !chunk.Full || chunk.hidden != 0 {
+		t.Errorf("unmarshalled %+v", chunk)
+	}
+	if len(chunk.Sections) != 1 || chunk.Sections[0].Y != -4 || !reflect.DeepEqual(chunk.Sections[0].Blocks, []byte{1, 255}) {
+		t.Errorf("sections %+v", chunk.Sections)
+	}
+	if !reflect.DeepEqual(chunk.Heights, map[string][]int64{"WORLD_SURFACE": {5, 6}}) {
+		t.Errorf("height maps %v", chunk.Heights)
+	}
+	if chunk.Light == nil || chunk.Light.Y != 2 || chunk.Any != "anything" {
+		t.Errorf("light %v, any %v", chunk.Light, chunk.Any)
+	}
+
+	var wrong struct {
+		Sections []struct{ Y string }
+	}
+	var err = Unmarshal(data, &wrong)
+	if err == nil || err.Error() != "nbt: can't unmarshal int into Sections[0].Y of type string" {
+		t.Errorf("error %v", err)
+	}
+
+	var small struct {
+		X int8 `nbt:"InhabitedTime"`
+	}
+	if err := Unmarshal(data, &small); err == nil {
+		t.Errorf("no error for a long into an int8")
+	}
+
+	// A root that isn't a struct
+	if err := Unmarshal(tagInt("xPos", -7), &small); err == nil {
+		t.Errorf("no error for a root int")
+	}
+}
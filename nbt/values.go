@@ -0,0 +1,50 @@
+package nbt
+
+// The values of a parsed struct are typed by how they were saved, and the
+// game has saved the same data as arrays in some versions and as lists in
+// others. These accessors take either.
+
+// Ints is an IntArray, a ByteArray, or a list of bytes, shorts or ints. An
+// empty list has no item type to go by, so any empty list gives no ints.
+func Ints(value interface{}) ([]int, bool) {
+	switch v := value.(type) {
+	case []int:
+		return v, true
+	case []byte:
+		var ints = make([]int, len(v))
+		for i, b := range v {
+			ints[i] = int(int8(b))
+		}
+		return ints, true
+	case []interface{}:
+		if len(v) == 0 {
+			return []int{}, true
+		}
+	}
+	return nil, false
+}
+
+// Longs is a LongArray or a list of longs, or any empty list.
+func Longs(value interface{}) ([]int64, bool) {
+	switch v := value.(type) {
+	case []int64:
+		return v, true
+	case []interface{}:
+		if len(v) == 0 {
+			return []int64{}, true
+		}
+	}
+	return nil, false
+}
+
+// Structs is a list of structs, skipping anything else.
+func Structs(value interface{}) []map[string]interface{} {
+	list, _ := value.([]interface{})
+	var structs = make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(map[string]interface{}); ok {
+			structs = append(structs, s)
+		}
+	}
+	return structs
+}
@@ -0,0 +1,120 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// The arrays of a chunk are most of its size, and copying each of them out
+// of the decompressed chunk into slices of its own doubles the memory
+// reading a chunk takes. A Reader made by NewBytesReader can instead give
+// views of its arrays: slices of the very buffer it reads.
+//
+// A view belongs to the buffer. It's only good for as long as the buffer
+// isn't changed or reused, and it mustn't be changed itself. Anything kept
+// after that should be copied, as Longs and Ints do. ReadBytes, ReadInts,
+// ReadLongs and everything built on them, such as Parse and ReadStruct,
+// always copy and never return views.
+
+// LongView is a LongArray, as the big endian bytes it was saved as.
+type LongView []byte
+
+func (v LongView) Len() int {
+	return len(v) / 8
+}
+
+func (v LongView) At(i int) int64 {
+	return int64(binary.BigEndian.Uint64(v[8*i:]))
+}
+
+// Longs copies the view.
+func (v LongView) Longs() []int64 {
+	var longs = make([]int64, v.Len())
+	for i := range longs {
+		longs[i] = v.At(i)
+	}
+	return longs
+}
+
+// IntView is an IntArray, as the big endian bytes it was saved as.
+type IntView []byte
+
+func (v IntView) Len() int {
+	return len(v) / 4
+}
+
+func (v IntView) At(i int) int {
+	return int(int32(binary.BigEndian.Uint32(v[4*i:])))
+}
+
+// Ints copies the view.
+func (v IntView) Ints() []int {
+	var ints = make([]int, v.Len())
+	for i := range ints {
+		ints[i] = v.At(i)
+	}
+	return ints
+}
+
+// NewBytesReader reads uncompressed NBT from memory, giving views of its
+// arrays rather than copies.
+func NewBytesReader(data []byte) *Reader {
+	var reader = newReader(bytes.NewReader(data), false)
+	reader.data = data
+	return reader
+}
+
+// ReadBytesView reads a ByteArray. From a Reader made by NewBytesReader the
+// bytes are a view of its buffer, and from any other they're a copy.
+func (r *Reader) ReadBytesView() ([]byte, error) {
+	return r.readArrayView(1)
+}
+
+// ReadIntsView reads an IntArray like ReadBytesView.
+func (r *Reader) ReadIntsView() (IntView, error) {
+	return r.readArrayView(4)
+}
+
+// ReadLongsView reads a LongArray like ReadBytesView.
+func (r *Reader) ReadLongsView() (LongView, error) {
+	return r.readArrayView(8)
+}
+
+func (r *Reader) readArrayView(itemSize int) ([]byte, error) {
+	length, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkLength(length, itemSize); err != nil {
+		return nil, err
+	}
+	var n = length * itemSize
+
+	if r.data == nil || r.littleEndian {
+		var array = make([]byte, n)
+		if _, err := io.ReadFull(r.r, array); err != nil {
+			return nil, err
+		}
+		if r.littleEndian {
+			// Views are always big endian
+			for i := 0; i < n; i += itemSize {
+				for a, b := i, i+itemSize-1; a < b; a, b = a+1, b-1 {
+					array[a], array[b] = array[b], array[a]
+				}
+			}
+		}
+		return array, nil
+	}
+
+	// The buffer has read ahead of the tags
+	var start = int(r.size.n) - r.r.Buffered()
+	if start+n > len(r.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if _, err := r.r.Discard(n); err != nil {
+		return nil, err
+	}
+	// Capped, so that appending to a view can't write over the buffer
+	return r.data[start : start+n : start+n], nil
+}
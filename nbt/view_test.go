@@ -0,0 +1,93 @@
+package nbt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestArrayViews(t *testing.T) {
+	var data = tagStruct("",
+		tagByteArray("Bytes", []byte{1, 2, 3}),
+		tagLongArray("Longs", []int64{-1, 1 << 40}))
+
+	var r = NewBytesReader(data)
+	r.ReadTag()
+	r.ReadTag()
+	bytes, err := r.ReadBytesView()
+	checkError(t, err, nil)
+	if !reflect.DeepEqual(bytes, []byte{1, 2, 3}) || cap(bytes) != 3 {
+		t.Errorf("bytes %v with capacity %d", bytes, cap(bytes))
+	}
+	if &bytes[0] != &data[len(data)-len(tagLongArray("Longs", []int64{0, 0}))-4] {
+		t.Errorf("bytes copied rather than a view")
+	}
+
+	r.ReadTag()
+	longs, err := r.ReadLongsView()
+	checkError(t, err, nil)
+	if longs.Len() != 2 || longs.At(0) != -1 || longs.At(1) != 1<<40 {
+		t.Errorf("longs %v", longs.Longs())
+	}
+	if typeId, _, err := r.ReadTag(); typeId != TagStructEnd || err != nil {
+		t.Errorf("type %d and error %v after the views", typeId, err)
+	}
+}
+
+func TestArrayViewsCopied(t *testing.T) {
+	// Readers of streams and of little endian NBT copy
+	var r = NewReader(bytes.NewReader([]byte{0, 0, 0, 2, 0, 0, 0, 5, 0xff, 0xff, 0xff, 0xfe}))
+	ints, err := r.ReadIntsView()
+	checkError(t, err, nil)
+	if !reflect.DeepEqual(ints.Ints(), []int{5, -2}) {
+		t.Errorf("ints %v", ints.Ints())
+	}
+
+	r = NewLittleEndianReader(bytes.NewReader([]byte{2, 0, 0, 0, 5, 0, 0, 0, 0xfe, 0xff, 0xff, 0xff}))
+	ints, err = r.ReadIntsView()
+	checkError(t, err, nil)
+	if !reflect.DeepEqual(ints.Ints(), []int{5, -2}) {
+		t.Errorf("little endian ints %v", ints.Ints())
+	}
+}
+
+func TestTruncatedArrayView(t *testing.T) {
+	var data = tagLongArray("Longs", []int64{1, 2})
+	var r = NewBytesReader(data[:len(data)-1])
+	r.ReadTag()
+	if _, err := r.ReadLongsView(); err == nil {
+		t.Errorf("no error reading a truncated array")
+	}
+}
+
+func TestReadChunkBytes(t *testing.T) {
+	var states = packBlockStates(5, false)
+	var palette [][]byte
+	for i := 0; i < 5; i++ {
+		palette = append(palette, tagStruct("", tagString("Name", []string{"air", "stone", "dirt", "sand", "glass"}[i])))
+	}
+	var data = tagStruct("",
+		tagInt("xPos", 4),
+		tagList("sections", TagStruct,
+			tagStruct("", tagByte("Y", 2),
+				tagStruct("block_states",
+					tagList("palette", TagStruct, palette...),
+					tagLongArray("data", states.blockStates.Longs())),
+				tagByteArray("SkyLight", bytes.Repeat([]byte{0x3f}, 2048)))))
+
+	expected, err := ReadChunkNbt(bytes.NewReader(data))
+	checkError(t, err, nil)
+	chunk, err := ReadChunkBytes(data)
+	checkError(t, err, nil)
+	if !reflect.DeepEqual(chunk, expected) {
+		t.Errorf("chunk read from memory differs from the one read from a stream")
+	}
+
+	// Nothing of the chunk is left in the buffer
+	for i := range data {
+		data[i] = 0
+	}
+	if !reflect.DeepEqual(chunk, expected) {
+		t.Errorf("chunk changed along with its buffer")
+	}
+}
@@ -0,0 +1,233 @@
+package nbt
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+var (
+	ErrMixedList = errors.New("List items aren't all the same type")
+)
+
+type Writer struct {
+	w            *bufio.Writer
+	littleEndian bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{bufio.NewWriter(w), false}
+}
+
+// NewLittleEndianWriter writes the little endian NBT used by Bedrock (and
+// Pocket) Edition.
+func NewLittleEndianWriter(w io.Writer) *Writer {
+	return &Writer{bufio.NewWriter(w), true}
+}
+
+// Write writes root as an unnamed struct of uncompressed NBT, the way Parse
+// reads it.
+//
+// The tag types come from the Go types of the values. Parse reads every
+// integer as an int, so ints are written as TAG_Int when they fit and
+// TAG_Long when they don't, and []int and []int64 as arrays rather than
+// lists. Use int8, int16, int32 and int64 (and []int32 for a list) to write
+// exactly the type needed.
+func Write(w io.Writer, root map[string]interface{}) error {
+	var nw = NewWriter(w)
+	if err := nw.WriteTag(TagStruct, ""); err != nil {
+		return err
+	}
+	if err := nw.WriteValue(root); err != nil {
+		return err
+	}
+	return nw.Flush()
+}
+
+// WriteGzip writes root gzipped, like level.dat and the old chunk files.
+func WriteGzip(w io.Writer, root map[string]interface{}) error {
+	var gz = gzip.NewWriter(w)
+	if err := Write(gz, root); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// WriteZlib writes root zlib compressed, like the chunks of region files.
+func WriteZlib(w io.Writer, root map[string]interface{}) error {
+	var zw = zlib.NewWriter(w)
+	if err := Write(zw, root); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) WriteTag(typeId TypeId, name string) error {
+	if err := w.w.WriteByte(byte(typeId)); err != nil || typeId == TagStructEnd {
+		return err
+	}
+	return w.WriteString(name)
+}
+
+func (w *Writer) WriteString(s string) error {
+	if len(s) > math.MaxUint16 {
+		return errors.New(fmt.Sprintf("string of %d bytes is too long", len(s)))
+	}
+	w.writeUintN(2, uint64(len(s)))
+	_, err := w.w.WriteString(s)
+	return err
+}
+
+// WriteValue writes a value without its tag, with the type TypeOf gives.
+func (w *Writer) WriteValue(value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// Sorted so the same struct always writes the same bytes
+		var names = make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var typeId, ok = TypeOf(v[name])
+			if !ok {
+				return errors.New(fmt.Sprintf("writing %s of type %T not supported", name, v[name]))
+			}
+			if err := w.WriteTag(typeId, name); err != nil {
+				return err
+			}
+			if err := w.WriteValue(v[name]); err != nil {
+				return err
+			}
+		}
+		return w.WriteTag(TagStructEnd, "")
+	case int8:
+		w.writeUintN(1, uint64(v))
+	case int16:
+		w.writeUintN(2, uint64(v))
+	case int32:
+		w.writeUintN(4, uint64(v))
+	case int64:
+		w.writeUintN(8, uint64(v))
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			w.writeUintN(8, uint64(v))
+		} else {
+			w.writeUintN(4, uint64(v))
+		}
+	case float32:
+		w.writeUintN(4, uint64(math.Float32bits(v)))
+	case float64:
+		w.writeUintN(8, math.Float64bits(v))
+	case string:
+		return w.WriteString(v)
+	case []byte:
+		w.writeUintN(4, uint64(len(v)))
+		w.w.Write(v)
+	case []int:
+		w.writeUintN(4, uint64(len(v)))
+		for _, x := range v {
+			w.writeUintN(4, uint64(x))
+		}
+	case []int64:
+		w.writeUintN(4, uint64(len(v)))
+		for _, x := range v {
+			w.writeUintN(8, uint64(x))
+		}
+	case []int32:
+		w.writeListHeader(TagInt32, len(v))
+		for _, x := range v {
+			w.writeUintN(4, uint64(x))
+		}
+	case []float32:
+		w.writeListHeader(TagFloat32, len(v))
+		for _, x := range v {
+			w.writeUintN(4, uint64(math.Float32bits(x)))
+		}
+	case []float64:
+		w.writeListHeader(TagFloat64, len(v))
+		for _, x := range v {
+			w.writeUintN(8, math.Float64bits(x))
+		}
+	case []interface{}:
+		// Empty lists have no items to take a type from
+		var itemTypeId = TagStructEnd
+		if len(v) != 0 {
+			itemTypeId, _ = TypeOf(v[0])
+		}
+		w.writeListHeader(itemTypeId, len(v))
+		for _, item := range v {
+			if typeId, _ := TypeOf(item); typeId != itemTypeId {
+				return ErrMixedList
+			}
+			if err := w.WriteValue(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New(fmt.Sprintf("writing type %T not supported", value))
+	}
+	return nil
+}
+
+// TypeOf is the tag type that WriteValue writes a value as.
+func TypeOf(value interface{}) (TypeId, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return TagStruct, true
+	case int8:
+		return TagInt8, true
+	case int16:
+		return TagInt16, true
+	case int32:
+		return TagInt32, true
+	case int64:
+		return TagInt64, true
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return TagInt64, true
+		}
+		return TagInt32, true
+	case float32:
+		return TagFloat32, true
+	case float64:
+		return TagFloat64, true
+	case string:
+		return TagString, true
+	case []byte:
+		return TagByteArray, true
+	case []int:
+		return TagIntArray, true
+	case []int64:
+		return TagLongArray, true
+	case []int32, []float32, []float64, []interface{}:
+		return TagList, true
+	}
+	return TagStructEnd, false
+}
+
+func (w *Writer) writeListHeader(itemTypeId TypeId, length int) {
+	w.w.WriteByte(byte(itemTypeId))
+	w.writeUintN(4, uint64(length))
+}
+
+// writeUintN writes the low n bytes of a. Errors stay with the buffered
+// writer until it's flushed.
+func (w *Writer) writeUintN(n int, a uint64) {
+	for i := 0; i < n; i++ {
+		if w.littleEndian {
+			w.w.WriteByte(byte(a >> uint(8*i)))
+		} else {
+			w.w.WriteByte(byte(a >> uint(8*(n-1-i))))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	var root = map[string]interface{}{
+		"DataVersion": 3465,
+		"LastPlayed":  int64(1 << 40),
+		"Seed":        1 << 40,
+		"Flag":        int8(-1),
+		"Short":       int16(300),
+		"Name":        "New World",
+		"Pos":         []float64{1.5, -60, 8.25},
+		"Rotation":    []float32{90, 0},
+		"Blocks":      []byte{1, 2, 3},
+		"HeightMap":   []int{64, 65},
+		"BlockStates": []int64{-1, 1 << 50},
+		"Empty":       []interface{}{},
+		"Level": map[string]interface{}{
+			"Entities": []interface{}{
+				map[string]interface{}{"id": "minecraft:cow"},
+				map[string]interface{}{"id": "minecraft:pig", "Tags": []interface{}{"a", "bc"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	checkError(t, WriteGzip(&buf, root), nil)
+	var gz, err = gzip.NewReader(&buf)
+	checkError(t, err, nil)
+	parsed, err := Parse(gz)
+	checkError(t, err, nil)
+
+	// Parse reads every integer as an int
+	root["LastPlayed"] = 1 << 40
+	root["Flag"] = -1
+	root["Short"] = 300
+	if !reflect.DeepEqual(parsed, root) {
+		t.Errorf("parsed %v\nnot %v", parsed, root)
+	}
+}
+
+func TestWriteSameBytes(t *testing.T) {
+	// Written in name order
+	var expected = tagStruct("",
+		tagByte("Y", -4),
+		tagLongArray("data", []int64{1, 2}),
+		tagList("palette", TagString, tagString("", "minecraft:plains")[3:]),
+		tagInt("xPos", 7))
+
+	var buf bytes.Buffer
+	checkError(t, Write(&buf, map[string]interface{}{
+		"Y":       int8(-4),
+		"xPos":    7,
+		"data":    []int64{1, 2},
+		"palette": []interface{}{"minecraft:plains"},
+	}), nil)
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("wrote % x\nnot % x", buf.Bytes(), expected)
+	}
+
+	checkError(t, Write(&buf, map[string]interface{}{"mixed": []interface{}{1, "a"}}), ErrMixedList)
+}
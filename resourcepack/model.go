@@ -0,0 +1,146 @@
+package resourcepack
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// BlockState picks the models of a block by its properties: either a
+// variant for each combination of them, or parts that each apply when the
+// properties match, for blocks such as fences made of a post and arms.
+type BlockState struct {
+	Variants  map[string]Variants `json:"variants"`
+	Multipart []Part              `json:"multipart"`
+}
+
+// Variants are models to choose between at random, by their weights. A
+// single variant can be saved without the array around it.
+type Variants []Variant
+
+// Variant is a model turned about the center of the block, first about the
+// x axis and then about the y axis, in steps of 90 degrees.
+type Variant struct {
+	Model  string `json:"model"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	UVLock bool   `json:"uvlock"`
+	Weight int    `json:"weight"`
+}
+
+func (v *Variants) UnmarshalJSON(data []byte) error {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		var variant Variant
+		if err := json.Unmarshal(data, &variant); err != nil {
+			return err
+		}
+		*v = Variants{variant}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]Variant)(v))
+}
+
+// Part is models that apply when the properties match When: each of its
+// properties has one of the values separated by | in it, or for OR and
+// AND, any or all of the conditions in the list match. A part without a
+// When always applies.
+type Part struct {
+	When  map[string]interface{} `json:"when"`
+	Apply Variants               `json:"apply"`
+}
+
+func (p Part) Applies(properties map[string]string) bool {
+	return conditionMatches(p.When, properties)
+}
+
+func conditionMatches(when map[string]interface{}, properties map[string]string) bool {
+	for key, value := range when {
+		switch key {
+		case "OR", "AND":
+			var conditions, _ = value.([]interface{})
+			var matched = false
+			for _, c := range conditions {
+				var condition, _ = c.(map[string]interface{})
+				var matches = conditionMatches(condition, properties)
+				if key == "AND" && !matches {
+					return false
+				}
+				matched = matched || matches
+			}
+			if key == "OR" && !matched {
+				return false
+			}
+		default:
+			var found = false
+			for _, option := range strings.Split(fmt.Sprint(value), "|") {
+				if properties[key] == option {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParseProperties reads the properties of a variant's key, such as
+// facing=east,half=bottom. Blocks without properties have the key "", or
+// "normal" in packs from before 1.13.
+func ParseProperties(key string) map[string]string {
+	var properties = make(map[string]string)
+	if key == "" || key == "normal" {
+		return properties
+	}
+	for _, property := range strings.Split(key, ",") {
+		if kv := strings.SplitN(property, "=", 2); len(kv) == 2 {
+			properties[kv[0]] = kv[1]
+		}
+	}
+	return properties
+}
+
+// Model is a block model. Elements come from its parents if it has none of
+// its own.
+type Model struct {
+	Parent   string    `json:"parent"`
+	Elements []Element `json:"elements"`
+}
+
+// Element is a box of a model, from and to in sixteenths of a block, with
+// the faces it has by their directions: down, up, north, south, west and
+// east.
+type Element struct {
+	From     [3]float64       `json:"from"`
+	To       [3]float64       `json:"to"`
+	Rotation *ElementRotation `json:"rotation"`
+	Faces    map[string]Face  `json:"faces"`
+}
+
+// ElementRotation turns an element by an angle in degrees about an x, y or
+// z axis through Origin, and with Rescale stretches it across the other two
+// axes to fill the block again.
+type ElementRotation struct {
+	Origin  [3]float64 `json:"origin"`
+	Axis    string     `json:"axis"`
+	Angle   float64    `json:"angle"`
+	Rescale bool       `json:"rescale"`
+}
+
+// Face is a side of an element. It's hidden when the block next to it in
+// the CullFace direction is solid.
+type Face struct {
+	CullFace string `json:"cullface"`
+	Texture  string `json:"texture"`
+}
+
+// IsCube reports whether every element of a model fills the whole block.
+func (m *Model) IsCube() bool {
+	for _, e := range m.Elements {
+		if e.From != [3]float64{0, 0, 0} || e.To != [3]float64{16, 16, 16} || e.Rotation != nil && e.Rotation.Angle != 0 {
+			return false
+		}
+	}
+	return len(m.Elements) != 0
+}
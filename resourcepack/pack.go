@@ -0,0 +1,183 @@
+package resourcepack
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Pack is the block states and block models of a resource pack, read from
+// its folder or zip, or from the client jar, which has the vanilla pack in
+// it. Nothing else of the pack is read.
+type Pack struct {
+	files  map[string][]byte // By their slash separated paths in the pack
+	models map[string]*Model
+}
+
+// Open reads a pack from a folder or a zip.
+func Open(path string) (*Pack, error) {
+	var fi, err = os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pack = &Pack{files: make(map[string][]byte), models: make(map[string]*Model)}
+	if fi.IsDir() {
+		err = pack.readDir(path)
+	} else {
+		err = pack.readZip(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(pack.files) == 0 {
+		return nil, errors.New(fmt.Sprintf("%s has no block states or models", path))
+	}
+	return pack, nil
+}
+
+// isPackFile reports whether a path in a pack is a block state or model,
+// such as assets/minecraft/models/block/stone.json.
+func isPackFile(name string) bool {
+	var parts = strings.SplitN(name, "/", 4)
+	return len(parts) == 4 && parts[0] == "assets" && (parts[2] == "blockstates" || parts[2] == "models") && strings.HasSuffix(name, ".json")
+}
+
+func (pack *Pack) readDir(dir string) error {
+	var assets = filepath.Join(dir, "assets")
+	if _, err := os.Stat(assets); err != nil {
+		return err
+	}
+	return filepath.Walk(assets, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		var rel, relErr = filepath.Rel(dir, path)
+		if relErr != nil {
+			return relErr
+		}
+		if rel = filepath.ToSlash(rel); isPackFile(rel) {
+			var data, readErr = ioutil.ReadFile(path)
+			if readErr != nil {
+				return readErr
+			}
+			pack.files[rel] = data
+		}
+		return nil
+	})
+}
+
+func (pack *Pack) readZip(archivePath string) error {
+	var archive, err = zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, f := range archive.File {
+		var name = strings.TrimPrefix(f.Name, "/")
+		if !isPackFile(name) {
+			continue
+		}
+		var r, openErr = f.Open()
+		if openErr != nil {
+			return openErr
+		}
+		var data, readErr = ioutil.ReadAll(r)
+		r.Close()
+		if readErr != nil {
+			return readErr
+		}
+		pack.files[name] = data
+	}
+	return nil
+}
+
+// BlockStateNames are the names of the blocks the pack has states for, with
+// their namespaces, such as minecraft:oak_stairs, sorted.
+func (pack *Pack) BlockStateNames() []string {
+	var names []string
+	for name := range pack.files {
+		var parts = strings.SplitN(name, "/", 4)
+		if parts[2] == "blockstates" && !strings.Contains(parts[3], "/") {
+			names = append(names, parts[1]+":"+strings.TrimSuffix(parts[3], ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BlockState reads the states of a block by its name. The namespace is
+// minecraft if the name doesn't have one.
+func (pack *Pack) BlockState(name string) (*BlockState, error) {
+	var namespace, path = splitName(name)
+	var data, ok = pack.files["assets/"+namespace+"/blockstates/"+path+".json"]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No block state %s", name))
+	}
+
+	var state = new(BlockState)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.New(fmt.Sprintf("Block state %s: %v", name, err))
+	}
+	return state, nil
+}
+
+// Model reads a block model by its name, such as block/oak_stairs, taking
+// its elements from its parents if it has none of its own. Models with
+// built in parents, such as chests, have no elements.
+func (pack *Pack) Model(name string) (*Model, error) {
+	return pack.model(name, 0)
+}
+
+// maxParents is how long a chain of parents can be before it's taken to go
+// round in a loop.
+const maxParents = 32
+
+func (pack *Pack) model(name string, depth int) (*Model, error) {
+	var namespace, path = splitName(name)
+	var key = namespace + ":" + path
+	if model, ok := pack.models[key]; ok {
+		return model, nil
+	}
+	if depth > maxParents {
+		return nil, errors.New(fmt.Sprintf("Model %s has parents in a loop", name))
+	}
+
+	var model = new(Model)
+	if strings.HasPrefix(path, "builtin/") {
+		pack.models[key] = model
+		return model, nil
+	}
+
+	var data, ok = pack.files["assets/"+namespace+"/models/"+path+".json"]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No model %s", name))
+	}
+	if err := json.Unmarshal(data, model); err != nil {
+		return nil, errors.New(fmt.Sprintf("Model %s: %v", name, err))
+	}
+
+	if model.Elements == nil && model.Parent != "" {
+		var parent, err = pack.model(model.Parent, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		model.Elements = parent.Elements
+	}
+	pack.models[key] = model
+	return model, nil
+}
+
+func splitName(name string) (namespace, path string) {
+	if i := strings.Index(name, ":"); i != -1 {
+		return name[:i], name[i+1:]
+	}
+	return "minecraft", name
+}
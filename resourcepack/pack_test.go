@@ -0,0 +1,146 @@
+package resourcepack
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testPackFiles = map[string]string{
+	"assets/minecraft/blockstates/anvil.json": `{"variants": {
+		"facing=east": {"model": "minecraft:block/anvil", "y": 270},
+		"facing=north": [{"model": "minecraft:block/anvil", "y": 180}, {"model": "block/anvil", "weight": 2}]}}`,
+	"assets/minecraft/blockstates/hopper.json": `{"multipart": [
+		{"apply": {"model": "block/hopper"}},
+		{"when": {"OR": [{"facing": "down"}, {"enabled": false}]}, "apply": {"model": "block/hopper", "x": 90}}]}`,
+	"assets/minecraft/models/block/anvil.json": `{"parent": "block/template_anvil", "textures": {"top": "block/anvil_top"}}`,
+	"assets/minecraft/models/block/template_anvil.json": `{"elements": [{"from": [2, 0, 2], "to": [14, 4, 14],
+		"rotation": {"origin": [8, 8, 8], "axis": "y", "angle": 45, "rescale": true},
+		"faces": {"down": {"texture": "#top", "cullface": "down"}, "up": {"texture": "#top"}}}]}`,
+	"assets/minecraft/models/block/hopper.json":     `{"parent": "builtin/entity"}`,
+	"assets/minecraft/models/block/loop.json":       `{"parent": "block/loop"}`,
+	"assets/minecraft/textures/block/anvil_top.png": "",
+	"pack.mcmeta": "{}",
+}
+
+func TestOpenPack(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "resourcepack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, data := range testPackFiles {
+		var path = filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pack, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPack(t, pack)
+}
+
+func TestOpenZippedPack(t *testing.T) {
+	var file, err = ioutil.TempFile("", "pack*.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	var archive = zip.NewWriter(file)
+	for name, data := range testPackFiles {
+		var w, err = archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	archive.Close()
+	file.Close()
+
+	pack, err := Open(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pack.files) != 6 {
+		t.Errorf("%d files read, not the 6 block states and models", len(pack.files))
+	}
+	checkPack(t, pack)
+}
+
+func checkPack(t *testing.T, pack *Pack) {
+	if names := pack.BlockStateNames(); !reflect.DeepEqual(names, []string{"minecraft:anvil", "minecraft:hopper"}) {
+		t.Errorf("block states %v", names)
+	}
+
+	anvil, err := pack.BlockState("anvil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variants := anvil.Variants["facing=east"]; len(variants) != 1 || variants[0].Y != 270 {
+		t.Errorf("east variants %+v", variants)
+	}
+	if variants := anvil.Variants["facing=north"]; len(variants) != 2 || variants[1].Weight != 2 {
+		t.Errorf("north variants %+v", variants)
+	}
+
+	model, err := pack.Model("minecraft:block/anvil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(model.Elements) != 1 || model.Elements[0].To != [3]float64{14, 4, 14} || !model.Elements[0].Rotation.Rescale {
+		t.Errorf("anvil elements %+v", model.Elements)
+	}
+	if face := model.Elements[0].Faces["down"]; face.CullFace != "down" || face.Texture != "#top" {
+		t.Errorf("anvil bottom %+v", face)
+	}
+	if model.IsCube() {
+		t.Errorf("anvil is a cube")
+	}
+
+	hopper, err := pack.BlockState("minecraft:hopper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hopper.Multipart) != 2 || !hopper.Multipart[0].Applies(nil) {
+		t.Fatalf("hopper parts %+v", hopper.Multipart)
+	}
+	if model, err := pack.Model(hopper.Multipart[0].Apply[0].Model); err != nil || len(model.Elements) != 0 {
+		t.Errorf("built in model %+v, error %v", model, err)
+	}
+
+	if _, err := pack.Model("block/loop"); err == nil {
+		t.Errorf("no error for parents in a loop")
+	}
+	if _, err := pack.Model("block/missing"); err == nil {
+		t.Errorf("no error for a missing model")
+	}
+}
+
+func TestPartApplies(t *testing.T) {
+	var part = Part{When: map[string]interface{}{
+		"OR": []interface{}{
+			map[string]interface{}{"north": "side|up", "east": "none"},
+			map[string]interface{}{"powered": true},
+		},
+	}}
+	for _, c := range []struct {
+		properties map[string]string
+		applies    bool
+	}{
+		{ParseProperties("east=none,north=up"), true},
+		{ParseProperties("east=side,north=up"), false},
+		{ParseProperties("powered=true"), true},
+		{ParseProperties("normal"), false},
+	} {
+		if part.Applies(c.properties) != c.applies {
+			t.Errorf("part applies to %v isn't %v", c.properties, c.applies)
+		}
+	}
+}